@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"log"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// Activity list the queries running in the database server, the idle connections and
+// the connection of the listing itself are left out
+func Activity() (jsonData []byte, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = db.QueryRow(statements.Activity).Scan(&jsonData)
+	return
+}
+
+// CancelBackend cancel the query running in the backend of pid, cancelled is false
+// when there is no backend with that pid
+func CancelBackend(pid int) (cancelled bool, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = db.QueryRow(statements.CancelBackend, pid).Scan(&cancelled)
+	return
+}
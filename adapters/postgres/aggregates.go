@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrMixedAggregates err throw when aggregates and columns are selected without _groupby
+var ErrMixedAggregates = errors.New("the columns selected with aggregates must be grouped with _groupby")
+
+// groupFunctions are the aggregates of `_select=FUNC:FIELD`
+var groupFunctions = map[string]bool{
+	"SUM":      true,
+	"AVG":      true,
+	"MAX":      true,
+	"MIN":      true,
+	"MEDIAN":   true,
+	"STDDEV":   true,
+	"VARIANCE": true,
+	"COUNT":    true,
+	// ARRAY_AGG and STRING_AGG collapse the rows of the groups in an array or a text
+	"ARRAY_AGG":  true,
+	"STRING_AGG": true,
+}
+
+// isGroupFunction check if field is an aggregate FUNC:FIELD, e.g. sum:amount or count:*
+func isGroupFunction(field string) bool {
+	parts := strings.SplitN(field, ":", 2)
+	return len(parts) == 2 && groupFunctions[strings.ToUpper(parts[0])]
+}
+
+// isAggregate check if the selected field is an aggregate, FUNC:FIELD or FUNC(FIELD)
+func isAggregate(field string) bool {
+	column, _ := splitAlias(field)
+	if isGroupFunction(column) {
+		return true
+	}
+	m := orderFunctionRegex.FindStringSubmatch(column)
+	return m != nil && groupFunctions[strings.ToUpper(m[1])]
+}
+
+// isAggregateCall check the FUNC(column) form of the aggregates, written as is in the
+// select, the column is a valid identifier or *
+func isAggregateCall(column string) bool {
+	m := orderFunctionRegex.FindStringSubmatch(column)
+	return m != nil && groupFunctions[strings.ToUpper(m[1])] && (m[2] == "*" || validIdentifier(m[2]))
+}
+
+// selectGroupFunction write the aggregate of FUNC:FIELD, the field is a column or *
+// counted with count:*. The delimiter of string_agg:FIELD:DELIMITER is a value numbered
+// placeholderID
+func selectGroupFunction(field string, placeholderID int) (aggregate string, values []interface{}, err error) {
+	parts := strings.SplitN(field, ":", 3)
+	column := parts[1]
+	switch strings.ToUpper(parts[0]) {
+	case "ARRAY_AGG":
+		if !validIdentifier(column) || len(parts) != 2 {
+			err = fmt.Errorf("invalid aggregate %s, use array_agg:column", field)
+			return
+		}
+		aggregate = fmt.Sprintf("ARRAY_AGG(%s)", quoteColumn(column))
+		return
+	case "STRING_AGG":
+		if !validIdentifier(column) || len(parts) != 3 {
+			err = fmt.Errorf("invalid aggregate %s, use string_agg:column:delimiter", field)
+			return
+		}
+		aggregate = fmt.Sprintf("STRING_AGG(%s::text, $%d)", quoteColumn(column), placeholderID)
+		values = append(values, parts[2])
+		return
+	}
+	if len(parts) == 3 {
+		column = fmt.Sprintf("%s:%s", column, parts[2])
+	}
+	switch {
+	case column == "*" && strings.ToUpper(parts[0]) == "COUNT":
+	case !validIdentifier(column):
+		err = fmt.Errorf("invalid aggregate %s", field)
+		return
+	default:
+		column = quoteColumn(column)
+	}
+	aggregate, err = NormalizeGroupFunction(fmt.Sprintf("%s:%s", parts[0], column))
+	return
+}
+
+// splitSelect split the fields of `_select` on the commas, the comma right after
+// `string_agg:column:` is its delimiter, e.g. string_agg:tag:,:as:tags
+func splitSelect(fields string) (columns []string) {
+	parts := strings.Split(fields, ",")
+	for i := 0; i < len(parts); i++ {
+		field := parts[i]
+		if f := strings.SplitN(field, ":", 3); len(f) == 3 && f[2] == "" && strings.ToUpper(f[0]) == "STRING_AGG" && i+1 < len(parts) {
+			// the next part is the rest of the field when it's empty or an alias
+			field = fmt.Sprint(field, ",")
+			if next := parts[i+1]; next == "" || strings.HasPrefix(next, ":as:") {
+				field = fmt.Sprint(field, next)
+				i++
+			}
+		}
+		if len(field) > 0 {
+			columns = append(columns, field)
+		}
+	}
+	return
+}
+
+// CheckSelectAggregates reject the selects of aggregates and columns without `_groupby`,
+// only the aggregates are selected in the single row of their result
+func CheckSelectAggregates(r *http.Request, cols []string) (err error) {
+	if r.URL.Query().Get("_groupby") != "" {
+		return
+	}
+	var aggregates, columns bool
+	for _, col := range cols {
+		if isAggregate(col) {
+			aggregates = true
+		} else {
+			columns = true
+		}
+	}
+	if aggregates && columns {
+		err = ErrMixedAggregates
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIsAggregate(t *testing.T) {
+	var testCases = []struct {
+		field    string
+		expected bool
+	}{
+		{"sum:amount", true},
+		{"COUNT:*", true},
+		{"avg:amount:as:average", true},
+		{"SUM(amount)", true},
+		{"sum(amount):as:total", true},
+		{"amount", false},
+		{"name:as:full_name", false},
+		{"lower(name)", false},
+		{"price*quantity:as:total", false},
+		{"array_agg:tag:as:tags", true},
+		{"string_agg:tag:,", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.field)
+		if aggregate := isAggregate(tc.field); aggregate != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, aggregate)
+		}
+	}
+}
+
+func TestCheckSelectAggregates(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		cols        []string
+		err         error
+	}{
+		{"columns", "/prest/public/test", []string{"id", "name"}, nil},
+		{"single aggregate", "/prest/public/test", []string{"sum:amount"}, nil},
+		{"count and sum", "/prest/public/test", []string{"count:*", "sum:amount"}, nil},
+		{"aggregate and column", "/prest/public/test", []string{"name", "sum:amount"}, ErrMixedAggregates},
+		{"aggregate and column grouped", "/prest/public/test?_groupby=name", []string{"name", "sum:amount"}, nil},
+		{"all columns", "/prest/public/test", []string{"*"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = CheckSelectAggregates(r, tc.cols); err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestSelectGroupFunction(t *testing.T) {
+	var testCases = []struct {
+		description string
+		field       string
+		aggregate   string
+		values      []interface{}
+		err         bool
+	}{
+		{"sum", "sum:amount", "SUM(amount)", nil, false},
+		{"count all", "count:*", "COUNT(*)", nil, false},
+		{"array_agg", "array_agg:tag", "ARRAY_AGG(tag)", nil, false},
+		{"string_agg", "string_agg:tag:,", "STRING_AGG(tag::text, $2)", []interface{}{","}, false},
+		{"string_agg with colons", "STRING_AGG:tag: : ", "STRING_AGG(tag::text, $2)", []interface{}{" : "}, false},
+		{"string_agg with an injection", "string_agg:tag:'); DROP TABLE tags; --", "STRING_AGG(tag::text, $2)", []interface{}{"'); DROP TABLE tags; --"}, false},
+		{"string_agg without delimiter", "string_agg:tag", "", nil, true},
+		{"array_agg of all", "array_agg:*", "", nil, true},
+		{"array_agg with extra arguments", "array_agg:tag:x", "", nil, true},
+		{"invalid column", "string_agg:tag;DROP:,", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		aggregate, values, err := selectGroupFunction(tc.field, 2)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if aggregate != tc.aggregate {
+			t.Errorf("expected %s, got %s", tc.aggregate, aggregate)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected values %v, got %v", tc.values, values)
+		}
+	}
+}
+
+func TestSplitSelect(t *testing.T) {
+	var testCases = []struct {
+		description string
+		fields      string
+		expected    []string
+	}{
+		{"columns", "user_id,tag", []string{"user_id", "tag"}},
+		{"comma delimiter last", "user_id,string_agg:tag:,", []string{"user_id", "string_agg:tag:,"}},
+		{"comma delimiter with alias", "user_id,string_agg:tag:,:as:tags,name", []string{"user_id", "string_agg:tag:,:as:tags", "name"}},
+		{"comma delimiter before a column", "string_agg:tag:,user_id", []string{"string_agg:tag:,", "user_id"}},
+		{"other delimiter", "string_agg:tag:;:as:tags,user_id", []string{"string_agg:tag:;:as:tags", "user_id"}},
+		{"empty fields", "user_id,,tag", []string{"user_id", "tag"}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if columns := splitSelect(tc.fields); !reflect.DeepEqual(columns, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, columns)
+		}
+	}
+}
+
+func TestSelectFieldsWithAggregateValues(t *testing.T) {
+	sql, values, err := SelectFieldsWithValues([]string{"user_id", "case:user_id:$gt:1:new:old:as:age", "string_agg:tag:,:as:tags"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT user_id,CASE WHEN user_id > $1 THEN $2 ELSE $3 END AS age,STRING_AGG(tag::text, $4) AS tags FROM"
+	if sql != expected {
+		t.Errorf("expected %s, got %s", expected, sql)
+	}
+	if expectedValues := []interface{}{"1", "new", "old", ","}; !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+
+	if _, err = SelectFields([]string{"user_id", "string_agg:tag:,"}); err != ErrCaseValues {
+		t.Errorf("expected %v, got %v", ErrCaseValues, err)
+	}
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrBulkDeleteIDs err throw when the bulk delete has no ids
+var ErrBulkDeleteIDs = errors.New("ids must be a non empty array")
+
+// BulkDelete is the body of the bulk delete, the rows with the ids in column are
+// deleted, e.g. {"ids": [1, 2, 3], "column": "id"}
+type BulkDelete struct {
+	IDs    []interface{} `json:"ids"`
+	Column string        `json:"column"`
+}
+
+// BulkDeleteByRequest create a single DELETE ... WHERE column = ANY($1) of the ids in
+// the body, sent as a single array parameter whatever their count, the column must be
+// a column of the table
+func BulkDeleteByRequest(r *http.Request, database, schema, table string) (statement Statement, err error) {
+	var body BulkDelete
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return
+	}
+	defer r.Body.Close()
+
+	columns, err := TableColumns(r.Context(), database, schema, table)
+	if err != nil {
+		return
+	}
+	return bulkDeleteStatement(body, database, schema, table, columns)
+}
+
+func bulkDeleteStatement(body BulkDelete, database, schema, table string, columns []string) (statement Statement, err error) {
+	if body.Column == "" {
+		err = errors.New("column is required")
+		return
+	}
+	if !containsString(columns, body.Column) {
+		err = fmt.Errorf("column %s does not exist in %s.%s", body.Column, schema, table)
+		return
+	}
+	if len(body.IDs) == 0 {
+		err = ErrBulkDeleteIDs
+		return
+	}
+
+	// the array is sent as text, postgres cast its elements to the type of the column
+	ids := make([]string, 0, len(body.IDs))
+	for _, id := range body.IDs {
+		switch id := id.(type) {
+		case string:
+			ids = append(ids, id)
+		case float64:
+			ids = append(ids, strconv.FormatFloat(id, 'f', -1, 64))
+		default:
+			err = fmt.Errorf("invalid id %v, use strings or numbers", id)
+			return
+		}
+	}
+
+	statement.SQL = fmt.Sprintf("%s WHERE %s = ANY($1)",
+		fmt.Sprintf(statements.DeleteQuery, QuoteName(database), QuoteName(schema), QuoteName(table)),
+		quoteIdentifier(body.Column))
+	statement.Values = []interface{}{pq.Array(ids)}
+	return
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestBulkDeleteStatement(t *testing.T) {
+	columns := []string{"id", "name", "userId"}
+
+	var testCases = []struct {
+		description string
+		body        BulkDelete
+		sql         string
+		values      []interface{}
+		err         bool
+	}{
+		{"ids", BulkDelete{IDs: []interface{}{float64(1), float64(2)}, Column: "id"}, "\nDELETE FROM prest.public.test WHERE \"id\" = ANY($1)", []interface{}{pq.Array([]string{"1", "2"})}, false},
+		{"text ids", BulkDelete{IDs: []interface{}{"a"}, Column: "name"}, "\nDELETE FROM prest.public.test WHERE \"name\" = ANY($1)", []interface{}{pq.Array([]string{"a"})}, false},
+		{"column with upper case", BulkDelete{IDs: []interface{}{float64(1)}, Column: "userId"}, "\nDELETE FROM prest.public.test WHERE \"userId\" = ANY($1)", []interface{}{pq.Array([]string{"1"})}, false},
+		{"decimal and large ids", BulkDelete{IDs: []interface{}{float64(1.5), float64(12345678901)}, Column: "id"}, "\nDELETE FROM prest.public.test WHERE \"id\" = ANY($1)", []interface{}{pq.Array([]string{"1.5", "12345678901"})}, false},
+		{"without column", BulkDelete{IDs: []interface{}{float64(1)}}, "", nil, true},
+		{"nonexistent column", BulkDelete{IDs: []interface{}{float64(1)}, Column: "id; drop table test"}, "", nil, true},
+		{"without ids", BulkDelete{Column: "id"}, "", nil, true},
+		{"invalid ids", BulkDelete{IDs: []interface{}{nil}, Column: "id"}, "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		statement, err := bulkDeleteStatement(tc.body, "prest", "public", "test", columns)
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if statement.SQL != tc.sql {
+			t.Errorf("expected %q, got %q", tc.sql, statement.SQL)
+		}
+		if !reflect.DeepEqual(statement.Values, tc.values) {
+			t.Errorf("expected %v, got %v", tc.values, statement.Values)
+		}
+	}
+}
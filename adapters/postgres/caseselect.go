@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCaseWithoutAlias err throw when a case `_select` field has no alias
+var ErrCaseWithoutAlias = errors.New("case requires an alias, e.g. case:amount:$gt:100:high:low:as:tier")
+
+// ErrCaseValues err throw when a case, coalesce or string_agg `_select` field with
+// literals is used without SelectFieldsWithValues
+var ErrCaseValues = errors.New("the literals of a case, coalesce or string_agg in _select require the values of the select")
+
+// caseField is a CASE WHEN of `_select`, Column is the compared column
+type caseField struct {
+	Column string
+	SQL    string
+	Values []interface{}
+}
+
+func isCaseField(field string) bool {
+	return strings.HasPrefix(field, "case:")
+}
+
+// parseCaseField write case:COLUMN:OPERATOR:VALUE:THEN:ELSE as
+// CASE WHEN COLUMN OPERATOR $1 THEN $2 ELSE $3 END, the value and the literals are
+// parameters numbered from placeholderID. Only ELSE can contain colons
+func parseCaseField(field string, placeholderID int) (cf caseField, err error) {
+	parts := strings.SplitN(field, ":", 6)
+	if len(parts) != 6 {
+		err = fmt.Errorf("invalid case %s, use case:column:operator:value:then:else", field)
+		return
+	}
+	if !validIdentifier(parts[1]) {
+		err = fmt.Errorf("invalid case column %s", parts[1])
+		return
+	}
+	operator, err := GetQueryOperator(parts[2])
+	if err != nil || !containsString(comparisonOperators, operator) {
+		err = fmt.Errorf("invalid case operator %s", parts[2])
+		return
+	}
+
+	cf.Column = strings.Trim(parts[1], `"`)
+	cf.SQL = fmt.Sprintf("CASE WHEN %s %s $%d THEN $%d ELSE $%d END",
+		quoteColumn(parts[1]), operator, placeholderID, placeholderID+1, placeholderID+2)
+	cf.Values = []interface{}{parts[3], parts[4], parts[5]}
+	return
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCaseField(t *testing.T) {
+	var testCases = []struct {
+		description string
+		field       string
+		sql         string
+		values      []interface{}
+		err         bool
+	}{
+		{"greater than", "case:amount:$gt:100:high:low", "CASE WHEN amount > $3 THEN $4 ELSE $5 END", []interface{}{"100", "high", "low"}, false},
+		{"equal", "case:status:$eq:1:active:inactive", "CASE WHEN status = $3 THEN $4 ELSE $5 END", []interface{}{"1", "active", "inactive"}, false},
+		{"else with colons", "case:created:$lt:2020:old:since 10:00", "CASE WHEN created < $3 THEN $4 ELSE $5 END", []interface{}{"2020", "old", "since 10:00"}, false},
+		{"injection in the literals", "case:amount:$gt:100:high' OR 1=1 --:low", "CASE WHEN amount > $3 THEN $4 ELSE $5 END", []interface{}{"100", "high' OR 1=1 --", "low"}, false},
+		{"without else", "case:amount:$gt:100:high", "", nil, true},
+		{"invalid column", "case:amount;DROP:$gt:100:high:low", "", nil, true},
+		{"invalid operator", "case:amount:$in:100:high:low", "", nil, true},
+		{"unknown operator", "case:amount:$gtt:100:high:low", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		cf, err := parseCaseField(tc.field, 3)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if tc.err {
+			continue
+		}
+		if cf.SQL != tc.sql {
+			t.Errorf("expected %s, got %s", tc.sql, cf.SQL)
+		}
+		if !reflect.DeepEqual(cf.Values, tc.values) {
+			t.Errorf("expected values %v, got %v", tc.values, cf.Values)
+		}
+	}
+}
+
+func TestSelectFieldsWithValues(t *testing.T) {
+	sql, values, err := SelectFieldsWithValues([]string{"amount", "case:amount:$gt:100:high:low:as:tier", "case:amount:$lte:0:empty:full:as:stock"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT amount,CASE WHEN amount > $1 THEN $2 ELSE $3 END AS tier,CASE WHEN amount <= $4 THEN $5 ELSE $6 END AS stock FROM"
+	if sql != expected {
+		t.Errorf("expected %s, got %s", expected, sql)
+	}
+	if expectedValues := []interface{}{"100", "high", "low", "0", "empty", "full"}; !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+
+	if _, _, err = SelectFieldsWithValues([]string{"case:amount:$gt:100:high:low"}, 1); err != ErrCaseWithoutAlias {
+		t.Errorf("expected %v, got %v", ErrCaseWithoutAlias, err)
+	}
+	if _, err = SelectFields([]string{"case:amount:$gt:100:high:low:as:tier"}); err != ErrCaseValues {
+		t.Errorf("expected %v, got %v", ErrCaseValues, err)
+	}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/nuveo/prest/statements"
+)
+
+// ChangedByRequest check `_changed=true`, the updated rows are returned with the
+// _changed list of the columns whose value changed instead of rows_affected
+func ChangedByRequest(r *http.Request) bool {
+	return r.URL.Query().Get("_changed") == "true"
+}
+
+// UpdateChangedQuery build the update of setSyntax of the rows matching where comparing
+// them with their values before the update, where is empty to update all rows
+func UpdateChangedQuery(database, schema, table, setSyntax, where string) string {
+	database, schema, table = QuoteName(database), QuoteName(schema), QuoteName(table)
+	if where != "" {
+		where = fmt.Sprint(" WHERE ", where)
+	}
+	return fmt.Sprintf(statements.UpdateChanged,
+		database, schema, table, setSyntax,
+		table, database, schema, table, where,
+		table,
+		table)
+}
+
+// UpdateChangedWithLock execute the query of UpdateChangedQuery holding the advisory
+// lock of lock, nil is no lock, in a transaction of the isolation level. rows is the
+// number of updated rows, the transaction is rolled back when ctx is done
+func UpdateChangedWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, rows int64, err error) {
+	defer timeQuery(ctx, SQL, params)()
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, SQL, params...).Scan(&jsonData, &rows)
+		if err != nil {
+			logger(ctx).Printf("could not update: %s\n Error: %v\n", SQL, err)
+		}
+		return err
+	})
+	return
+}
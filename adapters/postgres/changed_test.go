@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChangedByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    bool
+	}{
+		{"update returning the changed columns", "/prest/public/test?_changed=true&id=$eq.1", true},
+		{"plain update", "/prest/public/test?id=$eq.1", false},
+		{"disabled changed columns", "/prest/public/test?_changed=false", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("PATCH", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed := ChangedByRequest(r); changed != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, changed)
+		}
+	}
+}
+
+func TestUpdateChangedQuery(t *testing.T) {
+	var testCases = []struct {
+		description string
+		where       string
+		expected    []string
+	}{
+		{
+			"update with where",
+			"id = $1",
+			[]string{
+				"UPDATE prest.public.test SET name = $2",
+				"FROM (SELECT ctid AS _ctid, to_jsonb(test) AS _old FROM prest.public.test WHERE id = $1 FOR UPDATE) _prev",
+				"WHERE test.ctid = _prev._ctid",
+				"RETURNING to_jsonb(test) AS _new, _prev._old",
+			},
+		},
+		{
+			"update without where",
+			"",
+			[]string{"FROM (SELECT ctid AS _ctid, to_jsonb(test) AS _old FROM prest.public.test FOR UPDATE) _prev"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql := UpdateChangedQuery("prest", "public", "test", "name = $2", tc.where)
+		for _, expected := range tc.expected {
+			if !strings.Contains(sql, expected) {
+				t.Errorf("expected %q in %q", expected, sql)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCoalesceWithoutAlias err throw when a coalesce `_select` field has no alias
+var ErrCoalesceWithoutAlias = errors.New("coalesce requires an alias, e.g. coalesce:nickname:name:as:display")
+
+// coalesceField is a COALESCE of `_select`, Columns are its column arguments
+type coalesceField struct {
+	Columns []string
+	SQL     string
+	Values  []interface{}
+}
+
+func isCoalesceField(field string) bool {
+	return strings.HasPrefix(field, "coalesce:")
+}
+
+// parseCoalesceField write coalesce:ARG:ARG... as COALESCE(ARG, ARG...), the arguments
+// are columns or literals, the strings between single quotes and the numbers,
+// written as parameters numbered from placeholderID. A quote inside a string is doubled
+func parseCoalesceField(field string, placeholderID int) (cf coalesceField, err error) {
+	args, err := splitCoalesceArgs(strings.TrimPrefix(field, "coalesce:"))
+	if err != nil {
+		return
+	}
+	if len(args) < 2 {
+		err = fmt.Errorf("invalid coalesce %s, use coalesce:column:column_or_literal...", field)
+		return
+	}
+
+	sqlArgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "'"):
+			cf.Values = append(cf.Values, strings.Replace(arg[1:len(arg)-1], "''", "'", -1))
+		case numericLiteralRegex.MatchString(arg):
+			cf.Values = append(cf.Values, arg)
+		case validIdentifier(arg):
+			cf.Columns = append(cf.Columns, strings.Trim(arg, `"`))
+			sqlArgs = append(sqlArgs, quoteColumn(arg))
+			continue
+		default:
+			err = fmt.Errorf("invalid coalesce argument %s", arg)
+			return
+		}
+		sqlArgs = append(sqlArgs, fmt.Sprintf("$%d", placeholderID))
+		placeholderID++
+	}
+	if len(cf.Columns) == 0 {
+		err = fmt.Errorf("invalid coalesce %s, a column is required", field)
+		return
+	}
+	cf.SQL = fmt.Sprintf("COALESCE(%s)", strings.Join(sqlArgs, ", "))
+	return
+}
+
+// splitCoalesceArgs split the arguments of a coalesce on the colons out of the quoted
+// strings, e.g. name:'10:00' is name and '10:00'
+func splitCoalesceArgs(s string) (args []string, err error) {
+	var quoted bool
+	start := 0
+	for i, c := range s {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			args = append(args, s[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		err = fmt.Errorf("unterminated string in coalesce %s", s)
+		return
+	}
+	args = append(args, s[start:])
+	for _, arg := range args {
+		if arg == "" {
+			err = fmt.Errorf("empty argument in coalesce %s", s)
+			return
+		}
+		// a string is a single quoted token, e.g. not 'a'b'
+		if strings.Contains(arg, "'") && (!strings.HasPrefix(arg, "'") || !strings.HasSuffix(arg, "'") || len(arg) < 2 ||
+			strings.Contains(strings.Replace(arg[1:len(arg)-1], "''", "", -1), "'")) {
+			err = fmt.Errorf("invalid string %s in coalesce", arg)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCoalesceField(t *testing.T) {
+	var testCases = []struct {
+		description string
+		field       string
+		sql         string
+		columns     []string
+		values      []interface{}
+		err         bool
+	}{
+		{"columns", "coalesce:nickname:name", "COALESCE(nickname, name)", []string{"nickname", "name"}, nil, false},
+		{"string literal", "coalesce:nickname:'anonymous'", "COALESCE(nickname, $3)", []string{"nickname"}, []interface{}{"anonymous"}, false},
+		{"number literal", "coalesce:age:-1", "COALESCE(age, $3)", []string{"age"}, []interface{}{"-1"}, false},
+		{"columns and literals", "coalesce:nickname:name:'none'", "COALESCE(nickname, name, $3)", []string{"nickname", "name"}, []interface{}{"none"}, false},
+		{"literal with colons", "coalesce:opens:'10:00'", "COALESCE(opens, $3)", []string{"opens"}, []interface{}{"10:00"}, false},
+		{"literal with a quote", "coalesce:nickname:'it''s me'", "COALESCE(nickname, $3)", []string{"nickname"}, []interface{}{"it's me"}, false},
+		{"injection in the literal", "coalesce:nickname:''') OR 1=1 --'", "COALESCE(nickname, $3)", []string{"nickname"}, []interface{}{"') OR 1=1 --"}, false},
+		{"single argument", "coalesce:nickname", "", nil, nil, true},
+		{"only literals", "coalesce:'a':'b'", "", nil, nil, true},
+		{"empty argument", "coalesce:nickname::name", "", nil, nil, true},
+		{"invalid column", "coalesce:nickname:name;DROP", "", nil, nil, true},
+		{"unterminated string", "coalesce:nickname:'anonymous", "", nil, nil, true},
+		{"quote inside a string", "coalesce:nickname:'a'b'", "", nil, nil, true},
+		{"function", "coalesce:nickname:lower(name)", "", nil, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		cf, err := parseCoalesceField(tc.field, 3)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if tc.err {
+			continue
+		}
+		if cf.SQL != tc.sql {
+			t.Errorf("expected %s, got %s", tc.sql, cf.SQL)
+		}
+		if !reflect.DeepEqual(cf.Columns, tc.columns) {
+			t.Errorf("expected columns %v, got %v", tc.columns, cf.Columns)
+		}
+		if !reflect.DeepEqual(cf.Values, tc.values) {
+			t.Errorf("expected values %v, got %v", tc.values, cf.Values)
+		}
+	}
+}
+
+func TestSelectFieldsWithCoalesce(t *testing.T) {
+	sql, values, err := SelectFieldsWithValues([]string{"id", "coalesce:nickname:name:'anonymous':as:display", "coalesce:age:0:as:age"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT id,COALESCE(nickname, name, $1) AS display,COALESCE(age, $2) AS age FROM"
+	if sql != expected {
+		t.Errorf("expected %s, got %s", expected, sql)
+	}
+	if expectedValues := []interface{}{"anonymous", "0"}; !reflect.DeepEqual(values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values)
+	}
+
+	if _, _, err = SelectFieldsWithValues([]string{"coalesce:nickname:name"}, 1); err != ErrCoalesceWithoutAlias {
+		t.Errorf("expected %v, got %v", ErrCoalesceWithoutAlias, err)
+	}
+	if _, err = SelectFields([]string{"coalesce:nickname:name:as:display"}); err != nil {
+		t.Errorf("expected no error without literals, got %v", err)
+	}
+	if _, err = SelectFields([]string{"coalesce:nickname:'anonymous':as:display"}); err != ErrCaseValues {
+		t.Errorf("expected %v, got %v", ErrCaseValues, err)
+	}
+}
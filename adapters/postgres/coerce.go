@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/statements"
+)
+
+// columnCoercions are the expressions of the column types coerced in the JSON output,
+// by the names of pg_typeof, enabled by money_as_number, bytea_as_base64 and
+// timestamps. The booleans are already true and false in the JSON built by postgres
+func columnCoercions() map[string]string {
+	coercions := make(map[string]string)
+	if config.PrestConf.JSONMoneyAsNumber {
+		coercions["money"] = "%s::numeric"
+	}
+	if config.PrestConf.JSONByteaAsBase64 {
+		coercions["bytea"] = "encode(%s, 'base64')"
+	}
+	if config.PrestConf.JSONPreserveNumericPrecision {
+		// the digits of numeric are kept as written by postgres
+		coercions["numeric"] = "%s::text"
+	}
+	switch config.PrestConf.JSONTimestamps {
+	case TimestampRFC3339:
+		// the timestamptz are already written with their offset, e.g. 2017-06-01T12:00:00-03:00
+		coercions["timestamp without time zone"] = "CASE WHEN isfinite(%[1]s) THEN (to_json(%[1]s) #>> '{}') || 'Z' ELSE %[1]s::text END"
+	case TimestampEpochMS:
+		epoch := "CASE WHEN isfinite(%[1]s) THEN floor(extract(epoch FROM %[1]s) * 1000)::bigint END"
+		coercions["timestamp without time zone"] = epoch
+		coercions["timestamp with time zone"] = epoch
+	}
+	return coercions
+}
+
+// coerceColumns select the columns of SQL with the coercions of their types. SQL is
+// returned as is when no coercion is enabled, no column is coerced or the names of
+// the columns repeat
+func coerceColumns(ctx context.Context, p preparer, SQL string, params ...interface{}) (coerced string, err error) {
+	coerced = SQL
+	coercions := columnCoercions()
+	if len(coercions) == 0 {
+		return
+	}
+
+	names, types, err := selectTypes(ctx, p, SQL, params...)
+	if err != nil || types == nil {
+		return
+	}
+
+	fields := make([]string, 0, len(names))
+	var changed bool
+	for i, name := range names {
+		column := fmt.Sprintf("s.%s", quoteIdentifier(name))
+		if coercion, ok := coercions[types[i]]; ok {
+			column = fmt.Sprintf("%s AS %s", fmt.Sprintf(coercion, column), quoteIdentifier(name))
+			changed = true
+		}
+		fields = append(fields, column)
+	}
+	if changed {
+		coerced = fmt.Sprintf("SELECT %s FROM (%s) s", strings.Join(fields, ", "), SQL)
+	}
+	return
+}
+
+// selectTypes read the names of the columns of SQL from its result without rows and
+// their types with pg_typeof, the driver doesn't name the types of the columns. The
+// columns of a join on false are null with the types of SQL, so it isn't run. types is
+// nil when the names repeat, they can't be referenced
+func selectTypes(ctx context.Context, p preparer, SQL string, params ...interface{}) (names, types []string, err error) {
+	rows, done, err := queryRows(ctx, p, fmt.Sprintf("SELECT * FROM (%s) s LIMIT 0", SQL), params)
+	if err != nil {
+		return
+	}
+	names, err = rows.Columns()
+	done(err)
+	if err != nil || len(names) == 0 {
+		return
+	}
+
+	typeofs := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		typeofs = append(typeofs, fmt.Sprintf("pg_typeof(s.%s)::text", quoteIdentifier(name)))
+	}
+
+	types = make([]string, len(names))
+	dest := make([]interface{}, len(types))
+	for i := range types {
+		dest[i] = &types[i]
+	}
+	SQL = fmt.Sprintf(statements.SelectTypes, strings.Join(typeofs, ", "), SQL)
+	err = queryRow(ctx, p, SQL, params, dest...)
+	return
+}
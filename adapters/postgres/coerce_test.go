@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+)
+
+func TestColumnCoercions(t *testing.T) {
+	defer func(conf *config.Prest) {
+		config.PrestConf = conf
+	}(config.PrestConf)
+
+	var testCases = []struct {
+		description string
+		conf        config.Prest
+		expected    map[string]string
+	}{
+		{"disabled", config.Prest{}, map[string]string{}},
+		{"money", config.Prest{JSONMoneyAsNumber: true}, map[string]string{"money": "%s::numeric"}},
+		{"bytea", config.Prest{JSONByteaAsBase64: true}, map[string]string{"bytea": "encode(%s, 'base64')"}},
+		{"money and bytea", config.Prest{JSONMoneyAsNumber: true, JSONByteaAsBase64: true}, map[string]string{"money": "%s::numeric", "bytea": "encode(%s, 'base64')"}},
+		{"rfc3339 timestamps", config.Prest{JSONTimestamps: TimestampRFC3339}, map[string]string{
+			"timestamp without time zone": "CASE WHEN isfinite(%[1]s) THEN (to_json(%[1]s) #>> '{}') || 'Z' ELSE %[1]s::text END",
+		}},
+		{"epoch timestamps", config.Prest{JSONTimestamps: TimestampEpochMS}, map[string]string{
+			"timestamp without time zone": "CASE WHEN isfinite(%[1]s) THEN floor(extract(epoch FROM %[1]s) * 1000)::bigint END",
+			"timestamp with time zone":    "CASE WHEN isfinite(%[1]s) THEN floor(extract(epoch FROM %[1]s) * 1000)::bigint END",
+		}},
+		{"numeric precision", config.Prest{JSONPreserveNumericPrecision: true}, map[string]string{"numeric": "%s::text"}},
+		{"unknown timestamps format", config.Prest{JSONTimestamps: "other"}, map[string]string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		conf := tc.conf
+		config.PrestConf = &conf
+		if coercions := columnCoercions(); !reflect.DeepEqual(coercions, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, coercions)
+		}
+	}
+}
+
+func TestCoerceColumnsDisabled(t *testing.T) {
+	defer func(conf *config.Prest) {
+		config.PrestConf = conf
+	}(config.PrestConf)
+	config.PrestConf = &config.Prest{}
+
+	// nothing is prepared without coercions
+	coerced, err := coerceColumns(context.Background(), nil, "SELECT * FROM test")
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if coerced != "SELECT * FROM test" {
+		t.Errorf("expected the query as is, got %s", coerced)
+	}
+}
+
+func TestSelectTypes(t *testing.T) {
+	db, err := connection.Get()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var testCases = []struct {
+		description string
+		SQL         string
+		names       []string
+		types       []string
+	}{
+		{"columns of a table", "SELECT * FROM test_coercions", []string{"price", "data", "active"}, []string{"money", "bytea", "boolean"}},
+		{"expressions", "SELECT price::numeric AS amount, 1 AS one FROM test_coercions", []string{"amount", "one"}, []string{"numeric", "integer"}},
+		{"repeated names", "SELECT price, price FROM test_coercions", []string{"price", "price"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		names, types, err := selectTypes(context.Background(), db, tc.SQL)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(names, tc.names) {
+			t.Errorf("expected names %v, got %v", tc.names, names)
+		}
+		if !reflect.DeepEqual(types, tc.types) {
+			t.Errorf("expected types %v, got %v", tc.types, types)
+		}
+	}
+}
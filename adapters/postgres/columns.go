@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/statements"
+)
+
+// plainIdentifierRegex match column names without table, functions or operators
+var plainIdentifierRegex = regexp.MustCompile(`^[\pL_][\pL\pN_]*$`)
+
+// TableColumns list the columns of a table in information_schema, the query is cancelled
+// when ctx is done
+func TableColumns(ctx context.Context, database, schema, table string) (columns []string, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, statements.TableColumns, database, schema, table)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return
+		}
+		columns = append(columns, column)
+	}
+	err = rows.Err()
+	return
+}
+
+// TableColumn of a table in information_schema
+type TableColumn struct {
+	Schema   string `db:"table_schema"`
+	Table    string `db:"table_name"`
+	Name     string `db:"column_name"`
+	DataType string `db:"data_type"`
+	Nullable bool   `db:"nullable"`
+}
+
+// SchemasColumns list the columns of the tables of the schemas in the table order,
+// all the schemas but pg_catalog and information_schema when schemas is empty
+func SchemasColumns(database string, schemas []string) (columns []TableColumn, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var filter interface{}
+	if len(schemas) > 0 {
+		filter = pq.Array(schemas)
+	}
+	err = db.Select(&columns, statements.SchemasColumns, database, filter)
+	return
+}
+
+// ResolveColumnsByRequest rewrite the columns of the where, `_select`, `_order` and
+// `_count` parameters to the real names of the table columns compared case-insensitively,
+// it's enabled by case_insensitive_columns. Names with upper case are quoted, names
+// without match are kept and names matching more than one column return an error
+func ResolveColumnsByRequest(r *http.Request, database, schema, table string) (err error) {
+	if !config.PrestConf.CaseInsensitiveColumns {
+		return
+	}
+
+	columns, err := TableColumns(r.Context(), database, schema, table)
+	if err != nil {
+		return
+	}
+
+	queries := r.URL.Query()
+	resolved := make(map[string][]string, len(queries))
+	for key, values := range queries {
+		switch key {
+		case "_select":
+			values, err = resolveList(columns, values, resolveSelectField)
+		case "_order":
+			values, err = resolveList(columns, values, resolveOrderField)
+		case "_count":
+			values, err = resolveList(columns, values, resolveCountField)
+		default:
+			if !strings.HasPrefix(key, "_") {
+				key, err = resolveWhereKey(columns, key)
+			}
+		}
+		if err != nil {
+			return
+		}
+		resolved[key] = append(resolved[key], values...)
+	}
+
+	r.URL.RawQuery = url.Values(resolved).Encode()
+	return
+}
+
+type resolver func(columns []string, field string) (string, error)
+
+// resolveList resolve the fields of comma separated parameters
+func resolveList(columns, values []string, resolve resolver) (resolved []string, err error) {
+	for _, value := range values {
+		fields := strings.Split(value, ",")
+		for i, field := range fields {
+			if fields[i], err = resolve(columns, field); err != nil {
+				return
+			}
+		}
+		resolved = append(resolved, strings.Join(fields, ","))
+	}
+	return
+}
+
+func resolveSelectField(columns []string, field string) (string, error) {
+	column, alias := splitAlias(field)
+	// the column of jsonb fields, e.g. data->>name
+	var path string
+	if i := strings.Index(column, "->"); i >= 0 {
+		column, path = column[:i], column[i:]
+	}
+	column, err := resolveColumn(columns, column)
+	column += path
+	if err != nil || alias == "" {
+		return column, err
+	}
+	return fmt.Sprintf("%s:as:%s", column, alias), nil
+}
+
+func resolveCountField(columns []string, field string) (string, error) {
+	if !strings.HasPrefix(field, "distinct:") {
+		return resolveColumn(columns, field)
+	}
+	column, err := resolveColumn(columns, strings.TrimPrefix(field, "distinct:"))
+	return "distinct:" + column, err
+}
+
+func resolveOrderField(columns []string, field string) (resolved string, err error) {
+	var prefix, suffix string
+	if i := strings.Index(field, ":"); i >= 0 {
+		field, suffix = field[:i], field[i:]
+	}
+	if strings.HasPrefix(field, "-") {
+		field, prefix = field[1:], "-"
+	}
+	function, field := splitOrderFunction(field)
+	if field, err = resolveColumn(columns, field); err != nil {
+		return
+	}
+	if function != "" {
+		field = fmt.Sprintf("%s(%s)", function, field)
+	}
+	resolved = fmt.Sprint(prefix, field, suffix)
+	return
+}
+
+// resolveWhereKey resolve the field of filters, including the JSONb field:jsonb filters
+func resolveWhereKey(columns []string, key string) (resolved string, err error) {
+	var suffix string
+	if i := strings.Index(key, "->>"); i >= 0 {
+		key, suffix = key[:i], key[i:]
+	} else if i := strings.Index(key, ":"); i >= 0 {
+		key, suffix = key[:i], key[i:]
+	}
+	if key, err = resolveColumn(columns, key); err != nil {
+		return
+	}
+	resolved = fmt.Sprint(key, suffix)
+	return
+}
+
+// resolveColumn return the real name of field, an exact match wins over the others
+func resolveColumn(columns []string, field string) (column string, err error) {
+	if !plainIdentifierRegex.MatchString(field) {
+		column = field
+		return
+	}
+
+	var matches []string
+	for _, c := range columns {
+		if c == field {
+			matches = []string{c}
+			break
+		}
+		if strings.EqualFold(c, field) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		column = field
+	case 1:
+		column = matches[0]
+		if column != strings.ToLower(column) {
+			column = fmt.Sprintf(`"%s"`, column)
+		}
+	default:
+		err = fmt.Errorf("column %s is ambiguous, it matches %s", field, strings.Join(matches, ", "))
+	}
+	return
+}
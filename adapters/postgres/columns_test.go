@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestResolveColumn(t *testing.T) {
+	columns := []string{"id", "userId", "Name", "NAME", "email"}
+
+	var testCases = []struct {
+		description string
+		resolve     resolver
+		field       string
+		expected    string
+		err         bool
+	}{
+		{"same name", resolveColumn, "id", "id", false},
+		{"other case", resolveColumn, "ID", "id", false},
+		{"upper case column", resolveColumn, "userid", `"userId"`, false},
+		{"exact match of ambiguous column", resolveColumn, "Name", `"Name"`, false},
+		{"ambiguous column", resolveColumn, "name", "", true},
+		{"nonexistent column", resolveColumn, "password", "password", false},
+		{"not a plain column", resolveColumn, "test.userid", "test.userid", false},
+		{"select with alias", resolveSelectField, "USERID:as:user", `"userId":as:user`, false},
+		{"order desc with nulls", resolveOrderField, "-USERID:nullslast", `-"userId":nullslast`, false},
+		{"order by function", resolveOrderField, "-lower(USERID)", `-lower("userId")`, false},
+		{"jsonb filter", resolveWhereKey, "EMAIL->>domain:jsonb", "email->>domain:jsonb", false},
+		{"filter", resolveWhereKey, "UserId", `"userId"`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		column, err := tc.resolve(columns, tc.field)
+		if tc.err != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if !tc.err && column != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, column)
+		}
+	}
+}
+
+func TestResolveColumnsByRequest(t *testing.T) {
+	config.PrestConf.CaseInsensitiveColumns = true
+	defer func() {
+		config.PrestConf.CaseInsensitiveColumns = false
+	}()
+
+	r, err := http.NewRequest("GET", "/prest/public/test_case_columns?USERID=$eq.1&_select=userid,name&_order=-USERID&_count=userid", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ResolveColumnsByRequest(r, "prest", "public", "test_case_columns"); err != nil {
+		t.Fatalf("expected no errors, got %v", err)
+	}
+
+	queries := r.URL.Query()
+	expected := map[string]string{
+		`"userId"`: "$eq.1",
+		"_select":  `"userId",name`,
+		"_order":   `-"userId"`,
+		"_count":   `"userId"`,
+	}
+	for key, value := range expected {
+		if queries.Get(key) != value {
+			t.Errorf("expected %s=%s, got %q", key, value, queries.Get(key))
+		}
+	}
+
+	where, _, err := WhereByRequest(r, 1)
+	if err != nil {
+		t.Errorf("expected no errors on WhereByRequest, got %v", err)
+	}
+	if where != `"userId" = $1` {
+		t.Errorf("unexpected where %q", where)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 
 	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/nuveo/prest/config"
@@ -15,30 +18,105 @@ var (
 	// DB connection
 	DB  *sqlx.DB
 	err error
+
+	replicas        map[string]*sqlx.DB
+	replicaFailures map[string]time.Time
+	replicaDialing  map[string]bool
+	replicasMu      sync.Mutex
+	replicaNext     uint32
 )
 
+// ReplicaCooldown is the time a replica is skipped after a failed connection, its
+// selects run in the primary meanwhile
+var ReplicaCooldown = 30 * time.Second
+
+// URI is the connection string of the primary database
+func URI() string {
+	dbURI := fmt.Sprintf("user=%s dbname=%s host=%s port=%v sslmode=disable connect_timeout=%d",
+		config.PrestConf.PGUser,
+		config.PrestConf.PGDatabase,
+		config.PrestConf.PGHost,
+		config.PrestConf.PGPort,
+		config.PrestConf.PGConnTimeout)
+	if config.PrestConf.PGPass != "" {
+		dbURI += " password=" + config.PrestConf.PGPass
+	}
+	return dbURI
+}
+
 // Get get postgres connection
 func Get() (*sqlx.DB, error) {
 	if DB == nil {
-		dbURI := fmt.Sprintf("user=%s dbname=%s host=%s port=%v sslmode=disable connect_timeout=%d",
-			config.PrestConf.PGUser,
-			config.PrestConf.PGDatabase,
-			config.PrestConf.PGHost,
-			config.PrestConf.PGPort,
-			config.PrestConf.PGConnTimeout)
-		if config.PrestConf.PGPass != "" {
-			dbURI += " password=" + config.PrestConf.PGPass
-		}
-		DB, err = sqlx.Connect("postgres", dbURI)
+		DB, err = connect(URI())
 		if err != nil {
 			return nil, err
 		}
-		DB.SetMaxIdleConns(config.PrestConf.PGMaxIdleConn)
-		DB.SetMaxOpenConns(config.PrestConf.PGMAxOpenConn)
 	}
 	return DB, nil
 }
 
+// GetReplica get a read replica connection, the replicas of config.ReplicaURLs are
+// used in round-robin. nil is returned to use the primary without replicas, while the
+// replica is connected by another request or for ReplicaCooldown after it failed to
+// connect. The replica is connected without the lock, the other selects don't wait for it
+func GetReplica() (*sqlx.DB, error) {
+	urls := config.PrestConf.ReplicaURLs
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	url := urls[int(atomic.AddUint32(&replicaNext, 1)-1)%len(urls)]
+
+	replicasMu.Lock()
+	if replicas == nil {
+		replicas = make(map[string]*sqlx.DB, len(urls))
+		replicaFailures = make(map[string]time.Time, len(urls))
+		replicaDialing = make(map[string]bool, len(urls))
+	}
+	if db, ok := replicas[url]; ok {
+		replicasMu.Unlock()
+		return db, nil
+	}
+	if failedAt, ok := replicaFailures[url]; replicaDialing[url] || ok && time.Since(failedAt) < ReplicaCooldown {
+		replicasMu.Unlock()
+		return nil, nil
+	}
+	replicaDialing[url] = true
+	replicasMu.Unlock()
+
+	db, err := connect(url)
+
+	replicasMu.Lock()
+	defer replicasMu.Unlock()
+	if replicas == nil {
+		// closed meanwhile
+		if db != nil {
+			db.Close()
+		}
+		return nil, err
+	}
+	delete(replicaDialing, url)
+	if err != nil {
+		replicaFailures[url] = time.Now()
+		return nil, err
+	}
+	delete(replicaFailures, url)
+	replicas[url] = db
+	return db, nil
+}
+
+// connect open a connection pool with the pool settings of the config
+func connect(dbURI string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("postgres", dbURI)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxIdleConns(config.PrestConf.PGMaxIdleConn)
+	db.SetMaxOpenConns(config.PrestConf.PGMAxOpenConn)
+	db.SetConnMaxLifetime(time.Duration(config.PrestConf.PGConnMaxLifetime) * time.Second)
+	db.SetConnMaxIdleTime(time.Duration(config.PrestConf.PGConnMaxIdleTime) * time.Second)
+	return db, nil
+}
+
 // MustGet get postgres connection
 func MustGet() *sqlx.DB {
 	var err error
@@ -49,6 +127,26 @@ func MustGet() *sqlx.DB {
 	return DB
 }
 
+// Close close the connection pools of the primary database and the replicas, the
+// connections in use are closed when their queries finish
+func Close() (err error) {
+	replicasMu.Lock()
+	defer replicasMu.Unlock()
+	for _, db := range replicas {
+		if e := db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	replicas, replicaFailures, replicaDialing = nil, nil, nil
+	if DB != nil {
+		if e := DB.Close(); e != nil && err == nil {
+			err = e
+		}
+		DB = nil
+	}
+	return
+}
+
 // SetNativeDB enable to override sqlx native db
 func SetNativeDB(native *sql.DB) {
 	DB.DB = native
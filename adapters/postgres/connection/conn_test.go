@@ -1,7 +1,9 @@
 package connection
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	config "github.com/nuveo/prest/config"
@@ -54,3 +56,77 @@ func TestSetNativeDB(t *testing.T) {
 		t.Errorf("expected same memory address, but no was! %v %v", db.DB, mockedDB)
 	}
 }
+
+func TestGetReplica(t *testing.T) {
+	defer func() {
+		config.PrestConf.ReplicaURLs = nil
+	}()
+
+	t.Log("Without replicas")
+	db, err := GetReplica()
+	if err != nil {
+		t.Errorf("expected no error, but got: %v", err)
+	}
+	if db != nil {
+		t.Errorf("expected no replica, but got: %v", db)
+	}
+
+	t.Log("Unreachable replica")
+	config.PrestConf.ReplicaURLs = []string{"postgres://prest@127.0.0.1:1/prest?sslmode=disable&connect_timeout=1"}
+	_, err = GetReplica()
+	if err == nil {
+		t.Error("expected error, but no has!")
+	}
+
+	t.Log("Unreachable replica cooling down")
+	start := time.Now()
+	db, err = GetReplica()
+	if err != nil || db != nil {
+		t.Errorf("expected the primary, but got: %v, %v", db, err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected no connection while cooling down, took %v", elapsed)
+	}
+
+	t.Log("Unreachable replica after the cooldown")
+	defer func(cooldown time.Duration) {
+		ReplicaCooldown = cooldown
+	}(ReplicaCooldown)
+	ReplicaCooldown = 0
+	_, err = GetReplica()
+	if err == nil {
+		t.Error("expected error, but no has!")
+	}
+}
+
+func TestGetReplicaRoundRobin(t *testing.T) {
+	defer func() {
+		config.PrestConf.ReplicaURLs = nil
+	}()
+
+	uri := func(appName string) string {
+		return fmt.Sprintf("user=%s dbname=%s host=%s port=%v sslmode=disable application_name=%s",
+			config.PrestConf.PGUser,
+			config.PrestConf.PGDatabase,
+			config.PrestConf.PGHost,
+			config.PrestConf.PGPort,
+			appName)
+	}
+	config.PrestConf.ReplicaURLs = []string{uri("replica1"), uri("replica2")}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		db, err := GetReplica()
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		var name string
+		if err = db.QueryRow("SHOW application_name").Scan(&name); err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		got = append(got, name)
+	}
+	if got[0] == got[1] || got[0] != got[2] || got[1] != got[3] {
+		t.Errorf("expected replicas in round-robin, got: %v", got)
+	}
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrCopySourceRequired err throw when _from is missing in a copy
+var ErrCopySourceRequired = errors.New("_from is required")
+
+// ErrNoCommonColumns err throw when a copy without _select has no column in both tables
+var ErrNoCommonColumns = errors.New("source and target have no common columns")
+
+// CopyColumn is a source column copied to a target column
+type CopyColumn struct {
+	Source string
+	Target string
+}
+
+type columnType struct {
+	Name string `db:"name"`
+	Type string `db:"type"`
+}
+
+// CopySourceByRequest read the source table of a copy from `_from`, a table of schema
+// or schema.table
+func CopySourceByRequest(r *http.Request, schema string) (sourceSchema, source string, err error) {
+	from := r.URL.Query().Get("_from")
+	if from == "" {
+		err = ErrCopySourceRequired
+		return
+	}
+
+	sourceSchema, source = schema, from
+	if parts := strings.SplitN(from, ".", 2); len(parts) == 2 {
+		sourceSchema, source = parts[0], parts[1]
+	}
+	if invalidNames(sourceSchema, source) {
+		err = fmt.Errorf("invalid identifier %s", from)
+	}
+	return
+}
+
+// CopyColumnsByRequest pair the source and target columns of a copy, `_select` list the
+// source columns and `column:as:target` copy to a target column with another name,
+// without `_select` the columns with the same name in both tables are copied. The
+// columns must exist and the source types must be assignable to the target types
+func CopyColumnsByRequest(r *http.Request, sourceSchema, source, schema, table string) (columns []CopyColumn, err error) {
+	sourceTypes, err := copyColumnTypes(sourceSchema, source)
+	if err != nil {
+		return
+	}
+	targetTypes, err := copyColumnTypes(schema, table)
+	if err != nil {
+		return
+	}
+
+	fields := ColumnsByRequest(r)
+	if len(fields) == 1 && fields[0] == "*" {
+		for _, column := range targetTypes {
+			if _, ok := findColumnType(sourceTypes, column.Name); ok {
+				columns = append(columns, CopyColumn{Source: column.Name, Target: column.Name})
+			}
+		}
+		if len(columns) == 0 {
+			err = ErrNoCommonColumns
+			return
+		}
+	} else {
+		for _, field := range fields {
+			sourceColumn, targetColumn := splitAlias(field)
+			if targetColumn == "" {
+				targetColumn = sourceColumn
+			}
+			columns = append(columns, CopyColumn{Source: sourceColumn, Target: targetColumn})
+		}
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, column := range columns {
+		sourceType, ok := findColumnType(sourceTypes, column.Source)
+		if !ok {
+			err = fmt.Errorf("column %s does not exist in %s.%s", column.Source, sourceSchema, source)
+			return
+		}
+		targetType, ok := findColumnType(targetTypes, column.Target)
+		if !ok {
+			err = fmt.Errorf("column %s does not exist in %s.%s", column.Target, schema, table)
+			return
+		}
+
+		var assignable bool
+		err = db.QueryRow(statements.TypeAssignable, sourceType, targetType).Scan(&assignable)
+		if err != nil {
+			return
+		}
+		if !assignable {
+			err = fmt.Errorf("column %s (%s) can't be copied to %s (%s)", column.Source, sourceType, column.Target, targetType)
+			return
+		}
+	}
+	return
+}
+
+// CopyQuery build the INSERT ... SELECT of a copy, where filter the source rows
+func CopyQuery(database, sourceSchema, source, schema, table string, columns []CopyColumn, where string) string {
+	sourceColumns := make([]string, 0, len(columns))
+	targetColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sourceColumns = append(sourceColumns, quoteIdentifier(column.Source))
+		targetColumns = append(targetColumns, quoteIdentifier(column.Target))
+	}
+
+	sql := fmt.Sprintf(statements.CopyQuery,
+		QuoteName(database), QuoteName(schema), QuoteName(table), strings.Join(targetColumns, ", "),
+		strings.Join(sourceColumns, ", "), QuoteName(database), QuoteName(sourceSchema), QuoteName(source))
+	if where != "" {
+		sql = fmt.Sprint(sql, " WHERE ", where)
+	}
+	return sql
+}
+
+// Copy execute the INSERT ... SELECT of a copy in a transaction, the response is the
+// count of rows copied like the deletes
+func Copy(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return Delete(SQL, params...)
+}
+
+// CopyWithLock execute the copy holding the advisory lock of lock, nil is no lock,
+// in a transaction of the isolation level, empty is the default level. The transaction
+// is rolled back when ctx is done
+func CopyWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return DeleteWithLock(ctx, lock, isolation, SQL, params...)
+}
+
+func copyColumnTypes(schema, table string) (columns []columnType, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = db.Select(&columns, statements.ColumnTypes, schema, table)
+	return
+}
+
+func findColumnType(columns []columnType, name string) (string, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column.Type, true
+		}
+	}
+	return "", false
+}
+
+// quoteIdentifier quote the real name of a column found in the catalog
+func quoteIdentifier(name string) string {
+	return fmt.Sprintf(`"%s"`, strings.Replace(name, `"`, `""`, -1))
+}
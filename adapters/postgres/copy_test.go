@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCopySourceByRequest(t *testing.T) {
+	var testCases = []struct {
+		description  string
+		url          string
+		sourceSchema string
+		source       string
+		err          error
+	}{
+		{"source in the same schema", "/prest/public/test_archive/_copy?_from=test", "public", "test", nil},
+		{"source in other schema", "/prest/public/test_archive/_copy?_from=other.test", "other", "test", nil},
+		{"without source", "/prest/public/test_archive/_copy", "", "", ErrCopySourceRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sourceSchema, source, err := CopySourceByRequest(r, "public")
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if tc.err == nil && (sourceSchema != tc.sourceSchema || source != tc.source) {
+			t.Errorf("expected %s.%s, got %s.%s", tc.sourceSchema, tc.source, sourceSchema, source)
+		}
+	}
+
+	t.Log("invalid source")
+	r, _ := http.NewRequest("POST", "/prest/public/test_archive/_copy?_from=0test", nil)
+	if _, _, err := CopySourceByRequest(r, "public"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestCopyQuery(t *testing.T) {
+	columns := []CopyColumn{{Source: "id", Target: "id"}, {Source: "name", Target: "userName"}}
+
+	sql := CopyQuery("prest", "public", "test", "archive", "test", columns, "")
+	expected := `INSERT INTO prest.archive.test("id", "userName") SELECT "id", "name" FROM prest.public.test`
+	if sql != "\n"+expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+
+	sql = CopyQuery("prest", "public", "test", "archive", "test", columns, "id < $1")
+	if sql != "\n"+expected+" WHERE id < $1" {
+		t.Errorf("expected %q with where, got %q", expected, sql)
+	}
+}
+
+func TestCopyColumnsByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    []CopyColumn
+		err         bool
+	}{
+		{"common columns", "/?_from=test_categories", []CopyColumn{{"id", "id"}, {"parent_id", "parent_id"}}, false},
+		{"selected columns with target names", "/?_from=test_categories&_select=id,name:as:title", []CopyColumn{{"id", "id"}, {"name", "title"}}, false},
+		{"nonexistent source column", "/?_from=test_categories&_select=title", nil, true},
+		{"nonexistent target column", "/?_from=test_categories&_select=name", nil, true},
+		{"incompatible types", "/?_from=test_categories&_select=name:as:parent_id", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns, err := CopyColumnsByRequest(r, "public", "test_categories", "public", "test_categories_archive")
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(columns, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, columns)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+)
+
+// ErrInvalidCursor err throw when the _cursor can't be decoded, its signature doesn't
+// match or it was created for other order
+var ErrInvalidCursor = errors.New("invalid _cursor")
+
+// ErrCursorParameters err throw when _cursor is used with other pagination
+var ErrCursorParameters = errors.New("_cursor requires _order by a single column and can't be used with _page, _count, _envelope, _first or _last")
+
+// Cursor is the position of a keyset page, the rows after Value in the Direction of
+// the order by Column
+type Cursor struct {
+	Column    string      `json:"c"`
+	Value     interface{} `json:"v"`
+	Direction string      `json:"d"`
+}
+
+// Keyset is the pagination of `_cursor`, Cursor is nil in the first page
+type Keyset struct {
+	Column    string
+	Direction string
+	PageSize  int
+	Cursor    *Cursor
+}
+
+// KeysetByRequest read `_cursor` with `_order=column` or `_order=-column`, the empty
+// _cursor is the first page. nil is returned without _cursor
+func KeysetByRequest(r *http.Request) (keyset *Keyset, err error) {
+	queries := r.URL.Query()
+	if _, ok := queries["_cursor"]; !ok {
+		return
+	}
+
+	order := queries.Get("_order")
+	if order == "" || strings.ContainsAny(order, ",:()") ||
+		queries.Get(pageNumberKey) != "" || queries.Get("_count") != "" || queries.Get("_envelope") != "" ||
+		queries.Get("_first") != "" || queries.Get("_last") != "" {
+		err = ErrCursorParameters
+		return
+	}
+
+	keyset = &Keyset{Column: order, Direction: "ASC", PageSize: defaultPageSize}
+	if strings.HasPrefix(order, "-") {
+		keyset.Column, keyset.Direction = order[1:], "DESC"
+	}
+	if !validIdentifier(keyset.Column) {
+		err = fmt.Errorf("invalid identifier: %s", keyset.Column)
+		return
+	}
+
+	if size := queries.Get(pageSizeKey); size != "" {
+		if keyset.PageSize, err = strconv.Atoi(size); err != nil || keyset.PageSize < 1 {
+			err = fmt.Errorf("invalid page size %s", size)
+			return
+		}
+		if maxPageSize := config.PrestConf.MaxPageSize; maxPageSize > 0 && keyset.PageSize > maxPageSize {
+			err = fmt.Errorf("page size %d exceeds the maximum page size %d", keyset.PageSize, maxPageSize)
+			return
+		}
+	}
+
+	if token := queries.Get("_cursor"); token != "" {
+		var cursor Cursor
+		if cursor, err = DecodeCursor(token); err != nil {
+			return
+		}
+		// a cursor of other order would skip or repeat rows
+		if cursor.Column != keyset.Column || cursor.Direction != keyset.Direction {
+			err = ErrInvalidCursor
+			return
+		}
+		keyset.Cursor = &cursor
+	}
+	return
+}
+
+// Where create the condition of the rows after the cursor, it's empty in the first page
+func (keyset *Keyset) Where(initialPlaceholderID int) (whereSyntax string, values []interface{}) {
+	if keyset.Cursor == nil {
+		return
+	}
+	op := ">"
+	if keyset.Direction == "DESC" {
+		op = "<"
+	}
+	whereSyntax = fmt.Sprintf("%s %s $%d", quoteColumn(keyset.Column), op, initialPlaceholderID)
+	values = append(values, keyset.Cursor.Value)
+	return
+}
+
+// Limit of the page, the cursor replace the OFFSET
+func (keyset *Keyset) Limit() string {
+	return fmt.Sprintf("LIMIT %d", keyset.PageSize)
+}
+
+// NextCursor encode the cursor of the last row of a full page, it's empty in the last
+// page. The order column must be in the rows
+func (keyset *Keyset) NextCursor(jsonData []byte) (token string, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var rows []map[string]interface{}
+	if err = decoder.Decode(&rows); err != nil {
+		return
+	}
+	if len(rows) < keyset.PageSize {
+		return
+	}
+
+	value, ok := rows[len(rows)-1][strings.Trim(keyset.Column, `"`)]
+	if !ok || value == nil {
+		err = fmt.Errorf("the _order column %s must be selected and not null", keyset.Column)
+		return
+	}
+	if number, isNumber := value.(json.Number); isNumber {
+		value = number.String()
+	}
+	return EncodeCursor(Cursor{Column: keyset.Column, Value: value, Direction: keyset.Direction})
+}
+
+// EncodeCursor encode the cursor in base64 (URL encoding), with cursor.secret in the
+// config the HMAC-SHA256 of the payload is appended after a dot
+func EncodeCursor(cursor Cursor) (token string, err error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(payload)
+	if secret := config.PrestConf.CursorSecret; secret != "" {
+		token = fmt.Sprint(token, ".", base64.RawURLEncoding.EncodeToString(cursorSignature(secret, token)))
+	}
+	return
+}
+
+// DecodeCursor decode a cursor of EncodeCursor, with cursor.secret in the config the
+// cursors without a valid signature are rejected
+func DecodeCursor(token string) (cursor Cursor, err error) {
+	parts := strings.SplitN(token, ".", 2)
+	if secret := config.PrestConf.CursorSecret; secret != "" {
+		if len(parts) != 2 {
+			err = ErrInvalidCursor
+			return
+		}
+		signature, decodeErr := base64.RawURLEncoding.DecodeString(parts[1])
+		if decodeErr != nil || !hmac.Equal(signature, cursorSignature(secret, parts[0])) {
+			err = ErrInvalidCursor
+			return
+		}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+	if err = json.Unmarshal(payload, &cursor); err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+	switch cursor.Value.(type) {
+	case string:
+	default:
+		err = ErrInvalidCursor
+	}
+	return
+}
+
+func cursorSignature(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
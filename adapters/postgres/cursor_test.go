@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	defer func() { config.PrestConf.CursorSecret = "" }()
+
+	cursor := Cursor{Column: "id", Value: "10", Direction: "ASC"}
+	for _, secret := range []string{"", "s3cr3t"} {
+		t.Logf("secret %q", secret)
+		config.PrestConf.CursorSecret = secret
+		token, err := EncodeCursor(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeCursor(token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(decoded, cursor) {
+			t.Errorf("expected %+v, got %+v", cursor, decoded)
+		}
+	}
+
+	t.Log("unsigned cursor with secret")
+	config.PrestConf.CursorSecret = ""
+	unsigned, _ := EncodeCursor(cursor)
+	config.PrestConf.CursorSecret = "s3cr3t"
+	if _, err := DecodeCursor(unsigned); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	t.Log("cursor signed with other secret")
+	config.PrestConf.CursorSecret = "other"
+	other, _ := EncodeCursor(cursor)
+	config.PrestConf.CursorSecret = "s3cr3t"
+	if _, err := DecodeCursor(other); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	t.Log("tampered payload")
+	signed, _ := EncodeCursor(cursor)
+	tampered, _ := EncodeCursor(Cursor{Column: "id", Value: "99", Direction: "ASC"})
+	if _, err := DecodeCursor(tampered[:len(tampered)-2] + signed[len(signed)-2:]); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	t.Log("invalid base64")
+	config.PrestConf.CursorSecret = ""
+	if _, err := DecodeCursor("not a cursor"); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestKeysetByRequest(t *testing.T) {
+	token, err := EncodeCursor(Cursor{Column: "id", Value: "10", Direction: "DESC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCases = []struct {
+		description string
+		url         string
+		keyset      *Keyset
+		where       string
+		err         bool
+	}{
+		{"without cursor", "/prest/public/test?_order=id", nil, "", false},
+		{"first page", "/prest/public/test?_order=id&_cursor=&_page_size=5", &Keyset{Column: "id", Direction: "ASC", PageSize: 5}, "", false},
+		{"next page", "/prest/public/test?_order=-id&_cursor=" + token, &Keyset{Column: "id", Direction: "DESC", PageSize: 10, Cursor: &Cursor{Column: "id", Value: "10", Direction: "DESC"}}, "id < $3", false},
+		{"cursor of other order", "/prest/public/test?_order=id&_cursor=" + token, nil, "", true},
+		{"without order", "/prest/public/test?_cursor=", nil, "", true},
+		{"order by many columns", "/prest/public/test?_order=id,name&_cursor=", nil, "", true},
+		{"with page", "/prest/public/test?_order=id&_cursor=&_page=2", nil, "", true},
+		{"with count", "/prest/public/test?_order=id&_cursor=&_count=*", nil, "", true},
+		{"invalid page size", "/prest/public/test?_order=id&_cursor=&_page_size=0", nil, "", true},
+		{"invalid cursor", "/prest/public/test?_order=id&_cursor=x", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyset, err := KeysetByRequest(r)
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(keyset, tc.keyset) {
+			t.Errorf("expected %+v, got %+v", tc.keyset, keyset)
+		}
+		if keyset == nil {
+			continue
+		}
+		if where, _ := keyset.Where(3); where != tc.where {
+			t.Errorf("expected where %q, got %q", tc.where, where)
+		}
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	keyset := &Keyset{Column: "id", Direction: "ASC", PageSize: 2}
+
+	token, err := keyset.NextCursor([]byte(`[{"id":1}, {"id":9007199254740993}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Cursor{Column: "id", Value: "9007199254740993", Direction: "ASC"}); !reflect.DeepEqual(cursor, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cursor)
+	}
+
+	if token, err = keyset.NextCursor([]byte(`[{"id":1}]`)); err != nil || token != "" {
+		t.Errorf("expected no cursor in the last page, got %q, %v", token, err)
+	}
+
+	if _, err = keyset.NextCursor([]byte(`[{"name":"a"}, {"name":"b"}]`)); err == nil {
+		t.Error("expected error without the order column, got nil")
+	}
+}
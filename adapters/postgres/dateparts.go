@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// datePartOperators of the where filters compared with a part of the date, e.g.
+// created_at=$month.3 is EXTRACT(MONTH FROM created_at) = 3
+var datePartOperators = map[string]string{
+	"year":    "YEAR",
+	"quarter": "QUARTER",
+	"month":   "MONTH",
+	"week":    "WEEK",
+	"day":     "DAY",
+	"dow":     "DOW",
+	"hour":    "HOUR",
+}
+
+// datePart of the operator, ok is false when op isn't a date part
+func datePart(op string) (part string, ok bool) {
+	part, ok = datePartOperators[strings.TrimPrefix(op, "$")]
+	return
+}
+
+// CheckDatePartFilters validate that the columns filtered by a date part, e.g.
+// created_at=$month.3, are date or timestamp columns of the table
+func CheckDatePartFilters(r *http.Request, database, schema, table string) (err error) {
+	var columns []string
+	for key, values := range r.URL.Query() {
+		if strings.HasPrefix(key, "_") || strings.Contains(key, ":") {
+			continue
+		}
+		var filtered bool
+		for _, value := range values {
+			op := strings.Replace(removeOperatorRegex.FindString(value), ".", "", -1)
+			if _, ok := datePart(op); ok {
+				filtered = true
+			}
+		}
+		if !filtered {
+			continue
+		}
+		if columns == nil {
+			if columns, err = dateColumns(database, schema, table); err != nil {
+				return
+			}
+		}
+		if column := strings.Trim(key, `"`); !containsString(columns, column) {
+			err = fmt.Errorf("column %s is not a date or timestamp column of %s.%s", column, schema, table)
+			return
+		}
+	}
+	return
+}
+
+// dateColumns list the date and timestamp columns of a table
+func dateColumns(database, schema, table string) (columns []string, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	columns = []string{}
+	err = db.Select(&columns, statements.DateColumns, database, schema, table)
+	return
+}
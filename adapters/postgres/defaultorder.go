@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+)
+
+// primaryKeyOrder is the default_order_by of the primary key of the tables
+const primaryKeyOrder = "primary_key"
+
+// ErrPrimaryKeyOrder err wrapping the database errors reading the primary key of the
+// default order, they aren't errors of the request
+var ErrPrimaryKeyOrder = errors.New("could not read the primary key of the default order")
+
+// DefaultOrderByRequest create the ORDER BY of the selects paginated by `_page` without
+// `_order`, LIMIT and OFFSET don't return the rows in the same order without it. The
+// order is the fields of default_order_by, or the columns of the primary key of the
+// table with primary_key, qualified by the table when the select has joins. It's empty
+// when default_order_by is empty and is logged when the select can't be ordered: the
+// grouped, counted and aggregated selects and the tables without primary key
+func DefaultOrderByRequest(r *http.Request, database, schema, table string, cols []string) (order string, err error) {
+	queries := r.URL.Query()
+	defaultOrder := config.PrestConf.DefaultOrderBy
+	if defaultOrder == "" || queries.Get("_order") != "" || queries.Get(pageNumberKey) == "" {
+		return
+	}
+
+	name := fmt.Sprintf("%s.%s", schema, table)
+	if queries.Get("_groupby") != "" || queries.Get("_count") != "" || hasAggregate(cols) {
+		log.Printf("{order} the page of %s isn't ordered, the grouped, counted and aggregated selects need _order\n", name)
+		return
+	}
+
+	fields := strings.Split(defaultOrder, ",")
+	if defaultOrder == primaryKeyOrder {
+		if fields, err = PrimaryKeyColumns(r.Context(), database, schema, table); err != nil {
+			err = fmt.Errorf("%w: %w", ErrPrimaryKeyOrder, err)
+			return
+		}
+		if len(fields) == 0 {
+			log.Printf("{order} the page of %s isn't ordered, the table has no primary key\n", name)
+			return
+		}
+	}
+	if _, ok := queries["_join"]; ok {
+		for i, field := range fields {
+			fields[i] = qualifyOrderField(table, field)
+		}
+	}
+	return orderBy(strings.Join(fields, ","), false)
+}
+
+// qualifyOrderField qualify the column of an _order field by the table, e.g.
+// -created_at:nullslast is -table.created_at:nullslast, the functions and the qualified
+// columns are kept
+func qualifyOrderField(table, field string) string {
+	var direction, nulls string
+	if strings.HasPrefix(field, "-") {
+		direction, field = "-", field[1:]
+	}
+	if f := strings.SplitN(field, ":", 2); len(f) == 2 {
+		field, nulls = f[0], fmt.Sprint(":", f[1])
+	}
+	if !strings.ContainsAny(field, "(.") {
+		field = fmt.Sprintf("%s.%s", table, field)
+	}
+	return fmt.Sprint(direction, field, nulls)
+}
+
+// hasAggregate check if one of the selected fields is an aggregate
+func hasAggregate(cols []string) bool {
+	for _, col := range cols {
+		if isAggregate(col) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestDefaultOrderByRequest(t *testing.T) {
+	defaultOrder := config.PrestConf.DefaultOrderBy
+	defer func() { config.PrestConf.DefaultOrderBy = defaultOrder }()
+
+	var testCases = []struct {
+		description  string
+		defaultOrder string
+		url          string
+		cols         []string
+		expected     string
+		err          bool
+	}{
+		{"disabled", "", "/prest/public/test?_page=1", []string{"*"}, "", false},
+		{"without pagination", "id", "/prest/public/test", []string{"*"}, "", false},
+		{"with _order", "id", "/prest/public/test?_page=1&_order=name", []string{"*"}, "", false},
+		{"column", "id", "/prest/public/test?_page=2&_page_size=10", []string{"*"}, " ORDER BY id ASC", false},
+		{"columns", "-created_at:nullslast,id", "/prest/public/test?_page=1", []string{"*"}, " ORDER BY created_at DESC NULLS LAST, id ASC", false},
+		{"columns of a join", "-created_at:nullslast,id", "/prest/public/test?_page=1&_join=inner:test2:test2.name:$eq:test.name", []string{"*"}, " ORDER BY test.created_at DESC NULLS LAST, test.id ASC", false},
+		{"grouped", "id", "/prest/public/test?_page=1&_groupby=name", []string{"name"}, "", false},
+		{"counted", "id", "/prest/public/test?_page=1&_count=*", []string{"*"}, "", false},
+		{"aggregated", "id", "/prest/public/test?_page=1", []string{"sum:amount"}, "", false},
+		{"invalid column", "id;DROP TABLE test", "/prest/public/test?_page=1", []string{"*"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.DefaultOrderBy = tc.defaultOrder
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := DefaultOrderByRequest(r, "prest", "public", "test", tc.cols)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if order != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, order)
+		}
+	}
+}
+
+func TestDefaultOrderByPrimaryKey(t *testing.T) {
+	defaultOrder := config.PrestConf.DefaultOrderBy
+	defer func() { config.PrestConf.DefaultOrderBy = defaultOrder }()
+	config.PrestConf.DefaultOrderBy = "primary_key"
+
+	var testCases = []struct {
+		description string
+		table       string
+		expected    string
+	}{
+		{"primary key", "test_embed_orders", " ORDER BY id ASC"},
+		{"without primary key", "test", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", "/prest/public/"+tc.table+"?_page=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := DefaultOrderByRequest(r, "prest", "public", tc.table, []string{"*"})
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if order != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, order)
+		}
+	}
+}
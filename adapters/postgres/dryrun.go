@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/nuveo/prest/config"
+)
+
+// ErrDryRunDisabled err throw when _dryrun is used without enable_dry_run
+var ErrDryRunDisabled = errors.New("_dryrun is disabled")
+
+// DryRunByRequest check `_dryrun=true`, the statement is returned instead of executed
+func DryRunByRequest(r *http.Request) (dryRun bool, err error) {
+	if r.URL.Query().Get("_dryrun") != "true" {
+		return
+	}
+	if !config.PrestConf.EnableDryRun {
+		err = ErrDryRunDisabled
+		return
+	}
+	dryRun = true
+	return
+}
+
+// DryRun is the statement of a request with the values of its placeholders
+func DryRun(SQL string, params []interface{}) (jsonData []byte, err error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return json.Marshal(struct {
+		SQL    string        `json:"sql"`
+		Params []interface{} `json:"params"`
+	}{SQL, params})
+}
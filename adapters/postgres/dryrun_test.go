@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestDryRunByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		enabled     bool
+		dryRun      bool
+		err         error
+	}{
+		{"without _dryrun", "/prest/public/test", false, false, nil},
+		{"dry run", "/prest/public/test?_dryrun=true", true, true, nil},
+		{"dry run disabled", "/prest/public/test?_dryrun=true", false, false, ErrDryRunDisabled},
+		{"dry run false", "/prest/public/test?_dryrun=false", false, false, nil},
+	}
+
+	defer func() { config.PrestConf.EnableDryRun = false }()
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.EnableDryRun = tc.enabled
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dryRun, err := DryRunByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if dryRun != tc.dryRun {
+			t.Errorf("expected dry run %v, got %v", tc.dryRun, dryRun)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var testCases = []struct {
+		description string
+		sql         string
+		params      []interface{}
+		expected    string
+	}{
+		{"statement with params", "SELECT * FROM prest.public.test WHERE name = $1", []interface{}{"prest"}, `{"sql":"SELECT * FROM prest.public.test WHERE name = $1","params":["prest"]}`},
+		{"statement without params", "DELETE FROM prest.public.test", nil, `{"sql":"DELETE FROM prest.public.test","params":[]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		data, err := DryRun(tc.sql, tc.params)
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if string(data) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, data)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrEmbedParameters err throw when _embed is used with _count, _groupby, _recursive or aggregates
+var ErrEmbedParameters = errors.New("_embed can't be used with _count, _groupby, _recursive or aggregates")
+
+// foreignKey is a single column foreign key, the column of the child table references
+// the column of the parent table
+type foreignKey struct {
+	Child        string `db:"child"`
+	ChildColumn  string `db:"child_column"`
+	Parent       string `db:"parent"`
+	ParentColumn string `db:"parent_column"`
+}
+
+// EmbedByRequest read `_embed=table,...`, the tables related by a foreign key whose rows
+// are nested in the selected rows
+func EmbedByRequest(r *http.Request) (tables []string, err error) {
+	queries := r.URL.Query()
+	embed := queries.Get("_embed")
+	if embed == "" {
+		return
+	}
+	if queries.Get("_count") != "" || queries.Get("_groupby") != "" || queries.Get("_recursive") != "" {
+		err = ErrEmbedParameters
+		return
+	}
+	for _, col := range ColumnsByRequest(r) {
+		if isAggregate(col) {
+			err = ErrEmbedParameters
+			return
+		}
+	}
+
+	for _, table := range strings.Split(embed, ",") {
+		if !plainIdentifierRegex.MatchString(table) {
+			err = fmt.Errorf("invalid _embed table %s", table)
+			return
+		}
+		if containsString(tables, table) {
+			err = fmt.Errorf("table %s embedded twice", table)
+			return
+		}
+		tables = append(tables, table)
+	}
+	return
+}
+
+// EmbedColumns create the columns of the select of table nesting the rows of the
+// embedded tables. The rows of a table referencing table are an array, the row
+// referenced by table is an object (null without it). The tables are in the schema
+// of table and related by a single foreign key
+func EmbedColumns(database, schema, table string, tables []string) (columns []string, err error) {
+	keys, err := foreignKeys(schema, table)
+	if err != nil {
+		return
+	}
+	for _, embedded := range tables {
+		if !TablePermissions(embedded, "read") {
+			err = fmt.Errorf("you don't have permission to read %s", embedded)
+			return
+		}
+		var column string
+		if column, err = embedColumn(database, schema, table, embedded, keys); err != nil {
+			return
+		}
+		columns = append(columns, column)
+	}
+	return
+}
+
+// EmbedSelect add the embedded columns to the select of SelectFields
+func EmbedSelect(selectSQL string, columns []string) string {
+	if len(columns) == 0 {
+		return selectSQL
+	}
+	return fmt.Sprintf("%s,%s FROM", strings.TrimSuffix(selectSQL, " FROM"), strings.Join(columns, ","))
+}
+
+func embedColumn(database, schema, table, embedded string, keys []foreignKey) (column string, err error) {
+	fields, err := embedFields(embedded)
+	if err != nil {
+		return
+	}
+
+	var columns []string
+	for _, key := range keys {
+		switch {
+		case key.Child == embedded && key.Parent == table:
+			columns = append(columns, fmt.Sprintf(statements.EmbedMany, fields,
+				QuoteName(database), QuoteName(schema), QuoteName(embedded), quoteIdentifier(key.ChildColumn),
+				QuoteName(schema), QuoteName(table), quoteIdentifier(key.ParentColumn), QuoteName(embedded)))
+		case key.Parent == embedded && key.Child == table:
+			columns = append(columns, fmt.Sprintf(statements.EmbedOne, fields,
+				QuoteName(database), QuoteName(schema), QuoteName(embedded), quoteIdentifier(key.ParentColumn),
+				QuoteName(schema), QuoteName(table), quoteIdentifier(key.ChildColumn), QuoteName(embedded)))
+		}
+	}
+
+	switch len(columns) {
+	case 0:
+		err = fmt.Errorf("no foreign key between %s and %s", table, embedded)
+	case 1:
+		column = columns[0]
+	default:
+		err = fmt.Errorf("more than one foreign key between %s and %s", table, embedded)
+	}
+	return
+}
+
+// embedFields select the columns of the embedded table readable in restrict mode
+func embedFields(table string) (fields string, err error) {
+	if !config.PrestConf.AccessConf.Restrict {
+		return "embedded.*", nil
+	}
+
+	var columns []string
+	for _, t := range config.PrestConf.AccessConf.Tables {
+		if t.Name != table {
+			continue
+		}
+		for _, field := range t.Fields {
+			if field == "*" {
+				return "embedded.*", nil
+			}
+			columns = append(columns, fmt.Sprintf("embedded.%s", quoteIdentifier(field)))
+		}
+	}
+	if len(columns) == 0 {
+		err = fmt.Errorf("you don't have permission to read the fields of %s", table)
+		return
+	}
+	fields = strings.Join(columns, ",")
+	return
+}
+
+func foreignKeys(schema, table string) (keys []foreignKey, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = db.Select(&keys, statements.ForeignKeys, schema, table)
+	return
+}
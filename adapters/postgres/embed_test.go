@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestEmbedByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    []string
+		err         bool
+	}{
+		{"without embed", "/prest/public/orders", nil, false},
+		{"single table", "/prest/public/orders?_embed=items", []string{"items"}, false},
+		{"multiple tables", "/prest/public/orders?_embed=items,customers", []string{"items", "customers"}, false},
+		{"table embedded twice", "/prest/public/orders?_embed=items,items", nil, true},
+		{"invalid table", "/prest/public/orders?_embed=items%3BDROP", nil, true},
+		{"qualified table", "/prest/public/orders?_embed=public.items", nil, true},
+		{"with count", "/prest/public/orders?_embed=items&_count=*", nil, true},
+		{"with group by", "/prest/public/orders?_embed=items&_groupby=id", nil, true},
+		{"with recursive", "/prest/public/orders?_embed=items&_recursive=id:parent_id", nil, true},
+		{"with aggregates", "/prest/public/orders?_embed=items&_select=sum:total", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables, err := EmbedByRequest(r)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if !tc.err && !reflect.DeepEqual(tables, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, tables)
+		}
+	}
+}
+
+func TestEmbedColumn(t *testing.T) {
+	restrict := config.PrestConf.AccessConf.Restrict
+	config.PrestConf.AccessConf.Restrict = false
+	defer func() { config.PrestConf.AccessConf.Restrict = restrict }()
+
+	keys := []foreignKey{
+		{Child: "items", ChildColumn: "order_id", Parent: "orders", ParentColumn: "id"},
+		{Child: "orders", ChildColumn: "customer_id", Parent: "customers", ParentColumn: "id"},
+		{Child: "transfers", ChildColumn: "from_id", Parent: "orders", ParentColumn: "id"},
+		{Child: "transfers", ChildColumn: "to_id", Parent: "orders", ParentColumn: "id"},
+	}
+
+	var testCases = []struct {
+		description string
+		embedded    string
+		expected    string
+		err         bool
+	}{
+		{"referencing rows", "items", `(SELECT COALESCE(json_agg(embedded), '[]'::json) FROM (SELECT embedded.* FROM prest.public.items embedded WHERE embedded."order_id" = public.orders."id") embedded) AS items`, false},
+		{"referenced row", "customers", `(SELECT row_to_json(embedded) FROM (SELECT embedded.* FROM prest.public.customers embedded WHERE embedded."id" = public.orders."customer_id" LIMIT 1) embedded) AS customers`, false},
+		{"more than one foreign key", "transfers", "", true},
+		{"without foreign key", "users", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		column, err := embedColumn("prest", "public", "orders", tc.embedded, keys)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if column != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, column)
+		}
+	}
+}
+
+func TestEmbedFields(t *testing.T) {
+	restrict, tables := config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables
+	defer func() { config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables = restrict, tables }()
+	config.PrestConf.AccessConf.Restrict = true
+	config.PrestConf.AccessConf.Tables = []config.TablesConf{
+		{Name: "items", Permissions: []string{"read"}, Fields: []string{"id", "name"}},
+		{Name: "orders", Permissions: []string{"read"}, Fields: []string{"*"}},
+		{Name: "customers", Permissions: []string{"read"}},
+	}
+
+	var testCases = []struct {
+		description string
+		table       string
+		expected    string
+		err         bool
+	}{
+		{"permitted fields", "items", `embedded."id",embedded."name"`, false},
+		{"all the fields", "orders", "embedded.*", false},
+		{"without fields", "customers", "", true},
+		{"table not listed", "users", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		fields, err := embedFields(tc.table)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if fields != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, fields)
+		}
+	}
+}
+
+func TestEmbedSelect(t *testing.T) {
+	if sql := EmbedSelect("SELECT * FROM", nil); sql != "SELECT * FROM" {
+		t.Errorf("expected the select unchanged, got %s", sql)
+	}
+	if sql := EmbedSelect("SELECT id,name FROM", []string{"(a) AS a", "(b) AS b"}); sql != "SELECT id,name,(a) AS a,(b) AS b FROM" {
+		t.Errorf("expected the embedded columns, got %s", sql)
+	}
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrEnvelopeParameters err throw when _envelope is used with count, explain or a single row
+var ErrEnvelopeParameters = errors.New("_envelope can't be used with _count, _explain, _first or _last")
+
+// Envelope is a page of rows with the metadata of the pagination
+type Envelope struct {
+	Data json.RawMessage `json:"data"`
+	Meta EnvelopeMeta    `json:"meta"`
+}
+
+// EnvelopeMeta is the page, without pagination only the total is set
+type EnvelopeMeta struct {
+	Page     int   `json:"page,omitempty"`
+	PageSize int   `json:"page_size,omitempty"`
+	Total    int64 `json:"total"`
+}
+
+// EnvelopeByRequest check `_envelope`, the rows are wrapped with the pagination metadata
+func EnvelopeByRequest(r *http.Request) (envelope bool, err error) {
+	queries := r.URL.Query()
+	if queries.Get("_envelope") != "true" {
+		return
+	}
+	if queries.Get("_count") != "" || queries.Get("_explain") != "" || queries.Get("_first") != "" || queries.Get("_last") != "" {
+		err = ErrEnvelopeParameters
+		return
+	}
+	envelope = true
+	return
+}
+
+// QueryEnvelopeInSearchPath run the query limited by the page in a read replica, the
+// total of rows matching the query is counted by a window in the same query
+func QueryEnvelopeInSearchPath(ctx context.Context, searchPath, SQL, page string, pageNumber, pageSize int, params ...interface{}) (jsonData []byte, err error) {
+	envelope := Envelope{Meta: EnvelopeMeta{Page: pageNumber, PageSize: pageSize}}
+	err = onReplica(ctx, func(db *sqlx.DB) error {
+		return inSearchPath(ctx, db, searchPath, func(p preparer) error {
+			return queryEnvelope(ctx, p, &envelope, SQL, page, params...)
+		})
+	})
+	if err != nil {
+		return
+	}
+	jsonData, err = json.Marshal(envelope)
+	return
+}
+
+func queryEnvelope(ctx context.Context, p preparer, envelope *Envelope, SQL, page string, params ...interface{}) error {
+	SQL, err := coerceColumns(ctx, p, SQL, params...)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	err = queryRow(ctx, p, fmt.Sprintf(statements.Envelope, SQL, SQL, page), params, &data, &envelope.Meta.Total)
+	if err != nil {
+		return err
+	}
+	envelope.Data = formatJSON(data)
+	return nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEnvelopeByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		envelope    bool
+		err         error
+	}{
+		{"without _envelope", "/prest/public/test", false, nil},
+		{"envelope", "/prest/public/test?_envelope=true", true, nil},
+		{"envelope with pagination", "/prest/public/test?_envelope=true&_page=2", true, nil},
+		{"envelope disabled", "/prest/public/test?_envelope=false&_count=*", false, nil},
+		{"envelope with count", "/prest/public/test?_envelope=true&_count=*", false, ErrEnvelopeParameters},
+		{"envelope with first row", "/prest/public/test?_envelope=true&_order=id&_first=true", false, ErrEnvelopeParameters},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		envelope, err := EnvelopeByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if envelope != tc.envelope {
+			t.Errorf("expected envelope %v, got %v", tc.envelope, envelope)
+		}
+	}
+}
+
+func TestPageByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		page        int
+		pageSize    int
+	}{
+		{"without pagination", "/prest/public/test", 0, 0},
+		{"page with size", "/prest/public/test?_page=2&_page_size=20", 2, 20},
+		{"page with default size", "/prest/public/test?_page=3", 3, 10},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, pageSize, err := PageByRequest(r)
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if page != tc.page || pageSize != tc.pageSize {
+			t.Errorf("expected page %d of %d, got %d of %d", tc.page, tc.pageSize, page, pageSize)
+		}
+	}
+}
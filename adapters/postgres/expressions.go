@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrExpressionWithoutAlias err throw when a computed `_select` field has no alias
+var ErrExpressionWithoutAlias = errors.New("expression requires an alias, e.g. price*quantity:as:total")
+
+// expressionTokenRegex match the tokens of the computed fields: columns, numbers,
+// the arithmetic operators and parentheses
+var expressionTokenRegex = regexp.MustCompile(`^(\s+|[\pL_][\pL\pN_]*|\d+(\.\d+)?|[-+*/()])`)
+
+// expression is a computed field, the columns are the ones referenced by the SQL
+type expression struct {
+	SQL     string
+	Columns []string
+}
+
+// isExpression check if a `_select` field is arithmetic instead of a column, `*`,
+// `table.*`, the jsonb fields and the aggregates are not
+func isExpression(field string) bool {
+	if isCaseField(field) || isCoalesceField(field) || isJSONBField(field) || isGroupFunction(field) {
+		return false
+	}
+	if strings.ContainsAny(field, "+-/") {
+		return true
+	}
+	return strings.Contains(field, "*") && field != "*" && !strings.HasSuffix(field, ".*")
+}
+
+// parseExpression validate a computed field, only columns, numeric literals,
+// + - * / and parentheses are accepted, functions calls and anything else are rejected
+func parseExpression(field string) (expr expression, err error) {
+	var tokens []string
+	var operand bool
+	depth := 0
+	for rest := field; rest != ""; {
+		token := expressionTokenRegex.FindString(rest)
+		if token == "" {
+			err = fmt.Errorf("invalid expression %s: unexpected %q", field, rest[:1])
+			return
+		}
+		rest = rest[len(token):]
+		if strings.TrimSpace(token) == "" {
+			continue
+		}
+
+		switch {
+		case token == "(":
+			if operand {
+				err = fmt.Errorf("invalid expression %s: function calls are not allowed", field)
+				return
+			}
+			depth++
+		case token == ")":
+			if !operand || depth == 0 {
+				err = fmt.Errorf("invalid expression %s: unbalanced parentheses", field)
+				return
+			}
+			depth--
+		case strings.Contains("+-*/", token):
+			if !operand {
+				// unary minus, e.g. -price, spaced to never write a -- comment
+				if token != "-" {
+					err = fmt.Errorf("invalid expression %s: operator %s without operand", field, token)
+					return
+				}
+				tokens = append(tokens, "- ")
+				continue
+			}
+			operand = false
+			tokens = append(tokens, " "+token+" ")
+			continue
+		default:
+			if operand {
+				err = fmt.Errorf("invalid expression %s: missing operator before %s (encode + as %%2B)", field, token)
+				return
+			}
+			operand = true
+			if !strings.ContainsAny(token[:1], "0123456789") {
+				expr.Columns = append(expr.Columns, token)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	if !operand || depth != 0 {
+		err = fmt.Errorf("invalid expression %s", field)
+		return
+	}
+	expr.SQL = strings.Join(tokens, "")
+	return
+}
+
+// CheckSelectExpressions validate that the columns referenced by the computed fields
+// of `_select` exist in the table, the query is cancelled when ctx is done
+func CheckSelectExpressions(ctx context.Context, database, schema, table string, fields []string) (err error) {
+	var columns []string
+	for _, field := range fields {
+		field, _ = splitAlias(field)
+		if !isExpression(field) {
+			continue
+		}
+		var expr expression
+		if expr, err = parseExpression(field); err != nil {
+			return
+		}
+		if columns == nil {
+			if columns, err = TableColumns(ctx, database, schema, table); err != nil {
+				return
+			}
+		}
+		for _, column := range expr.Columns {
+			if !containsString(columns, column) {
+				err = fmt.Errorf("column %s does not exist in %s.%s", column, schema, table)
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsExpression(t *testing.T) {
+	var testCases = []struct {
+		field    string
+		expected bool
+	}{
+		{"price", false},
+		{"*", false},
+		{"test.*", false},
+		{"SUM(salary)", false},
+		{"price*quantity", true},
+		{"price+1", true},
+		{"-price", true},
+		{"price/2", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.field)
+		if isExpression(tc.field) != tc.expected {
+			t.Errorf("expected %v", tc.expected)
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	var testCases = []struct {
+		description string
+		field       string
+		sql         string
+		columns     []string
+	}{
+		{"product of columns", "price*quantity", "price * quantity", []string{"price", "quantity"}},
+		{"numeric literal", "price * 1.1", "price * 1.1", []string{"price"}},
+		{"parentheses", "(price+tax)/2", "(price + tax) / 2", []string{"price", "tax"}},
+		{"unary minus", "-price", "- price", []string{"price"}},
+		{"double unary minus", "--price", "- - price", []string{"price"}},
+		{"subtraction of negative", "price--1", "price - - 1", []string{"price"}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		expr, err := parseExpression(tc.field)
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+			continue
+		}
+		if expr.SQL != tc.sql {
+			t.Errorf("expected %q, got %q", tc.sql, expr.SQL)
+		}
+		if !reflect.DeepEqual(expr.Columns, tc.columns) {
+			t.Errorf("expected columns %v, got %v", tc.columns, expr.Columns)
+		}
+	}
+
+	var errorCases = []struct {
+		description string
+		field       string
+	}{
+		{"function call", "lower(name)+1"},
+		{"subquery", "(select 1)+1"},
+		{"unbalanced parentheses", "(price*2"},
+		{"closing parentheses", "price*2)"},
+		{"operator without operand", "*price"},
+		{"trailing operator", "price*"},
+		{"missing operator", "price quantity"},
+		{"string literal", "price+'1'"},
+		{"comment", "price/*x*/"},
+		{"semicolon", "price+1;drop"},
+		{"qualified column", "test.price*2"},
+	}
+
+	for _, tc := range errorCases {
+		t.Log(tc.description)
+		if _, err := parseExpression(tc.field); err == nil {
+			t.Errorf("expected error for %q", tc.field)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+)
+
+// ftsOperator compare a tsvector column with a full text query, e.g.
+// search_vector=$fts.postgres+database is search_vector @@ plainto_tsquery($1)
+const ftsOperator = "@@"
+
+// tsqueryRegex match the queries with tsquery operators sent to to_tsquery, the words
+// are letters, digits and _, quotes and backslashes are rejected
+var tsqueryRegex = regexp.MustCompile(`^[\pL\pN_&|!()<>\-:*\s]+$`)
+
+// ftsCondition create the full text search of column, the functions use the fts.language
+// of the config when it's set. The words of a value without the tsquery operators
+// (& | ! <-> and parentheses) are ANDed by plainto_tsquery, which ignore the punctuation,
+// and the values with operators are validated and sent to to_tsquery
+func ftsCondition(column, value string, initialPlaceholderID int) (condition string, values []interface{}, nextPlaceholderID int, err error) {
+	words := strings.Fields(value)
+	if len(words) == 0 {
+		err = fmt.Errorf("empty full text query of %s", column)
+		return
+	}
+	function := "plainto_tsquery"
+	value = strings.Join(words, " ")
+	if strings.ContainsAny(value, "&|!()<") {
+		if !tsqueryRegex.MatchString(value) || strings.Count(value, "(") != strings.Count(value, ")") {
+			err = fmt.Errorf("invalid full text query of %s: %s", column, value)
+			return
+		}
+		function = "to_tsquery"
+	}
+
+	nextPlaceholderID = initialPlaceholderID
+	if language := config.PrestConf.FTSLanguage; language != "" {
+		condition = fmt.Sprintf("%s @@ %s($%d::regconfig, $%d)", column, function, nextPlaceholderID, nextPlaceholderID+1)
+		values = append(values, language)
+		nextPlaceholderID++
+	} else {
+		condition = fmt.Sprintf("%s @@ %s($%d)", column, function, nextPlaceholderID)
+	}
+	values = append(values, value)
+	nextPlaceholderID++
+	return
+}
+
+// CheckFTSFilters validate that the columns filtered by $fts are tsvector columns of
+// the table
+func CheckFTSFilters(r *http.Request, database, schema, table string) (err error) {
+	var columns []string
+	for key, values := range r.URL.Query() {
+		if strings.HasPrefix(key, "_") || strings.Contains(key, ":") {
+			continue
+		}
+		var filtered bool
+		for _, value := range values {
+			if strings.Replace(removeOperatorRegex.FindString(value), ".", "", -1) == "$fts" {
+				filtered = true
+			}
+		}
+		if filtered {
+			columns = append(columns, key)
+		}
+	}
+	return checkFTSColumns(schema, table, columns)
+}
+
+// checkFTSColumns validate that columns are tsvector columns of the table
+func checkFTSColumns(schema, table string, columns []string) (err error) {
+	if len(columns) == 0 {
+		return
+	}
+	types, err := copyColumnTypes(schema, table)
+	if err != nil {
+		return
+	}
+	for _, column := range columns {
+		column = strings.Trim(column, `"`)
+		if columnType, _ := findColumnType(types, column); columnType != "tsvector" {
+			err = fmt.Errorf("column %s is not a tsvector column of %s.%s", column, schema, table)
+			return
+		}
+	}
+	return
+}
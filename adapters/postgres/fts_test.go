@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestFTSCondition(t *testing.T) {
+	defer func() { config.PrestConf.FTSLanguage = "" }()
+
+	var testCases = []struct {
+		description string
+		language    string
+		value       string
+		condition   string
+		values      []interface{}
+		next        int
+		err         bool
+	}{
+		{"single word", "", "postgres", "search_vector @@ plainto_tsquery($2)", []interface{}{"postgres"}, 3, false},
+		{"words are ANDed", "", " postgres  database ", "search_vector @@ plainto_tsquery($2)", []interface{}{"postgres database"}, 3, false},
+		{"apostrophe", "", "o'neil's database", "search_vector @@ plainto_tsquery($2)", []interface{}{"o'neil's database"}, 3, false},
+		{"tsquery operators", "", "postgres | !mysql", "search_vector @@ to_tsquery($2)", []interface{}{"postgres | !mysql"}, 3, false},
+		{"phrase", "", "full <-> text", "search_vector @@ to_tsquery($2)", []interface{}{"full <-> text"}, 3, false},
+		{"prefix", "", "post:* & (sql | db)", "search_vector @@ to_tsquery($2)", []interface{}{"post:* & (sql | db)"}, 3, false},
+		{"language", "english", "databases", "search_vector @@ plainto_tsquery($2::regconfig, $3)", []interface{}{"english", "databases"}, 4, false},
+		{"language with operators", "english", "postgres | mysql", "search_vector @@ to_tsquery($2::regconfig, $3)", []interface{}{"english", "postgres | mysql"}, 4, false},
+		{"empty query", "", " ", "", nil, 0, true},
+		{"operators with apostrophe", "", "o'neil | postgres", "", nil, 0, true},
+		{"operators with backslash", "", `postgres & \mysql`, "", nil, 0, true},
+		{"unbalanced parentheses", "", "(postgres | mysql", "", nil, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.FTSLanguage = tc.language
+		condition, values, next, err := ftsCondition("search_vector", tc.value, 2)
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if condition != tc.condition {
+			t.Errorf("expected %s, got %s", tc.condition, condition)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected %v, got %v", tc.values, values)
+		}
+		if next != tc.next {
+			t.Errorf("expected next placeholder %d, got %d", tc.next, next)
+		}
+	}
+}
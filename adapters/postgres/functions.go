@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrFunctionNotFound err throw when the function does not exist in the schema
+var ErrFunctionNotFound = errors.New("function not found")
+
+// FunctionArgument name and type of a function input argument, the name is empty for
+// the unnamed arguments
+type FunctionArgument struct {
+	Name string
+	Type string
+}
+
+// FunctionArguments get the input arguments of a function from pg_proc, the query is
+// cancelled when ctx is done and run again by withRetry on transient errors
+func FunctionArguments(ctx context.Context, schema, function string) (args []FunctionArgument, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = withRetry(ctx, func() (err error) {
+		args, err = functionArguments(ctx, db, schema, function)
+		return
+	})
+	return
+}
+
+func functionArguments(ctx context.Context, db *sqlx.DB, schema, function string) (args []FunctionArgument, err error) {
+	rows, err := db.QueryContext(ctx, statements.FunctionArguments, schema, function)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var found bool
+	var lastOID int64
+	for rows.Next() {
+		var oid int64
+		var name, argType sql.NullString
+		if err = rows.Scan(&oid, &name, &argType); err != nil {
+			return
+		}
+
+		if found && oid != lastOID {
+			err = fmt.Errorf("function %s is overloaded", function)
+			return
+		}
+		found = true
+		lastOID = oid
+
+		// functions without arguments
+		if !argType.Valid {
+			continue
+		}
+		args = append(args, FunctionArgument{Name: name.String, Type: argType.String})
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if !found {
+		err = ErrFunctionNotFound
+	}
+	return
+}
+
+// FunctionCallByRequest create a function call SQL binding the body values by argument name
+func FunctionCallByRequest(r *http.Request, database, schema, function string) (sql string, values []interface{}, err error) {
+	if invalidNames(database, schema, function) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	body := make(map[string]interface{})
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return
+	}
+	defer r.Body.Close()
+
+	args, err := FunctionArguments(r.Context(), schema, function)
+	if err != nil {
+		return
+	}
+
+	return functionCall(fmt.Sprintf("%s.%s.%s", QuoteName(database), QuoteName(schema), QuoteName(function)), args, body)
+}
+
+// functionCall use named notation so arguments with default values can be omitted. The
+// unnamed arguments are sent by position, e.g. "$1", and the arguments up to the last
+// unnamed one are bound positionally, they are required
+func functionCall(function string, args []FunctionArgument, body map[string]interface{}) (sql string, values []interface{}, err error) {
+	lastUnnamed := -1
+	keys := make([]string, len(args))
+	types := make(map[string]string)
+	for i, arg := range args {
+		keys[i] = arg.Name
+		if arg.Name == "" {
+			keys[i] = fmt.Sprintf("$%d", i+1)
+			lastUnnamed = i
+		}
+		types[keys[i]] = arg.Type
+	}
+
+	namedArgs := make([]string, 0)
+	for i, arg := range args {
+		value, ok := body[keys[i]]
+		if !ok {
+			if i <= lastUnnamed {
+				err = fmt.Errorf("argument %s is required, it's bound by position", keys[i])
+				values = nil
+				return
+			}
+			continue
+		}
+
+		switch value.(type) {
+		case []interface{}:
+			var array string
+			if array, err = parseArray(value); err != nil {
+				err = fmt.Errorf("argument %s: %v", keys[i], err)
+				return
+			}
+			values = append(values, array)
+		case map[string]interface{}:
+			var b []byte
+			b, err = json.Marshal(value)
+			if err != nil {
+				return
+			}
+			values = append(values, string(b))
+		default:
+			values = append(values, value)
+		}
+		if i <= lastUnnamed {
+			namedArgs = append(namedArgs, fmt.Sprintf("$%d::%s", len(values), arg.Type))
+			continue
+		}
+		namedArgs = append(namedArgs, fmt.Sprintf("%s => $%d::%s", quoteIdentifier(arg.Name), len(values), arg.Type))
+	}
+
+	for key := range body {
+		if _, ok := types[key]; !ok {
+			err = fmt.Errorf("unknown argument %s", key)
+			values = nil
+			return
+		}
+	}
+
+	sql = fmt.Sprintf(statements.FunctionCall, function, strings.Join(namedArgs, ", "))
+	return
+}
+
+// TableFunctionCall create the FROM of a table function called with the arguments of the
+// path, e.g. my_srf(1,10), they are bound positionally to placeholders casted to the
+// types of the arguments and the omitted last ones get their defaults
+func TableFunctionCall(ctx context.Context, database, schema, function, arguments string, initialPlaceholderID int) (from string, values []interface{}, err error) {
+	if invalidNames(database, schema, function) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	args, err := FunctionArguments(ctx, schema, function)
+	if err != nil {
+		return
+	}
+
+	return tableFunctionCall(fmt.Sprintf("%s.%s.%s", QuoteName(database), QuoteName(schema), QuoteName(function)), args, splitFunctionArguments(arguments), initialPlaceholderID)
+}
+
+// splitFunctionArguments split the comma separated arguments of the path, the single
+// quotes around an argument are removed
+func splitFunctionArguments(arguments string) (values []string) {
+	if strings.TrimSpace(arguments) == "" {
+		return
+	}
+	for _, value := range strings.Split(arguments, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) > 1 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = value[1 : len(value)-1]
+		}
+		values = append(values, value)
+	}
+	return
+}
+
+func tableFunctionCall(function string, args []FunctionArgument, arguments []string, initialPlaceholderID int) (from string, values []interface{}, err error) {
+	if len(arguments) > len(args) {
+		err = fmt.Errorf("function %s takes %d arguments, got %d", function, len(args), len(arguments))
+		return
+	}
+
+	placeholders := make([]string, 0, len(arguments))
+	for i, value := range arguments {
+		placeholders = append(placeholders, fmt.Sprintf("$%d::%s", initialPlaceholderID+i, args[i].Type))
+		values = append(values, value)
+	}
+	from = fmt.Sprintf("%s(%s)", function, strings.Join(placeholders, ", "))
+	return
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFunctionCall(t *testing.T) {
+	args := []FunctionArgument{{"a", "integer"}, {"b", "integer"}}
+
+	var testCases = []struct {
+		description string
+		body        map[string]interface{}
+		expectedSQL string
+		valuesLen   int
+		testError   bool
+	}{
+		{"Call function with all arguments", map[string]interface{}{"a": 1, "b": 2}, "\nSELECT * FROM prest.public.test_add(\"a\" => $1::integer, \"b\" => $2::integer)", 2, false},
+		{"Call function omitting an argument", map[string]interface{}{"b": 2}, "\nSELECT * FROM prest.public.test_add(\"b\" => $1::integer)", 1, false},
+		{"Call function without arguments", map[string]interface{}{}, "\nSELECT * FROM prest.public.test_add()", 0, false},
+		{"Call function with unknown argument", map[string]interface{}{"a": 1, "c": 2}, "", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql, values, err := functionCall("prest.public.test_add", args, tc.body)
+		if tc.testError {
+			if err == nil {
+				t.Error("expected errors, but no was!")
+			}
+		} else if err != nil {
+			t.Errorf("expected no errors, but got: %v", err)
+		}
+
+		if sql != tc.expectedSQL {
+			t.Errorf("expected %q, got: %q", tc.expectedSQL, sql)
+		}
+
+		if len(values) != tc.valuesLen {
+			t.Errorf("expected %d values, got: %d", tc.valuesLen, len(values))
+		}
+	}
+}
+
+func TestFunctionCallUnnamedArguments(t *testing.T) {
+	args := []FunctionArgument{{"", "integer"}, {"b", "integer"}, {"c", "integer"}}
+
+	var testCases = []struct {
+		description string
+		body        map[string]interface{}
+		expectedSQL string
+		valuesLen   int
+		testError   bool
+	}{
+		{"unnamed argument by position", map[string]interface{}{"$1": 1, "c": 3}, "\nSELECT * FROM prest.public.test_add($1::integer, \"c\" => $2::integer)", 2, false},
+		{"missing unnamed argument", map[string]interface{}{"b": 2}, "", 0, true},
+		{"named argument by position", map[string]interface{}{"$1": 1, "$2": 2}, "", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql, values, err := functionCall("prest.public.test_add", args, tc.body)
+		if tc.testError != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.testError, err)
+		}
+		if sql != tc.expectedSQL {
+			t.Errorf("expected %q, got: %q", tc.expectedSQL, sql)
+		}
+		if len(values) != tc.valuesLen {
+			t.Errorf("expected %d values, got: %d", tc.valuesLen, len(values))
+		}
+	}
+}
+
+func TestTableFunctionCall(t *testing.T) {
+	args := []FunctionArgument{{"start", "integer"}, {"stop", "integer"}, {"label", "text"}}
+
+	var testCases = []struct {
+		description string
+		arguments   string
+		expectedSQL string
+		values      []interface{}
+		testError   bool
+	}{
+		{"all arguments", "1,10,'a b'", "prest.public.test_series($3::integer, $4::integer, $5::text)", []interface{}{"1", "10", "a b"}, false},
+		{"omitting the last arguments", "1, 10", "prest.public.test_series($3::integer, $4::integer)", []interface{}{"1", "10"}, false},
+		{"without arguments", "", "prest.public.test_series()", nil, false},
+		{"too many arguments", "1,10,a,b", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		from, values, err := tableFunctionCall("prest.public.test_series", args, splitFunctionArguments(tc.arguments), 3)
+		if tc.testError != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.testError, err)
+		}
+		if from != tc.expectedSQL {
+			t.Errorf("expected %q, got %q", tc.expectedSQL, from)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected %v, got %v", tc.values, values)
+		}
+	}
+}
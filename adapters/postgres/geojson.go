@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// GeoJSONContentType is the media type of GeoJSON output
+const GeoJSONContentType = "application/geo+json"
+
+// ErrNoGeometryColumn err throw when GeoJSON is requested from a table without geometry
+var ErrNoGeometryColumn = errors.New("table has no geometry column")
+
+// GeometryColumnByRequest return the geometry column used as GeoJSON geometry when the
+// request accept application/geo+json, `_geom=column` choose between geometry columns
+func GeometryColumnByRequest(r *http.Request, database, schema, table string) (column string, err error) {
+	if !strings.Contains(r.Header.Get("Accept"), GeoJSONContentType) {
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var columns []string
+	err = db.Select(&columns, statements.GeometryColumns, database, schema, table)
+	if err != nil {
+		return
+	}
+
+	if len(columns) == 0 {
+		err = ErrNoGeometryColumn
+		return
+	}
+
+	column = columns[0]
+	hint := r.URL.Query().Get("_geom")
+	if hint == "" {
+		return
+	}
+
+	for _, c := range columns {
+		if c == hint {
+			column = hint
+			return
+		}
+	}
+	column = ""
+	err = fmt.Errorf("%s is not a geometry column", hint)
+	return
+}
+
+// QueryGeoJSON process queries returning a GeoJSON FeatureCollection
+func QueryGeoJSON(SQL string, geometryColumn string, params ...interface{}) (jsonData []byte, err error) {
+	return QueryGeoJSONInSearchPath(context.Background(), "", SQL, geometryColumn, params...)
+}
+
+// QueryGeoJSONInSearchPath is QueryGeoJSON with the search_path of the request
+func QueryGeoJSONInSearchPath(ctx context.Context, searchPath, SQL string, geometryColumn string, params ...interface{}) (jsonData []byte, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = inSearchPath(ctx, db, searchPath, func(p preparer) error {
+		SQL, err := coerceColumns(ctx, p, SQL, params...)
+		if err != nil {
+			return err
+		}
+		return queryRow(ctx, p, geoJSONQuery(SQL, geometryColumn), params, &jsonData)
+	})
+	if err != nil {
+		return
+	}
+
+	jsonData = formatJSON(jsonData)
+	return
+}
+
+func geoJSONQuery(SQL string, geometryColumn string) string {
+	return fmt.Sprintf(statements.GeoJSONFeatureCollection, geometryColumn, geometryColumn, SQL)
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGeoJSONQuery(t *testing.T) {
+	sql := geoJSONQuery("SELECT * FROM prest.public.test_places", "location")
+
+	var expectedSQL = []string{
+		"'type', 'FeatureCollection'",
+		"ST_AsGeoJSON(s.location)::json",
+		"to_jsonb(s) - 'location'",
+		"FROM (SELECT * FROM prest.public.test_places) s",
+	}
+	for _, expected := range expectedSQL {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %s in %s, but no was!", expected, sql)
+		}
+	}
+}
+
+func TestGeometryColumnByRequestWithoutAccept(t *testing.T) {
+	r, err := http.NewRequest("GET", "/prest/public/test_places?_geom=location", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	column, err := GeometryColumnByRequest(r, "prest", "public", "test_places")
+	if err != nil {
+		t.Errorf("expected no errors, but got: %v", err)
+	}
+
+	if column != "" {
+		t.Errorf("expected empty column, got: %s", column)
+	}
+}
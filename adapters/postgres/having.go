@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrHavingGroupBy err throw when _having is used without _groupby
+var ErrHavingGroupBy = errors.New("_having requires _groupby")
+
+// comparisonOperators are the comparisons of an aggregate in _having or of a case in _select
+var comparisonOperators = []string{"=", "!=", ">", ">=", "<", "<="}
+
+// HavingByRequest create the conditions of the repeated `_having=GROUPFUNC:FIELD:CONDITION:VALUE`,
+// e.g. `_having=sum:amount:$gt:1000&_having=count:*:$lt:50`. The conditions are ANDed and
+// their values are numbered from initialPlaceholderID
+func HavingByRequest(r *http.Request, initialPlaceholderID int) (havingSQL string, values []interface{}, err error) {
+	havings := r.URL.Query()["_having"]
+	if len(havings) == 0 {
+		return
+	}
+	if r.URL.Query().Get("_groupby") == "" {
+		err = ErrHavingGroupBy
+		return
+	}
+
+	conditions := make([]string, 0, len(havings))
+	for _, having := range havings {
+		params := strings.SplitN(having, ":", 4)
+		if len(params) != 4 || params[3] == "" {
+			err = fmt.Errorf("invalid _having %s, use GROUPFUNC:FIELD:CONDITION:VALUE", having)
+			return
+		}
+		var aggregate, operator string
+		if aggregate, err = havingAggregate(params[0], params[1]); err != nil {
+			return
+		}
+		if operator, err = GetQueryOperator(params[2]); err != nil || !containsString(comparisonOperators, operator) {
+			err = fmt.Errorf("invalid _having operator %s", params[2])
+			return
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", aggregate, operator, initialPlaceholderID))
+		values = append(values, params[3])
+		initialPlaceholderID++
+	}
+	havingSQL = strings.Join(conditions, " AND ")
+	return
+}
+
+// havingAggregate create the aggregate of a _having, count accept * as field
+func havingAggregate(groupFunc, field string) (aggregate string, err error) {
+	if strings.ToUpper(groupFunc) == "COUNT" {
+		if field == "*" {
+			return "COUNT(*)", nil
+		}
+	} else if _, err = NormalizeGroupFunction(fmt.Sprintf("%s:%s", groupFunc, field)); err != nil {
+		return
+	}
+	if !validIdentifier(field) {
+		err = fmt.Errorf("invalid _having field %s", field)
+		return
+	}
+	aggregate = fmt.Sprintf("%s(%s)", strings.ToUpper(groupFunc), quoteColumn(field))
+	return
+}
+
+// AppendHaving add the conditions of HavingByRequest to the group by clause, ANDed with the
+// having of `_groupby=FIELD->>having:...`
+func AppendHaving(groupBySQL, havingSQL string) string {
+	if havingSQL == "" {
+		return groupBySQL
+	}
+	if strings.Contains(groupBySQL, " HAVING ") {
+		return fmt.Sprintf("%s AND %s", groupBySQL, havingSQL)
+	}
+	return fmt.Sprintf("%s HAVING %s", groupBySQL, havingSQL)
+}
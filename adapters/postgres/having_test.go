@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHavingByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+		values      []interface{}
+		err         bool
+	}{
+		{"without having", "/prest/public/test?_groupby=age", "", nil, false},
+		{"single having", "/prest/public/test?_groupby=age&_having=sum:salary:$gt:1000", "SUM(salary) > $2", []interface{}{"1000"}, false},
+		{"multiple having", "/prest/public/test?_groupby=age&_having=sum:amount:$gt:1000&_having=count:*:$lt:50", "SUM(amount) > $2 AND COUNT(*) < $3", []interface{}{"1000", "50"}, false},
+		{"count of a field", "/prest/public/test?_groupby=age&_having=count:id:$gte:2", "COUNT(id) >= $2", []interface{}{"2"}, false},
+		{"value with colons", "/prest/public/test?_groupby=age&_having=max:created:$lt:2020-01-01T10:00:00", "MAX(created) < $2", []interface{}{"2020-01-01T10:00:00"}, false},
+		{"without groupby", "/prest/public/test?_having=sum:salary:$gt:1000", "", nil, true},
+		{"not an aggregate", "/prest/public/test?_groupby=age&_having=salary:$gt:1000", "", nil, true},
+		{"invalid group function", "/prest/public/test?_groupby=age&_having=sun:salary:$gt:1000", "", nil, true},
+		{"star out of count", "/prest/public/test?_groupby=age&_having=sum:*:$gt:1000", "", nil, true},
+		{"invalid field", "/prest/public/test?_groupby=age&_having=sum:salary)%3BDROP:$gt:1000", "", nil, true},
+		{"invalid operator", "/prest/public/test?_groupby=age&_having=sum:salary:$in:1000", "", nil, true},
+		{"without value", "/prest/public/test?_groupby=age&_having=sum:salary:$gt:", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		having, values, err := HavingByRequest(r, 2)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if having != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, having)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected values %v, got %v", tc.values, values)
+		}
+	}
+}
+
+func TestAppendHaving(t *testing.T) {
+	var testCases = []struct {
+		description string
+		groupBy     string
+		having      string
+		expected    string
+	}{
+		{"without having", "GROUP BY age", "", "GROUP BY age"},
+		{"having", "GROUP BY age", "SUM(salary) > $1", "GROUP BY age HAVING SUM(salary) > $1"},
+		{"with the having of groupby", "GROUP BY age HAVING SUM(salary) > 500", "COUNT(*) < $1", "GROUP BY age HAVING SUM(salary) > 500 AND COUNT(*) < $1"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if groupBy := AppendHaving(tc.groupBy, tc.having); groupBy != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, groupBy)
+		}
+	}
+}
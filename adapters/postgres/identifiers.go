@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+)
+
+// strictIdentifierRegex match a column optionally qualified by its table and schema,
+// e.g. name, users.name or public.users."userId". The names are ASCII letters, digits and
+// _ not starting by a digit, or the same characters between double quotes
+var strictIdentifierRegex = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*|"[a-zA-Z0-9_]+")(\.([a-zA-Z_][a-zA-Z0-9_]*|"[a-zA-Z0-9_]+")){0,2}$`)
+
+// numericLiteralRegex match the numbers written as is in the SQL
+var numericLiteralRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// validIdentifier check the identifiers of the request written in the SQL, they can't
+// be sent as parameters: columns of _order, _groupby, _count, filters and bodies. Each
+// name has at most 63 bytes, the limit of postgres. Spaces, quotes, parentheses,
+// operators, comments and semicolons are rejected
+func validIdentifier(identifier string) bool {
+	if !strictIdentifierRegex.MatchString(identifier) {
+		return false
+	}
+	for _, name := range strings.Split(identifier, ".") {
+		if len(strings.Trim(name, `"`)) > 63 {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidNames check the names written in the SQL without qualification, e.g. the
+// database, schema and table of the path, with validIdentifier
+func invalidNames(names ...string) bool {
+	for _, name := range names {
+		if strings.Contains(name, ".") || !validIdentifier(name) {
+			return true
+		}
+	}
+	return false
+}
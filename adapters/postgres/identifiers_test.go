@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestValidIdentifier(t *testing.T) {
+	var testCases = []struct {
+		identifier string
+		valid      bool
+	}{
+		{"name", true},
+		{"_name2", true},
+		{"users.name", true},
+		{"public.users.name", true},
+		{`public.users."userId"`, true},
+		{"ação", false},
+		{"", false},
+		{"2name", false},
+		{"name; DROP TABLE users", false},
+		{"name;DROP", false},
+		{"id--", false},
+		{"id/**/", false},
+		{"(select pg_sleep(1))", false},
+		{"a)or(b", false},
+		{"name desc", false},
+		{`na"me`, false},
+		{"na'me", false},
+		{"a.b.c.d", false},
+		{"users.", false},
+		{"*", false},
+		{"abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabc", true},
+		{"abcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcdefghijabcd", false},
+	}
+
+	for _, tc := range testCases {
+		if valid := validIdentifier(tc.identifier); valid != tc.valid {
+			t.Errorf("expected %v for %q, got %v", tc.valid, tc.identifier, valid)
+		}
+	}
+}
+
+func TestRejectInjectedIdentifiers(t *testing.T) {
+	payloads := []string{"name; DROP TABLE users", "id--", "(select pg_sleep(1))", "a)or(b", "name'"}
+	for _, payload := range payloads {
+		p := url.QueryEscape(payload)
+		t.Log(payload)
+
+		r, _ := http.NewRequest("GET", "/prest/public/test?_order="+p, nil)
+		if _, err := OrderByRequest(r); err == nil {
+			t.Errorf("expected error on _order=%s", payload)
+		}
+		r, _ = http.NewRequest("GET", "/prest/public/test?_order=-"+p, nil)
+		if _, err := OrderByRequest(r); err == nil {
+			t.Errorf("expected error on _order=-%s", payload)
+		}
+		r, _ = http.NewRequest("GET", "/prest/public/test?_groupby="+p, nil)
+		if _, _, err := GroupByRequest(r, 1); err == nil {
+			t.Errorf("expected error on _groupby=%s", payload)
+		}
+		r, _ = http.NewRequest("GET", "/prest/public/test?_groupby=rollup:"+p, nil)
+		if _, _, err := GroupByRequest(r, 1); err == nil {
+			t.Errorf("expected error on _groupby=rollup:%s", payload)
+		}
+		r, _ = http.NewRequest("GET", "/prest/public/test?_count="+p, nil)
+		if _, _, err := CountByRequest(r); err == nil {
+			t.Errorf("expected error on _count=%s", payload)
+		}
+		r, _ = http.NewRequest("GET", "/prest/public/test?"+p+"=1", nil)
+		if _, _, err := WhereByRequest(r, 1); err == nil {
+			t.Errorf("expected error on the filter %s", payload)
+		}
+		if _, err := SelectFields([]string{payload}); err == nil {
+			t.Errorf("expected error on _select=%s", payload)
+		}
+	}
+}
+
+func TestHavingClauseValue(t *testing.T) {
+	var testCases = []struct {
+		having   string
+		expected string
+		value    string
+	}{
+		{"having:sum:salary:$gt:500", "HAVING SUM(salary) > $3", "500"},
+		{"having:max:name:$eq:o'neil", "HAVING MAX(name) = $3", "o'neil"},
+		{"having:sum:salary:$gt:0 OR 1=1", "HAVING SUM(salary) > $3", "0 OR 1=1"},
+		{"having:sum:salary--:$gt:500", "", ""},
+		{"having:sum:salary:$in:500", "", ""},
+	}
+
+	for _, tc := range testCases {
+		having, value := havingClause(tc.having, 3)
+		if having != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, having)
+		}
+		if value != tc.value {
+			t.Errorf("expected value %q, got %q", tc.value, value)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrIfNotExistsWhere err throw when _if_not_exists is used without filters
+var ErrIfNotExistsWhere = errors.New("_if_not_exists requires a filter (WHERE)")
+
+// IfNotExistsByRequest check `_if_not_exists=true`, the row is inserted only if no row
+// matches the filters of the query string
+func IfNotExistsByRequest(r *http.Request) bool {
+	return r.URL.Query().Get("_if_not_exists") == "true"
+}
+
+// InsertIfNotExistsValues return the placeholders of the insert casted to the type of
+// their columns, the values of an INSERT ... SELECT aren't typed by the target columns
+func InsertIfNotExistsValues(schema, table, names string) (values string, err error) {
+	types, err := copyColumnTypes(schema, table)
+	if err != nil {
+		return
+	}
+	return castPlaceholders(strings.Split(names, ", "), types)
+}
+
+func castPlaceholders(names []string, types []columnType) (values string, err error) {
+	casts := make([]string, 0, len(names))
+	for i, name := range names {
+		columnType, ok := findColumnType(types, strings.Trim(name, `"`))
+		if !ok {
+			err = fmt.Errorf("column %s does not exist", name)
+			return
+		}
+		casts = append(casts, fmt.Sprintf("$%d::%s", i+1, columnType))
+	}
+	values = strings.Join(casts, ",")
+	return
+}
+
+// InsertIfNotExistsQuery build the insert of names guarded by NOT EXISTS of the rows
+// matching where, the first matching row is returned when nothing is inserted
+func InsertIfNotExistsQuery(database, schema, table, names, values, where string) string {
+	database, schema, table = QuoteName(database), QuoteName(schema), QuoteName(table)
+	return fmt.Sprintf(statements.InsertIfNotExists,
+		database, schema, table, where,
+		database, schema, table, names, values)
+}
+
+// IfNotExistsLock is the key of the advisory lock of the inserts if not exists of a
+// table, taken when the request has no `_lock`. Two concurrent inserts would both miss
+// the row of the other, the lock wait for the first one to commit
+func IfNotExistsLock(database, schema, table string) *int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "_if_not_exists.%s.%s.%s", database, schema, table)
+	key := int64(h.Sum64())
+	return &key
+}
+
+// InsertIfNotExistsWithLock execute the query of InsertIfNotExistsQuery holding the
+// advisory lock of lock, nil is no lock, in a transaction of the isolation level.
+// created tell if the row was inserted or found, the transaction is rolled back when ctx
+// is done
+func InsertIfNotExistsWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, created bool, err error) {
+	defer timeQuery(ctx, SQL, params)()
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, SQL, params...).Scan(&jsonData, &created)
+		if err != nil {
+			logger(ctx).Printf("could not insert if not exists: %s\n Error: %v\n", SQL, err)
+		}
+		return err
+	})
+	return
+}
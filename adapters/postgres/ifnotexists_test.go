@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIfNotExistsByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    bool
+	}{
+		{"insert if not exists", "/prest/public/test?_if_not_exists=true&name=$eq.prest", true},
+		{"plain insert", "/prest/public/test", false},
+		{"disabled insert if not exists", "/prest/public/test?_if_not_exists=false", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNotExists := IfNotExistsByRequest(r); ifNotExists != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, ifNotExists)
+		}
+	}
+}
+
+func TestCastPlaceholders(t *testing.T) {
+	types := []columnType{{Name: "id", Type: "integer"}, {Name: "userName", Type: "character varying"}, {Name: "tags", Type: "text[]"}}
+
+	values, err := castPlaceholders([]string{"id", `"userName"`, "tags"}, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "$1::integer,$2::character varying,$3::text[]"; values != expected {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+
+	t.Log("nonexistent column")
+	if _, err := castPlaceholders([]string{"nonexistent"}, types); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestInsertIfNotExistsQuery(t *testing.T) {
+	sql := InsertIfNotExistsQuery("prest", "public", "test", "name", "$1::text", "name = $2")
+	expected := `
+WITH existing AS (SELECT * FROM prest.public.test WHERE name = $2 LIMIT 1),
+inserted AS (INSERT INTO prest.public.test(name) SELECT $1::text WHERE NOT EXISTS (SELECT 1 FROM existing) RETURNING *)
+SELECT row_to_json(inserted), true FROM inserted
+UNION ALL
+SELECT row_to_json(existing), false FROM existing`
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestIfNotExistsLock(t *testing.T) {
+	lock := IfNotExistsLock("prest", "public", "test")
+	if lock == nil {
+		t.Fatal("expected a lock, got nil")
+	}
+
+	t.Log("same table")
+	if other := IfNotExistsLock("prest", "public", "test"); *other != *lock {
+		t.Errorf("expected the lock %d, got %d", *lock, *other)
+	}
+
+	t.Log("other table")
+	if other := IfNotExistsLock("prest", "public", "test2"); *other == *lock {
+		t.Errorf("expected a lock other than %d", *lock)
+	}
+}
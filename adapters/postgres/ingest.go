@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// Content types of the bodies ingested by COPY
+const (
+	CSVContentType    = "text/csv"
+	NDJSONContentType = "application/x-ndjson"
+)
+
+// ErrIngestEmpty err throw when an ingested body has no header or first row
+var ErrIngestEmpty = errors.New("body without columns, send a CSV header or a JSON object")
+
+// ErrIngestBody err wrapping the malformed rows of an ingested body and the values the
+// database can't convert to the types of the columns
+var ErrIngestBody = errors.New("invalid body")
+
+// ErrIngestColumn err wrapping the unknown and repeated columns of an ingested body
+var ErrIngestColumn = errors.New("invalid column")
+
+// IngestByRequest check the Content-Type of a POST to _copy, CSV and NDJSON bodies are
+// ingested with COPY instead of copying the rows of another table
+func IngestByRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == CSVContentType || mediaType == NDJSONContentType
+}
+
+// ingestReader read the rows of an ingested body, columns is read first
+type ingestReader interface {
+	columns() ([]string, error)
+	next() ([]interface{}, error)
+}
+
+// Ingest load the CSV or NDJSON body in schema.table with COPY in a transaction holding
+// the advisory lock of lock, nil is no lock. The columns are the header of the CSV, the
+// empty fields are NULL, or the keys of the first JSON object, the missing keys are NULL.
+// Nothing is loaded when a row fails. The body is read once, so the transaction isn't
+// retried. The transaction is rolled back when ctx is done
+func Ingest(ctx context.Context, lock *int64, isolation, schema, table, contentType string, body io.Reader) (jsonData []byte, err error) {
+	var reader ingestReader
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == NDJSONContentType {
+		reader = newNDJSONReader(body)
+	} else {
+		reader = newCSVReader(body)
+	}
+
+	columns, err := reader.columns()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrIngestBody, err)
+		return
+	}
+	if err = checkIngestColumns(schema, table, columns); err != nil {
+		return
+	}
+
+	var rows int64
+	err = transaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema(schema, table, columns...))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for {
+			values, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%w, row %d: %w", ErrIngestBody, rows+1, err)
+			}
+			if _, err = stmt.ExecContext(ctx, values...); err != nil {
+				return err
+			}
+			rows++
+		}
+		_, err = stmt.ExecContext(ctx)
+		return err
+	})
+	// the data exceptions (class 22) are values of the body, e.g. a text in a number
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "22" {
+		err = fmt.Errorf("%w: %w", ErrIngestBody, err)
+	}
+	if err != nil {
+		return
+	}
+	jsonData, err = json.Marshal(map[string]int64{"rows_affected": rows})
+	return
+}
+
+// checkIngestColumns validate that columns are distinct columns of schema.table
+func checkIngestColumns(schema, table string, columns []string) (err error) {
+	types, err := copyColumnTypes(schema, table)
+	if err != nil {
+		return
+	}
+	for i, column := range columns {
+		if _, ok := findColumnType(types, column); !ok {
+			return fmt.Errorf("%w: column %s does not exist in %s.%s", ErrIngestColumn, column, schema, table)
+		}
+		if containsString(columns[:i], column) {
+			return fmt.Errorf("%w: column %s is repeated", ErrIngestColumn, column)
+		}
+	}
+	return
+}
+
+type csvReader struct {
+	reader *csv.Reader
+}
+
+func newCSVReader(body io.Reader) *csvReader {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+	return &csvReader{reader: reader}
+}
+
+func (c *csvReader) columns() (columns []string, err error) {
+	header, err := c.reader.Read()
+	if err == io.EOF {
+		err = ErrIngestEmpty
+	}
+	if err != nil {
+		return
+	}
+	columns = append(columns, header...)
+	return
+}
+
+func (c *csvReader) next() (values []interface{}, err error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		return
+	}
+	values = make([]interface{}, len(record))
+	for i, field := range record {
+		if field != "" {
+			values[i] = field
+		}
+	}
+	return
+}
+
+type ndjsonReader struct {
+	decoder *json.Decoder
+	first   map[string]interface{}
+	keys    []string
+}
+
+func newNDJSONReader(body io.Reader) *ndjsonReader {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	return &ndjsonReader{decoder: decoder}
+}
+
+func (n *ndjsonReader) columns() (columns []string, err error) {
+	if err = n.decoder.Decode(&n.first); err == io.EOF || (err == nil && len(n.first) == 0) {
+		err = ErrIngestEmpty
+	}
+	if err != nil {
+		return
+	}
+	for key := range n.first {
+		n.keys = append(n.keys, key)
+	}
+	sort.Strings(n.keys)
+	columns = n.keys
+	return
+}
+
+func (n *ndjsonReader) next() (values []interface{}, err error) {
+	object := n.first
+	n.first = nil
+	if object == nil {
+		if err = n.decoder.Decode(&object); err != nil {
+			return
+		}
+	}
+
+	values = make([]interface{}, len(n.keys))
+	for i, key := range n.keys {
+		value := object[key]
+		delete(object, key)
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			var document []byte
+			if document, err = json.Marshal(value); err != nil {
+				return
+			}
+			value = string(document)
+		}
+		values[i] = value
+	}
+	for key := range object {
+		err = fmt.Errorf("column %s is not in the first row", key)
+		return
+	}
+	return
+}
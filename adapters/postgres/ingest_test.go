@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIngestByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		contentType string
+		expected    bool
+	}{
+		{"CSV", "text/csv", true},
+		{"CSV with charset", "text/csv; charset=utf-8", true},
+		{"NDJSON", "application/x-ndjson", true},
+		{"JSON", "application/json", false},
+		{"without content type", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", "/prest/public/test/_copy", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", tc.contentType)
+		if ingest := IngestByRequest(r); ingest != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, ingest)
+		}
+	}
+}
+
+func readIngest(reader ingestReader) (columns []string, rows [][]interface{}, err error) {
+	if columns, err = reader.columns(); err != nil {
+		return
+	}
+	for {
+		var values []interface{}
+		values, err = reader.next()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		rows = append(rows, values)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		columns     []string
+		rows        [][]interface{}
+		err         bool
+	}{
+		{"header and rows", "name,age\ngopher,10\n\"pREST, the API\",\n", []string{"name", "age"}, [][]interface{}{{"gopher", "10"}, {"pREST, the API", nil}}, false},
+		{"header without rows", "name,age\n", []string{"name", "age"}, nil, false},
+		{"empty body", "", nil, nil, true},
+		{"row with missing fields", "name,age\ngopher\n", []string{"name", "age"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		columns, rows, err := readIngest(newCSVReader(strings.NewReader(tc.body)))
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if !reflect.DeepEqual(columns, tc.columns) {
+			t.Errorf("expected columns %v, got %v", tc.columns, columns)
+		}
+		if !reflect.DeepEqual(rows, tc.rows) {
+			t.Errorf("expected rows %v, got %v", tc.rows, rows)
+		}
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		columns     []string
+		rows        [][]interface{}
+		err         bool
+	}{
+		{
+			"object with a key not in the first one",
+			`{"name": "gopher", "age": 10, "active": true}` + "\n" + `{"name": "prest", "tags": null}` + "\n",
+			nil, nil, true,
+		},
+		{
+			"objects with the keys of the first one",
+			`{"name": "gopher", "age": 10, "data": {"a": [1]}}` + "\n" + `{"name": "prest"}` + "\n",
+			[]string{"age", "data", "name"},
+			[][]interface{}{{"10", `{"a":[1]}`, "gopher"}, {nil, nil, "prest"}},
+			false,
+		},
+		{"empty body", "", nil, nil, true},
+		{"empty object", "{}\n", nil, nil, true},
+		{"not an object", `["gopher"]`, nil, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		columns, rows, err := readIngest(newNDJSONReader(strings.NewReader(tc.body)))
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if !reflect.DeepEqual(columns, tc.columns) {
+			t.Errorf("expected columns %v, got %v", tc.columns, columns)
+		}
+		for i := range rows {
+			for j, value := range rows[i] {
+				if value != nil {
+					rows[i][j] = fmt.Sprint(value)
+				}
+			}
+		}
+		if !reflect.DeepEqual(rows, tc.rows) {
+			t.Errorf("expected rows %v, got %v", tc.rows, rows)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrInvalidIsolation err throw when _isolation isn't an isolation level
+var ErrInvalidIsolation = errors.New("invalid _isolation, use read_committed, repeatable_read or serializable")
+
+// ErrIsolationParameters err throw when _isolation is used with explain or GeoJSON
+var ErrIsolationParameters = errors.New("_isolation can't be used with _explain or GeoJSON")
+
+// isolationLevels are the levels of `_isolation` and their SQL
+var isolationLevels = map[string]string{
+	"read_committed":  "READ COMMITTED",
+	"repeatable_read": "REPEATABLE READ",
+	"serializable":    "SERIALIZABLE",
+}
+
+// IsolationByRequest read `_isolation`, the isolation level of the transaction of the
+// operation, e.g. serializable or `REPEATABLE READ`. It's empty without `_isolation`,
+// the default level of the database is used
+func IsolationByRequest(r *http.Request) (isolation string, err error) {
+	queries := r.URL.Query()
+	value := queries.Get("_isolation")
+	if value == "" {
+		return
+	}
+	if queries.Get("_explain") != "" || strings.Contains(r.Header.Get("Accept"), GeoJSONContentType) {
+		err = ErrIsolationParameters
+		return
+	}
+
+	isolation, ok := isolationLevels[strings.ToLower(strings.Replace(strings.TrimSpace(value), " ", "_", -1))]
+	if !ok {
+		err = ErrInvalidIsolation
+	}
+	return
+}
+
+// setIsolation set the isolation level of tx, it must be run before the first query of
+// the transaction. Nothing is done without isolation
+func setIsolation(ctx context.Context, tx *sql.Tx, isolation string) (err error) {
+	if isolation == "" {
+		return
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(statements.SetTransactionIsolation, isolation))
+	return
+}
+
+// inIsolation run the read only fn in a transaction of the isolation level with the
+// search_path of the request, in the primary since the standbys don't run serializable
+// transactions
+func inIsolation(ctx context.Context, isolation, searchPath string, fn func(p preparer) error) error {
+	return inTransaction(ctx, nil, isolation, func(tx *sql.Tx) (err error) {
+		if searchPath != "" {
+			if _, err = tx.ExecContext(ctx, fmt.Sprintf(statements.SetSearchPath, searchPath)); err != nil {
+				return
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// QueryIsolated is QueryReplicaInSearchPath in a transaction of the isolation level
+func QueryIsolated(ctx context.Context, isolation, searchPath, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	err = inIsolation(ctx, isolation, searchPath, func(p preparer) (err error) {
+		jsonData, err = queryJSON(ctx, p, SQL, params...)
+		return
+	})
+	return
+}
+
+// QueryCountIsolated is QueryCountReplicaInSearchPath in a transaction of the isolation level
+func QueryCountIsolated(ctx context.Context, isolation, searchPath, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	err = inIsolation(ctx, isolation, searchPath, func(p preparer) (err error) {
+		jsonData, err = queryCount(ctx, p, SQL, params...)
+		return
+	})
+	return
+}
+
+// QueryEnvelopeIsolated is QueryEnvelopeInSearchPath in a transaction of the isolation level
+func QueryEnvelopeIsolated(ctx context.Context, isolation, searchPath, SQL, page string, pageNumber, pageSize int, params ...interface{}) (jsonData []byte, err error) {
+	envelope := Envelope{Meta: EnvelopeMeta{Page: pageNumber, PageSize: pageSize}}
+	err = inIsolation(ctx, isolation, searchPath, func(p preparer) error {
+		return queryEnvelope(ctx, p, &envelope, SQL, page, params...)
+	})
+	if err != nil {
+		return
+	}
+	jsonData, err = json.Marshal(envelope)
+	return
+}
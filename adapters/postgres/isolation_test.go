@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsolationByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		accept      string
+		expected    string
+		err         error
+	}{
+		{"without isolation", "/prest/public/test", "", "", nil},
+		{"serializable", "/prest/public/test?_isolation=serializable", "", "SERIALIZABLE", nil},
+		{"repeatable read", "/prest/public/test?_isolation=repeatable_read", "", "REPEATABLE READ", nil},
+		{"read committed in SQL", "/prest/public/test?_isolation=READ%20COMMITTED", "", "READ COMMITTED", nil},
+		{"invalid isolation", "/prest/public/test?_isolation=snapshot", "", "", ErrInvalidIsolation},
+		{"injection", "/prest/public/test?_isolation=serializable%3BDROP%20TABLE%20test", "", "", ErrInvalidIsolation},
+		{"isolation with explain", "/prest/public/test?_isolation=serializable&_explain=true", "", "", ErrIsolationParameters},
+		{"isolation with GeoJSON", "/prest/public/test?_isolation=serializable", GeoJSONContentType, "", ErrIsolationParameters},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", tc.accept)
+		isolation, err := IsolationByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if isolation != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, isolation)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+)
+
+// ErrJoinValues err throw when the filters of a derived table are used without JoinByRequestWithValues
+var ErrJoinValues = errors.New("the filters of a derived table in _join require the values of the join")
+
+// derivedTable of a join, TABLE@ALIAS, built with the parameters prefixed by
+// `_join.ALIAS.`, e.g. _join.ALIAS._select
+func derivedTable(queries url.Values, right string, initialPlaceholderID int) (sql string, values []interface{}, err error) {
+	parts := strings.SplitN(right, "@", 2)
+	table, alias := parts[0], parts[1]
+	if !validIdentifier(table) || !plainIdentifierRegex.MatchString(alias) {
+		err = fmt.Errorf("invalid derived table %s", right)
+		return
+	}
+
+	prefix := fmt.Sprintf("_join.%s.", alias)
+	params := url.Values{}
+	for key, value := range queries {
+		if strings.HasPrefix(key, prefix) {
+			params[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	sub := &http.Request{URL: &url.URL{RawQuery: params.Encode()}}
+
+	selectStr, err := SelectFields(ColumnsByRequest(sub))
+	if err != nil {
+		return
+	}
+	sql = fmt.Sprintf("%s %s", selectStr, quoteColumn(table))
+
+	where, values, err := whereByValues(params, initialPlaceholderID, nil)
+	if err != nil {
+		return
+	}
+	if where != "" {
+		sql = fmt.Sprint(sql, " WHERE ", where)
+	}
+
+	groupBySQL, groupByValues, err := GroupByRequest(sub, initialPlaceholderID+len(values))
+	if err != nil {
+		return
+	}
+	values = append(values, groupByValues...)
+	if groupBySQL != "" {
+		sql = fmt.Sprintf("%s %s", sql, groupBySQL)
+	}
+	sql = fmt.Sprintf("(%s) %s", sql, QuoteName(alias))
+	return
+}
+
+// JoinByRequestWithValues implements join like JoinByRequest, the table can be a derived
+// table TABLE@ALIAS selected with `_join.ALIAS._select`, `_join.ALIAS._groupby` and the
+// `_join.ALIAS.FIELD` filters. The values of the filters are numbered from initialPlaceholderID.
+// `_join` is repeated for several joins, in order, at most max_joins
+func JoinByRequestWithValues(r *http.Request, initialPlaceholderID int) (joins []string, values []interface{}, err error) {
+	queries := r.URL.Query()
+
+	var requestJoins []string
+	for _, join := range queries["_join"] {
+		if join != "" {
+			requestJoins = append(requestJoins, join)
+		}
+	}
+	if max := config.PrestConf.MaxJoins; max > 0 && len(requestJoins) > max {
+		err = fmt.Errorf("too many joins, %d of max_joins %d", len(requestJoins), max)
+		return
+	}
+
+	for _, join := range requestJoins {
+		joinQuery, joinValues, joinErr := joinClause(queries, join, initialPlaceholderID+len(values))
+		if joinErr != nil {
+			joins, values, err = nil, nil, joinErr
+			return
+		}
+		joins = append(joins, joinQuery)
+		values = append(values, joinValues...)
+	}
+	return
+}
+
+// joinClause create the join TYPE:TABLE:FIELD:OPERATOR:FIELD of a _join
+func joinClause(queries url.Values, join string, initialPlaceholderID int) (joinQuery string, values []interface{}, err error) {
+	joinArgs := strings.Split(join, ":")
+
+	if len(joinArgs) != 5 {
+		err = errors.New("Invalid number of arguments in join statement")
+		return
+	}
+
+	table := joinArgs[1]
+	if strings.Contains(table, "@") {
+		table, values, err = derivedTable(queries, table, initialPlaceholderID)
+		if err != nil {
+			return
+		}
+	} else if !validIdentifier(table) {
+		err = ErrInvalidIdentifier
+		return
+	} else {
+		table = quoteColumn(table)
+	}
+
+	if !validIdentifier(joinArgs[2]) || !validIdentifier(joinArgs[4]) {
+		err = ErrInvalidIdentifier
+		values = nil
+		return
+	}
+
+	op, err := GetQueryOperator(joinArgs[3])
+	if err != nil {
+		values = nil
+		return
+	}
+
+	joinQuery = fmt.Sprintf(" %s JOIN %s ON %s %s %s ", strings.ToUpper(joinArgs[0]), table, quoteColumn(joinArgs[2]), op, quoteColumn(joinArgs[4]))
+	return
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestJoinByRequestWithValues(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		join        string
+		values      []interface{}
+		testError   bool
+	}{
+		{"flat join", "/prest/public/test?_join=inner:test2:test2.name:$eq:test.name", " INNER JOIN test2 ON test2.name = test.name ", nil, false},
+		{"derived table", "/prest/public/users?_join=left:orders@totals:totals.user_id:$eq:users.id&_join.totals._select=user_id,sum(amount):as:total&_join.totals._groupby=user_id", " LEFT JOIN (SELECT user_id,sum(amount) AS total FROM orders GROUP BY user_id) totals ON totals.user_id = users.id ", nil, false},
+		{"derived table with filter", "/prest/public/users?_join=inner:orders@paid:paid.user_id:$eq:users.id&_join.paid.status=$eq.paid", " INNER JOIN (SELECT * FROM orders WHERE status = $3) paid ON paid.user_id = users.id ", []interface{}{"paid"}, false},
+		{"derived table with invalid alias", "/prest/public/users?_join=inner:orders@paid.x:paid.user_id:$eq:users.id", "", nil, true},
+		{"derived table with function as table", "/prest/public/users?_join=inner:pg_sleep(10)@paid:paid.user_id:$eq:users.id", "", nil, true},
+		{"derived table with invalid select", "/prest/public/users?_join=inner:orders@paid:paid.user_id:$eq:users.id&_join.paid._select=0id", "", nil, true},
+		{"multiple joins", "/prest/public/test?_join=inner:test2:test2.name:$eq:test.name&_join=left:test3:test3.id:$eq:test2.id", " INNER JOIN test2 ON test2.name = test.name  LEFT JOIN test3 ON test3.id = test2.id ", nil, false},
+		{"multiple derived tables", "/prest/public/users?_join=inner:orders@paid:paid.user_id:$eq:users.id&_join.paid.status=$eq.paid&_join=inner:payments@late:late.user_id:$eq:users.id&_join.late.days=$gt.30", " INNER JOIN (SELECT * FROM orders WHERE status = $3) paid ON paid.user_id = users.id  INNER JOIN (SELECT * FROM payments WHERE days > $4) late ON late.user_id = users.id ", []interface{}{"paid", "30"}, false},
+		{"multiple joins with an invalid join", "/prest/public/test?_join=inner:test2:test2.name:$eq:test.name&_join=left:test3:test3.id:$eq", "", nil, true},
+		{"derived table with invalid on", "/prest/public/users?_join=inner:orders@paid:paid.user_id:$eq:0users.id&_join.paid.status=$eq.paid", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joins, values, err := JoinByRequestWithValues(r, 3)
+		if tc.testError {
+			if err == nil {
+				t.Errorf("expected errors, got %v", joins)
+			}
+			if joins != nil || values != nil {
+				t.Errorf("expected empty join, got %v %v", joins, values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if join := strings.Join(joins, ""); join != tc.join {
+			t.Errorf("expected %q, got %q", tc.join, join)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected values %v, got %v", tc.values, values)
+		}
+	}
+}
+
+func TestJoinByRequestMaxJoins(t *testing.T) {
+	maxJoins := config.PrestConf.MaxJoins
+	defer func() { config.PrestConf.MaxJoins = maxJoins }()
+
+	url := "/prest/public/test?_join=inner:test2:test2.id:$eq:test.id&_join=inner:test3:test3.id:$eq:test.id&_join=inner:test4:test4.id:$eq:test.id"
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.PrestConf.MaxJoins = 2
+	if joins, _, err := JoinByRequestWithValues(r, 1); err == nil || joins != nil {
+		t.Errorf("expected error of max_joins, got %v", joins)
+	}
+
+	config.PrestConf.MaxJoins = 3
+	if joins, _, err := JoinByRequestWithValues(r, 1); err != nil || len(joins) != 3 {
+		t.Errorf("expected 3 joins, got %v %v", joins, err)
+	}
+
+	config.PrestConf.MaxJoins = 0
+	if joins, _, err := JoinByRequestWithValues(r, 1); err != nil || len(joins) != 3 {
+		t.Errorf("expected 3 joins without limit, got %v %v", joins, err)
+	}
+}
+
+func TestJoinByRequestDerivedTableValues(t *testing.T) {
+	r, err := http.NewRequest("GET", "/prest/public/users?_join=inner:orders@paid:paid.user_id:$eq:users.id&_join.paid.status=$eq.paid", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = JoinByRequest(r); err != ErrJoinValues {
+		t.Errorf("expected %v, got %v", ErrJoinValues, err)
+	}
+}
+
+func TestJoinByRequestQuoted(t *testing.T) {
+	defer func(quote bool) { config.PrestConf.QuoteIdentifiers = quote }(config.PrestConf.QuoteIdentifiers)
+	config.PrestConf.QuoteIdentifiers = true
+
+	var testCases = []struct {
+		description string
+		url         string
+		join        string
+	}{
+		{"flat join", "/prest/public/test?_join=inner:public.user:user.name:$eq:test.name", ` INNER JOIN "public"."user" ON "user"."name" = "test"."name" `},
+		{"derived table", "/prest/public/users?_join=left:order@select:select.user_id:$eq:users.id", ` LEFT JOIN (SELECT * FROM "order") "select" ON "select"."user_id" = "users"."id" `},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joins, _, err := JoinByRequestWithValues(r, 1)
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if join := strings.Join(joins, ""); join != tc.join {
+			t.Errorf("expected %q, got %q", tc.join, join)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Timestamp formats of the JSON output, see columnCoercions
+const (
+	TimestampRFC3339 = "rfc3339"
+	TimestampEpochMS = "epoch_ms"
+)
+
+// maxSafeInteger is the biggest integer a float64 represent without loss (2^53 - 1)
+const maxSafeInteger = 9007199254740991
+
+// numbersAsString quote the JSON numbers matched by quote, the JSON built by
+// postgres is rewritten in place so the columns order is kept
+func numbersAsString(data []byte, quote func(number []byte) bool) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i
+			for j < len(data) && bytes.IndexByte([]byte("0123456789+-.eE"), data[j]) >= 0 {
+				j++
+			}
+			number := data[i:j]
+			if quote(number) {
+				out.WriteByte('"')
+				out.Write(number)
+				out.WriteByte('"')
+			} else {
+				out.Write(number)
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// isBigInteger return true for integers out of the float64 safe range,
+// javascript clients would silently round them
+func isBigInteger(number []byte) bool {
+	if bytes.ContainsAny(number, ".eE") {
+		return false
+	}
+
+	n, err := strconv.ParseInt(string(number), 10, 64)
+	if err != nil {
+		// out of int64 range
+		return true
+	}
+	return n > maxSafeInteger || n < -maxSafeInteger
+}
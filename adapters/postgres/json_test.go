@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"testing"
+)
+
+func TestNumbersAsString(t *testing.T) {
+	var testCases = []struct {
+		description string
+		in          string
+		out         string
+	}{
+		{"Small numbers", `[{"age":30,"salary":-1250,"rate":12.5}]`, `[{"age":30,"salary":-1250,"rate":12.5}]`},
+		{"Max safe integer", `[{"id":9007199254740991}]`, `[{"id":9007199254740991}]`},
+		{"Big integer", `[{"id":9007199254740993}, {"id":-9007199254740993}]`, `[{"id":"9007199254740993"}, {"id":"-9007199254740993"}]`},
+		{"Integer out of int64 range", `{"n":123456789012345678901234567890}`, `{"n":"123456789012345678901234567890"}`},
+		{"Float with exponent", `{"n":1.5e+300}`, `{"n":1.5e+300}`},
+		{"Numbers inside strings", `{"name":"9007199254740993 \"9007199254740993\""}`, `{"name":"9007199254740993 \"9007199254740993\""}`},
+		{"Nested values", `{"data":{"ids":[1,9007199254740993]},"ok":true,"nothing":null}`, `{"data":{"ids":[1,"9007199254740993"]},"ok":true,"nothing":null}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		out := string(numbersAsString([]byte(tc.in), isBigInteger))
+		if out != tc.out {
+			t.Errorf("expected %s, got: %s", tc.out, out)
+		}
+	}
+}
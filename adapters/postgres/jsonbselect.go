@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrJSONBFieldWithoutAlias err throw when a jsonb `_select` field has no alias
+var ErrJSONBFieldWithoutAlias = errors.New("jsonb field requires an alias, e.g. data->>name:as:name")
+
+// jsonbFieldRegex match the jsonb fields of `_select`, a column followed by the keys of
+// the nested objects and the extracted key, e.g. data->>name or data->address->>city
+var jsonbFieldRegex = regexp.MustCompile(`^("[\pL\pN_]+"|[\pL_][\pL\pN_]*)((?:->[\pL\pN_]+)*)->>([\pL\pN_]+)$`)
+
+// jsonbField is a key of a jsonb column extracted as text
+type jsonbField struct {
+	SQL    string
+	Column string
+}
+
+// isJSONBField check if a `_select` field extract a key of a jsonb column
+func isJSONBField(field string) bool {
+	return !isCaseField(field) && !isCoalesceField(field) && strings.Contains(field, "->>")
+}
+
+// parseJSONBField write data->address->>city as data->'address'->>'city', the keys are
+// letters, digits and _ so they are safe as literals
+func parseJSONBField(field string) (jf jsonbField, err error) {
+	m := jsonbFieldRegex.FindStringSubmatch(field)
+	if m == nil {
+		err = fmt.Errorf("invalid jsonb field %s, use column->>key or column->key->>key", field)
+		return
+	}
+	jf.Column = strings.Trim(m[1], `"`)
+
+	path := []string{quoteColumn(m[1])}
+	if m[2] != "" {
+		for _, key := range strings.Split(strings.TrimPrefix(m[2], "->"), "->") {
+			path = append(path, fmt.Sprintf("'%s'", key))
+		}
+	}
+	jf.SQL = fmt.Sprintf("%s->>'%s'", strings.Join(path, "->"), m[3])
+	return
+}
+
+// CheckSelectJSONBFields validate that the columns of the jsonb fields of `_select`
+// are jsonb columns of the table
+func CheckSelectJSONBFields(database, schema, table string, fields []string) (err error) {
+	var types []columnType
+	for _, field := range fields {
+		field, _ = splitAlias(field)
+		if !isJSONBField(field) {
+			continue
+		}
+		var jf jsonbField
+		if jf, err = parseJSONBField(field); err != nil {
+			return
+		}
+		if types == nil {
+			if types, err = copyColumnTypes(schema, table); err != nil {
+				return
+			}
+		}
+		if columnType, _ := findColumnType(types, jf.Column); columnType != "jsonb" {
+			err = fmt.Errorf("column %s is not a jsonb column of %s.%s", jf.Column, schema, table)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestParseJSONBField(t *testing.T) {
+	var testCases = []struct {
+		description string
+		field       string
+		sql         string
+		column      string
+		err         bool
+	}{
+		{"key", "data->>name", "data->>'name'", "data", false},
+		{"nested key", "data->address->>city", "data->'address'->>'city'", "data", false},
+		{"quoted column", `"Data"->>name`, `"Data"->>'name'`, "Data", false},
+		{"key with digits", "data->>2fa", "data->>'2fa'", "data", false},
+		{"without extraction", "data->name", "", "", true},
+		{"quoted key", "data->>'name'", "", "", true},
+		{"empty key", "data->>", "", "", true},
+		{"invalid column", "0data->>name", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		jf, err := parseJSONBField(tc.field)
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if jf.SQL != tc.sql || jf.Column != tc.column {
+			t.Errorf("expected %s of %s, got %s of %s", tc.sql, tc.column, jf.SQL, jf.Column)
+		}
+	}
+}
+
+func TestJSONBFieldQuoteIdentifiers(t *testing.T) {
+	config.PrestConf.QuoteIdentifiers = true
+	defer func() { config.PrestConf.QuoteIdentifiers = false }()
+
+	jf, err := parseJSONBField("data->>name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"data"->>'name'`; jf.SQL != expected {
+		t.Errorf("expected %s, got %s", expected, jf.SQL)
+	}
+}
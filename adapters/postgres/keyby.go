@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrKeyByParameters err throw when _keyby is used with a response that isn't an array of rows
+var ErrKeyByParameters = errors.New("_keyby can't be used with _count, _explain, _envelope, _first, _last, XML or GeoJSON")
+
+// KeyByRequest read `_keyby=column`, the rows are answered in an object keyed by the
+// values of the column. It's empty without `_keyby`
+func KeyByRequest(r *http.Request) (column string, err error) {
+	queries := r.URL.Query()
+	column = queries.Get("_keyby")
+	if column == "" {
+		return
+	}
+	if queries.Get("_count") != "" || queries.Get("_explain") != "" || queries.Get("_envelope") != "" ||
+		queries.Get("_first") != "" || queries.Get("_last") != "" || XMLByRequest(r) ||
+		strings.Contains(r.Header.Get("Accept"), GeoJSONContentType) {
+		err = ErrKeyByParameters
+		return
+	}
+	if !validIdentifier(column) {
+		err = fmt.Errorf("invalid _keyby column %s", column)
+	}
+	return
+}
+
+// KeyRows turn a JSON array of rows into an object of the rows keyed by the value of
+// column, e.g. {"1": {"id": 1, ...}, "2": {"id": 2, ...}}. The rows keep their order, the
+// values of column must be unique strings or numbers
+func KeyRows(jsonData []byte, column string) (keyed []byte, err error) {
+	var rows []json.RawMessage
+	if err = json.Unmarshal(jsonData, &rows); err != nil {
+		return
+	}
+
+	keys := make(map[string]bool, len(rows))
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, row := range rows {
+		var columns map[string]json.RawMessage
+		if err = json.Unmarshal(row, &columns); err != nil {
+			return
+		}
+		value, ok := columns[column]
+		if !ok {
+			err = fmt.Errorf("_keyby column %s is not in the rows", column)
+			return
+		}
+		var key string
+		if key, err = rowKey(value); err != nil {
+			err = fmt.Errorf("invalid _keyby value %s of %s, use strings or numbers", value, column)
+			return
+		}
+		if keys[key] {
+			err = fmt.Errorf("duplicated _keyby value %s of %s", value, column)
+			return
+		}
+		keys[key] = true
+
+		if i > 0 {
+			buf.WriteString(", \n ")
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(row)
+	}
+	buf.WriteByte('}')
+	keyed = buf.Bytes()
+	return
+}
+
+// rowKey is the text of a string or a number
+func rowKey(value json.RawMessage) (key string, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var v interface{}
+	if err = decoder.Decode(&v); err != nil {
+		return
+	}
+	switch v := v.(type) {
+	case string:
+		key = v
+	case json.Number:
+		key = v.String()
+	default:
+		err = errors.New("the key must be a string or a number")
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestKeyByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		accept      string
+		expected    string
+		err         bool
+	}{
+		{"without keyby", "/prest/public/test", "", "", false},
+		{"keyby", "/prest/public/test?_keyby=id", "", "id", false},
+		{"keyby with count", "/prest/public/test?_keyby=id&_count=*", "", "id", true},
+		{"keyby with envelope", "/prest/public/test?_keyby=id&_envelope=true", "", "id", true},
+		{"keyby with first", "/prest/public/test?_keyby=id&_first=true&_order=id", "", "id", true},
+		{"keyby with XML", "/prest/public/test?_keyby=id", XMLContentType, "id", true},
+		{"keyby with GeoJSON", "/prest/public/test?_keyby=id", GeoJSONContentType, "id", true},
+		{"invalid column", "/prest/public/test?_keyby=id)%3BDROP", "", "id);DROP", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", tc.accept)
+		column, err := KeyByRequest(r)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if column != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, column)
+		}
+	}
+}
+
+func TestKeyRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		jsonData    string
+		column      string
+		expected    string
+		err         bool
+	}{
+		{"numbers", `[{"id":1,"name":"a"}, {"id":2,"name":"b"}]`, "id", "{\"1\":{\"id\":1,\"name\":\"a\"}, \n \"2\":{\"id\":2,\"name\":\"b\"}}", false},
+		{"strings keep the rows order", `[{"code":"z"}, {"code":"a\"b"}]`, "code", "{\"z\":{\"code\":\"z\"}, \n \"a\\\"b\":{\"code\":\"a\\\"b\"}}", false},
+		{"big numbers", `[{"id":12345678901234567890}]`, "id", `{"12345678901234567890":{"id":12345678901234567890}}`, false},
+		{"without rows", `[]`, "id", `{}`, false},
+		{"duplicated value", `[{"id":1}, {"id":1}]`, "id", "", true},
+		{"duplicated number and string", `[{"id":1}, {"id":"1"}]`, "id", "", true},
+		{"column not in the rows", `[{"id":1}]`, "name", "", true},
+		{"null value", `[{"id":null}]`, "id", "", true},
+		{"object value", `[{"id":{"a":1}}]`, "id", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		keyed, err := KeyRows([]byte(tc.jsonData), tc.column)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if !tc.err && string(keyed) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, keyed)
+		}
+	}
+}
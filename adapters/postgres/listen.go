@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+)
+
+const (
+	listenMinReconnect = 10 * time.Second
+	listenMaxReconnect = time.Minute
+	// listenPingInterval check the dedicated connection while there are no notifications
+	listenPingInterval = 90 * time.Second
+)
+
+// Listener receive the notifications of a channel in a dedicated connection, it
+// reconnects and listens again when the connection is lost
+type Listener struct {
+	listener *pq.Listener
+	channel  string
+}
+
+// Listen open a dedicated connection and LISTEN on channel
+func Listen(database, channel string) (l *Listener, err error) {
+	if chkInvalidIdentifier(database, channel) {
+		err = ErrInvalidIdentifier
+		return
+	}
+	if database != config.PrestConf.PGDatabase {
+		err = ErrDatabaseNotConnected
+		return
+	}
+
+	// the first connection error is returned, later errors are retried by pq
+	connected := make(chan error, 1)
+	listener := pq.NewListener(connection.URI(), listenMinReconnect, listenMaxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventConnected:
+			select {
+			case connected <- nil:
+			default:
+			}
+		case pq.ListenerEventConnectionAttemptFailed:
+			select {
+			case connected <- err:
+			default:
+			}
+		}
+	})
+
+	if err = <-connected; err != nil {
+		listener.Close()
+		return
+	}
+	if err = listener.Listen(channel); err != nil {
+		listener.Close()
+		return
+	}
+
+	l = &Listener{listener: listener, channel: channel}
+	return
+}
+
+// Wait block until a notification and return its payload, ctx.Err() is returned
+// when ctx is done, e.g. the client disconnected
+func (l *Listener) Wait(ctx context.Context) (payload string, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case n := <-l.listener.Notify:
+			// nil is sent after a reconnection, notifications may have been lost
+			if n == nil {
+				continue
+			}
+			payload = n.Extra
+			return
+		case <-time.After(listenPingInterval):
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close UNLISTEN the channel and close the dedicated connection
+func (l *Listener) Close() error {
+	l.listener.Unlisten(l.channel)
+	return l.listener.Close()
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrInvalidLock err throw when _lock is not an integer key
+var ErrInvalidLock = errors.New("invalid _lock, the key must be a 64 bits integer")
+
+// ErrRowLockTransaction err throw when a select is sent with `_lock`, the advisory locks
+// are taken by the writes and the row locks (FOR UPDATE) of a single select would be
+// released when it ends
+var ErrRowLockTransaction = errors.New("the selects can't take locks, the row locks require a transaction")
+
+// LockByRequest read `_lock`, the key of the advisory lock taken by the write in its
+// transaction, nil is returned without `_lock`
+func LockByRequest(r *http.Request) (lock *int64, err error) {
+	value := r.URL.Query().Get("_lock")
+	if value == "" {
+		return
+	}
+	key, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		err = ErrInvalidLock
+		return
+	}
+	lock = &key
+	return
+}
+
+// advisoryLock wait for the transaction scoped advisory lock of key, it's released by
+// the commit or rollback of tx. Nothing is done without key
+func advisoryLock(ctx context.Context, tx *sql.Tx, lock *int64) (err error) {
+	if lock == nil {
+		return
+	}
+	_, err = tx.ExecContext(ctx, statements.AdvisoryXactLock, *lock)
+	return
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLockByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		lock        int64
+		locked      bool
+		err         error
+	}{
+		{"without lock", "/prest/public/test", 0, false, nil},
+		{"lock", "/prest/public/test?_lock=42", 42, true, nil},
+		{"negative lock", "/prest/public/test?_lock=-42", -42, true, nil},
+		{"lock is not a number", "/prest/public/test?_lock=workers", 0, false, ErrInvalidLock},
+		{"lock overflow", "/prest/public/test?_lock=9223372036854775808", 0, false, ErrInvalidLock},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock, err := LockByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if (lock != nil) != tc.locked {
+			t.Errorf("expected locked %v, got %v", tc.locked, lock)
+		}
+		if lock != nil && *lock != tc.lock {
+			t.Errorf("expected lock %d, got %d", tc.lock, *lock)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"context"
+	"log"
+
+	"github.com/nuveo/prest/helpers"
+)
+
+// logger is the logger of the request of ctx, its messages start with the request ID
+// so the errors of the queries can be matched with the request
+func logger(ctx context.Context) *log.Logger {
+	id := helpers.ContextRequestID(ctx)
+	if id == "" {
+		return log.Default()
+	}
+	return log.New(log.Writer(), id+" ", log.Flags()|log.Lmsgprefix)
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nuveo/prest/helpers"
+)
+
+func TestLogger(t *testing.T) {
+	defer func(flags int) {
+		log.SetOutput(log.Writer())
+		log.SetFlags(flags)
+	}(log.Flags())
+
+	var testCases = []struct {
+		description string
+		ctx         context.Context
+		expected    string
+	}{
+		{"without request ID", context.Background(), "{retry} failed\n"},
+		{"with request ID", helpers.WithRequestID(httptest.NewRequest("GET", "/prest/public/test", nil), "request-1").Context(), "request-1 {retry} failed\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		log.SetFlags(0)
+		logger(tc.ctx).Println("{retry} failed")
+		if buf.String() != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, buf.String())
+		}
+	}
+}
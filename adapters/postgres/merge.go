@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrMergeRemove err throw when a _merge_remove item isn't column:key
+var ErrMergeRemove = errors.New("invalid _merge_remove, use column:key")
+
+// JSONBMerge of an update, the objects of the Columns in the body are concatenated to
+// the stored documents and the Remove keys are dropped from them
+type JSONBMerge struct {
+	Columns []string
+	Remove  []JSONBKey
+}
+
+// JSONBKey is a key of the document of a jsonb column
+type JSONBKey struct {
+	Column string
+	Key    string
+}
+
+// MergeByRequest read `_merge=column,...` and `_merge_remove=column:key,...`, nil is
+// returned without them
+func MergeByRequest(r *http.Request) (merge *JSONBMerge, err error) {
+	queries := r.URL.Query()
+	columns, remove := queries.Get("_merge"), queries.Get("_merge_remove")
+	if columns == "" && remove == "" {
+		return
+	}
+
+	merge = &JSONBMerge{}
+	if columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			if !validIdentifier(column) {
+				err = fmt.Errorf("invalid _merge column %s", column)
+				return
+			}
+			merge.Columns = append(merge.Columns, column)
+		}
+	}
+	if remove != "" {
+		for _, item := range strings.Split(remove, ",") {
+			parts := strings.SplitN(item, ":", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				err = ErrMergeRemove
+				return
+			}
+			if !validIdentifier(parts[0]) {
+				err = fmt.Errorf("invalid _merge_remove column %s", parts[0])
+				return
+			}
+			merge.Remove = append(merge.Remove, JSONBKey{Column: parts[0], Key: parts[1]})
+		}
+	}
+	return
+}
+
+// CheckMergeColumns validate that the columns of _merge and _merge_remove are jsonb
+// columns of the table
+func CheckMergeColumns(r *http.Request, database, schema, table string) (err error) {
+	merge, err := MergeByRequest(r)
+	if err != nil || merge == nil {
+		return
+	}
+
+	types, err := copyColumnTypes(schema, table)
+	if err != nil {
+		return
+	}
+	for _, column := range merge.columns() {
+		if columnType, _ := findColumnType(types, strings.Trim(column, `"`)); columnType != "jsonb" {
+			err = fmt.Errorf("column %s is not a jsonb column of %s.%s", column, schema, table)
+			return
+		}
+	}
+	return
+}
+
+// columns list the merged columns and the columns with removed keys once, in order
+func (merge *JSONBMerge) columns() (columns []string) {
+	for _, column := range merge.Columns {
+		if !containsString(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	for _, key := range merge.Remove {
+		if !containsString(columns, key.Column) {
+			columns = append(columns, key.Column)
+		}
+	}
+	return
+}
+
+// setByBody create the set of the merged columns, their objects are taken out of body
+func (merge *JSONBMerge) setByBody(body map[string]interface{}, initialPlaceholderID int) (setSyntax string, values []interface{}, err error) {
+	fields := make([]string, 0)
+	for _, column := range merge.columns() {
+		expression := fmt.Sprintf("COALESCE(%s, '{}')", quoteColumn(column))
+		if containsString(merge.Columns, column) {
+			object, ok := body[column].(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("_merge column %s must be a JSON object in the body", column)
+				return
+			}
+			delete(body, column)
+
+			var document []byte
+			if document, err = json.Marshal(object); err != nil {
+				return
+			}
+			expression = fmt.Sprintf("(%s || $%d::jsonb)", expression, initialPlaceholderID)
+			values = append(values, string(document))
+			initialPlaceholderID++
+		}
+		for _, key := range merge.Remove {
+			if key.Column != column {
+				continue
+			}
+			expression = fmt.Sprintf("(%s - $%d::text)", expression, initialPlaceholderID)
+			values = append(values, key.Key)
+			initialPlaceholderID++
+		}
+		fields = append(fields, fmt.Sprintf("%s=%s", quoteColumn(column), expression))
+	}
+	setSyntax = strings.Join(fields, ", ")
+	return
+}
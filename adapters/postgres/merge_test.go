@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMergeByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    *JSONBMerge
+		err         bool
+	}{
+		{"without merge", "/prest/public/testjson", nil, false},
+		{"merge columns", "/prest/public/testjson?_merge=data,meta", &JSONBMerge{Columns: []string{"data", "meta"}}, false},
+		{"remove keys", "/prest/public/testjson?_merge_remove=data:draft,meta:tmp", &JSONBMerge{Remove: []JSONBKey{{"data", "draft"}, {"meta", "tmp"}}}, false},
+		{"merge and remove", "/prest/public/testjson?_merge=data&_merge_remove=data:draft", &JSONBMerge{Columns: []string{"data"}, Remove: []JSONBKey{{"data", "draft"}}}, false},
+		{"invalid merge column", "/prest/public/testjson?_merge=0data", nil, true},
+		{"remove without key", "/prest/public/testjson?_merge_remove=data:", nil, true},
+		{"remove of invalid column", "/prest/public/testjson?_merge_remove=0data:draft", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("PATCH", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merge, err := MergeByRequest(r)
+		if tc.err != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if !tc.err && !reflect.DeepEqual(merge, tc.expected) {
+			t.Errorf("expected %+v, got %+v", tc.expected, merge)
+		}
+	}
+}
+
+func TestMergeSetByBody(t *testing.T) {
+	merge := &JSONBMerge{Columns: []string{"data"}}
+
+	t.Log("merged column missing in body")
+	if _, _, err := merge.setByBody(map[string]interface{}{"name": "prest"}, 1); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	t.Log("merged column not an object")
+	if _, _, err := merge.setByBody(map[string]interface{}{"data": "done"}, 1); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
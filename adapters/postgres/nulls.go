@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nuveo/prest/config"
+)
+
+// Behaviors of the NULL columns in the output, see FormatNulls
+const (
+	NullKeep  = "keep"
+	NullOmit  = "omit"
+	NullEmpty = "empty"
+)
+
+// ErrNullBehavior err throw when the null behavior isn't keep, omit or empty
+var ErrNullBehavior = errors.New("invalid _null_behavior, use keep, omit or empty")
+
+// NullBehaviorByRequest read `_null_behavior`, json.null_behavior of the config is used
+// without it
+func NullBehaviorByRequest(r *http.Request) (behavior string, err error) {
+	behavior = r.URL.Query().Get("_null_behavior")
+	if behavior == "" {
+		behavior = config.PrestConf.JSONNullBehavior
+	}
+	switch behavior {
+	case "", NullKeep:
+		behavior = NullKeep
+	case NullOmit, NullEmpty:
+	default:
+		err = ErrNullBehavior
+	}
+	return
+}
+
+// FormatNulls apply behavior on the NULL columns of a JSON array of rows, or of a single
+// row: omit drop the keys and empty replace them by "". The columns keep their order and
+// the nulls inside json and jsonb documents are kept
+func FormatNulls(jsonData []byte, behavior string) (formatted []byte, err error) {
+	if behavior == NullKeep {
+		formatted = jsonData
+		return
+	}
+
+	trimmed := bytes.TrimSpace(jsonData)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return formatRowNulls(trimmed, behavior)
+	}
+
+	var rows []json.RawMessage
+	if err = json.Unmarshal(trimmed, &rows); err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteString(", \n ")
+		}
+		if row, err = formatRowNulls(row, behavior); err != nil {
+			return
+		}
+		buf.Write(row)
+	}
+	buf.WriteByte(']')
+	formatted = buf.Bytes()
+	return
+}
+
+// FormatEnvelopeNulls apply FormatNulls on the rows in the data of an envelope
+func FormatEnvelopeNulls(jsonData []byte, behavior string) (formatted []byte, err error) {
+	if behavior == NullKeep {
+		formatted = jsonData
+		return
+	}
+
+	var envelope Envelope
+	if err = json.Unmarshal(jsonData, &envelope); err != nil {
+		return
+	}
+	if envelope.Data, err = FormatNulls(envelope.Data, behavior); err != nil {
+		return
+	}
+	formatted, err = json.Marshal(envelope)
+	return
+}
+
+func formatRowNulls(row []byte, behavior string) (formatted []byte, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(row))
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		// rows of scalar functions aren't objects
+		formatted = row
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for decoder.More() {
+		if token, err = decoder.Token(); err != nil {
+			return
+		}
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return
+		}
+		if string(value) == "null" {
+			if behavior == NullOmit {
+				continue
+			}
+			value = json.RawMessage(`""`)
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		var key []byte
+		if key, err = json.Marshal(token.(string)); err != nil {
+			return
+		}
+		fmt.Fprintf(&buf, "%s:%s", key, value)
+	}
+	buf.WriteByte('}')
+	formatted = buf.Bytes()
+	return
+}
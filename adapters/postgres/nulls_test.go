@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestNullBehaviorByRequest(t *testing.T) {
+	defer func() { config.PrestConf.JSONNullBehavior = "" }()
+
+	var testCases = []struct {
+		description string
+		url         string
+		config      string
+		expected    string
+		err         error
+	}{
+		{"default", "/prest/public/test", "", NullKeep, nil},
+		{"config", "/prest/public/test", NullOmit, NullOmit, nil},
+		{"parameter", "/prest/public/test?_null_behavior=empty", "", NullEmpty, nil},
+		{"parameter replace the config", "/prest/public/test?_null_behavior=keep", NullOmit, NullKeep, nil},
+		{"invalid parameter", "/prest/public/test?_null_behavior=drop", "", "", ErrNullBehavior},
+		{"invalid config", "/prest/public/test", "drop", "", ErrNullBehavior},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.JSONNullBehavior = tc.config
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		behavior, err := NullBehaviorByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if err == nil && behavior != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, behavior)
+		}
+	}
+}
+
+func TestFormatNulls(t *testing.T) {
+	var testCases = []struct {
+		description string
+		behavior    string
+		in          string
+		out         string
+	}{
+		{"keep", NullKeep, `[{"id":1,"name":null}]`, `[{"id":1,"name":null}]`},
+		{"omit", NullOmit, `[{"id":1,"name":null,"age":30}, {"id":2,"name":"prest","age":null}]`, `[{"id":1,"age":30}, ` + "\n" + ` {"id":2,"name":"prest"}]`},
+		{"empty", NullEmpty, `[{"id":1,"name":null,"age":30}]`, `[{"id":1,"name":"","age":30}]`},
+		{"omit the only column", NullOmit, `[{"name":null}]`, `[{}]`},
+		{"single row", NullOmit, `{"id":1,"name":null}`, `{"id":1}`},
+		{"no rows", NullOmit, `[]`, `[]`},
+		{"nulls of documents are kept", NullOmit, `[{"data":{"a":null},"tags":[null]}]`, `[{"data":{"a":null},"tags":[null]}]`},
+		{"rows of scalar functions", NullEmpty, `[1, null]`, `[1, ` + "\n" + ` null]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		out, err := FormatNulls([]byte(tc.in), tc.behavior)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(out) != tc.out {
+			t.Errorf("expected %s, got: %s", tc.out, out)
+		}
+	}
+
+	if _, err := FormatNulls([]byte(`[{"id":`), NullOmit); err == nil {
+		t.Error("expected error of invalid JSON, got nil")
+	}
+}
+
+func TestFormatEnvelopeNulls(t *testing.T) {
+	out, err := FormatEnvelopeNulls([]byte(`{"data":[{"id":1,"name":null}],"meta":{"page":1,"page_size":10,"total":1}}`), NullEmpty)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `{"data":[{"id":1,"name":""}],"meta":{"page":1,"page_size":10,"total":1}}`
+	if string(out) != expected {
+		t.Errorf("expected %s, got: %s", expected, out)
+	}
+}
@@ -1,17 +1,22 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nuveo/prest/adapters/postgres/connection"
 	"github.com/nuveo/prest/config"
 	"github.com/nuveo/prest/statements"
@@ -25,13 +30,41 @@ const (
 
 var removeOperatorRegex *regexp.Regexp
 var insertTableNameRegex *regexp.Regexp
+var quotedIdentifierRegex *regexp.Regexp
+var orderFunctionRegex *regexp.Regexp
 
 // ErrBodyEmpty err throw when body is empty
 var ErrBodyEmpty = errors.New("body is empty")
 
+// ErrInvalidIdentifier err throw when a database, schema, table or column name is invalid
+var ErrInvalidIdentifier = errors.New("Invalid identifier")
+
+// ErrMaterializedViewNotFound err throw when the object to refresh is not a materialized view
+var ErrMaterializedViewNotFound = errors.New("materialized view not found")
+
+// ErrExplainAnalyzeDisabled err throw when _explain=analyze is not enabled
+var ErrExplainAnalyzeDisabled = errors.New("_explain=analyze is disabled")
+
+// ErrFirstAndLast err throw when _first and _last are used together
+var ErrFirstAndLast = errors.New("_first and _last can't be used together")
+
+// ErrSingleRowWithoutOrder err throw when _first or _last is used without _order
+var ErrSingleRowWithoutOrder = errors.New("_first and _last require _order")
+
+// ErrSingleRowParameters err throw when _first or _last is used with pagination, count, explain or sample
+var ErrSingleRowParameters = errors.New("_first and _last can't be used with _page, _page_size, _count, _explain or _sample")
+
+// ErrVersionNotInBody err throw when the _version column is missing in the update body
+var ErrVersionNotInBody = errors.New("version column not in body")
+
+// ErrCountGroups err throw when _count isn't * with _groupby, the groups are counted
+var ErrCountGroups = errors.New("_count with _groupby count the groups, use _count=*")
+
 func init() {
 	removeOperatorRegex = regexp.MustCompile(`\$[a-z]+.`)
 	insertTableNameRegex = regexp.MustCompile(`(?i)INTO\s+([\w|\.]*\.)*(\w+)\s*\(`)
+	quotedIdentifierRegex = regexp.MustCompile(`^("[\pL\pN_]+"|[^".]+)(\.("[\pL\pN_]+"|[^".]+))*$`)
+	orderFunctionRegex = regexp.MustCompile(`^(\w+)\((.*)\)$`)
 }
 
 // chkInvalidIdentifier return true if identifier is invalid
@@ -41,6 +74,17 @@ func chkInvalidIdentifier(identifer ...string) bool {
 			return true
 		}
 
+		// quoted identifiers, e.g. "userId", only as whole names between dots
+		if strings.Contains(ival, `"`) {
+			if !quotedIdentifierRegex.MatchString(ival) {
+				return true
+			}
+			ival = strings.Replace(ival, `"`, "", -1)
+			if ival == "" || unicode.IsDigit([]rune(ival)[0]) {
+				return true
+			}
+		}
+
 		for _, v := range ival {
 			if !unicode.IsLetter(v) &&
 				!unicode.IsDigit(v) &&
@@ -61,26 +105,33 @@ func chkInvalidIdentifier(identifer ...string) bool {
 
 // WhereByRequest create interface for queries + where
 func WhereByRequest(r *http.Request, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	return whereByValues(r.URL.Query(), initialPlaceholderID, nil)
+}
+
+// WhereByRequestInTimeZone create the where like WhereByRequest, the values compared
+// with the timestamptz columns of tz are timestamps in the time zone of tz
+func WhereByRequestInTimeZone(r *http.Request, initialPlaceholderID int, tz *TimeZone) (whereSyntax string, values []interface{}, err error) {
+	return whereByValues(r.URL.Query(), initialPlaceholderID, tz)
+}
+
+// whereByValues create the where clause of the filters in queries, keys starting with `_` are ignored.
+// The repeated keys of a column are combined: the $eq values in an IN list, the $ne values
+// in a NOT IN list and the other operators with AND
+func whereByValues(queries url.Values, initialPlaceholderID int, tz *TimeZone) (whereSyntax string, values []interface{}, err error) {
 	whereKey := []string{}
-	whereValues := []string{}
-	var value, op string
+	whereValues := []interface{}{}
 
 	pid := initialPlaceholderID
-	for key, val := range r.URL.Query() {
-		if !strings.HasPrefix(key, "_") {
-
-			value = val[0]
-			if val[0] != "" {
-				op = removeOperatorRegex.FindString(val[0])
-				op = strings.Replace(op, ".", "", -1)
-				if op == "" {
-					op = "$eq"
-				}
-				value = removeOperatorRegex.ReplaceAllString(val[0], "")
-				op, err = GetQueryOperator(op)
-				if err != nil {
-					return
-				}
+	for key, vals := range queries {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+
+		var equals, notEquals []string
+		for _, val := range vals {
+			var value, op, part string
+			if value, op, part, err = parseFilter(val); err != nil {
+				return
 			}
 
 			keyInfo := strings.Split(key, ":")
@@ -88,16 +139,20 @@ func WhereByRequest(r *http.Request, initialPlaceholderID int) (whereSyntax stri
 			if len(keyInfo) > 1 {
 				switch keyInfo[1] {
 				case "jsonb":
-					jsonField := strings.Split(keyInfo[0], "->>")
-					if chkInvalidIdentifier(jsonField[0], jsonField[1]) {
+					if part != "" {
+						err = fmt.Errorf("date part operators can't filter jsonb fields: %s", keyInfo[0])
+						return
+					}
+					jsonField := strings.SplitN(keyInfo[0], "->>", 2)
+					if len(jsonField) != 2 || !validIdentifier(jsonField[0]) || !validIdentifier(jsonField[1]) {
 						err = fmt.Errorf("invalid identifier: %+v", jsonField)
 						return
 					}
 
-					whereKey = append(whereKey, fmt.Sprintf("%s->>'%s' %s $%d", jsonField[0], jsonField[1], op, pid))
+					whereKey = append(whereKey, fmt.Sprintf("%s->>'%s' %s $%d", quoteColumn(jsonField[0]), jsonField[1], op, pid))
 					whereValues = append(whereValues, value)
 				default:
-					if chkInvalidIdentifier(keyInfo[0]) {
+					if !validIdentifier(keyInfo[0]) {
 						err = fmt.Errorf("invalid identifier: %s", keyInfo[0])
 						return
 					}
@@ -106,34 +161,90 @@ func WhereByRequest(r *http.Request, initialPlaceholderID int) (whereSyntax stri
 				continue
 			}
 
-			if chkInvalidIdentifier(key) {
+			if !validIdentifier(key) {
 				err = fmt.Errorf("invalid identifier: %s", key)
 				return
 			}
+			column := quoteColumn(key)
+
+			switch {
+			case part != "":
+				whereKey = append(whereKey, fmt.Sprintf("EXTRACT(%s FROM %s) = $%d", part, column, pid))
+				whereValues = append(whereValues, value)
 
-			if value != "" {
-				whereKey = append(whereKey, fmt.Sprintf("%s %s $%d", key, op, pid))
+				pid++
+			case op == ftsOperator:
+				var condition string
+				var ftsValues []interface{}
+				if condition, ftsValues, pid, err = ftsCondition(column, value, pid); err != nil {
+					return
+				}
+				whereKey = append(whereKey, condition)
+				whereValues = append(whereValues, ftsValues...)
+			case value == "":
+				whereKey = append(whereKey, fmt.Sprintf("%s %s", column, op))
+			case op == "=":
+				equals = append(equals, value)
+			case op == "!=":
+				notEquals = append(notEquals, value)
+			default:
+				whereKey = append(whereKey, fmt.Sprintf("%s %s %s", column, op, tz.placeholder(key, op, pid)))
 				whereValues = append(whereValues, value)
 
 				pid++
-			} else {
-				whereKey = append(whereKey, fmt.Sprintf("%s %s", key, op))
 			}
 		}
-	}
 
-	for i := 0; i < len(whereKey); i++ {
-		if whereSyntax == "" {
-			whereSyntax += whereKey[i]
-		} else {
-			whereSyntax += " AND " + whereKey[i]
+		for _, list := range []struct {
+			op, listOp string
+			values     []string
+		}{{"=", "IN", equals}, {"!=", "NOT IN", notEquals}} {
+			if len(list.values) == 0 {
+				continue
+			}
+			placeholders := make([]string, len(list.values))
+			for i, value := range list.values {
+				placeholders[i] = tz.placeholder(key, list.op, pid)
+				whereValues = append(whereValues, value)
+				pid++
+			}
+			if len(placeholders) == 1 {
+				whereKey = append(whereKey, fmt.Sprintf("%s %s %s", quoteColumn(key), list.op, placeholders[0]))
+				continue
+			}
+			whereKey = append(whereKey, fmt.Sprintf("%s %s (%s)", quoteColumn(key), list.listOp, strings.Join(placeholders, ", ")))
 		}
+	}
 
-		if i < len(whereValues) {
-			values = append(values, whereValues[i])
-		}
+	whereSyntax = strings.Join(whereKey, " AND ")
+	if len(whereValues) > 0 {
+		values = whereValues
 	}
+	return
+}
 
+// parseFilter split the operator of the value of a filter, $eq by default, the date
+// part operators are `=` with the part of the date
+func parseFilter(filter string) (value, op, part string, err error) {
+	if filter == "" {
+		return
+	}
+	op = removeOperatorRegex.FindString(filter)
+	op = strings.Replace(op, ".", "", -1)
+	if op == "" {
+		op = "$eq"
+	}
+	value = removeOperatorRegex.ReplaceAllString(filter, "")
+	var ok bool
+	if part, ok = datePart(op); ok {
+		if _, err = strconv.Atoi(value); err != nil {
+			err = fmt.Errorf("invalid value %s of %s, it must be an integer", value, op)
+			return
+		}
+		op = "="
+		return
+	}
+	op, err = GetQueryOperator(op)
 	return
 }
 
@@ -145,6 +256,11 @@ func SetByRequest(r *http.Request, initialPlaceholderID int) (setSyntax string,
 	}
 	defer r.Body.Close()
 
+	return setByBody(body, initialPlaceholderID)
+}
+
+// setByBody create a set clause with the columns and values in body
+func setByBody(body map[string]interface{}, initialPlaceholderID int) (setSyntax string, values []interface{}, err error) {
 	if len(body) == 0 {
 		err = ErrBodyEmpty
 		return
@@ -152,15 +268,20 @@ func SetByRequest(r *http.Request, initialPlaceholderID int) (setSyntax string,
 
 	fields := make([]string, 0)
 	for key, value := range body {
-		if chkInvalidIdentifier(key) {
+		if !validIdentifier(key) {
 			err = errors.New("Set: Invalid identifier")
 			return
 		}
-		fields = append(fields, fmt.Sprintf("%s=$%d", key, initialPlaceholderID))
+		fields = append(fields, fmt.Sprintf("%s=$%d", quoteColumn(key), initialPlaceholderID))
 
 		switch value.(type) {
 		case []interface{}:
-			values = append(values, parseArray(value))
+			var array string
+			if array, err = parseArray(value); err != nil {
+				err = fmt.Errorf("column %s: %v", key, err)
+				return
+			}
+			values = append(values, array)
 		default:
 			values = append(values, value)
 		}
@@ -171,7 +292,87 @@ func SetByRequest(r *http.Request, initialPlaceholderID int) (setSyntax string,
 	return
 }
 
-// ParseInsertRequest create insert SQL
+// SetVersionByRequest create a set clause like SetByRequest, if _version=column is
+// in query string the column value in body is returned as a predicate to the where
+// and the column is incremented (optimistic locking). The jsonb columns of _merge and
+// _merge_remove are merged instead of replaced
+func SetVersionByRequest(r *http.Request, initialPlaceholderID int) (setSyntax string, versionWhere string, values []interface{}, err error) {
+	merge, err := MergeByRequest(r)
+	if err != nil {
+		return
+	}
+	version := r.URL.Query().Get("_version")
+	if version == "" && merge == nil {
+		setSyntax, values, err = SetByRequest(r, initialPlaceholderID)
+		return
+	}
+
+	body := make(map[string]interface{})
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return
+	}
+	defer r.Body.Close()
+
+	if merge == nil {
+		return setVersionByBody(body, version, initialPlaceholderID)
+	}
+
+	// the merged columns take the first placeholders
+	mergeSyntax, mergeValues, err := merge.setByBody(body, initialPlaceholderID)
+	if err != nil {
+		return
+	}
+	initialPlaceholderID += len(mergeValues)
+	switch {
+	case version != "":
+		setSyntax, versionWhere, values, err = setVersionByBody(body, version, initialPlaceholderID)
+	case len(body) > 0:
+		setSyntax, values, err = setByBody(body, initialPlaceholderID)
+	}
+	if err != nil {
+		return
+	}
+	if setSyntax != "" {
+		mergeSyntax = fmt.Sprint(mergeSyntax, ", ", setSyntax)
+	}
+	setSyntax = mergeSyntax
+	values = append(mergeValues, values...)
+	return
+}
+
+// setVersionByBody create a set clause incrementing the version column and the predicate
+// on its current value, the predicate placeholder come after the set values
+func setVersionByBody(body map[string]interface{}, version string, initialPlaceholderID int) (setSyntax string, versionWhere string, values []interface{}, err error) {
+	if invalidNames(version) {
+		err = errors.New("Version: Invalid identifier")
+		return
+	}
+	current, ok := body[version]
+	if !ok || current == nil {
+		err = ErrVersionNotInBody
+		return
+	}
+	delete(body, version)
+
+	column := quoteColumn(version)
+	increment := fmt.Sprintf("%s=%s+1", column, column)
+	if len(body) == 0 {
+		setSyntax = increment
+	} else {
+		setSyntax, values, err = setByBody(body, initialPlaceholderID)
+		if err != nil {
+			return
+		}
+		setSyntax = fmt.Sprint(setSyntax, ", ", increment)
+	}
+
+	versionWhere = fmt.Sprintf("%s=$%d", column, initialPlaceholderID+len(values))
+	values = append(values, current)
+	return
+}
+
+// ParseInsertRequest create insert SQL, only the columns in the body are listed so
+// the omitted ones get their DEFAULT, a null in the body inserts NULL
 func ParseInsertRequest(r *http.Request) (colsName string, colsValue string, values []interface{}, err error) {
 	body := make(map[string]interface{})
 	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -186,15 +387,20 @@ func ParseInsertRequest(r *http.Request) (colsName string, colsValue string, val
 
 	fields := make([]string, 0)
 	for key, value := range body {
-		if chkInvalidIdentifier(key) {
+		if !validIdentifier(key) {
 			err = errors.New("Insert: Invalid identifier")
 			return
 		}
-		fields = append(fields, key)
+		fields = append(fields, quoteColumn(key))
 
 		switch value.(type) {
 		case []interface{}:
-			values = append(values, parseArray(value))
+			var array string
+			if array, err = parseArray(value); err != nil {
+				err = fmt.Errorf("column %s: %v", key, err)
+				return
+			}
+			values = append(values, array)
 		default:
 			values = append(values, value)
 		}
@@ -238,88 +444,353 @@ func SchemaClause(req *http.Request) (query string, hasCount bool) {
 	return
 }
 
-// JoinByRequest implements join in queries
+// JoinByRequest implements join in queries, derived tables with filters require
+// JoinByRequestWithValues
 func JoinByRequest(r *http.Request) (values []string, err error) {
-	queries := r.URL.Query()
+	values, params, err := JoinByRequestWithValues(r, 1)
+	if err == nil && len(params) > 0 {
+		values, err = nil, ErrJoinValues
+	}
+	return
+}
 
-	if queries.Get("_join") == "" {
+// RecursiveByRequest implements WITH RECURSIVE for self-referencing tables,
+// `_recursive=parent_field:field` walk the rows where parent_field points to field
+// starting from the anchor rows (where clause or parent_field IS NULL), down to
+// `_recursive_depth` levels
+func RecursiveByRequest(r *http.Request, from string, anchorWhere string) (query string, err error) {
+	reqRecursive := r.URL.Query().Get("_recursive")
+	if reqRecursive == "" {
 		return
 	}
 
-	joinArgs := strings.Split(queries.Get("_join"), ":")
-
-	if len(joinArgs) != 5 {
-		err = errors.New("Invalid number of arguments in join statement")
+	recursiveArgs := strings.Split(reqRecursive, ":")
+	if len(recursiveArgs) != 2 {
+		err = errors.New("Invalid number of arguments in recursive statement")
 		return
 	}
 
-	if chkInvalidIdentifier(joinArgs[1], joinArgs[2], joinArgs[4]) {
-		err = errors.New("Invalid identifier")
+	if invalidNames(recursiveArgs...) {
+		err = ErrInvalidIdentifier
 		return
 	}
+	parentField, field := quoteColumn(recursiveArgs[0]), quoteColumn(recursiveArgs[1])
 
-	op, err := GetQueryOperator(joinArgs[3])
-	if err != nil {
-		return
+	var depthSQL string
+	if reqDepth := r.URL.Query().Get("_recursive_depth"); reqDepth != "" {
+		depth, convErr := strconv.Atoi(reqDepth)
+		if convErr != nil || depth < 1 {
+			err = fmt.Errorf("invalid recursive depth %s, use a positive integer", reqDepth)
+			return
+		}
+		depthSQL = fmt.Sprintf(statements.RecursiveDepth, depth)
 	}
 
-	joinQuery := fmt.Sprintf(" %s JOIN %s ON %s %s %s ", strings.ToUpper(joinArgs[0]), joinArgs[1], joinArgs[2], op, joinArgs[4])
-	values = append(values, joinQuery)
+	if anchorWhere == "" {
+		anchorWhere = fmt.Sprintf("%s IS NULL", parentField)
+	}
 
+	query = fmt.Sprintf(statements.RecursiveQuery, from, field, parentField, anchorWhere, depthSQL)
 	return
 }
 
-// SelectFields query
+// SelectFields query, the case, coalesce and string_agg fields with literals require
+// SelectFieldsWithValues
 func SelectFields(fields []string) (sql string, err error) {
+	sql, values, err := SelectFieldsWithValues(fields, 1)
+	if err == nil && len(values) > 0 {
+		sql, err = "", ErrCaseValues
+	}
+	return
+}
+
+// SelectFieldsWithValues query like SelectFields, the literals of the case and coalesce
+// fields and the delimiters of string_agg are values numbered from initialPlaceholderID
+func SelectFieldsWithValues(fields []string, initialPlaceholderID int) (sql string, values []interface{}, err error) {
 	if len(fields) == 0 {
 		err = errors.New("you must select at least one field")
 		return
 	}
 
+	selectFields := make([]string, 0, len(fields))
 	for _, field := range fields {
-		if chkInvalidIdentifier(field) {
+		column, alias := splitAlias(field)
+		if isCaseField(column) {
+			var cf caseField
+			if cf, err = parseCaseField(column, initialPlaceholderID+len(values)); err != nil {
+				return
+			}
+			if alias == "" {
+				err = ErrCaseWithoutAlias
+				return
+			}
+			column = cf.SQL
+			values = append(values, cf.Values...)
+		} else if isCoalesceField(column) {
+			var cf coalesceField
+			if cf, err = parseCoalesceField(column, initialPlaceholderID+len(values)); err != nil {
+				return
+			}
+			if alias == "" {
+				err = ErrCoalesceWithoutAlias
+				return
+			}
+			column = cf.SQL
+			values = append(values, cf.Values...)
+		} else if isGroupFunction(column) {
+			var aggregateValues []interface{}
+			if column, aggregateValues, err = selectGroupFunction(column, initialPlaceholderID+len(values)); err != nil {
+				return
+			}
+			values = append(values, aggregateValues...)
+		} else if isJSONBField(column) {
+			var jf jsonbField
+			if jf, err = parseJSONBField(column); err != nil {
+				return
+			}
+			if alias == "" {
+				err = ErrJSONBFieldWithoutAlias
+				return
+			}
+			column = jf.SQL
+		} else if isExpression(column) {
+			var expr expression
+			if expr, err = parseExpression(column); err != nil {
+				return
+			}
+			if alias == "" {
+				err = ErrExpressionWithoutAlias
+				return
+			}
+			column = expr.SQL
+		} else if column != "*" && !validIdentifier(strings.TrimSuffix(column, ".*")) && !isAggregateCall(column) {
 			err = fmt.Errorf("invalid identifier %s", field)
 			return
+		} else {
+			column = quoteColumn(column)
+		}
+
+		if alias != "" {
+			if chkInvalidAlias(alias) {
+				err = fmt.Errorf("invalid alias %s", alias)
+				return
+			}
+			column = fmt.Sprintf("%s AS %s", column, QuoteName(alias))
 		}
+		selectFields = append(selectFields, column)
 	}
 
-	sql = fmt.Sprintf("SELECT %s FROM", strings.Join(fields, ","))
+	sql = fmt.Sprintf("SELECT %s FROM", strings.Join(selectFields, ","))
+	return
+}
+
+// splitAlias split `column:as:alias` in column and alias
+func splitAlias(field string) (column, alias string) {
+	parts := strings.SplitN(field, ":as:", 2)
+	column = parts[0]
+	if len(parts) == 2 {
+		alias = parts[1]
+	}
 	return
 }
 
+// chkInvalidAlias return true if alias is not a plain identifier
+func chkInvalidAlias(alias string) bool {
+	return chkInvalidIdentifier(alias) || strings.ContainsAny(alias, "()[]*.-")
+}
+
 // OrderByRequest implements ORDER BY in queries
 func OrderByRequest(r *http.Request) (values string, err error) {
 	queries := r.URL.Query()
-	reqOrder := queries.Get("_order")
+	// _last read the first row of the reversed order
+	return orderBy(queries.Get("_order"), queries.Get("_last") == "true")
+}
+
+// reverseDirection is the opposite of the ORDER BY directions and nulls placements,
+// the order of _last is reversed to read its first row
+var reverseDirection = map[string]string{
+	"ASC":          "DESC",
+	"DESC":         "ASC",
+	" NULLS FIRST": " NULLS LAST",
+	" NULLS LAST":  " NULLS FIRST",
+	"":             "",
+}
 
+// orderBy create the ORDER BY of the fields of reqOrder, empty without fields
+func orderBy(reqOrder string, reverse bool) (values string, err error) {
 	if reqOrder != "" {
-		values = " ORDER BY "
 		orderingArr := strings.Split(reqOrder, ",")
+		fields := make([]string, 0, len(orderingArr))
 
-		for i, field := range orderingArr {
-			if chkInvalidIdentifier(field) {
-				err = errors.New("Invalid identifier")
-				values = ""
-				return
+		for _, field := range orderingArr {
+			var nulls string
+			if f := strings.SplitN(field, ":", 2); len(f) == 2 {
+				field = f[0]
+				nulls, err = nullsOrder(f[1])
+				if err != nil {
+					return
+				}
 			}
 
+			direction := "ASC"
 			if strings.HasPrefix(field, "-") {
-				field = fmt.Sprintf("%s DESC", field[1:])
+				field = field[1:]
+				direction = "DESC"
+			}
+			if reverse {
+				direction, nulls = reverseDirection[direction], reverseDirection[nulls]
 			}
 
-			values = fmt.Sprintf("%s %s", values, field)
-
-			// if have next order, append a comma
-			if i < len(orderingArr)-1 {
-				values = fmt.Sprintf("%s ,", values)
+			if field, err = orderExpression(field); err != nil {
+				return
 			}
+
+			fields = append(fields, fmt.Sprintf("%s %s%s", field, direction, nulls))
+		}
+		values = fmt.Sprint(" ORDER BY ", strings.Join(fields, ", "))
+	}
+	return
+}
+
+// orderFunctions are the functions accepted in _order fields, e.g. `_order=lower(name)`
+var orderFunctions = map[string]bool{
+	"lower":  true,
+	"upper":  true,
+	"length": true,
+	"abs":    true,
+}
+
+// splitOrderFunction split `function(column)` fields, function is empty for columns
+func splitOrderFunction(field string) (function, column string) {
+	if m := orderFunctionRegex.FindStringSubmatch(field); m != nil {
+		return m[1], m[2]
+	}
+	return "", field
+}
+
+// orderExpression validate the column of an _order field and its function
+func orderExpression(field string) (expression string, err error) {
+	function, column := splitOrderFunction(field)
+	if function != "" && !orderFunctions[strings.ToLower(function)] {
+		err = fmt.Errorf("invalid order function %s, use lower, upper, length or abs", function)
+		return
+	}
+	if !validIdentifier(column) {
+		err = ErrInvalidIdentifier
+		return
+	}
+	expression = quoteColumn(column)
+	if function != "" {
+		expression = fmt.Sprintf("%s(%s)", strings.ToLower(function), expression)
+	}
+	return
+}
+
+// nullsOrder implements the NULL ordering suffix of _order fields, e.g. `-created_at:nullslast`
+func nullsOrder(suffix string) (nulls string, err error) {
+	switch suffix {
+	case "nullsfirst":
+		nulls = " NULLS FIRST"
+	case "nullslast":
+		nulls = " NULLS LAST"
+	default:
+		err = fmt.Errorf("invalid order suffix %s, use nullsfirst or nullslast", suffix)
+	}
+	return
+}
+
+// SampleByRequest implements random sampling, `_sample=N` return N random rows and
+// `_sample=N%` read about N percent of the table pages using TABLESAMPLE SYSTEM
+func SampleByRequest(r *http.Request) (tableSample string, sampleLimit string, err error) {
+	queries := r.URL.Query()
+	reqSample := queries.Get("_sample")
+	if reqSample == "" {
+		return
+	}
+
+	if queries.Get("_order") != "" {
+		err = errors.New("_sample can't be used with _order")
+		return
+	}
+
+	if strings.HasSuffix(reqSample, "%") {
+		var percent float64
+		percent, err = strconv.ParseFloat(strings.TrimSuffix(reqSample, "%"), 64)
+		if err != nil {
+			return
+		}
+		if percent <= 0 || percent > 100 {
+			err = fmt.Errorf("invalid sample percentage %s", reqSample)
+			return
+		}
+		tableSample = fmt.Sprintf(" TABLESAMPLE SYSTEM (%s)", strconv.FormatFloat(percent, 'f', -1, 64))
+		return
+	}
+
+	if _, ok := queries[pageNumberKey]; ok {
+		err = errors.New("_sample can't be used with _page, use _sample=N%")
+		return
+	}
+
+	rows, err := strconv.Atoi(reqSample)
+	if err != nil {
+		return
+	}
+	if rows < 1 {
+		err = fmt.Errorf("invalid sample size %d", rows)
+		return
+	}
+	sampleLimit = fmt.Sprintf("ORDER BY random() LIMIT %d", rows)
+	return
+}
+
+// ExplainByRequest implements `_explain=true` (EXPLAIN) and `_explain=analyze` (EXPLAIN ANALYZE),
+// analyze executes the query and must be enabled by enable_explain_analyze
+func ExplainByRequest(r *http.Request) (explain string, err error) {
+	switch r.URL.Query().Get("_explain") {
+	case "", "false":
+	case "true":
+		explain = statements.Explain
+	case "analyze":
+		if !config.PrestConf.EnableExplainAnalyze {
+			err = ErrExplainAnalyzeDisabled
+			return
 		}
+		explain = statements.ExplainAnalyze
+	default:
+		err = fmt.Errorf("invalid _explain %s, use true or analyze", r.URL.Query().Get("_explain"))
 	}
 	return
 }
 
-// CountByRequest implements COUNT(fields) OPERTATION
-func CountByRequest(req *http.Request) (countQuery string, err error) {
+// Explain return the JSON plan of a query built with ExplainByRequest
+func Explain(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return ExplainInSearchPath(context.Background(), "", SQL, params...)
+}
+
+// ExplainInSearchPath is Explain with the search_path of the request, it's cancelled
+// when ctx is done
+func ExplainInSearchPath(ctx context.Context, searchPath, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	defer timeQuery(ctx, SQL, params)()
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = inSearchPath(ctx, db, searchPath, func(p preparer) error {
+		return p.QueryRowContext(ctx, SQL, params...).Scan(&jsonData)
+	})
+	return
+}
+
+// CountByRequest implements COUNT(fields) OPERTATION, `_count=distinct:column` count the
+// distinct values of column and `_count=estimate` return estimate to read the estimate
+// of rows with CountEstimate instead of counting them, a column named estimate is
+// counted with `_count="estimate"`. With `_groupby` the groups are
+// counted, `_count=*` return statements.SelectGroups and the grouped select is wrapped
+// by CountGroupsQuery
+func CountByRequest(req *http.Request) (countQuery string, estimate bool, err error) {
 	queries := req.URL.Query()
 	countFields := queries.Get("_count")
 
@@ -327,134 +798,377 @@ func CountByRequest(req *http.Request) (countQuery string, err error) {
 		return
 	}
 
+	if countFields == "estimate" {
+		estimate = true
+		return
+	}
+
+	if queries.Get("_groupby") != "" {
+		if countFields != "*" {
+			err = ErrCountGroups
+			return
+		}
+		countQuery = statements.SelectGroups
+		return
+	}
+
+	if strings.HasPrefix(countFields, "distinct:") {
+		column := strings.TrimPrefix(countFields, "distinct:")
+		if !validIdentifier(column) {
+			err = ErrInvalidIdentifier
+			return
+		}
+		countQuery = fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM", quoteColumn(column))
+		return
+	}
+
 	for _, field := range strings.Split(countFields, ",") {
-		if field != "*" && chkInvalidIdentifier(field) {
-			err = errors.New("Invalid identifier")
+		if field != "*" && !validIdentifier(field) {
+			err = ErrInvalidIdentifier
 			return
 		}
 	}
 
-	countQuery = fmt.Sprintf("SELECT COUNT(%s) FROM", countFields)
+	countQuery = fmt.Sprintf("SELECT COUNT(%s) FROM", quoteColumns(countFields))
 
 	return
 }
 
+// CountGroupsQuery count the rows of the grouped select SQL, the groups of `_groupby`
+func CountGroupsQuery(SQL string) string {
+	return fmt.Sprintf(statements.CountRows, SQL)
+}
+
 // Query process queries
 func Query(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return QueryContext(context.Background(), SQL, params...)
+}
+
+// QueryContext is Query cancelled when ctx is done, e.g. with the context of the request,
+// it's run again by withRetry on transient errors
+func QueryContext(ctx context.Context, SQL string, params ...interface{}) (jsonData []byte, err error) {
 	db, err := connection.Get()
 	if err != nil {
-		log.Println(err)
+		logger(ctx).Println(err)
 		return
 	}
+	err = withRetry(ctx, func() (err error) {
+		jsonData, err = queryJSON(ctx, db, SQL, params...)
+		return
+	})
+	return
+}
 
-	SQL = fmt.Sprintf("SELECT json_agg(s) FROM (%s) s", SQL)
+// QueryReplica process read only queries in a read replica
+func QueryReplica(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return QueryReplicaInSearchPath(context.Background(), "", SQL, params...)
+}
+
+// QueryReplicaInSearchPath is QueryReplica with the search_path of the request, it's
+// cancelled when ctx is done
+func QueryReplicaInSearchPath(ctx context.Context, searchPath, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	err = onReplica(ctx, func(db *sqlx.DB) error {
+		return inSearchPath(ctx, db, searchPath, func(p preparer) (err error) {
+			jsonData, err = queryJSON(ctx, p, SQL, params...)
+			return
+		})
+	})
+	return
+}
 
-	prepare, err := db.Prepare(SQL)
+// onReplica run fn in a read replica, it's run again in the primary when there are no
+// replicas or the replica fails, SQL errors of the query are returned as is and
+// nothing is run again once ctx is done
+func onReplica(ctx context.Context, fn func(db *sqlx.DB) error) (err error) {
+	db, err := connection.GetReplica()
+	if err == nil && db != nil {
+		err = fn(db)
+		if _, ok := err.(*pq.Error); err == nil || ok || ctx.Err() != nil {
+			return
+		}
+	}
+	if err != nil {
+		logger(ctx).Println("{replica}", err)
+	}
+
+	db, err = connection.Get()
 	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+	return fn(db)
+}
+
+func queryJSON(ctx context.Context, p preparer, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	if SQL, err = coerceColumns(ctx, p, SQL, params...); err != nil {
 		return
 	}
-	defer prepare.Close()
+	SQL = fmt.Sprintf("SELECT json_agg(s) FROM (%s) s", SQL)
 
-	err = prepare.QueryRow(params...).Scan(&jsonData)
+	err = queryRow(ctx, p, SQL, params, &jsonData)
 
 	if len(jsonData) == 0 {
 		jsonData = []byte("[]")
 	}
+
+	jsonData = formatJSON(jsonData)
 	return
 }
 
-// QueryCount process queries with count
-func QueryCount(SQL string, params ...interface{}) ([]byte, error) {
+// formatJSON apply the output configurations on the JSON built by postgres
+func formatJSON(jsonData []byte) []byte {
+	if config.PrestConf.JSONBigNumbersAsString {
+		jsonData = numbersAsString(jsonData, isBigInteger)
+	}
+	return jsonData
+}
+
+// Ping check the database with a `SELECT 1` limited by timeout
+func Ping(timeout time.Duration) (stats sql.DBStats, err error) {
 	db, err := connection.Get()
 	if err != nil {
 		log.Println(err)
-		return nil, err
+		return
 	}
 
-	prepare, err := db.Prepare(SQL)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var one int
+	err = db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
 	if err != nil {
-		return nil, err
+		return
+	}
+
+	stats = db.Stats()
+	return
+}
+
+// QueryCount process queries with count
+func QueryCount(SQL string, params ...interface{}) ([]byte, error) {
+	return QueryCountContext(context.Background(), SQL, params...)
+}
+
+// QueryCountContext is QueryCount cancelled when ctx is done, it's run again by withRetry
+// on transient errors
+func QueryCountContext(ctx context.Context, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
 	}
+	err = withRetry(ctx, func() (err error) {
+		jsonData, err = queryCount(ctx, db, SQL, params...)
+		return
+	})
+	return
+}
 
+// QueryCountReplica process queries with count in a read replica
+func QueryCountReplica(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return QueryCountReplicaInSearchPath(context.Background(), "", SQL, params...)
+}
+
+// QueryCountReplicaInSearchPath is QueryCountReplica with the search_path of the
+// request, it's cancelled when ctx is done
+func QueryCountReplicaInSearchPath(ctx context.Context, searchPath, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	err = onReplica(ctx, func(db *sqlx.DB) error {
+		return inSearchPath(ctx, db, searchPath, func(p preparer) (err error) {
+			jsonData, err = queryCount(ctx, p, SQL, params...)
+			return
+		})
+	})
+	return
+}
+
+// CountEstimate read the approximate count of rows of a table in a read replica, it's
+// as current as the last VACUUM or ANALYZE of the table
+func CountEstimate(ctx context.Context, schema, table string) (jsonData []byte, err error) {
+	err = onReplica(ctx, func(db *sqlx.DB) (err error) {
+		jsonData, err = queryCount(ctx, db, statements.CountEstimate, schema, table)
+		return
+	})
+	return
+}
+
+// CountRows count the rows of a query in a read replica
+func CountRows(SQL string, params ...interface{}) (count int64, err error) {
+	return CountRowsInSearchPath(context.Background(), "", SQL, params...)
+}
+
+// CountRowsInSearchPath is CountRows with the search_path of the request, it's
+// cancelled when ctx is done
+func CountRowsInSearchPath(ctx context.Context, searchPath, SQL string, params ...interface{}) (count int64, err error) {
+	err = onReplica(ctx, func(db *sqlx.DB) error {
+		return inSearchPath(ctx, db, searchPath, func(p preparer) error {
+			return queryRow(ctx, p, fmt.Sprintf(statements.CountRows, SQL), params, &count)
+		})
+	})
+	return
+}
+
+func queryCount(ctx context.Context, p preparer, SQL string, params ...interface{}) ([]byte, error) {
 	var result struct {
 		Count int64 `json:"count"`
 	}
 
-	row := prepare.QueryRow(params...)
-	if err := row.Scan(&result.Count); err != nil {
+	err := queryRow(ctx, p, SQL, params, &result.Count)
+	if err != nil {
 		return nil, err
 	}
 
 	return json.Marshal(result)
 }
 
+// SingleRowByRequest check `_first` and `_last`, they select only the first row of the
+// order, or the last one, returned as an object instead of an array
+func SingleRowByRequest(r *http.Request) (single bool, err error) {
+	queries := r.URL.Query()
+	first, last := queries.Get("_first") == "true", queries.Get("_last") == "true"
+	if !first && !last {
+		return
+	}
+
+	switch {
+	case first && last:
+		err = ErrFirstAndLast
+	case queries.Get("_order") == "":
+		err = ErrSingleRowWithoutOrder
+	case queries.Get(pageNumberKey) != "" || queries.Get(pageSizeKey) != "" || queries.Get("_count") != "" || queries.Get("_explain") != "" || queries.Get("_sample") != "":
+		err = ErrSingleRowParameters
+	default:
+		single = true
+	}
+	return
+}
+
+// CountOnlyByRequest check `_page_size=0`, only the total of rows is wanted and the rows
+// aren't selected. _count and _explain keep their own output
+func CountOnlyByRequest(r *http.Request) bool {
+	queries := r.URL.Query()
+	return queries.Get(pageSizeKey) == "0" && queries.Get("_count") == "" && queries.Get("_explain") == ""
+}
+
 // PaginateIfPossible func
 func PaginateIfPossible(r *http.Request) (paginatedQuery string, err error) {
+	pageNumber, pageSize, err := PageByRequest(r)
+	if err != nil || pageNumber == 0 {
+		return
+	}
+	paginatedQuery = fmt.Sprintf("LIMIT %d OFFSET(%d - 1) * %d", pageSize, pageNumber, pageSize)
+	return
+}
+
+// PageByRequest read the page number and size of `_page` and `_page_size`, the page is
+// 0 without pagination
+func PageByRequest(r *http.Request) (pageNumber, pageSize int, err error) {
 	values := r.URL.Query()
 	if _, ok := values[pageNumberKey]; !ok {
-		paginatedQuery = ""
 		return
 	}
-	pageNumber, err := strconv.Atoi(values[pageNumberKey][0])
+	pageNumber, err = strconv.Atoi(values[pageNumberKey][0])
 	if err != nil {
 		return
 	}
-	pageSize := defaultPageSize
+	if pageNumber < 1 {
+		err = fmt.Errorf("invalid page %d, pages start at 1", pageNumber)
+		return
+	}
+	pageSize = defaultPageSize
 	if size, ok := values[pageSizeKey]; ok {
 		pageSize, err = strconv.Atoi(size[0])
 		if err != nil {
 			return
 		}
 	}
-	paginatedQuery = fmt.Sprintf("LIMIT %d OFFSET(%d - 1) * %d", pageSize, pageNumber, pageSize)
+	if pageSize < 0 {
+		err = fmt.Errorf("invalid page size %d", pageSize)
+		return
+	}
+	maxPageSize := config.PrestConf.MaxPageSize
+	if maxPageSize > 0 && pageSize > maxPageSize {
+		if !config.PrestConf.ClampPageSize {
+			err = fmt.Errorf("page size %d exceeds the maximum page size %d", pageSize, maxPageSize)
+			return
+		}
+		pageSize = maxPageSize
+	}
 	return
 }
 
-func parseArray(value interface{}) string {
+// parseArray convert a JSON array to a Postgres array literal, nested arrays are
+// multidimensional arrays. The elements must have the same JSON type and objects
+// are rejected, the conversion to the element type of the column is left to Postgres
+func parseArray(value interface{}) (literal string, err error) {
 	switch value.(type) {
 	case []interface{}:
-		var aux string
+		var aux, kind string
 		for _, v := range value.([]interface{}) {
+			if v != nil {
+				if kind != "" && jsonKind(v) != kind {
+					err = fmt.Errorf("array elements must have the same type, got %s and %s", kind, jsonKind(v))
+					return
+				}
+				kind = jsonKind(v)
+			}
+			var element string
+			if element, err = parseArray(v); err != nil {
+				return
+			}
 			if aux != "" {
 				aux += ","
 			}
-			aux += parseArray(v)
+			aux += element
 		}
-		return "{" + aux + "}"
+		literal = "{" + aux + "}"
 	case string:
 		aux := value.(string)
 		aux = strings.Replace(aux, `\`, `\\`, -1)
 		aux = strings.Replace(aux, `"`, `\"`, -1)
-		return `"` + aux + `"`
+		literal = `"` + aux + `"`
 	case int:
-		return strconv.Itoa(value.(int))
+		literal = strconv.Itoa(value.(int))
+	case float64:
+		literal = strconv.FormatFloat(value.(float64), 'f', -1, 64)
+	case json.Number:
+		literal = value.(json.Number).String()
+	case bool:
+		literal = strconv.FormatBool(value.(bool))
+	case nil:
+		literal = "NULL"
+	default:
+		err = fmt.Errorf("array elements of type %s can't be stored in an array column", jsonKind(value))
 	}
-	return ""
+	return
 }
 
-// Insert execute insert sql into a table
-func Insert(SQL string, params ...interface{}) (jsonData []byte, err error) {
-	db, err := connection.Get()
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("could not begin transaction: %v\n", err)
-		return
+// jsonKind name the JSON type of a decoded value
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
 	}
+	return "number"
+}
 
-	defer func() {
-		switch err {
-		case nil:
-			tx.Commit()
-		default:
-			tx.Rollback()
-		}
-	}()
+// Insert execute insert sql into a table
+func Insert(SQL string, params ...interface{}) (jsonData []byte, err error) {
+	return InsertWithLock(context.Background(), nil, "", SQL, params...)
+}
 
+// InsertWithLock execute the insert holding the advisory lock of lock, nil is no lock,
+// in a transaction of the isolation level, empty is the default level. The transaction
+// is rolled back when ctx is done
+func InsertWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	defer timeQuery(ctx, SQL, params)()
 	tableName := insertTableNameRegex.FindStringSubmatch(SQL)
 	if len(tableName) < 2 {
 		err = errors.New("unable to find table name")
@@ -462,48 +1176,163 @@ func Insert(SQL string, params ...interface{}) (jsonData []byte, err error) {
 	}
 	SQL = fmt.Sprintf("%s RETURNING row_to_json(%s)", SQL, tableName[2])
 
-	stmt, err := tx.Prepare(SQL)
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, SQL)
+		if err != nil {
+			logger(ctx).Printf("could not prepare sql: %s\n Error: %v\n", SQL, err)
+			return err
+		}
+		return stmt.QueryRowContext(ctx, params...).Scan(&jsonData)
+	})
 	if err != nil {
-		log.Printf("could not prepare sql: %s\n Error: %v\n", SQL, err)
 		return
 	}
 
-	err = stmt.QueryRow(params...).Scan(&jsonData)
+	jsonData = formatJSON(jsonData)
 	return
 }
 
 // Delete execute delete sql into a table
 func Delete(SQL string, params ...interface{}) (jsonData []byte, err error) {
-	var result sql.Result
+	return DeleteWithLock(context.Background(), nil, "", SQL, params...)
+}
+
+// DeleteWithLock execute the delete holding the advisory lock of lock, nil is no lock,
+// in a transaction of the isolation level, empty is the default level. The transaction
+// is rolled back when ctx is done
+func DeleteWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	defer timeQuery(ctx, SQL, params)()
 	var rowsAffected int64
 
-	db, err := connection.Get()
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, SQL, params...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
 	if err != nil {
-		log.Println(err)
 		return
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("could not begin transaction: %v\n", err)
+	data := make(map[string]interface{})
+	data["rows_affected"] = rowsAffected
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// Statement is a SQL with the values of its placeholders
+type Statement struct {
+	SQL    string
+	Values []interface{}
+}
+
+// BulkUpdateEntry is an update of the bulk update body, where use the same
+// syntax of the query string filters, e.g. {"where": {"id": "$eq.1"}, "set": {"name": "prest"}}
+type BulkUpdateEntry struct {
+	Where map[string]interface{} `json:"where"`
+	Set   map[string]interface{} `json:"set"`
+}
+
+// BulkUpdateByRequest create one UPDATE for each entry of the body
+func BulkUpdateByRequest(r *http.Request, database, schema, table string) (bulk []Statement, err error) {
+	var entries []BulkUpdateEntry
+	if err = json.NewDecoder(r.Body).Decode(&entries); err != nil {
 		return
 	}
+	defer r.Body.Close()
 
-	defer func() {
-		switch err {
-		case nil:
-			tx.Commit()
+	if len(entries) == 0 {
+		err = ErrBodyEmpty
+		return
+	}
+
+	for i, entry := range entries {
+		var statement Statement
+		statement, err = bulkUpdateStatement(entry, database, schema, table)
+		if err != nil {
+			err = fmt.Errorf("entry %d: %v", i, err)
+			bulk = nil
+			return
+		}
+		bulk = append(bulk, statement)
+	}
+	return
+}
+
+func bulkUpdateStatement(entry BulkUpdateEntry, database, schema, table string) (statement Statement, err error) {
+	filters := url.Values{}
+	for key, value := range entry.Where {
+		switch value := value.(type) {
+		case string:
+			filters.Set(key, value)
+		case float64:
+			filters.Set(key, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			filters.Set(key, strconv.FormatBool(value))
 		default:
-			tx.Rollback()
+			err = fmt.Errorf("invalid where value of %s, use strings, numbers or booleans", key)
+			return
 		}
-	}()
+	}
 
-	result, err = tx.Exec(SQL, params...)
+	where, whereValues, err := whereByValues(filters, 1, nil)
 	if err != nil {
 		return
 	}
 
-	rowsAffected, err = result.RowsAffected()
+	if where == "" {
+		err = errors.New("where is required")
+		return
+	}
+
+	setSyntax, setValues, err := setByBody(entry.Set, len(whereValues)+1)
+	if err != nil {
+		return
+	}
+
+	statement.SQL = fmt.Sprint(fmt.Sprintf(statements.UpdateQuery, QuoteName(database), QuoteName(schema), QuoteName(table), setSyntax), " WHERE ", where)
+	statement.Values = append(whereValues, setValues...)
+	return
+}
+
+// BulkUpdate execute the updates in a single transaction and return the total of rows affected
+func BulkUpdate(bulk []Statement) (jsonData []byte, err error) {
+	return BulkUpdateWithLock(context.Background(), nil, "", bulk)
+}
+
+// BulkUpdateWithLock execute the bulk update holding the advisory lock of lock, nil is no lock,
+// in a transaction of the isolation level, empty is the default level. The transaction
+// is rolled back when ctx is done
+func BulkUpdateWithLock(ctx context.Context, lock *int64, isolation string, bulk []Statement) (jsonData []byte, err error) {
+	// the updates run in one transaction, they are logged as one query
+	sqls := make([]string, 0, len(bulk))
+	var values []interface{}
+	for _, statement := range bulk {
+		sqls = append(sqls, statement.SQL)
+		values = append(values, statement.Values...)
+	}
+	defer timeQuery(ctx, strings.Join(sqls, ";"), values)()
+
+	var rowsAffected int64
+
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		rowsAffected = 0
+		for _, statement := range bulk {
+			result, err := tx.ExecContext(ctx, statement.SQL, statement.Values...)
+			if err != nil {
+				return err
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			rowsAffected += affected
+		}
+		return nil
+	})
 	if err != nil {
 		return
 	}
@@ -516,48 +1345,78 @@ func Delete(SQL string, params ...interface{}) (jsonData []byte, err error) {
 
 // Update execute update sql into a table
 func Update(SQL string, params ...interface{}) (jsonData []byte, err error) {
-	var result sql.Result
+	return UpdateWithLock(context.Background(), nil, "", SQL, params...)
+}
+
+// UpdateWithLock execute the update holding the advisory lock of lock, nil is no lock,
+// in a transaction of the isolation level, empty is the default level. The transaction
+// is rolled back when ctx is done
+func UpdateWithLock(ctx context.Context, lock *int64, isolation, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	defer timeQuery(ctx, SQL, params)()
 	var rowsAffected int64
 
-	db, err := connection.Get()
+	err = inTransaction(ctx, lock, isolation, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, SQL)
+		if err != nil {
+			logger(ctx).Printf("could not prepare sql: %s\n Error: %v\n", SQL, err)
+			return err
+		}
+
+		result, err := stmt.ExecContext(ctx, params...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
 	if err != nil {
-		log.Println(err)
 		return
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("could not begin transaction: %v\n", err)
+	data := make(map[string]interface{})
+	data["rows_affected"] = rowsAffected
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// RefreshMaterializedView execute refresh materialized view, concurrently refresh
+// don't lock selects but require an unique index on the view
+func RefreshMaterializedView(ctx context.Context, database, schema, view string, concurrently bool) (jsonData []byte, err error) {
+	if invalidNames(database, schema, view) {
+		err = ErrInvalidIdentifier
 		return
 	}
 
-	defer func() {
-		switch err {
-		case nil:
-			tx.Commit()
-		default:
-			tx.Rollback()
-		}
-	}()
-
-	stmt, err := tx.Prepare(SQL)
+	db, err := connection.Get()
 	if err != nil {
-		log.Printf("could not prepare sql: %s\n Error: %v\n", SQL, err)
+		logger(ctx).Println(err)
 		return
 	}
 
-	result, err = stmt.Exec(params...)
+	var exists bool
+	err = db.QueryRowContext(ctx, statements.MaterializedViewExists, schema, view).Scan(&exists)
 	if err != nil {
 		return
 	}
 
-	rowsAffected, err = result.RowsAffected()
+	if !exists {
+		err = ErrMaterializedViewNotFound
+		return
+	}
+
+	var concurrentlySQL string
+	if concurrently {
+		concurrentlySQL = "CONCURRENTLY "
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(statements.RefreshMaterializedView, concurrentlySQL, QuoteName(database), QuoteName(schema), QuoteName(view)))
 	if err != nil {
 		return
 	}
 
 	data := make(map[string]interface{})
-	data["rows_affected"] = rowsAffected
+	data["materialized_view"] = fmt.Sprintf("%s.%s", schema, view)
+	data["concurrently"] = concurrently
 	jsonData, err = json.Marshal(data)
 	return
 }
@@ -588,6 +1447,8 @@ func GetQueryOperator(op string) (string, error) {
 		return "IS NOT NULL", nil
 	case "null":
 		return "IS NULL", nil
+	case "fts":
+		return ftsOperator, nil
 	}
 
 	err := errors.New("Invalid operator")
@@ -635,18 +1496,39 @@ func FieldsPermissions(r *http.Request, table string, op string) []string {
 					return t.Fields
 				}
 
-				if queries.Get("_groupby") != "" {
-					if strings.Contains(col, ":") {
-						groupFunc, err := NormalizeGroupFunction(col)
-						if err == nil {
-							permittedCols = append(permittedCols, groupFunc)
-						}
-					} else {
+				column, _ := splitAlias(col)
+				if isGroupFunction(column) {
+					// aggregates need the permission of their column, count:* of the table
+					field := strings.SplitN(column, ":", 3)[1]
+					if field == "*" || containsString(t.Fields, field) {
+						permittedCols = append(permittedCols, col)
+					}
+				} else if queries.Get("_groupby") != "" {
+					permittedCols = append(permittedCols, col)
+				} else if isCoalesceField(column) {
+					// coalesce fields need the permission of all their columns
+					if cf, err := parseCoalesceField(column, 1); err == nil && containsAll(t.Fields, cf.Columns) {
+						permittedCols = append(permittedCols, col)
+					}
+				} else if isCaseField(column) {
+					// case fields need the permission of their column
+					if cf, err := parseCaseField(column, 1); err == nil && containsString(t.Fields, cf.Column) {
+						permittedCols = append(permittedCols, col)
+					}
+				} else if isJSONBField(column) {
+					// jsonb fields need the permission of their column
+					if jf, err := parseJSONBField(column); err == nil && containsString(t.Fields, jf.Column) {
+						permittedCols = append(permittedCols, col)
+					}
+				} else if isExpression(column) {
+					// computed fields need the permission of all their columns
+					if expr, err := parseExpression(column); err == nil && containsAll(t.Fields, expr.Columns) {
 						permittedCols = append(permittedCols, col)
 					}
 				} else {
 					for _, f := range t.Fields {
-						if col == f {
+						// quoted by ResolveColumnsByRequest
+						if strings.Trim(column, `"`) == f {
 							permittedCols = append(permittedCols, col)
 						}
 					}
@@ -665,12 +1547,7 @@ func ColumnsByRequest(r *http.Request) []string {
 	var columns []string
 
 	for _, j := range columnsArr {
-		cArgs := strings.Split(j, ",")
-		for _, columnName := range cArgs {
-			if len(columnName) > 0 {
-				columns = append(columns, columnName)
-			}
-		}
+		columns = append(columns, splitSelect(j)...)
 	}
 	if len(columns) == 0 {
 		return []string{"*"}
@@ -678,50 +1555,161 @@ func ColumnsByRequest(r *http.Request) []string {
 	return columns
 }
 
-// GroupByClause get params in request to add group by clause
+// ExcludeColumnsByRequest remove the columns of `_exclude` from cols, "*" is expanded
+// to the columns of the table, excluded columns must exist in the table
+func ExcludeColumnsByRequest(r *http.Request, database, schema, table string, cols []string) (columns []string, err error) {
+	reqExclude := r.URL.Query().Get("_exclude")
+	if reqExclude == "" {
+		columns = cols
+		return
+	}
+	if r.URL.Query().Get("_select") != "" {
+		err = errors.New("_exclude can't be used with _select")
+		return
+	}
+
+	tableColumns, err := TableColumns(r.Context(), database, schema, table)
+	if err != nil {
+		return
+	}
+
+	exclude := make(map[string]bool)
+	for _, column := range strings.Split(reqExclude, ",") {
+		if !containsString(tableColumns, column) {
+			err = fmt.Errorf("column %s does not exist", column)
+			return
+		}
+		exclude[column] = true
+	}
+
+	if len(cols) == 1 && cols[0] == "*" {
+		cols = tableColumns
+	}
+	for _, column := range cols {
+		if !exclude[column] {
+			columns = append(columns, column)
+		}
+	}
+	return
+}
+
+func containsAll(values, wanted []string) bool {
+	for _, value := range wanted {
+		if !containsString(values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupByClause get params in request to add group by clause, an invalid grouping
+// set returns an empty clause, see GroupByRequest
 func GroupByClause(r *http.Request) (groupBySQL string) {
+	groupBySQL, _, _ = GroupByRequest(r, 1)
+	return
+}
+
+// GroupByRequest create the group by clause of `_groupby`, the fields can be the grouping
+// sets `rollup:field1,field2` and `cube:field1,field2`, their fields are validated. The
+// value of the having is numbered from initialPlaceholderID
+func GroupByRequest(r *http.Request, initialPlaceholderID int) (groupBySQL string, values []interface{}, err error) {
 	queries := r.URL.Query()
 	groupQuery := queries.Get("_groupby")
 	if groupQuery == "" {
 		return
 	}
 
+	fields, havingQuery, havingValue := groupQuery, "", ""
 	if strings.Contains(groupQuery, "->>having") {
-		params := strings.Split(groupQuery, ":")
-		groupFieldQuery := strings.Split(groupQuery, "->>having")
-		if len(params) != 5 {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
-		// groupFunc, field, condition, conditionValue string
-		groupFunc, err := NormalizeGroupFunction(fmt.Sprintf("%s:%s", params[1], params[2]))
-		if err != nil {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
+		groupFieldQuery := strings.SplitN(groupQuery, "->>having", 2)
+		fields = groupFieldQuery[0]
+		havingQuery, havingValue = havingClause(groupFieldQuery[1], initialPlaceholderID)
+	}
 
-		operator, err := GetQueryOperator(params[3])
-		if err != nil {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
+	if fields, err = groupingSets(fields); err != nil {
+		return
+	}
+	groupBySQL = fmt.Sprintf(statements.GroupBy, fields)
+	if havingQuery != "" {
+		groupBySQL = fmt.Sprintf("%s %s", groupBySQL, havingQuery)
+		values = append(values, havingValue)
+	}
+	return
+}
 
-		havingQuery := fmt.Sprintf(statements.Having, groupFunc, operator, params[4])
-		groupBySQL = fmt.Sprintf("%s %s", fmt.Sprintf(statements.GroupBy, groupFieldQuery[0]), havingQuery)
+// havingClause create the having of `:GROUPFUNC:FIELDNAME:CONDITION:VALUE` with the value
+// sent as the parameter placeholderID, it's empty when the having is invalid
+func havingClause(having string, placeholderID int) (havingSQL, value string) {
+	params := strings.Split(having, ":")
+	if len(params) != 5 {
+		return
+	}
+	// groupFunc, field, condition, conditionValue string
+	if !validIdentifier(params[2]) {
+		return
+	}
+	groupFunc, err := NormalizeGroupFunction(fmt.Sprintf("%s:%s", params[1], quoteColumn(params[2])))
+	if err != nil {
+		return
+	}
+
+	operator, err := GetQueryOperator(params[3])
+	if err != nil || !containsString(comparisonOperators, operator) {
 		return
 	}
 
-	groupBySQL = fmt.Sprintf(statements.GroupBy, groupQuery)
+	havingSQL = fmt.Sprintf(statements.Having, groupFunc, operator, fmt.Sprintf("$%d", placeholderID))
+	value = params[4]
 	return
 }
 
+// groupingSets create ROLLUP(...) and CUBE(...) of the rollup: and cube: prefixes, the
+// other fields are kept
+func groupingSets(fields string) (string, error) {
+	parts := strings.SplitN(fields, ":", 2)
+	if len(parts) != 2 {
+		for _, field := range strings.Split(fields, ",") {
+			if !validIdentifier(strings.TrimSpace(field)) {
+				return "", fmt.Errorf("invalid _groupby field %s", field)
+			}
+		}
+		return quoteColumns(fields), nil
+	}
+	var set string
+	switch strings.ToLower(parts[0]) {
+	case "rollup":
+		set = "ROLLUP"
+	case "cube":
+		set = "CUBE"
+	default:
+		return "", fmt.Errorf("invalid grouping set %s, use rollup or cube", parts[0])
+	}
+	columns := strings.Split(parts[1], ",")
+	for i, column := range columns {
+		if !validIdentifier(column) {
+			return "", fmt.Errorf("invalid %s column %s", parts[0], column)
+		}
+		columns[i] = quoteColumn(column)
+	}
+	return fmt.Sprintf("%s(%s)", set, strings.Join(columns, ", ")), nil
+}
+
 // NormalizeGroupFunction normalize url params values to sql group functions
 func NormalizeGroupFunction(paramValue string) (groupFuncSQL string, err error) {
 	values := strings.Split(paramValue, ":")
 	groupFunc := strings.ToUpper(values[0])
 
 	switch groupFunc {
-	case "SUM", "AVG", "MAX", "MIN", "MEDIAN", "STDDEV", "VARIANCE":
+	case "SUM", "AVG", "MAX", "MIN", "MEDIAN", "STDDEV", "VARIANCE", "COUNT":
 		// values[1] it's a field in table
 		groupFuncSQL = fmt.Sprintf("%s(%s)", groupFunc, values[1])
 		return
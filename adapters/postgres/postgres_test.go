@@ -1,14 +1,19 @@
 package postgres
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"bytes"
 
+	"github.com/lib/pq"
 	"github.com/nuveo/prest/config"
 	"github.com/nuveo/prest/statements"
 )
@@ -48,6 +53,11 @@ func TestParseInsertRequest(t *testing.T) {
 			t.Errorf("expected errors %v in where by request, got %v", tc.err, err)
 		}
 
+		// the omitted columns are left out to get their defaults
+		if err == nil && len(strings.Split(colsNames, ", ")) != len(tc.body) {
+			t.Errorf("expected only the %d columns of the body in %s", len(tc.body), colsNames)
+		}
+
 		for _, sql := range tc.expectedColNames {
 			if !strings.Contains(colsNames, sql) {
 				t.Errorf("expected %s in %s, but not was!", sql, colsNames)
@@ -113,6 +123,147 @@ func TestSetByRequest(t *testing.T) {
 	}
 }
 
+func TestSetVersionByRequest(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		url            string
+		body           string
+		expectedSQL    string
+		expectedWhere  string
+		expectedValues []interface{}
+		err            error
+	}{
+		{"set without version", "/", `{"name": "prest"}`, "name=$1", "", []interface{}{"prest"}, nil},
+		{"set with version", "/?_version=version", `{"name": "prest", "version": 3}`, "name=$1, version=version+1", "version=$2", []interface{}{"prest", float64(3)}, nil},
+		{"set only version", "/?_version=version", `{"version": 3}`, "version=version+1", "version=$1", []interface{}{float64(3)}, nil},
+		{"set with version missing in body", "/?_version=version", `{"name": "prest"}`, "", "", nil, ErrVersionNotInBody},
+		{"set with merge", "/?_merge=data", `{"name": "prest", "data": {"status": "done"}}`, "data=(COALESCE(data, '{}') || $1::jsonb), name=$2", "", []interface{}{`{"status":"done"}`, "prest"}, nil},
+		{"set only merge and remove", "/?_merge=data&_merge_remove=data:draft,data:tmp", `{"data": {"status": "done"}}`, "data=(((COALESCE(data, '{}') || $1::jsonb) - $2::text) - $3::text)", "", []interface{}{`{"status":"done"}`, "draft", "tmp"}, nil},
+		{"set only remove", "/?_merge_remove=data:draft", `{}`, "data=(COALESCE(data, '{}') - $1::text)", "", []interface{}{"draft"}, nil},
+		{"set with merge and version", "/?_merge=data&_version=version", `{"data": {"status": "done"}, "version": 3}`, "data=(COALESCE(data, '{}') || $1::jsonb), version=version+1", "version=$2", []interface{}{`{"status":"done"}`, float64(3)}, nil},
+		{"set with invalid merge remove", "/?_merge_remove=data", `{"name": "prest"}`, "", "", nil, ErrMergeRemove},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("PATCH", tc.url, strings.NewReader(tc.body))
+		if err != nil {
+			t.Errorf("expected no errors in http request, got %v", err)
+		}
+
+		setSyntax, versionWhere, values, err := SetVersionByRequest(req, 1)
+		if err != tc.err {
+			t.Errorf("expected errors %v in set version by request, got %v", tc.err, err)
+		}
+		if setSyntax != tc.expectedSQL {
+			t.Errorf("expected %q, got %q", tc.expectedSQL, setSyntax)
+		}
+		if versionWhere != tc.expectedWhere {
+			t.Errorf("expected %q, got %q", tc.expectedWhere, versionWhere)
+		}
+		if !reflect.DeepEqual(values, tc.expectedValues) {
+			t.Errorf("expected %v, got %v", tc.expectedValues, values)
+		}
+	}
+
+	req, _ := http.NewRequest("PATCH", "/?_version=0version", strings.NewReader(`{"0version": 1}`))
+	_, _, _, err := SetVersionByRequest(req, 1)
+	if err == nil {
+		t.Error("expected error with invalid version identifier, got nil")
+	}
+}
+
+func TestExplainByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		analyze     bool
+		expected    string
+		err         error
+	}{
+		{"without explain", "/", false, "", nil},
+		{"explain false", "/?_explain=false", false, "", nil},
+		{"explain", "/?_explain=true", false, statements.Explain, nil},
+		{"explain analyze disabled", "/?_explain=analyze", false, "", ErrExplainAnalyzeDisabled},
+		{"explain analyze enabled", "/?_explain=analyze", true, statements.ExplainAnalyze, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.EnableExplainAnalyze = tc.analyze
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Errorf("expected no errors in http request, got %v", err)
+		}
+		explain, err := ExplainByRequest(req)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if explain != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, explain)
+		}
+	}
+	config.PrestConf.EnableExplainAnalyze = false
+
+	req, _ := http.NewRequest("GET", "/?_explain=verbose", nil)
+	if _, err := ExplainByRequest(req); err == nil {
+		t.Error("expected error with invalid _explain, got nil")
+	}
+}
+
+func TestBulkUpdateByRequest(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		body           string
+		expectedSQL    []string
+		expectedValues [][]interface{}
+		err            bool
+	}{
+		{"Bulk update with one entry", `[{"where": {"id": "$eq.1"}, "set": {"name": "prest"}}]`, []string{"\nUPDATE prest.public.test SET name=$2 WHERE id = $1"}, [][]interface{}{{"1", "prest"}}, false},
+		{"Bulk update with many entries", `[{"where": {"id": 1}, "set": {"name": "one"}}, {"where": {"id": 2}, "set": {"name": "two"}}]`, []string{"\nUPDATE prest.public.test SET name=$2 WHERE id = $1", "\nUPDATE prest.public.test SET name=$2 WHERE id = $1"}, [][]interface{}{{"1", "one"}, {"2", "two"}}, false},
+		{"Bulk update with a large id", `[{"where": {"id": 1000000}, "set": {"name": "prest"}}]`, []string{"\nUPDATE prest.public.test SET name=$2 WHERE id = $1"}, [][]interface{}{{"1000000", "prest"}}, false},
+		{"Bulk update with a null where", `[{"where": {"id": null}, "set": {"name": "prest"}}]`, nil, nil, true},
+		{"Bulk update with an object where", `[{"where": {"id": {"a": 1}}, "set": {"name": "prest"}}]`, nil, nil, true},
+		{"Bulk update with empty body", `[]`, nil, nil, true},
+		{"Bulk update without where", `[{"set": {"name": "prest"}}]`, nil, nil, true},
+		{"Bulk update without set", `[{"where": {"id": "$eq.1"}}]`, nil, nil, true},
+		{"Bulk update with invalid where", `[{"where": {"0id": "$eq.1"}, "set": {"name": "prest"}}]`, nil, nil, true},
+		{"Bulk update with invalid body", `{"where": {"id": "$eq.1"}}`, nil, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("POST", "/", strings.NewReader(tc.body))
+		if err != nil {
+			t.Errorf("expected no errors in http request, got %v", err)
+		}
+
+		bulk, err := BulkUpdateByRequest(req, "prest", "public", "test")
+		if tc.err && err == nil {
+			t.Error("expected errors, but no was!")
+		}
+
+		if !tc.err && err != nil {
+			t.Errorf("expected no errors, but got %v", err)
+		}
+
+		if len(bulk) != len(tc.expectedSQL) {
+			t.Errorf("expected %d statements, got %d", len(tc.expectedSQL), len(bulk))
+			continue
+		}
+
+		for i, statement := range bulk {
+			if statement.SQL != tc.expectedSQL[i] {
+				t.Errorf("expected %q, got %q", tc.expectedSQL[i], statement.SQL)
+			}
+
+			if fmt.Sprint(statement.Values) != fmt.Sprint(tc.expectedValues[i]) {
+				t.Errorf("expected %v, got %v", tc.expectedValues[i], statement.Values)
+			}
+		}
+	}
+}
+
 func TestWhereByRequest(t *testing.T) {
 	var testCases = []struct {
 		description    string
@@ -125,6 +276,12 @@ func TestWhereByRequest(t *testing.T) {
 		{"Where by request with spaced values", "/prest/public/test5?name=$eq.prest tester", []string{"name = $"}, []string{"prest tester"}, nil},
 		{"Where by request with jsonb field", "/prest/public/test_jsonb_bug?name=$eq.goku&data->>description:jsonb=$eq.testing", []string{"name = $", "data->>'description' = $", " AND "}, []string{"goku", "testing"}, nil},
 		{"Where by request with dot values", "/prest/public/test5?name=$eq.prest.txt tester", []string{"name = $"}, []string{"prest.txt tester"}, nil},
+		{"Where by request with repeated equals", "/prest/public/test?id=$eq.1&id=2", []string{"id IN ($1, $2)"}, []string{"1", "2"}, nil},
+		{"Where by request with repeated not equals", "/prest/public/test?id=$ne.1&id=$ne.2&id=$ne.3", []string{"id NOT IN ($1, $2, $3)"}, []string{"1", "2", "3"}, nil},
+		{"Where by request with repeated range", "/prest/public/test?age=$gte.18&age=$lt.65", []string{"age >= $1", "age < $2", " AND "}, []string{"18", "65"}, nil},
+		{"Where by request with repeated mixed operators", "/prest/public/test?age=$gt.18&age=$eq.20&age=$eq.30&age=$ne.25", []string{"age > $1", "age IN ($2, $3)", "age != $4"}, []string{"18", "20", "30", "25"}, nil},
+		{"Where by request with date part", "/prest/public/test_timestamps?created_at=$month.3&updated_at=$dow.1", []string{"EXTRACT(MONTH FROM created_at) = $", "EXTRACT(DOW FROM updated_at) = $", " AND "}, []string{"3", "1"}, nil},
+		{"Where by request with full text search", "/prest/public/test_articles?search_vector=$fts.postgres+database", []string{"search_vector @@ plainto_tsquery($1)"}, []string{"postgres database"}, nil},
 	}
 
 	for _, tc := range testCases {
@@ -161,7 +318,10 @@ func TestInvalidWhereByRequest(t *testing.T) {
 	}{
 		{"Where by request without jsonb key", "/prest/public/test_jsonb_bug?name=$eq.nuveo&data->>description:bla"},
 		{"Where by request with jsonb field invalid", "/prest/public/test_jsonb_bug?name=$eq.nuveo&data->>0description:jsonb=$eq.bla"},
+		{"Where by request with jsonb key injection", "/prest/public/test_jsonb_bug?data->>a'%20OR%20'1:jsonb=$eq.1"},
 		{"Where by request with field invalid", "/prest/public/test?0name=$eq.prest"},
+		{"Where by request with date part not integer", "/prest/public/test_timestamps?created_at=$year.last"},
+		{"Where by request with date part of jsonb field", "/prest/public/test_jsonb_bug?data->>created:jsonb=$year.2023"},
 	}
 
 	for _, tc := range testCases {
@@ -192,18 +352,26 @@ func TestGroupByClause(t *testing.T) {
 		url         string
 		expectedSQL string
 		emptyCase   bool
+		err         bool
 	}{
-		{"Group by clause with one field", "/prest/public/test5?_groupby=celphone", "GROUP BY celphone", false},
-		{"Group by clause with two fields", "/prest/public/test5?_groupby=celphone,name", "GROUP BY celphone,name", false},
-		{"Group by clause without fields", "/prest/public/test5?_groupby=", "", true},
+		{"Group by clause with one field", "/prest/public/test5?_groupby=celphone", "GROUP BY celphone", false, false},
+		{"Group by clause with two fields", "/prest/public/test5?_groupby=celphone,name", "GROUP BY celphone,name", false, false},
+		{"Group by clause without fields", "/prest/public/test5?_groupby=", "", true, false},
 
 		// having tests
-		{"Group by clause with having clause", "/prest/public/test5?_groupby=celphone->>having:sum:salary:$gt:500", "GROUP BY celphone HAVING SUM(salary) > 500", false},
+		{"Group by clause with having clause", "/prest/public/test5?_groupby=celphone->>having:sum:salary:$gt:500", "GROUP BY celphone HAVING SUM(salary) > $1", false, false},
 
 		// having errors, but continue with group by
-		{"Group by clause with wrong having clause (insufficient params)", "/prest/public/test5?_groupby=celphone->>having:sum:salary", "GROUP BY celphone", false},
-		{"Group by clause with wrong having clause (wrong query operator)", "/prest/public/test5?_groupby=celphone->>having:sum:salary:$at:500", "GROUP BY celphone", false},
-		{"Group by clause with wrong having clause (wrong group func)", "/prest/public/test5?_groupby=celphone->>having:sun:salary:$gt:500", "GROUP BY celphone", false},
+		{"Group by clause with wrong having clause (insufficient params)", "/prest/public/test5?_groupby=celphone->>having:sum:salary", "GROUP BY celphone", false, false},
+		{"Group by clause with wrong having clause (wrong query operator)", "/prest/public/test5?_groupby=celphone->>having:sum:salary:$at:500", "GROUP BY celphone", false, false},
+		{"Group by clause with wrong having clause (wrong group func)", "/prest/public/test5?_groupby=celphone->>having:sun:salary:$gt:500", "GROUP BY celphone", false, false},
+
+		// grouping sets
+		{"Group by clause with rollup", "/prest/public/test5?_groupby=rollup:region,category", "GROUP BY ROLLUP(region, category)", false, false},
+		{"Group by clause with cube", "/prest/public/test5?_groupby=cube:region,category", "GROUP BY CUBE(region, category)", false, false},
+		{"Group by clause with rollup and having clause", "/prest/public/test5?_groupby=rollup:region->>having:sum:salary:$gt:500", "GROUP BY ROLLUP(region) HAVING SUM(salary) > $1", false, false},
+		{"Group by clause with invalid rollup column", "/prest/public/test5?_groupby=rollup:region,0category", "", true, true},
+		{"Group by clause with invalid grouping set", "/prest/public/test5?_groupby=sets:region", "", true, true},
 	}
 
 	for _, tc := range testCases {
@@ -226,6 +394,10 @@ func TestGroupByClause(t *testing.T) {
 		if groupBySQL != tc.expectedSQL {
 			t.Errorf("expected %s, got %s", tc.expectedSQL, groupBySQL)
 		}
+
+		if _, _, err := GroupByRequest(req, 1); tc.err != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
 	}
 }
 
@@ -273,6 +445,137 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+func TestQueryNumbers(t *testing.T) {
+	var testCases = []struct {
+		description        string
+		bigNumbersAsString bool
+		expected           string
+	}{
+		{"Numeric columns as JSON numbers", false, `[{"age":30,"population":7500000000,"big":9007199254740993,"price":12.50,"rate":0.25}]`},
+		{"Big integers as JSON strings", true, `[{"age":30,"population":7500000000,"big":"9007199254740993","price":12.50,"rate":0.25}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.JSONBigNumbersAsString = tc.bigNumbersAsString
+		response, err := Query("SELECT age, population, big, price, rate FROM prest.public.test_numbers")
+		if err != nil {
+			t.Errorf("expected no errors, but got %s", err)
+		}
+
+		if string(response) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, string(response))
+		}
+	}
+	config.PrestConf.JSONBigNumbersAsString = true
+}
+
+func TestQueryNumericPrecision(t *testing.T) {
+	var testCases = []struct {
+		description string
+		preserve    bool
+		expected    string
+	}{
+		{"Numeric as JSON number", false, `[{"amount":12345678901234567890.1234567890,"price":12.50}]`},
+		{"Numeric as JSON string", true, `[{"amount":"12345678901234567890.1234567890","price":"12.50"}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.JSONPreserveNumericPrecision = tc.preserve
+		response, err := Query("SELECT amount, price FROM prest.public.test_decimals")
+		if err != nil {
+			t.Errorf("expected no errors, but got %s", err)
+		}
+
+		if string(response) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, string(response))
+		}
+	}
+	config.PrestConf.JSONPreserveNumericPrecision = false
+}
+
+func TestQueryCoercions(t *testing.T) {
+	var testCases = []struct {
+		description string
+		money       bool
+		bytea       bool
+		sql         string
+		params      []interface{}
+		expected    string
+	}{
+		{"Bytea in hex", false, false, "SELECT data, active FROM prest.public.test_coercions", nil, `[{"data":"\\x68656c6c6f","active":true}]`},
+		{"Money as number", true, false, "SELECT price, data FROM prest.public.test_coercions", nil, `[{"price":12.50,"data":"\\x68656c6c6f"}]`},
+		{"Bytea as base64", false, true, "SELECT data, active FROM prest.public.test_coercions", nil, `[{"data":"aGVsbG8=","active":true}]`},
+		{"Money and bytea with params", true, true, "SELECT price, data FROM prest.public.test_coercions WHERE active = $1", []interface{}{true}, `[{"price":12.50,"data":"aGVsbG8="}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.JSONMoneyAsNumber, config.PrestConf.JSONByteaAsBase64 = tc.money, tc.bytea
+		response, err := Query(tc.sql, tc.params...)
+		if err != nil {
+			t.Errorf("expected no errors, but got %s", err)
+		}
+
+		if string(response) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, string(response))
+		}
+	}
+	config.PrestConf.JSONMoneyAsNumber, config.PrestConf.JSONByteaAsBase64 = false, false
+}
+
+func TestQueryTimestamps(t *testing.T) {
+	var testCases = []struct {
+		description string
+		format      string
+		sql         string
+		expected    string
+	}{
+		{"Format of postgres", "", "SELECT created_at, updated_at FROM prest.public.test_timestamps WHERE id = 1", `[{"created_at":"2023-01-01T02:00:00+00:00","updated_at":"2023-01-01T02:00:00"}]`},
+		{"RFC 3339", TimestampRFC3339, "SELECT created_at, updated_at FROM prest.public.test_timestamps WHERE id = 1", `[{"created_at":"2023-01-01T02:00:00+00:00","updated_at":"2023-01-01T02:00:00Z"}]`},
+		{"Epoch milliseconds", TimestampEpochMS, "SELECT created_at, updated_at FROM prest.public.test_timestamps WHERE id = 1", `[{"created_at":1672538400000,"updated_at":1672538400000}]`},
+		{"Text and jsonb kept", TimestampEpochMS, `SELECT updated_at::text AS text, json_build_object('2023-01-01T02:00:00', updated_at)::jsonb AS doc FROM prest.public.test_timestamps WHERE id = 1`, `[{"text":"2023-01-01 02:00:00","doc":{"2023-01-01T02:00:00": "2023-01-01T02:00:00"}}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.JSONTimestamps = tc.format
+		response, err := Query(tc.sql)
+		if err != nil {
+			t.Errorf("expected no errors, but got %s", err)
+		}
+
+		if string(response) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, string(response))
+		}
+	}
+	config.PrestConf.JSONTimestamps = ""
+}
+
+func TestQueryColumnsOrder(t *testing.T) {
+	var testCases = []struct {
+		description string
+		sql         string
+		expected    string
+	}{
+		{"Columns in table order", "SELECT * FROM prest.public.test_numbers", `[{"age":30,"population":7500000000,"big":"9007199254740993","price":12.50,"rate":0.25}]`},
+		{"Columns in select order", "SELECT rate, price, big, population, age FROM prest.public.test_numbers", `[{"rate":0.25,"price":12.50,"big":"9007199254740993","population":7500000000,"age":30}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		response, err := Query(tc.sql)
+		if err != nil {
+			t.Errorf("expected no errors, but got %s", err)
+		}
+
+		if string(response) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, string(response))
+		}
+	}
+}
+
 func TestInvalidQuery(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -305,6 +608,8 @@ func TestPaginateIfPossible(t *testing.T) {
 	}{
 		{"Paginate if possible", "/databases?dbname=prest&test=cool&_page=1&_page_size=20", "LIMIT 20 OFFSET(1 - 1) * 20", nil},
 		{"Invalid Paginate if possible", "/databases?dbname=prest&test=cool", "", nil},
+		{"Paginate with default page size", "/databases?_page=2", "LIMIT 10 OFFSET(2 - 1) * 10", nil},
+		{"Paginate with max page size", "/databases?_page=1&_page_size=1000", "LIMIT 1000 OFFSET(1 - 1) * 1000", nil},
 	}
 
 	for _, tc := range testCase {
@@ -325,6 +630,32 @@ func TestPaginateIfPossible(t *testing.T) {
 	}
 }
 
+func TestCountOnlyByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    bool
+	}{
+		{"page size zero", "/prest/public/test?_page_size=0", true},
+		{"page size zero with page", "/prest/public/test?_page=2&_page_size=0", true},
+		{"page size", "/prest/public/test?_page=1&_page_size=10", false},
+		{"without page size", "/prest/public/test", false},
+		{"page size zero with count", "/prest/public/test?_page_size=0&_count=*", false},
+		{"page size zero with explain", "/prest/public/test?_page_size=0&_explain=true", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countOnly := CountOnlyByRequest(req); countOnly != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, countOnly)
+		}
+	}
+}
+
 func TestInvalidPaginateIfPossible(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -332,6 +663,9 @@ func TestInvalidPaginateIfPossible(t *testing.T) {
 	}{
 		{"Paginate with invalid page value", "/databases?dbname=prest&test=cool&_page=X&_page_size=20"},
 		{"Paginate with invalid page size value", "/databases?dbname=prest&test=cool&_page=1&_page_size=K"},
+		{"Paginate with page size greater than max page size", "/databases?_page=1&_page_size=1001"},
+		{"Paginate with negative page size", "/databases?_page=1&_page_size=-1"},
+		{"Paginate with page zero", "/databases?_page=0&_page_size=10"},
 	}
 
 	for _, tc := range testCases {
@@ -352,6 +686,26 @@ func TestInvalidPaginateIfPossible(t *testing.T) {
 	}
 }
 
+func TestClampPaginateIfPossible(t *testing.T) {
+	config.PrestConf.ClampPageSize = true
+	defer func() { config.PrestConf.ClampPageSize = false }()
+
+	req, err := http.NewRequest("GET", "/databases?_page=1&_page_size=1001", nil)
+	if err != nil {
+		t.Errorf("expected no errors in http request, but got %s", err)
+	}
+
+	sql, err := PaginateIfPossible(req)
+	if err != nil {
+		t.Errorf("expected no errors, but got %s", err)
+	}
+
+	expected := "LIMIT 1000 OFFSET(1 - 1) * 1000"
+	if sql != expected {
+		t.Errorf("expected %s, got: %s", expected, sql)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -477,6 +831,13 @@ func TestChkInvaidIdentifier(t *testing.T) {
 		{"0fildName", true},
 		{"fild'Name", true},
 		{"fild\"Name", true},
+		{"\"fildName\"", false},
+		{"test.\"fildName\"", false},
+		{"\"fild\".\"Name\"", false},
+		{"\"fild Name\"", true},
+		{"\"fild\"OR\"Name\"", true},
+		{"\"\"", true},
+		{"\"0fildName\"", true},
 		{"fild;Name", true},
 		{"SUM(test)", false},
 		{"_123456789_123456789_123456789_123456789_123456789_123456789_12345", true},
@@ -502,6 +863,7 @@ func TestJoinByRequest(t *testing.T) {
 		{"Join missing param", "/prest/public/test?_join=inner:test2:test2.name:$eq", []string{}, true},
 		{"Join invalid operator", "/prest/public/test?_join=inner:test2:test2.name:notexist:test.name", []string{}, true},
 		{"Join invalid fields", "/prest/public/test?_join=inner:0test2:test2.name:notexist:test.name", []string{}, true},
+		{"Join function as table", "/prest/public/test?_join=inner:pg_sleep(10):test.id:$eq:test.id", []string{}, true},
 	}
 
 	for _, tc := range testCases {
@@ -570,17 +932,121 @@ func TestJoinByRequest(t *testing.T) {
 	}
 }
 
+func TestRecursiveByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		anchorWhere string
+		expectedSQL []string
+		testError   bool
+	}{
+		{"Recursive with anchor where", "/prest/public/test_categories?_recursive=parent_id:id", "id = $1", []string{"WITH RECURSIVE tree_walk AS", "FROM prest.public.test_categories node WHERE id = $1", "INNER JOIN tree_walk w ON c.parent_id = (w.node).id", "WHERE c.id <> ALL(w.path)\n", "tree AS (SELECT (node).* FROM tree_walk)"}, false},
+		{"Recursive with depth", "/prest/public/test_categories?_recursive=parent_id:id&_recursive_depth=3", "id = $1", []string{"WHERE c.id <> ALL(w.path) AND w.depth < 3\n"}, false},
+		{"Recursive invalid depth", "/prest/public/test_categories?_recursive=parent_id:id&_recursive_depth=0", "", []string{}, true},
+		{"Recursive depth not a number", "/prest/public/test_categories?_recursive=parent_id:id&_recursive_depth=a", "", []string{}, true},
+		{"Recursive without anchor where", "/prest/public/test_categories?_recursive=parent_id:id", "", []string{"WHERE parent_id IS NULL"}, false},
+		{"Recursive empty params", "/prest/public/test_categories?_recursive=", "", []string{}, false},
+		{"Recursive missing param", "/prest/public/test_categories?_recursive=parent_id", "", []string{}, true},
+		{"Recursive invalid fields", "/prest/public/test_categories?_recursive=0parent_id:id", "", []string{}, true},
+		{"Recursive function as field", "/prest/public/test_categories?_recursive=pg_sleep(10):id", "", []string{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Errorf("expected no errors on NewRequest, got: %v", err)
+		}
+
+		sql, err := RecursiveByRequest(req, "prest.public.test_categories", tc.anchorWhere)
+		if tc.testError {
+			if err == nil {
+				t.Error("expected errors, but no was!")
+			}
+
+			if sql != "" {
+				t.Errorf("expected empty sql, but got: %s", sql)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no errors, but got: %v", err)
+		}
+
+		for _, expected := range tc.expectedSQL {
+			if !strings.Contains(sql, expected) {
+				t.Errorf("expected %s in %s, but no was!", expected, sql)
+			}
+		}
+	}
+}
+
+func TestSampleByRequest(t *testing.T) {
+	var testCases = []struct {
+		description         string
+		url                 string
+		expectedTableSample string
+		expectedLimit       string
+		testError           bool
+	}{
+		{"Sample rows", "/prest/public/test?_sample=100", "", "ORDER BY random() LIMIT 100", false},
+		{"Sample percentage", "/prest/public/test?_sample=10%25", " TABLESAMPLE SYSTEM (10)", "", false},
+		{"Sample decimal percentage with pagination", "/prest/public/test?_sample=0.5%25&_page=1", " TABLESAMPLE SYSTEM (0.5)", "", false},
+		{"Sample empty params", "/prest/public/test?_sample=", "", "", false},
+		{"Sample with order", "/prest/public/test?_sample=100&_order=name", "", "", true},
+		{"Sample rows with pagination", "/prest/public/test?_sample=100&_page=1", "", "", true},
+		{"Sample invalid rows", "/prest/public/test?_sample=A", "", "", true},
+		{"Sample zero rows", "/prest/public/test?_sample=0", "", "", true},
+		{"Sample invalid percentage", "/prest/public/test?_sample=101%25", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Errorf("expected no errors on NewRequest, got: %v", err)
+		}
+
+		tableSample, limit, err := SampleByRequest(req)
+		if tc.testError && err == nil {
+			t.Error("expected errors, but no was!")
+		}
+
+		if !tc.testError && err != nil {
+			t.Errorf("expected no errors, but got: %v", err)
+		}
+
+		if tableSample != tc.expectedTableSample {
+			t.Errorf("expected %q, got: %q", tc.expectedTableSample, tableSample)
+		}
+
+		if limit != tc.expectedLimit {
+			t.Errorf("expected %q, got: %q", tc.expectedLimit, limit)
+		}
+	}
+}
+
 func TestCountFields(t *testing.T) {
 	var testCases = []struct {
 		description string
 		url         string
 		expectedSQL string
+		estimate    bool
 		testError   bool
 	}{
-		{"Count fields from table", "/prest/public/test5?_count=celphone", "SELECT COUNT(celphone) FROM", false},
-		{"Count all from table", "/prest/public/test5?_count=*", "SELECT COUNT(*) FROM", false},
-		{"Count with empty params", "/prest/public/test5?_count=", "", false},
-		{"Count with invalid columns", "/prest/public/test5?_count=celphone,0name", "", true},
+		{"Count fields from table", "/prest/public/test5?_count=celphone", "SELECT COUNT(celphone) FROM", false, false},
+		{"Count all from table", "/prest/public/test5?_count=*", "SELECT COUNT(*) FROM", false, false},
+		{"Count with empty params", "/prest/public/test5?_count=", "", false, false},
+		{"Count estimate", "/prest/public/test5?_count=estimate", "", true, false},
+		{"Count a column named estimate", `/prest/public/test5?_count="estimate"`, `SELECT COUNT("estimate") FROM`, false, false},
+		{"Count distinct values", "/prest/public/test5?_count=distinct:celphone", "SELECT COUNT(DISTINCT celphone) FROM", false, false},
+		{"Count distinct values of invalid column", "/prest/public/test5?_count=distinct:0celphone", "", false, true},
+		{"Count distinct values of many columns", "/prest/public/test5?_count=distinct:name,celphone", "", false, true},
+		{"Count with invalid columns", "/prest/public/test5?_count=celphone,0name", "", false, true},
+		{"Count groups", "/prest/public/test5?_count=*&_groupby=celphone", "SELECT 1 FROM", false, false},
+		{"Count fields of groups", "/prest/public/test5?_count=name&_groupby=celphone", "", false, true},
+		{"Count distinct values of groups", "/prest/public/test5?_count=distinct:name&_groupby=celphone", "", false, true},
 	}
 
 	for _, tc := range testCases {
@@ -590,7 +1056,10 @@ func TestCountFields(t *testing.T) {
 			t.Errorf("expected no errors on NewRequest, got: %v", err)
 		}
 
-		sql, err := CountByRequest(req)
+		sql, estimate, err := CountByRequest(req)
+		if estimate != tc.estimate {
+			t.Errorf("expected estimate %v, got %v", tc.estimate, estimate)
+		}
 		if tc.testError {
 			if err == nil {
 				t.Error("expected errors, but no was!")
@@ -612,6 +1081,14 @@ func TestCountFields(t *testing.T) {
 
 }
 
+func TestCountGroupsQuery(t *testing.T) {
+	sql := CountGroupsQuery("SELECT 1 FROM prest.public.test5 GROUP BY celphone")
+	expected := "\nSELECT COUNT(*) FROM (SELECT 1 FROM prest.public.test5 GROUP BY celphone) s"
+	if sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
 func TestDatabaseClause(t *testing.T) {
 	var testCases = []struct {
 		description   string
@@ -676,6 +1153,7 @@ func TestGetQueryOperator(t *testing.T) {
 		{"$nin", "NOT IN"},
 		{"$notnull", "IS NOT NULL"},
 		{"$null", "IS NULL"},
+		{"$fts", "@@"},
 	}
 
 	for _, tc := range testCases {
@@ -749,6 +1227,143 @@ func TestOrderByRequest(t *testing.T) {
 	if order != "" {
 		t.Errorf("expected order empty, got: %s", order)
 	}
+
+	t.Log("Query ORDER BY many columns with mixed directions")
+	r, err = http.NewRequest("GET", "/prest/public/test?_order=region,-created_at,name", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	order, err = OrderByRequest(r)
+	if err != nil {
+		t.Errorf("expected no errors on OrderByRequest, got: %v", err)
+	}
+	if expected := " ORDER BY region ASC, created_at DESC, name ASC"; order != expected {
+		t.Errorf("expected %q, got: %q", expected, order)
+	}
+
+	for _, invalid := range []string{"name,0number", "-0name", "name,-0number", "name,", "-"} {
+		t.Logf("Query ORDER BY invalid column in %s", invalid)
+		r, err = http.NewRequest("GET", "/prest/public/test?_order="+invalid, nil)
+		if err != nil {
+			t.Errorf("expected no errors on NewRequest, got: %v", err)
+		}
+
+		order, err = OrderByRequest(r)
+		if err == nil {
+			t.Errorf("expected errors on OrderByRequest, got: %v", err)
+		}
+		if order != "" {
+			t.Errorf("expected order empty, got: %s", order)
+		}
+	}
+
+	t.Log("Query ORDER BY with NULLS FIRST/LAST")
+	r, err = http.NewRequest("GET", "/prest/public/test?_order=name:nullslast,-number:nullsfirst,id", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	order, err = OrderByRequest(r)
+	if err != nil {
+		t.Errorf("expected no errors on OrderByRequest, got: %v", err)
+	}
+	if expected := " ORDER BY name ASC NULLS LAST, number DESC NULLS FIRST, id ASC"; order != expected {
+		t.Errorf("expected %q, got: %q", expected, order)
+	}
+
+	var orderFunctionCases = []struct {
+		description string
+		order       string
+		expected    string
+		err         bool
+	}{
+		{"function", "lower(name)", " ORDER BY lower(name) ASC", false},
+		{"function desc", "-length(name)", " ORDER BY length(name) DESC", false},
+		{"function with nulls and columns", "-abs(number):nullslast,UPPER(name),id", " ORDER BY abs(number) DESC NULLS LAST, upper(name) ASC, id ASC", false},
+		{"unknown function", "pg_sleep(10)", "", true},
+		{"nested function", "lower(upper(name))", "", true},
+		{"function of invalid column", "lower(0name)", "", true},
+		{"function without column", "lower()", "", true},
+	}
+
+	for _, tc := range orderFunctionCases {
+		t.Logf("Query ORDER BY %s", tc.description)
+		r, err = http.NewRequest("GET", "/prest/public/test?_order="+tc.order, nil)
+		if err != nil {
+			t.Errorf("expected no errors on NewRequest, got: %v", err)
+		}
+
+		order, err = OrderByRequest(r)
+		if tc.err != (err != nil) {
+			t.Errorf("expected error %v, got: %v", tc.err, err)
+		}
+		if order != tc.expected {
+			t.Errorf("expected %q, got: %q", tc.expected, order)
+		}
+	}
+
+	t.Log("Query ORDER BY invalid nulls suffix")
+	r, err = http.NewRequest("GET", "/prest/public/test?_order=name:nullsmiddle", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	order, err = OrderByRequest(r)
+	if err == nil {
+		t.Errorf("expected errors on OrderByRequest, got: %v", err)
+	}
+
+	if order != "" {
+		t.Errorf("expected order empty, got: %s", order)
+	}
+
+	t.Log("Query ORDER BY reversed by _last")
+	r, err = http.NewRequest("GET", "/prest/public/test?_order=name:nullslast,-number,id&_last=true", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	order, err = OrderByRequest(r)
+	if err != nil {
+		t.Errorf("expected no errors on OrderByRequest, got: %v", err)
+	}
+	if expected := " ORDER BY name DESC NULLS FIRST, number ASC, id DESC"; order != expected {
+		t.Errorf("expected %q, got: %q", expected, order)
+	}
+}
+
+func TestSingleRowByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		single      bool
+		err         error
+	}{
+		{"without _first and _last", "/prest/public/test?_order=id", false, nil},
+		{"first row", "/prest/public/test?_order=id&_first=true", true, nil},
+		{"last row", "/prest/public/test?_order=id&_last=true", true, nil},
+		{"first disabled", "/prest/public/test?_order=id&_first=false", false, nil},
+		{"first and last", "/prest/public/test?_order=id&_first=true&_last=true", false, ErrFirstAndLast},
+		{"first without order", "/prest/public/test?_first=true", false, ErrSingleRowWithoutOrder},
+		{"first with pagination", "/prest/public/test?_order=id&_first=true&_page=2", false, ErrSingleRowParameters},
+		{"last with count", "/prest/public/test?_order=id&_last=true&_count=*", false, ErrSingleRowParameters},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		single, err := SingleRowByRequest(r)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if single != tc.single {
+			t.Errorf("expected single %v, got %v", tc.single, single)
+		}
+	}
 }
 
 func TestTablePermissions(t *testing.T) {
@@ -789,6 +1404,11 @@ func TestFieldsPermissions(t *testing.T) {
 		{"Read invalid field", "/prest/public/test_list_only_id?_select=name", "test_list_only_id", "read", 0},
 		{"Read non existing field", "/prest/public/test_list_only_id?_select=non_existing_field", "test_list_only_id", "read", 0},
 		{"Select with *", "/prest/public/test_list_only_id?_select=*", "test_list_only_id", "read", 1},
+		{"Read valid field with alias", "/prest/public/test_list_only_id?_select=id:as:code", "test_list_only_id", "read", 1},
+		{"Read invalid field with alias", "/prest/public/test_list_only_id?_select=name:as:id", "test_list_only_id", "read", 0},
+		{"Aggregate of valid field", "/prest/public/test_list_only_id?_select=max:id", "test_list_only_id", "read", 1},
+		{"Aggregate of invalid field", "/prest/public/test_list_only_id?_select=max:name", "test_list_only_id", "read", 0},
+		{"Count of the rows", "/prest/public/test_list_only_id?_select=count:*", "test_list_only_id", "read", 1},
 	}
 
 	for _, tc := range testCases {
@@ -836,6 +1456,13 @@ func TestSelectFields(t *testing.T) {
 	}{
 		{"One field", []string{"test"}, "SELECT test FROM"},
 		{"More field", []string{"test", "test02"}, "SELECT test,test02 FROM"},
+		{"Field with alias", []string{"name:as:full_name", "email"}, "SELECT name AS full_name,email FROM"},
+		{"Group function with alias", []string{"SUM(salary):as:total"}, "SELECT SUM(salary) AS total FROM"},
+		{"Expression with alias", []string{"price", "price*quantity:as:total"}, "SELECT price,price * quantity AS total FROM"},
+		{"JSONb field with alias", []string{"id", "data->>name:as:name"}, "SELECT id,data->>'name' AS name FROM"},
+		{"Nested JSONb field with alias", []string{"data->address->>city:as:city"}, "SELECT data->'address'->>'city' AS city FROM"},
+		{"Aggregate", []string{"sum:amount"}, "SELECT SUM(amount) FROM"},
+		{"Count and sum with alias", []string{"count:*", "sum:amount:as:total"}, "SELECT COUNT(*),SUM(amount) AS total FROM"},
 	}
 	var testErrorCases = []struct {
 		description string
@@ -844,6 +1471,16 @@ func TestSelectFields(t *testing.T) {
 	}{
 		{"Invalid fields", []string{"0test", "test02"}, ""},
 		{"Empty fields", []string{}, ""},
+		{"Invalid alias", []string{"name:as:full;name"}, ""},
+		{"Alias with expression", []string{"name:as:lower(name)"}, ""},
+		{"Invalid field with alias", []string{"0name:as:full_name"}, ""},
+		{"Expression without alias", []string{"price*quantity"}, ""},
+		{"Expression with function", []string{"lower(name)*2:as:total"}, ""},
+		{"JSONb field without alias", []string{"data->>name"}, ""},
+		{"JSONb field with invalid key", []string{"data->>'name':as:name"}, ""},
+		{"JSONb field with injection", []string{"data->>name'||pg_sleep(1)||':as:name"}, ""},
+		{"Aggregate of *", []string{"sum:*"}, ""},
+		{"Aggregate with injection", []string{"sum:amount);DROP TABLE test;--"}, ""},
 	}
 
 	for _, tc := range testCases {
@@ -899,25 +1536,38 @@ func TestColumnsByRequest(t *testing.T) {
 }
 
 func TestParseArray(t *testing.T) {
-	in := []interface{}{"value 1", "value 2", "value 3"}
-	ret := parseArray(in)
-	retString := `{"value 1","value 2","value 3"}`
-	if ret != retString {
-		t.Errorf("Error expected %s, got %s", retString, ret)
-	}
-
-	in = []interface{}{10, 20, 30}
-	ret = parseArray(in)
-	retString = `{10,20,30}`
-	if ret != retString {
-		t.Errorf("Error expected %s, got %s", retString, ret)
+	var testCases = []struct {
+		description string
+		in          []interface{}
+		expected    string
+		testError   bool
+	}{
+		{"array of strings", []interface{}{"value 1", "value 2", "value 3"}, `{"value 1","value 2","value 3"}`, false},
+		{"array of integers", []interface{}{10, 20, 30}, `{10,20,30}`, false},
+		{"empty array", []interface{}{}, `{}`, false},
+		{"array of JSON numbers", []interface{}{float64(1), 2.5, float64(1e21)}, `{1,2.5,1000000000000000000000}`, false},
+		{"array of booleans with null", []interface{}{true, nil, false}, `{true,NULL,false}`, false},
+		{"multidimensional array", []interface{}{[]interface{}{float64(1), float64(2)}, []interface{}{float64(3), float64(4)}}, `{{1,2},{3,4}}`, false},
+		{"array with quotes", []interface{}{`say "hi"`, `c:\dir`}, `{"say \"hi\"","c:\\dir"}`, false},
+		{"array of mixed types", []interface{}{"one", float64(2)}, "", true},
+		{"array of objects", []interface{}{map[string]interface{}{"name": "prest"}}, "", true},
 	}
 
-	in = []interface{}{}
-	ret = parseArray(in)
-	retString = `{}`
-	if ret != retString {
-		t.Errorf("Error expected %s, got %s", retString, ret)
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		ret, err := parseArray(tc.in)
+		if tc.testError {
+			if err == nil {
+				t.Errorf("expected errors, got %s", ret)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if ret != tc.expected {
+			t.Errorf("Error expected %s, got %s", tc.expected, ret)
+		}
 	}
 }
 
@@ -934,6 +1584,7 @@ func TestNormalizeGroupFunction(t *testing.T) {
 		{"Normalize MEDIAN Function", "median:age", "MEDIAN(age)"},
 		{"Normalize STDDEV Function", "stddev:age", "STDDEV(age)"},
 		{"Normalize VARIANCE Function", "variance:age", "VARIANCE(age)"},
+		{"Normalize COUNT Function", "count:*", "COUNT(*)"},
 	}
 
 	for _, tc := range testCases {
@@ -947,3 +1598,91 @@ func TestNormalizeGroupFunction(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryCancelled(t *testing.T) {
+	var testCases = []struct {
+		description string
+		run         func(ctx context.Context) error
+	}{
+		{"select", func(ctx context.Context) (err error) {
+			_, err = QueryContext(ctx, "SELECT pg_sleep(10)")
+			return
+		}},
+		{"select in the search path", func(ctx context.Context) (err error) {
+			_, err = QueryReplicaInSearchPath(ctx, "public", "SELECT pg_sleep(10)")
+			return
+		}},
+		{"raw query", func(ctx context.Context) (err error) {
+			_, err = RawQuery(ctx, "SELECT pg_sleep(10)")
+			return
+		}},
+		{"write in a transaction", func(ctx context.Context) (err error) {
+			_, err = WriteSQL(ctx, "SELECT pg_sleep(10)", nil)
+			return
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		err := tc.run(ctx)
+		cancel()
+		if err == nil {
+			t.Errorf("expected the query to be cancelled")
+			continue
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code != "57014" {
+			t.Errorf("expected query_canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed >= 10*time.Second {
+			t.Errorf("expected the query to be aborted, it ran for %v", elapsed)
+		}
+	}
+}
+
+func TestQueryClientDisconnected(t *testing.T) {
+	defer func(size int) { config.PrestConf.StatementCacheSize = size }(config.PrestConf.StatementCacheSize)
+
+	var testCases = []struct {
+		description string
+		cacheSize   int
+	}{
+		{"select", 0},
+		{"cached select", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.StatementCacheSize = tc.cacheSize
+		errs := make(chan error, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := QueryContext(r.Context(), "SELECT pg_sleep(10)")
+			errs <- err
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if _, err = http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+			t.Errorf("expected the client to disconnect")
+		}
+		cancel()
+
+		select {
+		case err = <-errs:
+			if err == nil {
+				t.Errorf("expected the query to be cancelled")
+			}
+			if elapsed := time.Since(start); elapsed >= 10*time.Second {
+				t.Errorf("expected the query to be aborted, it ran for %v", elapsed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected the query to be cancelled when the client disconnects")
+		}
+		server.Close()
+	}
+}
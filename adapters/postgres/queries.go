@@ -2,9 +2,9 @@ package postgres
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -73,17 +73,19 @@ func ParseScript(scriptPath string, queryURL url.Values) (sqlQuery string, value
 	return
 }
 
-// WriteSQL perform INSERT's, UPDATE's, DELETE's operations
-func WriteSQL(sql string, values []interface{}) (resultByte []byte, err error) {
+// WriteSQL perform INSERT's, UPDATE's, DELETE's operations, the transaction is rolled
+// back when ctx is done
+func WriteSQL(ctx context.Context, sql string, values []interface{}) (resultByte []byte, err error) {
+	defer timeQuery(ctx, sql, values)()
 	db, err := connection.Get()
 	if err != nil {
-		log.Println(err)
+		logger(ctx).Println(err)
 		return
 	}
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("could not begin transaction: %v\n", err)
+		logger(ctx).Printf("could not begin transaction: %v\n", err)
 		return
 	}
 
@@ -102,10 +104,10 @@ func WriteSQL(sql string, values []interface{}) (resultByte []byte, err error) {
 		valuesAux = append(valuesAux, values[i])
 	}
 
-	result, err := tx.Exec(sql, valuesAux...)
+	result, err := tx.ExecContext(ctx, sql, valuesAux...)
 	if err != nil {
 		tx.Rollback()
-		log.Printf("sql = %+v\n", sql)
+		logger(ctx).Printf("sql = %+v\n", sql)
 		err = fmt.Errorf("could not peform sql: %v", err)
 		return
 	}
@@ -124,13 +126,13 @@ func WriteSQL(sql string, values []interface{}) (resultByte []byte, err error) {
 	return
 }
 
-// ExecuteScripts run sql templates created by users
-func ExecuteScripts(method, sql string, values []interface{}) (result []byte, err error) {
+// ExecuteScripts run sql templates created by users, they're cancelled when ctx is done
+func ExecuteScripts(ctx context.Context, method, sql string, values []interface{}) (result []byte, err error) {
 	switch method {
 	case "GET":
-		result, err = Query(sql, values...)
+		result, err = QueryContext(ctx, sql, values...)
 	case "POST", "PUT", "PATCH", "DELETE":
-		result, err = WriteSQL(sql, values)
+		result, err = WriteSQL(ctx, sql, values)
 	default:
 		err = fmt.Errorf("invalid method %s", err)
 	}
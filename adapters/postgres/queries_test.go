@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
@@ -116,7 +117,7 @@ func TestValidWriteSQL(t *testing.T) {
 	}
 	for _, tc := range testValidCases {
 		t.Log(tc.description)
-		_, err := WriteSQL(tc.sql, tc.values)
+		_, err := WriteSQL(context.Background(), tc.sql, tc.values)
 		if tc.err != err {
 			t.Error(tc.err, err)
 		}
@@ -136,7 +137,7 @@ func TestInvalidWriteSQL(t *testing.T) {
 
 	for _, tc := range testInvalidCases {
 		t.Log(tc.description)
-		_, err := WriteSQL(tc.sql, tc.values)
+		_, err := WriteSQL(context.Background(), tc.sql, tc.values)
 		if err == nil {
 			t.Errorf("expected nil, but got %v", err)
 		}
@@ -160,7 +161,7 @@ func TestExecuteScripts(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Log(tc.description)
-		_, err := ExecuteScripts(tc.method, tc.sql, tc.values)
+		_, err := ExecuteScripts(context.Background(), tc.method, tc.sql, tc.values)
 		if tc.err != err {
 			t.Errorf("expected no errors, but got %s", err)
 		}
@@ -168,7 +169,7 @@ func TestExecuteScripts(t *testing.T) {
 
 	t.Log("Get errors with invalid HTTP Method")
 	values := make([]interface{}, 0)
-	result, err := ExecuteScripts("ANY", "SELECT * FROM test7", values)
+	result, err := ExecuteScripts(context.Background(), "ANY", "SELECT * FROM test7", values)
 	if len(result) > 0 {
 		t.Errorf("expected empty result, but got %s", result)
 	}
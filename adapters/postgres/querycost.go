@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nuveo/prest/statements"
+)
+
+// QueryCost estimate the total cost of the plan of SQL with EXPLAIN, the query isn't
+// executed. It's a round trip more per request, it's run only with query_cost_header
+func QueryCost(ctx context.Context, searchPath, SQL string, params ...interface{}) (cost float64, err error) {
+	plan, err := ExplainInSearchPath(ctx, searchPath, statements.Explain+SQL, params...)
+	if err != nil {
+		return
+	}
+	return planCost(plan)
+}
+
+// planCost read the total cost of the top node of a plan of EXPLAIN (FORMAT JSON)
+func planCost(plan []byte) (cost float64, err error) {
+	var plans []struct {
+		Plan struct {
+			TotalCost *float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err = json.Unmarshal(plan, &plans); err != nil {
+		return
+	}
+	if len(plans) == 0 || plans[0].Plan.TotalCost == nil {
+		err = errors.New("plan without total cost")
+		return
+	}
+	cost = *plans[0].Plan.TotalCost
+	return
+}
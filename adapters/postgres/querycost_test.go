@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPlanCost(t *testing.T) {
+	var testCases = []struct {
+		description string
+		plan        string
+		cost        float64
+		err         bool
+	}{
+		{"seq scan", `[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.00, "Total Cost": 22.70, "Plan Rows": 1270}}]`, 22.70, false},
+		{"top node of a join", `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 61.35, "Plans": [{"Node Type": "Seq Scan", "Total Cost": 22.70}]}}]`, 61.35, false},
+		{"zero cost", `[{"Plan": {"Node Type": "Result", "Total Cost": 0}}]`, 0, false},
+		{"without total cost", `[{"Plan": {"Node Type": "Seq Scan"}}]`, 0, true},
+		{"without plans", `[]`, 0, true},
+		{"invalid JSON", `{`, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		cost, err := planCost([]byte(tc.plan))
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if cost != tc.cost {
+			t.Errorf("expected %v, got %v", tc.cost, cost)
+		}
+	}
+}
+
+func TestQueryCost(t *testing.T) {
+	cost, err := QueryCost(context.Background(), "", "SELECT * FROM test WHERE name = $1", "prest")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cost <= 0 {
+		t.Errorf("expected a positive cost, got %v", cost)
+	}
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/nuveo/prest/config"
+)
+
+// QuoteName double quote a name of the catalog (database, schema, table or column),
+// e.g. Order is "Order", when quote_identifiers is enabled. The names already quoted
+// are kept, the double quotes inside the others are rejected by chkInvalidIdentifier
+// and doubled here in case they reach the query
+func QuoteName(name string) string {
+	if !config.PrestConf.QuoteIdentifiers || name == "" || quotedName(name) {
+		return name
+	}
+	return quoteIdentifier(name)
+}
+
+// quoteColumn quote the names of a column reference, e.g. t.select is "t"."select", when
+// quote_identifiers is enabled. The expressions (functions, operators, *, json paths)
+// are kept as written
+func quoteColumn(field string) string {
+	if !config.PrestConf.QuoteIdentifiers {
+		return field
+	}
+	names := strings.Split(field, ".")
+	for _, name := range names {
+		if !plainIdentifierRegex.MatchString(name) && !quotedName(name) {
+			return field
+		}
+	}
+	for i, name := range names {
+		names[i] = QuoteName(name)
+	}
+	return strings.Join(names, ".")
+}
+
+// quoteColumns apply quoteColumn on the fields of a comma separated list
+func quoteColumns(fields string) string {
+	if !config.PrestConf.QuoteIdentifiers {
+		return fields
+	}
+	columns := strings.Split(fields, ",")
+	for i, column := range columns {
+		columns[i] = quoteColumn(strings.TrimSpace(column))
+	}
+	return strings.Join(columns, ", ")
+}
+
+func quotedName(name string) bool {
+	return len(name) > 1 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`)
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestQuoteName(t *testing.T) {
+	var testCases = []struct {
+		description string
+		enabled     bool
+		name        string
+		expected    string
+	}{
+		{"disabled", false, "Order", "Order"},
+		{"mixed case", true, "Order", `"Order"`},
+		{"reserved word", true, "select", `"select"`},
+		{"already quoted", true, `"Order"`, `"Order"`},
+		{"embedded double quote", true, `Or"der`, `"Or""der"`},
+		{"empty", true, "", ""},
+	}
+
+	defer func() { config.PrestConf.QuoteIdentifiers = false }()
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.QuoteIdentifiers = tc.enabled
+		if name := QuoteName(tc.name); name != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, name)
+		}
+	}
+}
+
+func TestQuoteColumn(t *testing.T) {
+	var testCases = []struct {
+		description string
+		enabled     bool
+		field       string
+		expected    string
+	}{
+		{"disabled", false, "select", "select"},
+		{"column", true, "select", `"select"`},
+		{"table and column", true, "Order.userName", `"Order"."userName"`},
+		{"quoted table", true, `"Order".name`, `"Order"."name"`},
+		{"all columns", true, "*", "*"},
+		{"function", true, "count(id)", "count(id)"},
+		{"json path", true, "data->>'name'", "data->>'name'"},
+	}
+
+	defer func() { config.PrestConf.QuoteIdentifiers = false }()
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.QuoteIdentifiers = tc.enabled
+		if column := quoteColumn(tc.field); column != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, column)
+		}
+	}
+}
+
+func TestQuoteIdentifiersInClauses(t *testing.T) {
+	config.PrestConf.QuoteIdentifiers = true
+	defer func() { config.PrestConf.QuoteIdentifiers = false }()
+
+	r, err := http.NewRequest("GET", `/prest/public/Order?select=$eq.a&_order=-userName,lower(name)&_groupby=select,userName`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, _, err := WhereByRequest(r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"select" = $1`; where != expected {
+		t.Errorf("expected where %s, got %s", expected, where)
+	}
+
+	order, err := OrderByRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := ` ORDER BY "userName" DESC, lower("name") ASC`; order != expected {
+		t.Errorf("expected order %s, got %s", expected, order)
+	}
+
+	groupBy, _, err := GroupByRequest(r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `GROUP BY "select", "userName"`; groupBy != expected {
+		t.Errorf("expected group by %s, got %s", expected, groupBy)
+	}
+
+	r, err = http.NewRequest("GET", `/prest/public/Order?_count=id`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, _, err := CountByRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `SELECT COUNT("id") FROM`; count != expected {
+		t.Errorf("expected count %s, got %s", expected, count)
+	}
+
+	fields, err := SelectFields([]string{"select", "Order.userName:as:User", "count(id):as:total"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `SELECT "select","Order"."userName" AS "User",count(id) AS "total" FROM`; fields != expected {
+		t.Errorf("expected select %s, got %s", expected, fields)
+	}
+
+	set, _, err := setByBody(map[string]interface{}{"select": "a"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"select"=$1`; set != expected {
+		t.Errorf("expected set %s, got %s", expected, set)
+	}
+
+	sql, err := createTableSQL("prest", "public", TableSpec{Name: "Order", Columns: []ColumnSpec{{Name: "select", Type: "text", PrimaryKey: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\nCREATE TABLE \"prest\".\"public\".\"Order\" (\"select\" text, PRIMARY KEY (\"select\"))"; sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
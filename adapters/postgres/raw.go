@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+)
+
+// ErrNotReadOnlyQuery err throw when a raw query is not a single SELECT
+var ErrNotReadOnlyQuery = errors.New("only a single SELECT statement is allowed")
+
+var readOnlyQueryRegex = regexp.MustCompile(`(?i)^(SELECT|WITH)\b`)
+
+// RawQuery execute a SELECT sent by the client in a read only transaction,
+// params are bound to the $n placeholders. The query is cancelled when ctx is done
+// and run again by withRetry on transient errors
+func RawQuery(ctx context.Context, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	defer timeQuery(ctx, SQL, params)()
+	SQL, err = readOnlyQuery(SQL)
+	if err != nil {
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = withRetry(ctx, func() (err error) {
+		jsonData, err = rawQuery(ctx, db, SQL, params...)
+		return
+	})
+	return
+}
+
+func rawQuery(ctx context.Context, db *sqlx.DB, SQL string, params ...interface{}) (jsonData []byte, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger(ctx).Printf("could not begin transaction: %v\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return
+	}
+	if SQL, err = coerceColumns(ctx, tx, SQL, params...); err != nil {
+		return
+	}
+
+	prepare, err := tx.PrepareContext(ctx, fmt.Sprintf("SELECT json_agg(s) FROM (%s) s", SQL))
+	if err != nil {
+		return
+	}
+	defer prepare.Close()
+
+	err = prepare.QueryRowContext(ctx, params...).Scan(&jsonData)
+	if len(jsonData) == 0 {
+		jsonData = []byte("[]")
+	}
+
+	jsonData = formatJSON(jsonData)
+	return
+}
+
+// readOnlyQuery accept a single statement starting with SELECT or WITH,
+// the read only transaction reject data-modifying CTEs
+func readOnlyQuery(SQL string) (query string, err error) {
+	query = strings.TrimSuffix(strings.TrimSpace(SQL), ";")
+	if strings.Contains(query, ";") || !readOnlyQueryRegex.MatchString(query) {
+		err = ErrNotReadOnlyQuery
+	}
+	return
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import "testing"
+
+func TestReadOnlyQuery(t *testing.T) {
+	var testCases = []struct {
+		description string
+		sql         string
+		expected    string
+		err         error
+	}{
+		{"select", "SELECT * FROM test WHERE id = $1", "SELECT * FROM test WHERE id = $1", nil},
+		{"select lower case with trailing semicolon", "  select 1;\n", "select 1", nil},
+		{"with", "WITH t AS (SELECT 1) SELECT * FROM t", "WITH t AS (SELECT 1) SELECT * FROM t", nil},
+		{"delete", "DELETE FROM test", "", ErrNotReadOnlyQuery},
+		{"multiple statements", "SELECT 1; DROP TABLE test", "", ErrNotReadOnlyQuery},
+		{"subquery escape", "SELECT 1) s; DROP TABLE test; --", "", ErrNotReadOnlyQuery},
+		{"select prefix", "SELECTED", "", ErrNotReadOnlyQuery},
+		{"empty", "", "", ErrNotReadOnlyQuery},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		query, err := readOnlyQuery(tc.sql)
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if err == nil && query != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, query)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+)
+
+// retryableCodes are the SQLSTATE of the transient errors besides the connection
+// exceptions (class 08), the statements failed with them can be run again
+var retryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// isRetryable tell if err is a transient error of the database or of its connection
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return retryableCodes[pqErr.Code] || pqErr.Code.Class() == "08"
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// withRetry run fn up to config.RetryAttempts times while it fails with the transient
+// errors of isRetryable, the wait between the attempts start at config.RetryBackoffMS
+// and is doubled after each attempt. Nothing is retried once ctx is done. The tables
+// cached by TableExists are evicted when fn fails on an undefined table
+func withRetry(ctx context.Context, fn func() error) (err error) {
+	backoff := time.Duration(config.PrestConf.RetryBackoffMS) * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		evictExistingTables(err)
+		if attempt >= config.PrestConf.RetryAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return
+		}
+		logger(ctx).Printf("{retry} attempt %d of %d failed, retrying in %v: %v\n", attempt, config.PrestConf.RetryAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// inTransaction run fn in a transaction of the isolation level, empty is the default
+// level, holding the advisory lock of lock, nil is no lock. The transaction is
+// committed when fn succeed and rolled back otherwise, it's run again by withRetry on
+// transient errors. A lost connection while committing isn't retried, the transaction
+// may have been committed. The statements of the transaction are cancelled and the
+// transaction is rolled back when ctx is done, e.g. when the client disconnects
+func inTransaction(ctx context.Context, lock *int64, isolation string, fn func(tx *sql.Tx) error) error {
+	return withRetry(ctx, func() error {
+		return transaction(ctx, lock, isolation, fn)
+	})
+}
+
+// transaction is a single attempt of inTransaction, for the fn that can't be run again
+// e.g. the ones reading a stream
+func transaction(ctx context.Context, lock *int64, isolation string, fn func(tx *sql.Tx) error) (err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger(ctx).Printf("could not begin transaction: %v\n", err)
+		return
+	}
+
+	if err = setIsolation(ctx, tx, isolation); err == nil {
+		if err = advisoryLock(ctx, tx, lock); err == nil {
+			err = fn(tx)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			err = fmt.Errorf("could not commit transaction: %v", err)
+		}
+	}
+	return
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/config"
+)
+
+func TestIsRetryable(t *testing.T) {
+	var testCases = []struct {
+		description string
+		err         error
+		expected    bool
+	}{
+		{"no error", nil, false},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"syntax error", &pq.Error{Code: "42601"}, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"connection reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"wrapped bad connection", fmt.Errorf("could not query: %w", driver.ErrBadConn), true},
+		{"lost connection while committing", fmt.Errorf("could not commit transaction: %v", driver.ErrBadConn), false},
+		{"other error", errors.New("unable to find table name"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if retryable := isRetryable(tc.err); retryable != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, retryable)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	attempts, backoff := config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS
+	defer func() {
+		config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS = attempts, backoff
+	}()
+	config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS = 3, 1
+
+	var testCases = []struct {
+		description string
+		errs        []error
+		attempts    int
+		err         bool
+	}{
+		{"success", []error{nil}, 1, false},
+		{"transient error then success", []error{&pq.Error{Code: "40001"}, nil}, 2, false},
+		{"transient errors up to the max attempts", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, &pq.Error{Code: "40001"}, nil}, 3, true},
+		{"non retryable error", []error{&pq.Error{Code: "23505"}, nil}, 1, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		calls := 0
+		err := withRetry(context.Background(), func() error {
+			err := tc.errs[calls]
+			calls++
+			return err
+		})
+		if calls != tc.attempts {
+			t.Errorf("expected %d attempts, got %d", tc.attempts, calls)
+		}
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestWithRetryDisabled(t *testing.T) {
+	attempts := config.PrestConf.RetryAttempts
+	defer func() { config.PrestConf.RetryAttempts = attempts }()
+	config.PrestConf.RetryAttempts = 1
+
+	calls := 0
+	err := withRetry(context.Background(), func() error {
+		calls++
+		return &pq.Error{Code: "40001"}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("expected a single failed attempt, got %d attempts and %v", calls, err)
+	}
+}
+
+func TestWithRetryCancelled(t *testing.T) {
+	attempts, backoff := config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS
+	defer func() {
+		config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS = attempts, backoff
+	}()
+	config.PrestConf.RetryAttempts, config.PrestConf.RetryBackoffMS = 3, 1000
+
+	// the client went away during the first attempt, nothing is retried
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := withRetry(ctx, func() error {
+		calls++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("expected a single failed attempt, got %d attempts and %v", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected no backoff after the cancellation, waited %v", elapsed)
+	}
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nuveo/prest/config"
+)
+
+// RowCap is the maximum of rows returned by the selects of schema.table without page
+// size, the cap of the table in table_row_cap or the default one, 0 is no cap
+func RowCap(schema, table string) int {
+	name := fmt.Sprintf("%s.%s", schema, table)
+	for _, rowCap := range config.PrestConf.TableRowCaps {
+		if rowCap.Name == name {
+			return rowCap.Cap
+		}
+	}
+	return config.PrestConf.TableRowCap
+}
+
+// CapRows keep the first rowCap rows of a JSON array, truncated tell if rows were removed
+func CapRows(jsonData []byte, rowCap int) (capped []byte, truncated bool, err error) {
+	var rows []json.RawMessage
+	if err = json.Unmarshal(jsonData, &rows); err != nil {
+		return
+	}
+	if len(rows) <= rowCap {
+		capped = jsonData
+		return
+	}
+	truncated = true
+	capped, err = json.Marshal(rows[:rowCap])
+	return
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestRowCap(t *testing.T) {
+	config.PrestConf.TableRowCap = 1000
+	config.PrestConf.TableRowCaps = []config.RowCapConf{{Name: "public.logs", Cap: 10}, {Name: "public.small", Cap: 0}}
+	defer func() {
+		config.PrestConf.TableRowCap = 0
+		config.PrestConf.TableRowCaps = nil
+	}()
+
+	var testCases = []struct {
+		description string
+		schema      string
+		table       string
+		expected    int
+	}{
+		{"cap of the table", "public", "logs", 10},
+		{"table without cap", "public", "small", 0},
+		{"default cap", "public", "test", 1000},
+		{"table of other schema", "other", "logs", 1000},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if rowCap := RowCap(tc.schema, tc.table); rowCap != tc.expected {
+			t.Errorf("expected %d, got %d", tc.expected, rowCap)
+		}
+	}
+}
+
+func TestCapRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		rows        string
+		expected    string
+		truncated   bool
+	}{
+		{"rows under the cap", `[{"id":1}]`, `[{"id":1}]`, false},
+		{"rows in the cap", "[{\"id\":1}, \n {\"id\":2}]", "[{\"id\":1}, \n {\"id\":2}]", false},
+		{"rows over the cap", "[{\"id\":1}, \n {\"id\":2}, \n {\"id\":3}]", `[{"id":1},{"id":2}]`, true},
+		{"no rows", `[]`, `[]`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		capped, truncated, err := CapRows([]byte(tc.rows), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(capped) != tc.expected || truncated != tc.truncated {
+			t.Errorf("expected %q (truncated %v), got %q (truncated %v)", tc.expected, tc.truncated, string(capped), truncated)
+		}
+	}
+
+	t.Log("invalid rows")
+	if _, _, err := CapRows([]byte(`{"id":1}`), 2); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
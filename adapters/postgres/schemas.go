@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrSchemaAlreadyExists err throw when creating a schema with a name in use
+var ErrSchemaAlreadyExists = errors.New("schema already exists")
+
+// ErrSchemaNotFound err throw when dropping a schema that does not exist
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// ErrDatabaseNotConnected err throw when the database is not the one pREST is connected to
+var ErrDatabaseNotConnected = errors.New("database is not the connected database")
+
+// postgres error codes, see https://www.postgresql.org/docs/current/static/errcodes-appendix.html
+const (
+	duplicateColumn   = "42701"
+	duplicateSchema   = "42P06"
+	duplicateTable    = "42P07"
+	invalidSchemaName = "3F000"
+	undefinedTable    = "42P01"
+)
+
+// CreateSchema create a schema in the connected database
+func CreateSchema(ctx context.Context, database, schema string) (jsonData []byte, err error) {
+	sql, err := schemaStatement(database, schema, statements.CreateSchema)
+	if err != nil {
+		return
+	}
+	return execSchemaStatement(ctx, sql, schema)
+}
+
+// DropSchema drop a schema of the connected database, cascade drop the objects in it
+func DropSchema(ctx context.Context, database, schema string, cascade bool) (jsonData []byte, err error) {
+	var cascadeSQL string
+	if cascade {
+		cascadeSQL = " CASCADE"
+	}
+	sql, err := schemaStatement(database, schema, statements.DropSchema, cascadeSQL)
+	if err != nil {
+		return
+	}
+	return execSchemaStatement(ctx, sql, schema)
+}
+
+// schemaStatement validate the names and format the DDL statement
+func schemaStatement(database, schema, statement string, args ...interface{}) (sql string, err error) {
+	if invalidNames(database, schema) {
+		err = ErrInvalidIdentifier
+		return
+	}
+	if database != config.PrestConf.PGDatabase {
+		err = ErrDatabaseNotConnected
+		return
+	}
+	sql = fmt.Sprintf(statement, append([]interface{}{QuoteName(schema)}, args...)...)
+	return
+}
+
+func execSchemaStatement(ctx context.Context, sql, schema string) (jsonData []byte, err error) {
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	_, err = db.ExecContext(ctx, sql)
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case duplicateSchema:
+			err = ErrSchemaAlreadyExists
+		case invalidSchemaName:
+			err = ErrSchemaNotFound
+		}
+	}
+	if err != nil {
+		return
+	}
+	ResetStatementCache()
+	resetExistingTables()
+
+	data := make(map[string]interface{})
+	data["schema"] = schema
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// SchemaExists check if the schema exists in the connected database, the query is
+// cancelled when ctx is done
+func SchemaExists(ctx context.Context, schema string) (exists bool, err error) {
+	if invalidNames(schema) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = db.QueryRowContext(ctx, statements.SchemaExists, schema).Scan(&exists)
+	return
+}
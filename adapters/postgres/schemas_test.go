@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchemaStatement(t *testing.T) {
+	var testCases = []struct {
+		description string
+		database    string
+		schema      string
+		statement   string
+		args        []interface{}
+		expectedSQL string
+		err         bool
+	}{
+		{"create schema", "prest", "tenant", "CREATE SCHEMA %s", nil, "CREATE SCHEMA tenant", false},
+		{"drop schema with cascade", "prest", "tenant", "DROP SCHEMA %s%s", []interface{}{" CASCADE"}, "DROP SCHEMA tenant CASCADE", false},
+		{"invalid schema name", "prest", "tenant; DROP SCHEMA public", "CREATE SCHEMA %s", nil, "", true},
+		{"schema name with a comment", "prest", "a--", "CREATE SCHEMA %s", nil, "", true},
+		{"schema name with a dot", "prest", "public.tenant", "CREATE SCHEMA %s", nil, "", true},
+		{"schema name with a quote", "prest", `tenant"`, "DROP SCHEMA %s%s", []interface{}{" CASCADE"}, "", true},
+		{"schema name too long", "prest", strings.Repeat("a", 64), "CREATE SCHEMA %s", nil, "", true},
+		{"invalid database name", "0prest", "tenant", "CREATE SCHEMA %s", nil, "", true},
+		{"other database", "otherdb", "tenant", "CREATE SCHEMA %s", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql, err := schemaStatement(tc.database, tc.schema, tc.statement, tc.args...)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if sql != tc.expectedSQL {
+			t.Errorf("expected %q, got %q", tc.expectedSQL, sql)
+		}
+	}
+}
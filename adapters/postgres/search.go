@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SearchWhereByRequest create the where of the filter tree in the body of _search, e.g.
+// {"and": [{"name": {"$eq": "x"}}, {"or": [{"age": {"$lt": 18}}, {"age": {"$gt": 65}}]}]}.
+// The keys of an object are ANDed, "and" and "or" list the conditions combined in
+// parentheses and "not" negate a condition. The other keys are columns compared with a
+// value ($eq) or the operators of the query string filters. The columns of $fts must be
+// tsvector columns of schema.table
+func SearchWhereByRequest(r *http.Request, schema, table string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	whereSyntax, values, ftsColumns, err := searchWhere(r, initialPlaceholderID)
+	if err != nil {
+		return
+	}
+	if err = checkFTSColumns(schema, table, ftsColumns); err != nil {
+		whereSyntax, values = "", nil
+	}
+	return
+}
+
+// searchWhere compile the filter tree of the body, ftsColumns are the columns of $fts
+func searchWhere(r *http.Request, initialPlaceholderID int) (whereSyntax string, values []interface{}, ftsColumns []string, err error) {
+	filter := make(map[string]interface{})
+	if err = json.NewDecoder(r.Body).Decode(&filter); err != nil && err != io.EOF {
+		return
+	}
+	defer r.Body.Close()
+
+	s := &search{pid: initialPlaceholderID}
+	if whereSyntax, err = s.node(filter); err != nil {
+		return
+	}
+	values, ftsColumns = s.values, s.ftsColumns
+	return
+}
+
+// search compile a filter tree numbering the placeholders of its values
+type search struct {
+	pid        int
+	values     []interface{}
+	ftsColumns []string
+}
+
+func (s *search) node(node map[string]interface{}) (whereSyntax string, err error) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var condition string
+		switch key {
+		case "and", "or":
+			condition, err = s.list(key, node[key])
+		case "not":
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("not must be an object")
+				return
+			}
+			if condition, err = s.node(child); err == nil && condition != "" {
+				condition = fmt.Sprintf("NOT (%s)", condition)
+			}
+		default:
+			condition, err = s.column(key, node[key])
+		}
+		if err != nil {
+			return
+		}
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	whereSyntax = strings.Join(conditions, " AND ")
+	if len(conditions) > 1 {
+		whereSyntax = fmt.Sprintf("(%s)", whereSyntax)
+	}
+	return
+}
+
+func (s *search) list(op string, value interface{}) (whereSyntax string, err error) {
+	children, ok := value.([]interface{})
+	if !ok || len(children) == 0 {
+		err = fmt.Errorf("%s must be a non empty array of conditions", op)
+		return
+	}
+
+	conditions := make([]string, 0, len(children))
+	for _, child := range children {
+		node, ok := child.(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("the conditions of %s must be objects", op)
+			return
+		}
+		var condition string
+		if condition, err = s.node(node); err != nil {
+			return
+		}
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if len(conditions) > 0 {
+		whereSyntax = fmt.Sprintf("(%s)", strings.Join(conditions, fmt.Sprintf(" %s ", strings.ToUpper(op))))
+	}
+	return
+}
+
+// column compare a column with a value, $eq by default, or with the operators of an object
+func (s *search) column(column string, value interface{}) (whereSyntax string, err error) {
+	if !validIdentifier(column) {
+		err = fmt.Errorf("invalid identifier: %s", column)
+		return
+	}
+
+	column = quoteColumn(column)
+	operators, ok := value.(map[string]interface{})
+	if !ok {
+		return s.compare(column, "$eq", value)
+	}
+	if len(operators) == 0 {
+		err = fmt.Errorf("column %s without operators", column)
+		return
+	}
+
+	ops := make([]string, 0, len(operators))
+	for op := range operators {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	conditions := make([]string, 0, len(ops))
+	for _, op := range ops {
+		var condition string
+		if condition, err = s.compare(column, op, operators[op]); err != nil {
+			return
+		}
+		conditions = append(conditions, condition)
+	}
+	whereSyntax = strings.Join(conditions, " AND ")
+	if len(conditions) > 1 {
+		whereSyntax = fmt.Sprintf("(%s)", whereSyntax)
+	}
+	return
+}
+
+func (s *search) compare(column, op string, value interface{}) (whereSyntax string, err error) {
+	operator, err := GetQueryOperator(op)
+	if err != nil {
+		err = fmt.Errorf("invalid operator %s of %s", op, column)
+		return
+	}
+
+	switch operator {
+	case "IS NULL", "IS NOT NULL":
+		if value != true {
+			err = fmt.Errorf("%s of %s must be true", op, column)
+			return
+		}
+		whereSyntax = fmt.Sprintf("%s %s", column, operator)
+	case ftsOperator:
+		text, ok := value.(string)
+		if !ok {
+			err = fmt.Errorf("%s of %s must be a string", op, column)
+			return
+		}
+		var values []interface{}
+		if whereSyntax, values, s.pid, err = ftsCondition(column, text, s.pid); err != nil {
+			return
+		}
+		s.values = append(s.values, values...)
+		s.ftsColumns = append(s.ftsColumns, column)
+	case "IN", "NOT IN":
+		items, ok := value.([]interface{})
+		if !ok || len(items) == 0 {
+			err = fmt.Errorf("%s of %s must be a non empty array", op, column)
+			return
+		}
+		placeholders := make([]string, 0, len(items))
+		for _, item := range items {
+			var placeholder string
+			if placeholder, err = s.placeholder(column, item); err != nil {
+				return
+			}
+			placeholders = append(placeholders, placeholder)
+		}
+		whereSyntax = fmt.Sprintf("%s %s (%s)", column, operator, strings.Join(placeholders, ", "))
+	default:
+		if value == nil && operator == "=" {
+			whereSyntax = fmt.Sprintf("%s IS NULL", column)
+			return
+		}
+		var placeholder string
+		if placeholder, err = s.placeholder(column, value); err != nil {
+			return
+		}
+		whereSyntax = fmt.Sprintf("%s %s %s", column, operator, placeholder)
+	}
+	return
+}
+
+// placeholder bind a scalar value
+func (s *search) placeholder(column string, value interface{}) (placeholder string, err error) {
+	switch value.(type) {
+	case string, float64, bool:
+	default:
+		err = fmt.Errorf("invalid value of %s, use strings, numbers or booleans", column)
+		return
+	}
+	placeholder = fmt.Sprintf("$%d", s.pid)
+	s.values = append(s.values, value)
+	s.pid++
+	return
+}
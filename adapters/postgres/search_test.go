@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchWhereByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		where       string
+		values      []interface{}
+		err         bool
+	}{
+		{"empty filter", `{}`, "", nil, false},
+		{"without body", ``, "", nil, false},
+		{"equal value", `{"name": "prest"}`, "name = $3", []interface{}{"prest"}, false},
+		{"null value", `{"name": null}`, "name IS NULL", nil, false},
+		{"operators of a column", `{"age": {"$gte": 18, "$lt": 65}}`, "(age >= $3 AND age < $4)", []interface{}{float64(18), float64(65)}, false},
+		{"keys of an object are ANDed", `{"name": "prest", "age": {"$gt": 18}}`, "(age > $3 AND name = $4)", []interface{}{float64(18), "prest"}, false},
+		{"and with nested or", `{"and": [{"name": {"$eq": "x"}}, {"or": [{"age": {"$lt": 18}}, {"age": {"$gt": 65}}]}]}`, "(name = $3 AND (age < $4 OR age > $5))", []interface{}{"x", float64(18), float64(65)}, false},
+		{"not", `{"not": {"or": [{"name": "a"}, {"name": "b"}]}}`, "NOT ((name = $3 OR name = $4))", []interface{}{"a", "b"}, false},
+		{"in and nin", `{"id": {"$in": [1, 2]}, "name": {"$nin": ["a"]}}`, "(id IN ($3, $4) AND name NOT IN ($5))", []interface{}{float64(1), float64(2), "a"}, false},
+		{"null operators", `{"or": [{"name": {"$null": true}}, {"age": {"$notnull": true}}]}`, "(name IS NULL OR age IS NOT NULL)", nil, false},
+		{"full text search", `{"search_vector": {"$fts": "postgres database"}}`, "search_vector @@ plainto_tsquery($3)", []interface{}{"postgres database"}, false},
+
+		// errors
+		{"invalid column", `{"0name": "prest"}`, "", nil, true},
+		{"invalid operator", `{"name": {"$like": "prest"}}`, "", nil, true},
+		{"empty or", `{"or": []}`, "", nil, true},
+		{"or of values", `{"or": ["a"]}`, "", nil, true},
+		{"not of a value", `{"not": "a"}`, "", nil, true},
+		{"in without array", `{"id": {"$in": 1}}`, "", nil, true},
+		{"object value", `{"name": {"$eq": {"a": 1}}}`, "", nil, true},
+		{"null operator without true", `{"name": {"$null": false}}`, "", nil, true},
+		{"full text search of a number", `{"search_vector": {"$fts": 1}}`, "", nil, true},
+		{"column without operators", `{"name": {}}`, "", nil, true},
+		{"invalid body", `[]`, "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", "/prest/public/test/_search", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		where, values, _, err := searchWhere(r, 3)
+		if tc.err != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if tc.err {
+			continue
+		}
+		if where != tc.where {
+			t.Errorf("expected %q, got %q", tc.where, where)
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected %v, got %v", tc.values, values)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nuveo/prest/statements"
+)
+
+// preparer is a connection pool or a transaction
+type preparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SearchPathByRequest read `_search_path`, the comma separated schemas searched for the
+// tables that are not qualified by a schema, e.g. in joins
+func SearchPathByRequest(r *http.Request) (searchPath string, err error) {
+	value := r.URL.Query().Get("_search_path")
+	if value == "" {
+		return
+	}
+
+	schemas := strings.Split(value, ",")
+	for _, schema := range schemas {
+		if !plainIdentifierRegex.MatchString(schema) || len(schema) > 63 {
+			err = fmt.Errorf("invalid schema %q in _search_path", schema)
+			return
+		}
+	}
+	searchPath = strings.Join(schemas, ", ")
+	return
+}
+
+// inSearchPath run fn in a transaction with SET LOCAL search_path, so the setting ends
+// with the transaction and never leaks to the other requests using the pooled
+// connection. Without search path fn is run in the pool. The read only fn is run
+// again by withRetry on transient errors
+func inSearchPath(ctx context.Context, db *sqlx.DB, searchPath string, fn func(p preparer) error) error {
+	return withRetry(ctx, func() error {
+		return runInSearchPath(ctx, db, searchPath, fn)
+	})
+}
+
+func runInSearchPath(ctx context.Context, db *sqlx.DB, searchPath string, fn func(p preparer) error) (err error) {
+	if searchPath == "" {
+		return fn(db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	// the queries are read only, nothing to commit
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(statements.SetSearchPath, searchPath)); err != nil {
+		return
+	}
+	return fn(tx)
+}
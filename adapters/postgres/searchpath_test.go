@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSearchPathByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		searchPath  string
+		err         bool
+	}{
+		{"without search path", "/prest/public/test", "", false},
+		{"one schema", "/prest/public/test?_search_path=sales", "sales", false},
+		{"many schemas", "/prest/public/test?_search_path=sales,public", "sales, public", false},
+		{"invalid schema", "/prest/public/test?_search_path=sales%3Bdrop", "", true},
+		{"quoted schema", "/prest/public/test?_search_path=%22Sales%22", "", true},
+		{"empty schema", "/prest/public/test?_search_path=sales,", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		searchPath, err := SearchPathByRequest(r)
+		if (err != nil) != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if searchPath != tc.searchPath {
+			t.Errorf("expected %q, got %q", tc.searchPath, searchPath)
+		}
+	}
+}
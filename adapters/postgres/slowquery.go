@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+)
+
+// redacted replace the parameters of the slow queries with slow_query_redact_params
+const redacted = "[REDACTED]"
+
+// slowQueryLogger write one JSON object per line, without the log prefix, so the
+// lines can be parsed by log aggregators
+var slowQueryLogger = log.New(os.Stderr, "", 0)
+
+// SlowQuery is the JSON logged for the queries slower than slow_query_ms
+type SlowQuery struct {
+	Time       time.Time     `json:"time"`
+	Level      string        `json:"level"`
+	Message    string        `json:"message"`
+	Path       string        `json:"path"`
+	RequestID  string        `json:"request_id,omitempty"`
+	SQL        string        `json:"sql"`
+	Params     []interface{} `json:"params"`
+	DurationMS int64         `json:"duration_ms"`
+}
+
+// timeQuery start the timer of SQL, the returned func log it with logSlowQuery, e.g.
+// defer timeQuery(ctx, SQL, params)()
+func timeQuery(ctx context.Context, SQL string, params []interface{}) func() {
+	start := time.Now()
+	return func() {
+		logSlowQuery(ctx, SQL, params, time.Since(start))
+	}
+}
+
+// logSlowQuery log the query run with the context of a request when duration exceeds
+// slow_query_ms, 0 disable the log
+func logSlowQuery(ctx context.Context, SQL string, params []interface{}, duration time.Duration) {
+	threshold := config.PrestConf.SlowQueryMS
+	if threshold <= 0 || duration < time.Duration(threshold)*time.Millisecond {
+		return
+	}
+
+	entry, err := json.Marshal(slowQuery(ctx, SQL, params, duration))
+	if err != nil {
+		log.Println("{logSlowQuery}", err)
+		return
+	}
+	slowQueryLogger.Println(string(entry))
+}
+
+func slowQuery(ctx context.Context, SQL string, params []interface{}, duration time.Duration) SlowQuery {
+	if params == nil {
+		params = []interface{}{}
+	}
+	if config.PrestConf.SlowQueryRedactParams {
+		redactedParams := make([]interface{}, len(params))
+		for i := range params {
+			redactedParams[i] = redacted
+		}
+		params = redactedParams
+	}
+
+	return SlowQuery{
+		Time:       time.Now().UTC(),
+		Level:      "warning",
+		Message:    "slow query",
+		Path:       helpers.ContextRequestPath(ctx),
+		RequestID:  helpers.ContextRequestID(ctx),
+		SQL:        SQL,
+		Params:     params,
+		DurationMS: int64(duration / time.Millisecond),
+	}
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+)
+
+func TestLogSlowQuery(t *testing.T) {
+	defer func(logger *log.Logger, conf *config.Prest) {
+		slowQueryLogger = logger
+		config.PrestConf = conf
+	}(slowQueryLogger, config.PrestConf)
+
+	var testCases = []struct {
+		description string
+		conf        config.Prest
+		duration    time.Duration
+		logged      bool
+		params      []interface{}
+	}{
+		{"disabled", config.Prest{}, time.Second, false, nil},
+		{"faster than the threshold", config.Prest{SlowQueryMS: 100}, 99 * time.Millisecond, false, nil},
+		{"slower than the threshold", config.Prest{SlowQueryMS: 100}, 150 * time.Millisecond, true, []interface{}{"prest", float64(1)}},
+		{"redacted params", config.Prest{SlowQueryMS: 100, SlowQueryRedactParams: true}, time.Second, true, []interface{}{redacted, redacted}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		var buf bytes.Buffer
+		slowQueryLogger = log.New(&buf, "", 0)
+		conf := tc.conf
+		config.PrestConf = &conf
+
+		r := helpers.WithRequestID(httptest.NewRequest("GET", "/prest/public/test", nil), "request-1")
+		logSlowQuery(r.Context(), "SELECT * FROM prest.public.test WHERE name = $1 AND id = $2", []interface{}{"prest", 1}, tc.duration)
+
+		if !tc.logged {
+			if buf.Len() > 0 {
+				t.Errorf("expected no log, got %s", buf.String())
+			}
+			continue
+		}
+
+		var entry SlowQuery
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+		}
+		if entry.Path != "/prest/public/test" || entry.RequestID != "request-1" || entry.SQL == "" || entry.DurationMS != int64(tc.duration/time.Millisecond) {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+		if !reflect.DeepEqual(entry.Params, tc.params) {
+			t.Errorf("expected params %v, got %v", tc.params, entry.Params)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/config"
+)
+
+// StatementCacheStats are the hits and misses of the cache of prepared statements
+type StatementCacheStats struct {
+	Size    int     `json:"size"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// stmtKey is a statement prepared in a connection pool, the primary or a replica
+type stmtKey struct {
+	db  *sqlx.DB
+	SQL string
+}
+
+// cachedStmt is closed when it's evicted and no request uses it
+type cachedStmt struct {
+	key     stmtKey
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// stmtCache is the LRU cache of the statements prepared in the connection pools
+type stmtCache struct {
+	mu     sync.Mutex
+	items  map[stmtKey]*list.Element
+	lru    *list.List
+	hits   int64
+	misses int64
+}
+
+var statementCache = &stmtCache{items: make(map[stmtKey]*list.Element), lru: list.New()}
+
+// prepare prepare SQL in p, the statements of the connection pools are reused across
+// the requests when statement_cache_size is set, the statements of the transactions
+// are closed by done. done must be called with the error of the statement, the
+// cached statements failed by postgres are evicted, e.g. after a change of their tables
+func prepare(p preparer, SQL string) (stmt *sql.Stmt, done func(err error), err error) {
+	db, ok := p.(*sqlx.DB)
+	size := config.PrestConf.StatementCacheSize
+	if !ok || size <= 0 {
+		if stmt, err = p.Prepare(SQL); err != nil {
+			return
+		}
+		done = func(error) { stmt.Close() }
+		return
+	}
+
+	cached, err := statementCache.get(stmtKey{db: db, SQL: SQL}, size)
+	if err != nil {
+		return
+	}
+	stmt = cached.stmt
+	done = func(err error) { statementCache.release(cached, err) }
+	return
+}
+
+// cacheable tell if the statements of p are cached by prepare
+func cacheable(p preparer) bool {
+	_, ok := p.(*sqlx.DB)
+	return ok && config.PrestConf.StatementCacheSize > 0
+}
+
+// queryRow scan the row of SQL run in p, the cached statement when SQL is cached. SQL
+// is cancelled when ctx is done, e.g. when the client disconnects
+func queryRow(ctx context.Context, p preparer, SQL string, params []interface{}, dest ...interface{}) (err error) {
+	defer timeQuery(ctx, SQL, params)()
+	if !cacheable(p) {
+		return p.QueryRowContext(ctx, SQL, params...).Scan(dest...)
+	}
+	stmt, done, err := prepare(p, SQL)
+	if err != nil {
+		return
+	}
+	err = stmt.QueryRowContext(ctx, params...).Scan(dest...)
+	done(err)
+	return
+}
+
+// queryRows is queryRow of the queries returning rows, done must be called when the
+// rows are read
+func queryRows(ctx context.Context, p preparer, SQL string, params []interface{}) (rows *sql.Rows, done func(err error), err error) {
+	logged := timeQuery(ctx, SQL, params)
+	if !cacheable(p) {
+		if rows, err = p.QueryContext(ctx, SQL, params...); err != nil {
+			logged()
+			return
+		}
+		done = func(error) {
+			rows.Close()
+			logged()
+		}
+		return
+	}
+	stmt, release, err := prepare(p, SQL)
+	if err != nil {
+		return
+	}
+	if rows, err = stmt.QueryContext(ctx, params...); err != nil {
+		release(err)
+		logged()
+		return
+	}
+	done = func(err error) {
+		rows.Close()
+		release(err)
+		logged()
+	}
+	return
+}
+
+func (c *stmtCache) get(key stmtKey, size int) (cached *cachedStmt, err error) {
+	c.mu.Lock()
+	if e, ok := c.items[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(e)
+		cached = e.Value.(*cachedStmt)
+		cached.refs++
+		c.mu.Unlock()
+		return
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// prepared without the lock, the other statements are served meanwhile
+	stmt, err := key.db.Prepare(key.SQL)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		stmt.Close()
+		cached = e.Value.(*cachedStmt)
+		cached.refs++
+		return
+	}
+	cached = &cachedStmt{key: key, stmt: stmt, refs: 1}
+	c.items[key] = c.lru.PushFront(cached)
+	for c.lru.Len() > size {
+		c.evict(c.lru.Back().Value.(*cachedStmt))
+	}
+	return
+}
+
+func (c *stmtCache) release(cached *cachedStmt, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached.refs--
+	if _, ok := err.(*pq.Error); ok && !cached.evicted {
+		c.evict(cached)
+		return
+	}
+	if cached.evicted && cached.refs == 0 {
+		cached.stmt.Close()
+	}
+}
+
+// evict remove cached from the cache, it's closed when no request uses it
+func (c *stmtCache) evict(cached *cachedStmt) {
+	if e, ok := c.items[cached.key]; ok {
+		c.lru.Remove(e)
+		delete(c.items, cached.key)
+	}
+	cached.evicted = true
+	if cached.refs == 0 {
+		cached.stmt.Close()
+	}
+}
+
+// ResetStatementCache evict all the cached statements, they are prepared again by
+// the next requests. It's called by the endpoints changing the schema
+func ResetStatementCache() {
+	c := statementCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back().Value.(*cachedStmt))
+	}
+}
+
+// StatementCache return the size, hits and misses of the cache of prepared statements
+func StatementCache() (stats StatementCacheStats) {
+	c := statementCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats = StatementCacheStats{Size: c.lru.Len(), Hits: c.hits, Misses: c.misses}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return
+}
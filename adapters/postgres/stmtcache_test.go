@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"container/list"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/config"
+)
+
+// countingDriver count the statements prepared and closed, the statements can't run
+type countingDriver struct {
+	prepared, closed int64
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	return &countingConn{d: d}, nil
+}
+
+type countingConn struct {
+	d *countingDriver
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddInt64(&c.d.prepared, 1)
+	return &countingStmt{d: c.d}, nil
+}
+
+func (c *countingConn) Close() error { return nil }
+
+func (c *countingConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type countingStmt struct {
+	d *countingDriver
+}
+
+func (s *countingStmt) Close() error {
+	atomic.AddInt64(&s.d.closed, 1)
+	return nil
+}
+
+func (s *countingStmt) NumInput() int { return -1 }
+
+func (s *countingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *countingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var testDriver = &countingDriver{}
+
+func init() {
+	sql.Register("stmtcache_test", testDriver)
+}
+
+func TestPrepareStatementCache(t *testing.T) {
+	defer func(conf *config.Prest, cache *stmtCache) {
+		config.PrestConf = conf
+		statementCache = cache
+	}(config.PrestConf, statementCache)
+
+	db, err := sqlx.Open("stmtcache_test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	config.PrestConf = &config.Prest{StatementCacheSize: 2}
+	statementCache = &stmtCache{items: make(map[stmtKey]*list.Element), lru: list.New()}
+	prepared, closed := testDriver.prepared, testDriver.closed
+
+	t.Log("the statements are prepared once")
+	for _, SQL := range []string{"SELECT 1", "SELECT 1", "SELECT 2", "SELECT 1"} {
+		_, done, err := prepare(db, SQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(nil)
+	}
+	if n := testDriver.prepared - prepared; n != 2 {
+		t.Errorf("expected 2 statements prepared, got %d", n)
+	}
+	stats := StatementCache()
+	if stats.Size != 2 || stats.Hits != 2 || stats.Misses != 2 || stats.HitRate != 0.5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	t.Log("the least recently used statement is evicted")
+	_, done, err := prepare(db, "SELECT 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(nil)
+	if n := testDriver.closed - closed; n != 1 {
+		t.Errorf("expected 1 statement closed, got %d", n)
+	}
+	if _, ok := statementCache.items[stmtKey{db: db, SQL: "SELECT 2"}]; ok {
+		t.Errorf("expected SELECT 2 evicted")
+	}
+
+	t.Log("the statements in use are closed when they are released")
+	_, done, err = prepare(db, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ResetStatementCache()
+	if n := testDriver.closed - closed; n != 2 {
+		t.Errorf("expected SELECT 3 closed and SELECT 1 open, got %d closed", n)
+	}
+	done(nil)
+	if n := testDriver.closed - closed; n != 3 {
+		t.Errorf("expected 3 statements closed, got %d", n)
+	}
+	if stats := StatementCache(); stats.Size != 0 {
+		t.Errorf("expected an empty cache, got %+v", stats)
+	}
+
+	t.Log("the statements failed by postgres are evicted")
+	_, done, err = prepare(db, "SELECT 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(&pq.Error{Code: "0A000", Message: "cached plan must not change result type"})
+	if stats := StatementCache(); stats.Size != 0 {
+		t.Errorf("expected SELECT 4 evicted, got %+v", stats)
+	}
+}
+
+func TestPrepareWithoutStatementCache(t *testing.T) {
+	defer func(conf *config.Prest) {
+		config.PrestConf = conf
+	}(config.PrestConf)
+
+	db, err := sqlx.Open("stmtcache_test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	config.PrestConf = &config.Prest{}
+	prepared, closed := testDriver.prepared, testDriver.closed
+
+	for i := 0; i < 2; i++ {
+		_, done, err := prepare(db, "SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(nil)
+	}
+	if n := testDriver.prepared - prepared; n != 2 {
+		t.Errorf("expected 2 statements prepared, got %d", n)
+	}
+	if n := testDriver.closed - closed; n != 2 {
+		t.Errorf("expected 2 statements closed, got %d", n)
+	}
+}
@@ -0,0 +1,405 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrTableAlreadyExists err throw when creating a table with a name in use
+var ErrTableAlreadyExists = errors.New("table already exists")
+
+// ErrTableWithoutColumns err throw when creating a table without columns
+var ErrTableWithoutColumns = errors.New("table without columns")
+
+// ErrColumnAlreadyExists err throw when adding a column with a name in use
+var ErrColumnAlreadyExists = errors.New("column already exists")
+
+// ErrInvalidColumnType err throw when a column type is not in the allowed types
+var ErrInvalidColumnType = errors.New("invalid column type")
+
+// ErrTableNotFound err throw when the table of the request doesn't exist
+var ErrTableNotFound = errors.New("table not found")
+
+// existingTables cache the tables found by TableExists, only found tables are cached
+// so tables created later are seen without invalidation. It's cleared by the endpoints
+// changing the schema and when a query fails on a table that doesn't exist, e.g. one
+// dropped outside pREST
+var existingTables = struct {
+	sync.RWMutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+// columnTypes allowed in CREATE TABLE
+var columnTypes = map[string]bool{
+	"smallint":                    true,
+	"integer":                     true,
+	"int":                         true,
+	"bigint":                      true,
+	"serial":                      true,
+	"bigserial":                   true,
+	"numeric":                     true,
+	"decimal":                     true,
+	"real":                        true,
+	"double precision":            true,
+	"boolean":                     true,
+	"text":                        true,
+	"varchar":                     true,
+	"character varying":           true,
+	"char":                        true,
+	"character":                   true,
+	"date":                        true,
+	"time":                        true,
+	"timestamp":                   true,
+	"timestamptz":                 true,
+	"timestamp with time zone":    true,
+	"timestamp without time zone": true,
+	"interval":                    true,
+	"uuid":                        true,
+	"json":                        true,
+	"jsonb":                       true,
+	"bytea":                       true,
+	"inet":                        true,
+}
+
+// columnTypeRegex split a type in name, optional modifiers, e.g. varchar(255), and array suffix
+var columnTypeRegex = regexp.MustCompile(`^([a-z ]+?)\s*(\(\s*\d+\s*(,\s*\d+\s*)?\))?(\[\])?$`)
+
+// ColumnSpec definition of a column to create
+type ColumnSpec struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Nullable   *bool       `json:"nullable"`
+	Default    interface{} `json:"default"`
+	PrimaryKey bool        `json:"primary_key"`
+}
+
+// TableSpec definition of a table to create
+type TableSpec struct {
+	Name    string       `json:"name"`
+	Columns []ColumnSpec `json:"columns"`
+}
+
+// PrimaryKey return the primary key columns of a table as a JSON array, empty when it has
+// no primary key, the query is cancelled when ctx is done
+func PrimaryKey(ctx context.Context, database, schema, table string) (jsonData []byte, err error) {
+	if invalidNames(database, schema, table) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = db.QueryRowContext(ctx, statements.PrimaryKey, schema, table).Scan(&jsonData)
+	return
+}
+
+// Columns list the name and type of the columns of a table, with comments the
+// COMMENT ON COLUMN of each column is returned too. The query is cancelled when ctx is done
+func Columns(ctx context.Context, database, schema, table string, comments bool) (jsonData []byte, err error) {
+	if invalidNames(database, schema, table) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	var comment string
+	if comments {
+		comment = statements.ColumnsComment
+	}
+	err = db.QueryRowContext(ctx, fmt.Sprintf(statements.Columns, comment), schema, table).Scan(&jsonData)
+	return
+}
+
+// PrimaryKeyColumns list the primary key columns of a table in the key order, it's
+// empty for tables without primary key
+func PrimaryKeyColumns(ctx context.Context, database, schema, table string) (columns []string, err error) {
+	jsonData, err := PrimaryKey(ctx, database, schema, table)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(jsonData, &columns)
+	return
+}
+
+// CreateTable create a table in the schema from the spec
+func CreateTable(ctx context.Context, database, schema string, spec TableSpec) (jsonData []byte, err error) {
+	sql, err := createTableSQL(database, schema, spec)
+	if err != nil {
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	_, err = db.ExecContext(ctx, sql)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == duplicateTable {
+		err = ErrTableAlreadyExists
+	}
+	if err != nil {
+		return
+	}
+	ResetStatementCache()
+	resetExistingTables()
+
+	data := make(map[string]interface{})
+	data["table"] = fmt.Sprintf("%s.%s", schema, spec.Name)
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// TruncateTable remove all the rows of a table, restartIdentity reset the sequences
+// of its columns and cascade truncate the tables referencing it too
+func TruncateTable(ctx context.Context, database, schema, table string, restartIdentity, cascade bool) (jsonData []byte, err error) {
+	if invalidNames(database, schema, table) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	var restartIdentitySQL, cascadeSQL string
+	if restartIdentity {
+		restartIdentitySQL = " RESTART IDENTITY"
+	}
+	if cascade {
+		cascadeSQL = " CASCADE"
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(statements.TruncateTable, QuoteName(database), QuoteName(schema), QuoteName(table), restartIdentitySQL, cascadeSQL))
+	if err != nil {
+		return
+	}
+
+	data := make(map[string]interface{})
+	data["table"] = fmt.Sprintf("%s.%s", schema, table)
+	data["restart_identity"] = restartIdentity
+	data["cascade"] = cascade
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// TableExists check in information_schema if the table (or view) exists, the tables
+// found are cached to avoid a round trip per request. The query is cancelled when ctx is done
+func TableExists(ctx context.Context, database, schema, table string) (exists bool, err error) {
+	if invalidNames(database, schema, table) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", database, schema, table)
+	existingTables.RLock()
+	exists = existingTables.names[name]
+	existingTables.RUnlock()
+	if exists {
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	err = db.QueryRowContext(ctx, statements.TableExists, database, schema, table).Scan(&exists)
+	if err != nil || !exists {
+		return
+	}
+
+	existingTables.Lock()
+	existingTables.names[name] = true
+	existingTables.Unlock()
+	return
+}
+
+// resetExistingTables clear the cache of TableExists, e.g. after dropping a schema
+func resetExistingTables() {
+	existingTables.Lock()
+	existingTables.names = make(map[string]bool)
+	existingTables.Unlock()
+}
+
+// evictExistingTables clear the cache of TableExists when err is an undefined table, the
+// failed query doesn't tell which of its tables is missing
+func evictExistingTables(err error) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		resetExistingTables()
+	}
+}
+
+// createTableSQL build the CREATE TABLE statement, identifiers and types are validated
+// and defaults are written as quoted literals
+func createTableSQL(database, schema string, spec TableSpec) (sql string, err error) {
+	if invalidNames(database, schema, spec.Name) {
+		err = ErrInvalidIdentifier
+		return
+	}
+	if len(spec.Columns) == 0 {
+		err = ErrTableWithoutColumns
+		return
+	}
+
+	columns := make([]string, 0, len(spec.Columns))
+	primaryKey := make([]string, 0)
+	for _, column := range spec.Columns {
+		var definition string
+		definition, err = columnDefinition(column)
+		if err != nil {
+			return
+		}
+		columns = append(columns, definition)
+
+		if column.PrimaryKey {
+			primaryKey = append(primaryKey, QuoteName(column.Name))
+		}
+	}
+
+	if len(primaryKey) > 0 {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+	}
+
+	sql = fmt.Sprintf(statements.CreateTable, QuoteName(database), QuoteName(schema), QuoteName(spec.Name), strings.Join(columns, ", "))
+	return
+}
+
+// AddColumn add a column to a table from the spec
+func AddColumn(ctx context.Context, database, schema, table string, column ColumnSpec) (jsonData []byte, err error) {
+	sql, err := addColumnSQL(database, schema, table, column)
+	if err != nil {
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		logger(ctx).Println(err)
+		return
+	}
+
+	_, err = db.ExecContext(ctx, sql)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == duplicateColumn {
+		err = fmt.Errorf("%w: %s", ErrColumnAlreadyExists, column.Name)
+	}
+	if err != nil {
+		return
+	}
+	ResetStatementCache()
+	resetExistingTables()
+
+	data := make(map[string]interface{})
+	data["table"] = fmt.Sprintf("%s.%s", schema, table)
+	data["column"] = column.Name
+	jsonData, err = json.Marshal(data)
+	return
+}
+
+// addColumnSQL build the ALTER TABLE ADD COLUMN statement
+func addColumnSQL(database, schema, table string, column ColumnSpec) (sql string, err error) {
+	if invalidNames(database, schema, table) {
+		err = ErrInvalidIdentifier
+		return
+	}
+
+	definition, err := columnDefinition(column)
+	if err != nil {
+		return
+	}
+	if column.PrimaryKey {
+		definition = fmt.Sprint(definition, " PRIMARY KEY")
+	}
+
+	sql = fmt.Sprintf(statements.AddColumn, QuoteName(database), QuoteName(schema), QuoteName(table), definition)
+	return
+}
+
+// columnDefinition write the name, type, nullability and default of a column
+func columnDefinition(column ColumnSpec) (definition string, err error) {
+	if invalidNames(column.Name) {
+		err = fmt.Errorf("%w: %s", ErrInvalidIdentifier, column.Name)
+		return
+	}
+
+	columnType, err := normalizeColumnType(column.Type)
+	if err != nil {
+		return
+	}
+
+	definition = fmt.Sprintf("%s %s", QuoteName(column.Name), columnType)
+	if column.Nullable != nil && !*column.Nullable {
+		definition = fmt.Sprint(definition, " NOT NULL")
+	}
+	if column.Default != nil {
+		var literal string
+		literal, err = defaultLiteral(column.Default)
+		if err != nil {
+			return
+		}
+		definition = fmt.Sprint(definition, " DEFAULT ", literal)
+	}
+	return
+}
+
+// normalizeColumnType check the type against the allowed types
+func normalizeColumnType(columnType string) (normalized string, err error) {
+	normalized = strings.ToLower(strings.TrimSpace(columnType))
+	match := columnTypeRegex.FindStringSubmatch(normalized)
+	if match == nil || !columnTypes[match[1]] {
+		err = fmt.Errorf("%w: %s", ErrInvalidColumnType, columnType)
+		return
+	}
+	return
+}
+
+// defaultLiteral write a JSON value as a SQL literal
+func defaultLiteral(value interface{}) (literal string, err error) {
+	switch v := value.(type) {
+	case bool:
+		literal = strings.ToUpper(strconv.FormatBool(v))
+	case float64:
+		literal = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		literal = quoteLiteral(v)
+	default:
+		var b []byte
+		b, err = json.Marshal(v)
+		if err != nil {
+			return
+		}
+		literal = quoteLiteral(string(b))
+	}
+	return
+}
+
+// quoteLiteral quote a string to be used as a SQL literal
+func quoteLiteral(literal string) string {
+	literal = strings.Replace(literal, `'`, `''`, -1)
+	if strings.Contains(literal, `\`) {
+		return fmt.Sprintf(`E'%s'`, strings.Replace(literal, `\`, `\\`, -1))
+	}
+	return fmt.Sprintf(`'%s'`, literal)
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	notNull := false
+	var testCases = []struct {
+		description string
+		spec        TableSpec
+		expectedSQL string
+		err         string
+	}{
+		{"create table", TableSpec{Name: "tenant", Columns: []ColumnSpec{
+			{Name: "id", Type: "serial", PrimaryKey: true},
+			{Name: "name", Type: "VARCHAR(255)", Nullable: &notNull},
+			{Name: "price", Type: "numeric(10, 2)", Default: float64(1.5)},
+			{Name: "active", Type: "boolean", Default: true},
+			{Name: "tags", Type: "text[]"},
+			{Name: "created", Type: "timestamp with time zone"},
+		}}, "CREATE TABLE prest.public.tenant (id serial, name varchar(255) NOT NULL, price numeric(10, 2) DEFAULT 1.5, active boolean DEFAULT TRUE, tags text[], created timestamp with time zone, PRIMARY KEY (id))", ""},
+		{"create table with composite primary key", TableSpec{Name: "tenant", Columns: []ColumnSpec{
+			{Name: "a", Type: "integer", PrimaryKey: true},
+			{Name: "b", Type: "integer", PrimaryKey: true},
+		}}, "CREATE TABLE prest.public.tenant (a integer, b integer, PRIMARY KEY (a, b))", ""},
+		{"quote string defaults", TableSpec{Name: "tenant", Columns: []ColumnSpec{
+			{Name: "name", Type: "text", Default: "o'neil"},
+			{Name: "path", Type: "text", Default: `c:\tmp`},
+			{Name: "data", Type: "jsonb", Default: map[string]interface{}{"a": 1}},
+		}}, `CREATE TABLE prest.public.tenant (name text DEFAULT 'o''neil', path text DEFAULT E'c:\\tmp', data jsonb DEFAULT '{"a":1}')`, ""},
+		{"unknown type", TableSpec{Name: "tenant", Columns: []ColumnSpec{{Name: "id", Type: "integer; DROP TABLE test"}}}, "", "invalid column type"},
+		{"type not allowed", TableSpec{Name: "tenant", Columns: []ColumnSpec{{Name: "id", Type: "regclass"}}}, "", "invalid column type"},
+		{"invalid column name", TableSpec{Name: "tenant", Columns: []ColumnSpec{{Name: "0id", Type: "integer"}}}, "", "Invalid identifier: 0id"},
+		{"invalid table name", TableSpec{Name: "0tenant", Columns: []ColumnSpec{{Name: "id", Type: "integer"}}}, "", "Invalid identifier"},
+		{"without columns", TableSpec{Name: "tenant"}, "", "table without columns"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql, err := createTableSQL("prest", "public", tc.spec)
+		if tc.err == "" && err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected error %q, got %v", tc.err, err)
+		}
+		if sql = strings.TrimSpace(sql); sql != tc.expectedSQL {
+			t.Errorf("expected %q, got %q", tc.expectedSQL, sql)
+		}
+	}
+}
+
+func TestAddColumnSQL(t *testing.T) {
+	notNull := false
+	var testCases = []struct {
+		description string
+		table       string
+		column      ColumnSpec
+		expectedSQL string
+		err         string
+	}{
+		{"add column", "tenant", ColumnSpec{Name: "age", Type: "integer"}, "ALTER TABLE prest.public.tenant ADD COLUMN age integer", ""},
+		{"add column not null with default", "tenant", ColumnSpec{Name: "name", Type: "text", Nullable: &notNull, Default: "prest"}, "ALTER TABLE prest.public.tenant ADD COLUMN name text NOT NULL DEFAULT 'prest'", ""},
+		{"add primary key column", "tenant", ColumnSpec{Name: "id", Type: "bigserial", PrimaryKey: true}, "ALTER TABLE prest.public.tenant ADD COLUMN id bigserial PRIMARY KEY", ""},
+		{"unknown type", "tenant", ColumnSpec{Name: "age", Type: "integer DEFAULT 1"}, "", "invalid column type"},
+		{"invalid column name", "tenant", ColumnSpec{Name: "age;", Type: "integer"}, "", "Invalid identifier: age;"},
+		{"invalid table name", "0tenant", ColumnSpec{Name: "age", Type: "integer"}, "", "Invalid identifier"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		sql, err := addColumnSQL("prest", "public", tc.table, tc.column)
+		if tc.err == "" && err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("expected error %q, got %v", tc.err, err)
+		}
+		if sql = strings.TrimSpace(sql); sql != tc.expectedSQL {
+			t.Errorf("expected %q, got %q", tc.expectedSQL, sql)
+		}
+	}
+}
+
+func TestTableExists(t *testing.T) {
+	var testCases = []struct {
+		description string
+		database    string
+		schema      string
+		table       string
+		exists      bool
+		err         bool
+	}{
+		{"table", "prest", "public", "test", true, false},
+		{"view", "prest", "public", "view_test", true, false},
+		{"nonexistent table", "prest", "public", "test_nonexistent", false, false},
+		{"table in other schema", "prest", "information_schema", "test", false, false},
+		{"invalid table", "prest", "public", "0test", false, true},
+	}
+
+	resetExistingTables()
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		exists, err := TableExists(context.Background(), tc.database, tc.schema, tc.table)
+		if tc.err != (err != nil) {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if exists != tc.exists {
+			t.Errorf("expected exists %v, got %v", tc.exists, exists)
+		}
+		if cached := existingTables.names["prest.public."+tc.table]; tc.schema == "public" && cached != tc.exists {
+			t.Errorf("expected cached %v, got %v", tc.exists, cached)
+		}
+	}
+}
+
+func TestEvictExistingTables(t *testing.T) {
+	var testCases = []struct {
+		description string
+		err         error
+		evicted     bool
+	}{
+		{"no error", nil, false},
+		{"other error", &pq.Error{Code: "23505"}, false},
+		{"undefined table", &pq.Error{Code: undefinedTable}, true},
+		{"wrapped undefined table", fmt.Errorf("query: %w", &pq.Error{Code: undefinedTable}), true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		existingTables.Lock()
+		existingTables.names = map[string]bool{"prest.public.test": true}
+		existingTables.Unlock()
+		withRetry(context.Background(), func() error { return tc.err })
+		if cached := existingTables.names["prest.public.test"]; cached == tc.evicted {
+			t.Errorf("expected evicted %v, got cached %v", tc.evicted, cached)
+		}
+	}
+	resetExistingTables()
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/statements"
+)
+
+// ErrInvalidTimeZone err throw when _tz is not a time zone name, e.g. America/Sao_Paulo
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+// timeZoneRegex match time zone names, e.g. America/Sao_Paulo or UTC
+var timeZoneRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+\-]*(/[A-Za-z0-9_+\-]+)*$`)
+
+// TimeZone of the timestamps compared with the timestamptz columns of a table
+type TimeZone struct {
+	Name    string
+	columns map[string]bool
+}
+
+// TimeZoneByRequest read `_tz`, the literals compared with the timestamptz columns of
+// the table are interpreted in that time zone, nil is returned without `_tz`
+func TimeZoneByRequest(r *http.Request, database, schema, table string) (tz *TimeZone, err error) {
+	name := r.URL.Query().Get("_tz")
+	if name == "" {
+		return
+	}
+	if !timeZoneRegex.MatchString(name) {
+		err = ErrInvalidTimeZone
+		return
+	}
+
+	db, err := connection.Get()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var columns []string
+	err = db.Select(&columns, statements.TimestamptzColumns, database, schema, table)
+	if err != nil {
+		return
+	}
+
+	tz = &TimeZone{Name: name, columns: make(map[string]bool, len(columns))}
+	for _, column := range columns {
+		tz.columns[column] = true
+	}
+	return
+}
+
+// placeholder of the value compared with key, the timestamptz columns are compared
+// with the value as a timestamp AT TIME ZONE
+func (tz *TimeZone) placeholder(key, op string, pid int) string {
+	if tz == nil || !tz.columns[strings.Trim(key, `"`)] {
+		return fmt.Sprintf("$%d", pid)
+	}
+	switch op {
+	case "=", "!=", ">", ">=", "<", "<=":
+		return fmt.Sprintf("($%d::timestamp AT TIME ZONE '%s')", pid, tz.Name)
+	}
+	return fmt.Sprintf("$%d", pid)
+}
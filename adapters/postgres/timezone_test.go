@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWhereByRequestInTimeZone(t *testing.T) {
+	tz := &TimeZone{Name: "America/Sao_Paulo", columns: map[string]bool{"created_at": true}}
+
+	var testCases = []struct {
+		description string
+		url         string
+		tz          *TimeZone
+		expected    string
+	}{
+		{"timestamptz column", "/prest/public/test?created_at=$gt.2023-01-01", tz, "created_at > ($1::timestamp AT TIME ZONE 'America/Sao_Paulo')"},
+		{"other column", "/prest/public/test?name=$eq.prest", tz, "name = $1"},
+		{"operator without value", "/prest/public/test?created_at=$null", tz, "created_at IS NULL"},
+		{"without time zone", "/prest/public/test?created_at=$gt.2023-01-01", nil, "created_at > $1"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		where, _, err := WhereByRequestInTimeZone(r, 1, tc.tz)
+		if err != nil {
+			t.Errorf("expected no errors, got %v", err)
+		}
+		if where != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, where)
+		}
+	}
+}
+
+func TestTimeZoneByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		columns     map[string]bool
+		err         error
+	}{
+		{"without time zone", "/prest/public/test_timestamps", nil, nil},
+		{"invalid time zone", "/prest/public/test_timestamps?_tz=UTC';DROP", nil, ErrInvalidTimeZone},
+		{"time zone", "/prest/public/test_timestamps?_tz=America/Sao_Paulo", map[string]bool{"created_at": true}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tz, err := TimeZoneByRequest(r, "prest", "public", "test_timestamps")
+		if err != tc.err {
+			t.Errorf("expected error %v, got %v", tc.err, err)
+		}
+		if tc.columns == nil && tz != nil {
+			t.Errorf("expected no time zone, got %+v", tz)
+		}
+		if tc.columns != nil && (tz == nil || len(tz.columns) != len(tc.columns) || !tz.columns["created_at"]) {
+			t.Errorf("expected columns %v, got %+v", tc.columns, tz)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nuveo/prest/config"
+)
+
+// UUIDColumnsByRequest fill the uuid columns configured for the table (uuid.tables)
+// that are missing in the insert body with random (v4) UUIDs, the body is rewritten
+func UUIDColumnsByRequest(r *http.Request, schema, table string) (err error) {
+	columns := uuidColumns(fmt.Sprintf("%s.%s", schema, table))
+	if len(columns) == 0 {
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	body := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&body); err != nil {
+		// invalid bodies are reported by the insert
+		err = nil
+		return
+	}
+
+	for _, column := range columns {
+		if _, ok := body[column]; ok {
+			continue
+		}
+		if body[column], err = newUUID(); err != nil {
+			return
+		}
+	}
+
+	if data, err = json.Marshal(body); err != nil {
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return
+}
+
+func uuidColumns(name string) (columns []string) {
+	for _, conf := range config.PrestConf.UUIDColumns {
+		if conf.Name == name {
+			columns = append(columns, conf.Column)
+		}
+	}
+	return
+}
+
+// newUUID generate a random UUID, version 4 variant RFC 4122
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
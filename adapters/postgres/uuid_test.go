@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+)
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUID(t *testing.T) {
+	first, err := newUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uuidRegex.MatchString(first) || !uuidRegex.MatchString(second) {
+		t.Errorf("expected v4 UUIDs, got %s and %s", first, second)
+	}
+	if first == second {
+		t.Errorf("expected different UUIDs, got %s twice", first)
+	}
+}
+
+func TestUUIDColumnsByRequest(t *testing.T) {
+	defer func(conf *config.Prest) { config.PrestConf = conf }(config.PrestConf)
+	config.PrestConf = &config.Prest{UUIDColumns: []config.UUIDColumnConf{
+		{Name: "public.test_uuid", Column: "id"},
+	}}
+
+	var testCases = []struct {
+		description string
+		table       string
+		body        string
+		generated   bool
+	}{
+		{"missing uuid column", "test_uuid", `{"name":"prest","total":12345678901234567890}`, true},
+		{"uuid column in the body", "test_uuid", `{"id":"c1e7d7d1-0d64-4b2a-8f4e-0a1c5b1a9e11","name":"prest"}`, false},
+		{"table without uuid columns", "test", `{"name":"prest"}`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("POST", "/prest/public/"+tc.table, bytes.NewBufferString(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = UUIDColumnsByRequest(r, "public", tc.table); err != nil {
+			t.Fatal(err)
+		}
+
+		body := make(map[string]interface{})
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err = decoder.Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		id, ok := body["id"].(string)
+		if tc.generated && (!ok || !uuidRegex.MatchString(id)) {
+			t.Errorf("expected a generated id, got %v", body["id"])
+		}
+		if !tc.generated && tc.table == "test_uuid" && id != "c1e7d7d1-0d64-4b2a-8f4e-0a1c5b1a9e11" {
+			t.Errorf("expected the id of the body, got %v", body["id"])
+		}
+		if !tc.generated && tc.table == "test" && ok {
+			t.Errorf("expected no id, got %v", body["id"])
+		}
+		if total, ok := body["total"].(json.Number); ok && total.String() != "12345678901234567890" {
+			t.Errorf("expected the numbers to be kept, got %s", total)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// XMLContentType is the content type of the rows rendered by RowsXML
+const XMLContentType = "application/xml"
+
+// XMLByRequest check `Accept: application/xml`, the _renderer of the query string
+// takes precedence over the header
+func XMLByRequest(r *http.Request) bool {
+	return r.URL.Query().Get("_renderer") == "" && strings.Contains(r.Header.Get("Accept"), XMLContentType)
+}
+
+// RowsXML render a JSON array of rows, or a single row, as
+// <rows><row><column>value</column></row></rows>. The columns keep their order,
+// NULL is an empty element with xsi:nil="true" and the JSON of objects and arrays
+// (json and jsonb columns) is the text of their elements
+func RowsXML(jsonData []byte) (xmlData []byte, err error) {
+	var rows []json.RawMessage
+	trimmed := bytes.TrimSpace(jsonData)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		rows = []json.RawMessage{trimmed}
+	} else if err = json.Unmarshal(trimmed, &rows); err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	buf.WriteString(`<rows xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`)
+	for _, row := range rows {
+		buf.WriteString("<row>")
+		if err = writeXMLRow(&buf, row); err != nil {
+			return
+		}
+		buf.WriteString("</row>")
+	}
+	buf.WriteString("</rows>")
+	xmlData = buf.Bytes()
+	return
+}
+
+// writeXMLRow write the columns of a JSON object in the order of the object
+func writeXMLRow(buf *bytes.Buffer, row json.RawMessage) (err error) {
+	decoder := json.NewDecoder(bytes.NewReader(row))
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		err = fmt.Errorf("row is not a JSON object")
+		return
+	}
+
+	for decoder.More() {
+		if token, err = decoder.Token(); err != nil {
+			return
+		}
+		name := xmlElementName(token.(string))
+
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return
+		}
+
+		switch {
+		case string(value) == "null":
+			fmt.Fprintf(buf, `<%s xsi:nil="true"/>`, name)
+			continue
+		case value[0] == '"':
+			var text string
+			if err = json.Unmarshal(value, &text); err != nil {
+				return
+			}
+			value = json.RawMessage(text)
+		}
+		fmt.Fprintf(buf, "<%s>", name)
+		if err = xml.EscapeText(buf, value); err != nil {
+			return
+		}
+		fmt.Fprintf(buf, "</%s>", name)
+	}
+	return
+}
+
+// xmlElementName sanitize a column name into an element name, the characters not
+// allowed are replaced by _ and names not starting by a letter or _ are prefixed by _
+func xmlElementName(column string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, column)
+	if name == "" {
+		return "_"
+	}
+	first := []rune(name)[0]
+	if (!unicode.IsLetter(first) && first != '_') || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestXMLByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		accept      string
+		expected    bool
+	}{
+		{"accept xml", "/prest/public/test", "application/xml", true},
+		{"accept xml between other types", "/prest/public/test", "text/html, application/xml;q=0.9", true},
+		{"accept json", "/prest/public/test", "application/json", false},
+		{"without accept", "/prest/public/test", "", false},
+		{"renderer of the query string", "/prest/public/test?_renderer=xml", "application/xml", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r, err := http.NewRequest("GET", tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", tc.accept)
+		if xml := XMLByRequest(r); xml != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, xml)
+		}
+	}
+}
+
+func TestRowsXML(t *testing.T) {
+	const header = `<?xml version="1.0" encoding="UTF-8"?><rows xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`
+	var testCases = []struct {
+		description string
+		json        string
+		expected    string
+		err         bool
+	}{
+		{"rows keep the order of the columns", `[{"name":"prest","id":1},{"name":"x","id":2}]`, header + `<row><name>prest</name><id>1</id></row><row><name>x</name><id>2</id></row></rows>`, false},
+		{"no rows", `[]`, header + `</rows>`, false},
+		{"single row", `{"id":1}`, header + `<row><id>1</id></row></rows>`, false},
+		{"escaped values", `[{"name":"a < b & \"c\""}]`, header + `<row><name>a &lt; b &amp; &#34;c&#34;</name></row></rows>`, false},
+		{"null", `[{"name":null}]`, header + `<row><name xsi:nil="true"/></row></rows>`, false},
+		{"booleans", `[{"active":true}]`, header + `<row><active>true</active></row></rows>`, false},
+		{"jsonb document", `[{"data":{"a":[1,2]}}]`, header + `<row><data>{&#34;a&#34;:[1,2]}</data></row></rows>`, false},
+		{"sanitized column names", `[{"first name":1,"1st":2,"xmlid":3,"count(*)":4}]`, header + `<row><first_name>1</first_name><_1st>2</_1st><_xmlid>3</_xmlid><count___>4</count___></row></rows>`, false},
+		{"invalid json", `[{"id":`, "", true},
+		{"rows are not objects", `[1]`, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		xmlData, err := RowsXML([]byte(tc.json))
+		if tc.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(xmlData) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, xmlData)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/nuveo/prest/config"
@@ -10,7 +12,6 @@ import (
 	"github.com/nuveo/prest/config/router"
 	"github.com/nuveo/prest/controllers"
 	"github.com/nuveo/prest/middlewares"
-	"github.com/rs/cors"
 	"github.com/spf13/cobra"
 	"github.com/urfave/negroni"
 	// postgres driver for migrate
@@ -53,31 +54,147 @@ func app() {
 	n := cfgMiddleware.GetApp()
 	r := router.Get()
 
-	r.HandleFunc("/databases", controllers.GetDatabases).Methods("GET")
-	r.HandleFunc("/schemas", controllers.GetSchemas).Methods("GET")
-	r.HandleFunc("/tables", controllers.GetTables).Methods("GET")
+	r.HandleFunc("/_health", controllers.Health).Methods("GET")
+	tenantClaim := config.PrestConf.TenantClaim
+	if tenantClaim != "" {
+		// the catalog of all the tenants is listed by the admins
+		r.Handle("/databases", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.GetDatabases)),
+		)).Methods("GET")
+		r.Handle("/schemas", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.GetSchemas)),
+		)).Methods("GET")
+		r.Handle("/tables", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.GetTables)),
+		)).Methods("GET")
+		r.Handle("/_openapi.json", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.OpenAPI)),
+		)).Methods("GET")
+	} else {
+		r.HandleFunc("/databases", controllers.GetDatabases).Methods("GET")
+		r.HandleFunc("/schemas", controllers.GetSchemas).Methods("GET")
+		r.HandleFunc("/tables", controllers.GetTables).Methods("GET")
+		r.HandleFunc("/_openapi.json", controllers.OpenAPI).Methods("GET")
+	}
 	r.HandleFunc("/_QUERIES/{queriesLocation}/{script}", controllers.ExecuteFromScripts)
-	r.HandleFunc("/{database}/{schema}", controllers.GetTablesByDatabaseAndSchema).Methods("GET")
+	if config.PrestConf.EnableRawQuery {
+		r.Handle("/_query", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.RawQuery)),
+		)).Methods("POST")
+	}
+	if config.PrestConf.EnableActivity {
+		r.Handle("/_activity", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.Activity)),
+		)).Methods("GET")
+		r.Handle("/_activity/{pid:[0-9]+}", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.CancelActivity)),
+		)).Methods("DELETE")
+	}
+	// the channels are checked like the tables, the read permission in restrict mode
+	listenRoutes := mux.NewRouter()
+	listenRoutes.HandleFunc("/{database}/_listen/{channel}", controllers.Listen).Methods("GET")
+	listen := negroni.New(middlewares.AccessControl())
+	if tenantClaim != "" {
+		listen.Use(middlewares.Tenant(tenantClaim))
+	}
+	listen.UseHandler(listenRoutes)
+	r.Handle("/{database}/_listen/{channel}", listen).Methods("GET")
+	if tenantClaim != "" {
+		// the vars of the route are read after the schema of the tenant is set
+		schemaRoutes := mux.NewRouter()
+		schemaRoutes.HandleFunc("/{database}/{schema}", controllers.GetTablesByDatabaseAndSchema).Methods("GET")
+		r.Handle("/{database}/{schema}", negroni.New(
+			middlewares.Tenant(tenantClaim),
+			negroni.Wrap(schemaRoutes),
+		)).Methods("GET")
+	} else {
+		r.HandleFunc("/{database}/{schema}", controllers.GetTablesByDatabaseAndSchema).Methods("GET")
+	}
+	r.Handle("/{database}/_schema", negroni.New(
+		middlewares.AdminOnly(),
+		negroni.Wrap(http.HandlerFunc(controllers.CreateSchema)),
+	)).Methods("POST")
+	r.Handle("/{database}/_schema/{name}", negroni.New(
+		middlewares.AdminOnly(),
+		negroni.Wrap(http.HandlerFunc(controllers.DropSchema)),
+	)).Methods("DELETE")
+	r.Handle("/{database}/{schema}/_table", negroni.New(
+		middlewares.AdminOnly(),
+		negroni.Wrap(http.HandlerFunc(controllers.CreateTable)),
+	)).Methods("POST")
+	r.Handle("/{database}/{schema}/{table}/_column", negroni.New(
+		middlewares.AdminOnly(),
+		negroni.Wrap(http.HandlerFunc(controllers.AddColumn)),
+	)).Methods("PATCH")
+	if config.PrestConf.EnableTruncate {
+		r.Handle("/{database}/{schema}/{table}/_truncate", negroni.New(
+			middlewares.AdminOnly(),
+			negroni.Wrap(http.HandlerFunc(controllers.TruncateTable)),
+		)).Methods("POST")
+	}
 
 	crudRoutes := mux.NewRouter().PathPrefix("/").Subrouter().StrictSlash(true)
 
-	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.SelectFromTables).Methods("GET")
+	crudRoutes.HandleFunc("/{database}/{schema}/{function:[^/()]+}({arguments:[^/]*})", controllers.SelectFromFunction).Methods("GET")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.SelectFromTables).Methods("GET", "HEAD")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.InsertInTables).Methods("POST")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.DeleteFromTable).Methods("DELETE")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.UpdateTable).Methods("PUT", "PATCH")
+	crudRoutes.HandleFunc("/{database}/{schema}/functions/{function}", controllers.ExecuteFunction).Methods("POST")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_bulk_update", controllers.BulkUpdateTable).Methods("POST")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_bulk", controllers.BulkDeleteTable).Methods("DELETE")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_copy", controllers.CopyTable).Methods("POST")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_search", controllers.SearchTable).Methods("POST")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_refresh", controllers.RefreshMaterializedView).Methods("POST")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_pk", controllers.PrimaryKey).Methods("GET")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/_columns", controllers.Columns).Methods("GET")
+
+	schemas, err := middlewares.LoadJSONSchemas(config.PrestConf.JSONSchemas)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
 
-	r.PathPrefix("/").Handler(negroni.New(
-		middlewares.AccessControl(),
-		negroni.Wrap(crudRoutes),
-	))
+	crud := negroni.New()
+	if tenantClaim != "" {
+		crud.Use(middlewares.Tenant(tenantClaim))
+	}
+	crud.Use(middlewares.AccessControl())
+	crud.Use(middlewares.JSONSchemaValidation(schemas))
+	crud.UseHandler(crudRoutes)
+	if config.PrestConf.DefaultSchema != "" {
+		r.Handle("/{table}", negroni.New(
+			middlewares.DefaultSchema(config.PrestConf.DefaultDatabase, config.PrestConf.DefaultSchema),
+			negroni.Wrap(crud),
+		))
+	}
+	r.PathPrefix("/").Handler(crud)
+
+	n.Use(middlewares.InFlight())
+
+	if config.PrestConf.NormalizePaths {
+		n.Use(middlewares.NormalizePath())
+	}
 
-	if config.PrestConf.CORSAllowOrigin != nil {
-		c := cors.New(cors.Options{
-			AllowedOrigins: config.PrestConf.CORSAllowOrigin,
-		})
-		n.Use(c)
+	if config.PrestConf.MaxBodyBytes > 0 {
+		n.Use(middlewares.MaxBodyBytes(config.PrestConf.MaxBodyBytes))
+	}
+
+	if config.PrestConf.CORSAllowOrigin != nil || len(config.PrestConf.CORSPaths) > 0 {
+		n.Use(middlewares.CORS(config.PrestConf.CORSAllowOrigin, config.PrestConf.CORSPaths))
 	}
 
 	n.UseHandler(r)
-	n.Run(fmt.Sprintf(":%v", config.PrestConf.HTTPPort))
+	timeout := time.Duration(config.PrestConf.ShutdownTimeout) * time.Second
+	if err := serve(fmt.Sprintf(":%v", config.PrestConf.HTTPPort), n, timeout); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
 }
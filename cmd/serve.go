@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nuveo/prest/adapters/postgres/connection"
+	"github.com/nuveo/prest/controllers"
+	"github.com/nuveo/prest/middlewares"
+)
+
+// serve listen on addr until SIGTERM or SIGINT, the in-flight requests are then
+// drained for up to timeout before the server and the connection pools are closed.
+// The streams of _listen are ended at once, they never finish by themselves
+func serve(addr string, handler http.Handler, timeout time.Duration) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(stop)
+	server := &http.Server{Addr: addr, Handler: handler}
+	server.RegisterOnShutdown(controllers.ShutdownListen)
+	return serveUntil(server, stop, timeout)
+}
+
+func serveUntil(server *http.Server, stop <-chan os.Signal, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s\n", server.Addr)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		drained := middlewares.InFlightRequests()
+		log.Printf("%v received, draining %d requests for up to %v\n", sig, drained, timeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			remaining := middlewares.InFlightRequests()
+			log.Printf("could not drain %d requests: %v\n", remaining, err)
+			server.Close()
+			drained -= remaining
+		}
+		log.Printf("drained %d requests\n", drained)
+	}
+
+	if err := connection.Close(); err != nil {
+		log.Printf("could not close the database connections: %v\n", err)
+	}
+	return nil
+}
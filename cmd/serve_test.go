@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nuveo/prest/middlewares"
+	"github.com/urfave/negroni"
+)
+
+func TestServeUntilDrainsRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	started := make(chan struct{})
+	n := negroni.New(middlewares.InFlight())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serveUntil(&http.Server{Addr: addr, Handler: n}, stop, time.Second)
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr + "/")
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+			return
+		}
+	}()
+
+	<-started
+	stop <- syscall.SIGTERM
+	if err := <-served; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	select {
+	case code := <-status:
+		if code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Error("the in-flight request was not drained")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+
 	"github.com/spf13/viper"
 )
 
@@ -23,6 +24,42 @@ type AccessConf struct {
 	Tables   []TablesConf
 }
 
+// JSONSchemaConf JSON Schema file of a table, name is schema.table
+type JSONSchemaConf struct {
+	Name string `mapstructure:"name"`
+	File string `mapstructure:"file"`
+}
+
+// WebhookConf webhooks of the writes in a table, Name is schema.table and Events
+// filter the operations (insert, update and delete), all of them when empty
+type WebhookConf struct {
+	Name   string   `mapstructure:"name"`
+	URLs   []string `mapstructure:"urls"`
+	Events []string `mapstructure:"events"`
+}
+
+// UUIDColumnConf uuid column filled by pREST when it's missing in the inserts of a
+// table, name is schema.table
+type UUIDColumnConf struct {
+	Name   string `mapstructure:"name"`
+	Column string `mapstructure:"column"`
+}
+
+// RowCapConf maximum of rows returned by the selects of a table without page size,
+// name is schema.table
+type RowCapConf struct {
+	Name string `mapstructure:"name"`
+	Cap  int    `mapstructure:"cap"`
+}
+
+// CORSConf CORS policy of the requests to path and its subpaths
+type CORSConf struct {
+	Path         string   `mapstructure:"path"`
+	AllowOrigin  []string `mapstructure:"alloworigin"`
+	AllowMethods []string `mapstructure:"allowmethods"`
+	AllowHeaders []string `mapstructure:"allowheaders"`
+}
+
 // Prest basic config
 type Prest struct {
 	// HTTPPort Declare which http port the PREST used
@@ -41,6 +78,98 @@ type Prest struct {
 	AccessConf      AccessConf
 	CORSAllowOrigin []string
 	Debug           bool
+	// CORSPaths CORS policies of paths, e.g. a table, instead of CORSAllowOrigin
+	CORSPaths []CORSConf
+	// JSONBigNumbersAsString return integers out of the float64 safe range as strings
+	JSONBigNumbersAsString bool
+	// JSONPreserveNumericPrecision return the numeric columns as strings, e.g. the values of numeric(30,10) columns a float64 can't represent
+	JSONPreserveNumericPrecision bool
+	// JSONMoneyAsNumber return the money columns as numbers instead of strings with the currency symbol, e.g. 1234.50 instead of "$1,234.50"
+	JSONMoneyAsNumber bool
+	// JSONByteaAsBase64 return the bytea columns as base64 strings instead of the hex format of postgres, e.g. "aGVsbG8=" instead of "\\x68656c6c6f"
+	JSONByteaAsBase64 bool
+	// MaxPageSize is the biggest _page_size accepted, 0 disable the limit
+	MaxPageSize int
+	// ClampPageSize use MaxPageSize when it's exceeded instead of return an error
+	ClampPageSize bool
+	// AdminRole is the JWT role claim required by administrative endpoints
+	AdminRole string
+	// EnableRawQuery enable the /_query endpoint to run SELECT statements sent by admins
+	EnableRawQuery bool
+	// JSONSchemas validate insert and update bodies of the tables
+	JSONSchemas []JSONSchemaConf
+	// EnableExplainAnalyze allow _explain=analyze, it executes the query
+	EnableExplainAnalyze bool
+	// EnableCompression compress responses with gzip or deflate when the client accepts it
+	EnableCompression bool
+	// CompressionMinSize is the smallest body, in bytes, that is compressed
+	CompressionMinSize int
+	// PGConnMaxLifetime is the seconds a connection can be reused, 0 means forever
+	PGConnMaxLifetime int
+	// PGConnMaxIdleTime is the seconds a connection can be idle, 0 means forever
+	PGConnMaxIdleTime int
+	// ReplicaURLs are connection strings of read replicas used by selects in round-robin
+	ReplicaURLs []string
+	// Webhooks notified after writes in the tables
+	Webhooks []WebhookConf
+	// CaseInsensitiveColumns resolve the columns of the requests to the real names ignoring case
+	CaseInsensitiveColumns bool
+	// InsertStatusCreated answer inserts with 201 Created instead of 200 OK
+	InsertStatusCreated bool
+	// DefaultDatabase and DefaultSchema of the short paths /{table}, the short paths are
+	// enabled by DefaultSchema and DefaultDatabase is PGDatabase when empty
+	DefaultDatabase string
+	DefaultSchema   string
+	// OpenAPISchemas are the schemas described by /_openapi.json, all of them when empty
+	OpenAPISchemas []string
+	// EnableTruncate enable the /{database}/{schema}/{table}/_truncate endpoint for admins
+	EnableTruncate bool
+	// SlowQueryMS is the duration, in milliseconds, of the queries logged as slow, 0 disable the log
+	SlowQueryMS int
+	// SlowQueryRedactParams log the slow queries without the values of their parameters
+	SlowQueryRedactParams bool
+	// UUIDColumns are generated with random UUIDs when the inserts don't have them
+	UUIDColumns []UUIDColumnConf
+	// MaxBodyBytes is the maximum size of the request bodies, 0 disable the limit
+	MaxBodyBytes int64
+	// EnableDryRun allow _dryrun=true, the statements are returned instead of executed
+	EnableDryRun bool
+	// EnableActivity enable the /_activity endpoints for admins, to list and cancel the running queries
+	EnableActivity bool
+	// TableRowCap is the maximum of rows returned by the selects without page size, 0 disable the cap
+	TableRowCap int
+	// TableRowCaps replace TableRowCap for some tables
+	TableRowCaps []RowCapConf
+	// NormalizePaths collapse the repeated slashes and strip the trailing slash of the paths before the routing
+	NormalizePaths bool
+	// JSONTimestamps is the format of the timestamp columns in the JSON output, rfc3339 or epoch_ms, empty keeps the format of postgres
+	JSONTimestamps string
+	// JSONNullBehavior is the output of the NULL columns of the selects, keep, omit or empty
+	JSONNullBehavior string
+	// FTSLanguage is the text search configuration of the $fts filters, e.g. english, the default_text_search_config of postgres when empty
+	FTSLanguage string
+	// QuoteIdentifiers double quote the databases, schemas, tables and columns in the queries, e.g. for reserved words or upper case names
+	QuoteIdentifiers bool
+	// CursorSecret sign the cursors of the keyset pagination with HMAC-SHA256, the cursors are only base64 encoded when empty
+	CursorSecret string
+	// ShutdownTimeout is the seconds the in-flight requests are drained on SIGTERM or SIGINT before the server is stopped
+	ShutdownTimeout int
+	// RetryAttempts is the maximum of attempts of the selects and of the write transactions failed with transient errors, 1 disable the retries
+	RetryAttempts int
+	// RetryBackoffMS is the wait, in milliseconds, before the first retry, it's doubled after each retry
+	RetryBackoffMS int
+	// RequestIDHeader is the header of the request ID read from the requests, generated when missing, and echoed in the responses
+	RequestIDHeader string
+	// StatementCacheSize is the maximum of statements prepared in the connection pools reused across the requests, 0 disable the cache
+	StatementCacheSize int
+	// MaxJoins is the maximum of _join of a request, 0 disable the limit
+	MaxJoins int
+	// DefaultOrderBy is the order of the paginated selects without _order, the fields of _order or primary_key, empty disable the default order
+	DefaultOrderBy string
+	// TenantClaim is the JWT claim with the schema of the tenant, the schema of the paths is replaced by it, empty disable the tenants
+	TenantClaim string
+	// QueryCostHeader set X-Query-Cost in the responses of the selects with the total cost of the plan of EXPLAIN, it's a round trip more per request
+	QueryCostHeader bool
 }
 
 // PrestConf config variable
@@ -58,12 +187,44 @@ func viperCfg() {
 	viper.SetConfigName(file)
 	viper.SetConfigType("toml")
 	viper.SetDefault("http.port", 3000)
+	viper.SetDefault("http.shutdown_timeout", 30)
+	viper.SetDefault("http.request_id_header", "X-Request-ID")
+	viper.SetDefault("retry.attempts", 1)
+	viper.SetDefault("retry.backoff_ms", 100)
 	viper.SetDefault("pg.host", "127.0.0.1")
 	viper.SetDefault("pg.port", 5432)
 	viper.SetDefault("pg.maxidleconn", 10)
 	viper.SetDefault("pg.maxopenconn", 10)
 	viper.SetDefault("pg.conntimeout", 10)
+	viper.SetDefault("pg.connmaxlifetime", 0)
+	viper.SetDefault("pg.connmaxidletime", 0)
+	viper.SetDefault("pg.statement_cache_size", 0)
 	viper.SetDefault("debug", false)
+	viper.SetDefault("json.bignumbersasstring", true)
+	viper.SetDefault("json.preserve_numeric_precision", false)
+	viper.SetDefault("json.timestamps", "")
+	viper.SetDefault("json.null_behavior", "keep")
+	viper.SetDefault("pagination.maxpagesize", 1000)
+	viper.SetDefault("pagination.clamp", false)
+	viper.SetDefault("pagination.default_order_by", "")
+	viper.SetDefault("jwt.adminrole", "admin")
+	viper.SetDefault("tenant.claim", "")
+	viper.SetDefault("enable_raw_query", false)
+	viper.SetDefault("enable_explain_analyze", false)
+	viper.SetDefault("query_cost_header", false)
+	viper.SetDefault("enable_truncate", false)
+	viper.SetDefault("slow_query_ms", 0)
+	viper.SetDefault("slow_query_redact_params", false)
+	viper.SetDefault("max_body_bytes", 10<<20)
+	viper.SetDefault("max_joins", 5)
+	viper.SetDefault("enable_dry_run", false)
+	viper.SetDefault("enable_activity", false)
+	viper.SetDefault("table_row_cap.default", 0)
+	viper.SetDefault("normalize_paths", true)
+	viper.SetDefault("case_insensitive_columns", false)
+	viper.SetDefault("insert_status_created", false)
+	viper.SetDefault("compression.enabled", true)
+	viper.SetDefault("compression.minsize", 1024)
 
 	user, err := user.Current()
 	if err != nil {
@@ -85,6 +246,10 @@ func Parse(cfg *Prest) (err error) {
 		}
 	}
 	cfg.HTTPPort = viper.GetInt("http.port")
+	cfg.ShutdownTimeout = viper.GetInt("http.shutdown_timeout")
+	cfg.RequestIDHeader = viper.GetString("http.request_id_header")
+	cfg.RetryAttempts = viper.GetInt("retry.attempts")
+	cfg.RetryBackoffMS = viper.GetInt("retry.backoff_ms")
 	cfg.PGHost = viper.GetString("pg.host")
 	cfg.PGPort = viper.GetInt("pg.port")
 	cfg.PGUser = viper.GetString("pg.user")
@@ -93,12 +258,52 @@ func Parse(cfg *Prest) (err error) {
 	cfg.PGMaxIdleConn = viper.GetInt("pg.maxidleconn")
 	cfg.PGMAxOpenConn = viper.GetInt("pg.maxopenconn")
 	cfg.PGConnTimeout = viper.GetInt("pg.conntimeout")
+	cfg.PGConnMaxLifetime = viper.GetInt("pg.connmaxlifetime")
+	cfg.PGConnMaxIdleTime = viper.GetInt("pg.connmaxidletime")
+	cfg.StatementCacheSize = viper.GetInt("pg.statement_cache_size")
+	cfg.ReplicaURLs = viper.GetStringSlice("pg.replica_urls")
 	cfg.JWTKey = viper.GetString("jwt.key")
 	cfg.MigrationsPath = viper.GetString("migrations")
 	cfg.AccessConf.Restrict = viper.GetBool("access.restrict")
 	cfg.QueriesPath = viper.GetString("queries.location")
 	cfg.CORSAllowOrigin = viper.GetStringSlice("cors.alloworigin")
 	cfg.Debug = viper.GetBool("debug")
+	cfg.JSONBigNumbersAsString = viper.GetBool("json.bignumbersasstring")
+	cfg.JSONPreserveNumericPrecision = viper.GetBool("json.preserve_numeric_precision")
+	cfg.JSONMoneyAsNumber = viper.GetBool("json.money_as_number")
+	cfg.JSONByteaAsBase64 = viper.GetBool("json.bytea_as_base64")
+	cfg.JSONTimestamps = viper.GetString("json.timestamps")
+	cfg.JSONNullBehavior = viper.GetString("json.null_behavior")
+	cfg.FTSLanguage = viper.GetString("fts.language")
+	cfg.QuoteIdentifiers = viper.GetBool("quote_identifiers")
+	cfg.CursorSecret = viper.GetString("cursor.secret")
+	cfg.MaxPageSize = viper.GetInt("pagination.maxpagesize")
+	cfg.ClampPageSize = viper.GetBool("pagination.clamp")
+	cfg.DefaultOrderBy = viper.GetString("pagination.default_order_by")
+	cfg.AdminRole = viper.GetString("jwt.adminrole")
+	cfg.TenantClaim = viper.GetString("tenant.claim")
+	cfg.EnableRawQuery = viper.GetBool("enable_raw_query")
+	cfg.EnableExplainAnalyze = viper.GetBool("enable_explain_analyze")
+	cfg.QueryCostHeader = viper.GetBool("query_cost_header")
+	cfg.EnableTruncate = viper.GetBool("enable_truncate")
+	cfg.SlowQueryMS = viper.GetInt("slow_query_ms")
+	cfg.SlowQueryRedactParams = viper.GetBool("slow_query_redact_params")
+	cfg.MaxBodyBytes = viper.GetInt64("max_body_bytes")
+	cfg.MaxJoins = viper.GetInt("max_joins")
+	cfg.EnableDryRun = viper.GetBool("enable_dry_run")
+	cfg.EnableActivity = viper.GetBool("enable_activity")
+	cfg.TableRowCap = viper.GetInt("table_row_cap.default")
+	cfg.NormalizePaths = viper.GetBool("normalize_paths")
+	cfg.CaseInsensitiveColumns = viper.GetBool("case_insensitive_columns")
+	cfg.InsertStatusCreated = viper.GetBool("insert_status_created")
+	cfg.DefaultDatabase = viper.GetString("default_database")
+	cfg.DefaultSchema = viper.GetString("default_schema")
+	if cfg.DefaultDatabase == "" {
+		cfg.DefaultDatabase = cfg.PGDatabase
+	}
+	cfg.OpenAPISchemas = viper.GetStringSlice("openapi.schemas")
+	cfg.EnableCompression = viper.GetBool("compression.enabled")
+	cfg.CompressionMinSize = viper.GetInt("compression.minsize")
 
 	var t []TablesConf
 	err = viper.UnmarshalKey("access.tables", &t)
@@ -108,6 +313,46 @@ func Parse(cfg *Prest) (err error) {
 
 	cfg.AccessConf.Tables = t
 
+	var schemas []JSONSchemaConf
+	err = viper.UnmarshalKey("jsonschema.tables", &schemas)
+	if err != nil {
+		return err
+	}
+
+	cfg.JSONSchemas = schemas
+
+	var corsPaths []CORSConf
+	err = viper.UnmarshalKey("cors.paths", &corsPaths)
+	if err != nil {
+		return err
+	}
+
+	cfg.CORSPaths = corsPaths
+
+	var webhooks []WebhookConf
+	err = viper.UnmarshalKey("webhooks.tables", &webhooks)
+	if err != nil {
+		return err
+	}
+
+	cfg.Webhooks = webhooks
+
+	var uuidColumns []UUIDColumnConf
+	err = viper.UnmarshalKey("uuid.tables", &uuidColumns)
+	if err != nil {
+		return err
+	}
+
+	cfg.UUIDColumns = uuidColumns
+
+	var rowCaps []RowCapConf
+	err = viper.UnmarshalKey("table_row_cap.tables", &rowCaps)
+	if err != nil {
+		return err
+	}
+
+	cfg.TableRowCaps = rowCaps
+
 	return
 }
 
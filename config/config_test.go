@@ -64,4 +64,28 @@ func TestParse(t *testing.T) {
 	if cfg.HTTPPort != 4000 {
 		t.Errorf("expected port: 4000, got: %d", cfg.HTTPPort)
 	}
+
+	os.Setenv("PREST_PG_MAXOPENCONN", "50")
+	os.Setenv("PREST_PG_CONNMAXLIFETIME", "300")
+	viperCfg()
+	cfg = &Prest{}
+	err = Parse(cfg)
+	os.Unsetenv("PREST_PG_MAXOPENCONN")
+	os.Unsetenv("PREST_PG_CONNMAXLIFETIME")
+
+	if err != nil {
+		t.Errorf("expected no errors, but got %v", err)
+	}
+
+	if cfg.PGMAxOpenConn != 50 {
+		t.Errorf("expected max open connections: 50, got: %d", cfg.PGMAxOpenConn)
+	}
+
+	if cfg.PGConnMaxLifetime != 300 {
+		t.Errorf("expected connection max lifetime: 300, got: %d", cfg.PGConnMaxLifetime)
+	}
+
+	if cfg.PGConnMaxIdleTime != 0 {
+		t.Errorf("expected connection max idle time: 0, got: %d", cfg.PGConnMaxIdleTime)
+	}
 }
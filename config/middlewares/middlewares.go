@@ -15,7 +15,9 @@ var (
 	// BaseStack Middlewares
 	BaseStack = []negroni.Handler{
 		negroni.Handler(negroni.NewRecovery()),
-		negroni.Handler(negroni.NewLogger()),
+		negroni.Handler(middlewares.Logger()),
+		negroni.Handler(middlewares.RequestID()),
+		negroni.Handler(middlewares.Compression()),
 		negroni.Handler(middlewares.HandlerSet()),
 	}
 )
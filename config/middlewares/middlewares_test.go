@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"bufio"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 	"github.com/nuveo/prest/config"
 	"github.com/nuveo/prest/config/router"
@@ -181,3 +183,349 @@ func appTest() *negroni.Negroni {
 	n.UseHandler(r)
 	return n
 }
+
+func TestJWTPublicPaths(t *testing.T) {
+	os.Setenv("PREST_DEBUG", "false")
+	config.Load()
+	app = nil
+	r := mux.NewRouter()
+	r.HandleFunc("/_health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) })
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) })
+	n := GetApp()
+	n.UseHandler(r)
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/_health")
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code 200, but got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status code 401, but got %d", resp.StatusCode)
+	}
+	MiddlewareStack = []negroni.Handler{}
+	os.Setenv("PREST_DEBUG", "true")
+}
+
+func TestHandlerSetKeepsHeaders(t *testing.T) {
+	n := negroni.New(middlewares.HandlerSet())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Affected-Rows", "3")
+		w.Write([]byte(`{"rows_affected":3}`))
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if header := resp.Header.Get("X-Affected-Rows"); header != "3" {
+		t.Errorf("expected X-Affected-Rows 3, got: %q", header)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected application/json content type, got: %q", contentType)
+	}
+}
+
+func TestHandlerSetKeepsXML(t *testing.T) {
+	n := negroni.New(middlewares.HandlerSet())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<rows><row><id>1</id></row></rows>`))
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/xml" {
+		t.Errorf("expected application/xml content type, got: %q", contentType)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != `<rows><row><id>1</id></row></rows>` {
+		t.Errorf("expected the XML of the controller, got: %s", body)
+	}
+}
+
+func TestDefaultSchema(t *testing.T) {
+	crudRoutes := mux.NewRouter()
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		w.Write([]byte(vars["database"] + "." + vars["schema"] + "." + vars["table"] + "?" + r.URL.RawQuery))
+	})
+	r := mux.NewRouter()
+	r.Handle("/{table}", negroni.New(
+		middlewares.DefaultSchema("prest", "public"),
+		negroni.Wrap(crudRoutes),
+	))
+	r.PathPrefix("/").Handler(crudRoutes)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+	}{
+		{"short path", "/test?name=$eq.prest", "prest.public.test?name=$eq.prest"},
+		{"full path", "/otherdb/other/test", "otherdb.other.test?"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Get(server.URL + tc.url)
+		if err != nil {
+			t.Fatal("Expected run without errors but was", err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != tc.expected {
+			t.Errorf("expected %q, got: %q", tc.expected, body)
+		}
+	}
+}
+
+func TestHandlerSetCreated(t *testing.T) {
+	n := negroni.New(middlewares.HandlerSet())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201, got: %d", resp.StatusCode)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != `{"id":1}` {
+		t.Errorf("expected body not wrapped as error, got: %s", body)
+	}
+}
+
+func TestHandlerSetEventStream(t *testing.T) {
+	n := negroni.New(middlewares.HandlerSet())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected text/event-stream content type, got: %q", contentType)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "data: hello\n\n" {
+		t.Errorf("expected event, got: %q", body)
+	}
+}
+
+func TestHandlerSetEventStreamWithoutAccept(t *testing.T) {
+	done := make(chan struct{})
+	n := negroni.New(middlewares.HandlerSet())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+	defer close(done)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected text/event-stream content type, got: %q", contentType)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal("Expected run without errors but was", err.Error())
+	}
+	if line != "data: hello\n" {
+		t.Errorf("expected the event before the handler returns, got: %q", line)
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	os.Setenv("PREST_DEBUG", "false")
+	config.Load()
+	config.PrestConf.JWTKey = "secret"
+	n := negroni.New(middlewares.AdminOnly())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) }))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	sign := func(key string, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+
+	var testCases = []struct {
+		description   string
+		authorization string
+		status        int
+	}{
+		{"without JWT", "", http.StatusForbidden},
+		{"JWT without role", sign("secret", jwt.MapClaims{"name": "prest"}), http.StatusForbidden},
+		{"JWT with other role", sign("secret", jwt.MapClaims{"role": "reader"}), http.StatusForbidden},
+		{"JWT with admin role signed with other key", sign("other", jwt.MapClaims{"role": "admin"}), http.StatusForbidden},
+		{"JWT with admin role", sign("secret", jwt.MapClaims{"role": "admin"}), http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("POST", server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Expected run without errors but was", err.Error())
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected status code %d, but got %d", tc.status, resp.StatusCode)
+		}
+	}
+
+	os.Setenv("PREST_DEBUG", "true")
+	config.Load()
+}
+
+func TestJSONSchemaValidation(t *testing.T) {
+	schemas, err := middlewares.LoadJSONSchemas([]config.JSONSchemaConf{{Name: "public.test5", File: "../../testdata/jsonschema/test5.json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := negroni.New(middlewares.JSONSchemaValidation(schemas))
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		method      string
+		url         string
+		body        string
+		status      int
+		response    string
+	}{
+		{"valid insert", "POST", "/prest/public/test5", `{"name": "prest"}`, http.StatusOK, `{"name": "prest"}`},
+		{"invalid insert", "POST", "/prest/public/test5", `{"celphone": "abc", "other": 1}`, http.StatusUnprocessableEntity, `{"error":{"code":"VALIDATION_FAILED","message":"body does not match the JSON Schema of the table","detail":["(root): name is required","celphone: must match ^[0-9]+$","(root): other is not allowed"]}}`},
+		{"partial update", "PATCH", "/prest/public/test5?id=1", `{"celphone": "123"}`, http.StatusOK, `{"celphone": "123"}`},
+		{"invalid update", "PUT", "/prest/public/test5?id=1", `{"name": ""}`, http.StatusUnprocessableEntity, `{"error":{"code":"VALIDATION_FAILED","message":"body does not match the JSON Schema of the table","detail":["name: must have at least 1 characters"]}}`},
+		{"table without schema", "POST", "/prest/public/test", `{"other": 1}`, http.StatusOK, `{"other": 1}`},
+		{"table action", "POST", "/prest/public/test5/_bulk_update", `[]`, http.StatusOK, `[]`},
+		{"select", "GET", "/prest/public/test5", ``, http.StatusOK, ``},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest(tc.method, server.URL+tc.url, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Expected run without errors but was", err.Error())
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected status code %d, but got %d", tc.status, resp.StatusCode)
+		}
+		if string(body) != tc.response {
+			t.Errorf("expected %q, but got %q", tc.response, string(body))
+		}
+	}
+}
+
+func TestCORSPaths(t *testing.T) {
+	n := negroni.New(middlewares.CORS([]string{"http://default.com"}, []config.CORSConf{
+		{Path: "/prest/public", AllowOrigin: []string{"http://schema.com"}},
+		{Path: "/prest/public/test", AllowOrigin: []string{"*"}, AllowMethods: []string{"GET", "PATCH"}, AllowHeaders: []string{"X-Custom"}},
+	}))
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) }))
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	var testCases = []struct {
+		description  string
+		method       string
+		url          string
+		origin       string
+		allowOrigin  string
+		allowMethods string
+	}{
+		{"table policy", "GET", "/prest/public/test", "http://any.com", "http://any.com", ""},
+		{"table policy preflight", "OPTIONS", "/prest/public/test", "http://any.com", "http://any.com", "PATCH"},
+		{"table policy method not allowed", "OPTIONS", "/prest/public/test", "http://any.com", "", ""},
+		{"schema policy", "GET", "/prest/public/test2", "http://schema.com", "http://schema.com", ""},
+		{"schema policy other origin", "GET", "/prest/public/test2", "http://any.com", "", ""},
+		{"default policy", "GET", "/databases", "http://default.com", "http://default.com", ""},
+		{"default policy other origin", "GET", "/databases", "http://schema.com", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest(tc.method, server.URL+tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", tc.origin)
+		if tc.method == "OPTIONS" {
+			method := tc.allowMethods
+			if method == "" {
+				method = "DELETE"
+			}
+			req.Header.Set("Access-Control-Request-Method", method)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Expected run without errors but was", err.Error())
+		}
+		if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != tc.allowOrigin {
+			t.Errorf("expected Access-Control-Allow-Origin %q, but got %q", tc.allowOrigin, origin)
+		}
+		if methods := resp.Header.Get("Access-Control-Allow-Methods"); methods != tc.allowMethods {
+			t.Errorf("expected Access-Control-Allow-Methods %q, but got %q", tc.allowMethods, methods)
+		}
+	}
+}
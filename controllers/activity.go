@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
+)
+
+// Activity list the queries running in the database, from pg_stat_activity
+func Activity(w http.ResponseWriter, r *http.Request) {
+	object, err := postgres.Activity()
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Activity", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// CancelActivity cancel the query running in the backend of the pid with pg_cancel_backend
+func CancelActivity(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform CancelActivity", err)
+		return
+	}
+
+	cancelled, err := postgres.CancelBackend(pid)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform pg_cancel_backend", err)
+		return
+	}
+	if !cancelled {
+		err = fmt.Errorf("no backend with pid %d", pid)
+		helpers.ErrorResponse(w, http.StatusNotFound, helpers.CodeNotFound, "could not perform CancelActivity", err)
+		return
+	}
+
+	object, err := json.Marshal(map[string]interface{}{"pid": pid, "cancelled": cancelled})
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform CancelActivity", err)
+		return
+	}
+	w.Write(object)
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestActivity(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_activity", Activity).Methods("GET")
+	router.HandleFunc("/_activity/{pid}", CancelActivity).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		status      int
+	}{
+		{"list the running queries", "/_activity", "GET", http.StatusOK},
+		{"cancel a backend that doesn't exist", "/_activity/1", "DELETE", http.StatusNotFound},
+		{"cancel an invalid pid", "/_activity/postgres", "DELETE", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, nil, tc.method, tc.status, "Activity")
+	}
+}
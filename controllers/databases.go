@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
 	"github.com/nuveo/prest/statements"
 )
 
@@ -12,20 +13,27 @@ import (
 func GetDatabases(w http.ResponseWriter, r *http.Request) {
 	requestWhere, values, err := postgres.WhereByRequest(r, 1)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
 
 	query, hasCount := postgres.DatabaseClause(r)
-	sqlDatabases := fmt.Sprint(query, statements.DatabasesWhere)
-
-	if requestWhere != "" {
-		sqlDatabases = fmt.Sprint(sqlDatabases, " AND ", requestWhere)
+	sqlDatabases := query
+
+	// template databases are hidden unless _templates=true
+	switch {
+	case r.URL.Query().Get("_templates") != "true":
+		sqlDatabases = fmt.Sprint(sqlDatabases, statements.DatabasesWhere)
+		if requestWhere != "" {
+			sqlDatabases = fmt.Sprint(sqlDatabases, " AND ", requestWhere)
+		}
+	case requestWhere != "":
+		sqlDatabases = fmt.Sprint(sqlDatabases, " WHERE ", requestWhere)
 	}
 
 	order, err := postgres.OrderByRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
 		return
 	}
 
@@ -37,14 +45,14 @@ func GetDatabases(w http.ResponseWriter, r *http.Request) {
 
 	page, err := postgres.PaginateIfPossible(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform PaginateIfPossible", err)
 		return
 	}
 
 	sqlDatabases = fmt.Sprint(sqlDatabases, " ", page)
-	object, err := postgres.Query(sqlDatabases, values...)
+	object, err := postgres.QueryContext(r.Context(), sqlDatabases, values...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Query", err)
 		return
 	}
 
@@ -21,6 +21,8 @@ func TestGetDatabases(t *testing.T) {
 		{"Get databases with custom order clause", "/databases?_order=datname", "GET", http.StatusOK},
 		{"Get databases with custom where clause and pagination", "/databases?datname=$eq.prest&_page=1&_page_size=20", "GET", http.StatusOK},
 		{"Get databases with COUNT clause", "/databases?_count=*", "GET", http.StatusOK},
+		{"Get databases including templates", "/databases?_templates=true", "GET", http.StatusOK},
+		{"Get databases including templates with custom where clause", "/databases?_templates=true&datname=$eq.template1", "GET", http.StatusOK},
 		{"Get databases with custom where invalid clause", "/databases?0datname=prest", "GET", http.StatusBadRequest},
 		{"Get databases with custom where and pagination invalid", "/databases?datname=$eq.prest&_page=A", "GET", http.StatusBadRequest},
 		{"Get databases with noexistent column", "/databases?datatata=$eq.test", "GET", http.StatusBadRequest},
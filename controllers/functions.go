@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
+)
+
+// ExecuteFunction perform a function call with the named arguments sent in the body
+func ExecuteFunction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	function := vars["function"]
+
+	sql, values, err := postgres.FunctionCallByRequest(r, database, schema, function)
+	if err != nil {
+		status, code := http.StatusBadRequest, helpers.CodeInvalidBody
+		if err == postgres.ErrFunctionNotFound {
+			status, code = http.StatusNotFound, helpers.CodeNotFound
+		}
+		helpers.ErrorResponse(w, status, code, "could not perform FunctionCallByRequest", err)
+		return
+	}
+
+	object, err := postgres.QueryContext(r.Context(), sql, values...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform function call", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// SelectFromFunction perform select in the rows of a table function called with the
+// arguments of the path, e.g. /{database}/{schema}/generate_series(1,10)
+func SelectFromFunction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	function := vars["function"]
+
+	where, values, err := postgres.WhereByRequest(r, 1)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
+		return
+	}
+
+	from, args, err := postgres.TableFunctionCall(r.Context(), database, schema, function, vars["arguments"], len(values)+1)
+	if err != nil {
+		status, code := http.StatusBadRequest, helpers.CodeInvalidParameter
+		if err == postgres.ErrFunctionNotFound {
+			status, code = http.StatusNotFound, helpers.CodeNotFound
+		}
+		helpers.ErrorResponse(w, status, code, "could not perform TableFunctionCall", err)
+		return
+	}
+	values = append(values, args...)
+
+	nullBehavior, err := postgres.NullBehaviorByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform NullBehaviorByRequest", err)
+		return
+	}
+
+	cols := postgres.FieldsPermissions(r, function, "read")
+	if len(cols) == 0 {
+		helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, "you don't have permission for this action, please check the permitted fields for this function", nil)
+		return
+	}
+
+	selectStr, err := postgres.SelectFields(cols)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform SelectFields", err)
+		return
+	}
+
+	sql := fmt.Sprintf("%s %s", selectStr, from)
+
+	countQuery, estimate, err := postgres.CountByRequest(r)
+	if err != nil || estimate {
+		if err == nil {
+			err = fmt.Errorf("_count=estimate can't be used with functions")
+		}
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidCount, "could not perform CountByRequest", err)
+		return
+	}
+	if countQuery != "" {
+		sql = fmt.Sprintf("%s %s", countQuery, from)
+	}
+
+	if where != "" {
+		sql = fmt.Sprint(sql, " WHERE ", where)
+	}
+
+	order, err := postgres.OrderByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
+		return
+	}
+	if order != "" {
+		sql = fmt.Sprintf("%s %s", sql, order)
+	}
+
+	page, err := postgres.PaginateIfPossible(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform PaginateIfPossible", err)
+		return
+	}
+	if page != "" {
+		sql = fmt.Sprintf("%s %s", sql, page)
+	}
+
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		writeDryRun(w, sql, values, err)
+		return
+	}
+
+	runQuery := postgres.QueryContext
+	if countQuery != "" {
+		runQuery = postgres.QueryCountContext
+	}
+
+	object, err := runQuery(r.Context(), sql, values...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform function call", err)
+		return
+	}
+
+	object, err = postgres.FormatNulls(object, nullBehavior)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform FormatNulls", err)
+		return
+	}
+
+	w.Write(object)
+}
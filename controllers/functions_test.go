@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestExecuteFunction(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/functions/{function}", ExecuteFunction).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"execute scalar function", "/prest/public/functions/test_add", map[string]interface{}{"a": 1, "b": 2}, http.StatusOK, "[{\"test_add\":3}]"},
+		{"execute scalar function with default argument", "/prest/public/functions/test_add", map[string]interface{}{"a": 1}, http.StatusOK, "[{\"test_add\":11}]"},
+		{"execute table function", "/prest/public/functions/test_categories_by_parent", map[string]interface{}{"parent": 1}, http.StatusOK, "[{\"id\":2,\"name\":\"fantasy\"}]"},
+		{"execute function without arguments", "/prest/public/functions/test_now", nil, http.StatusOK, ""},
+
+		// errors
+		{"execute function with unknown argument", "/prest/public/functions/test_add", map[string]interface{}{"c": 1}, http.StatusBadRequest, ""},
+		{"execute function with type mismatch", "/prest/public/functions/test_add", map[string]interface{}{"a": "one"}, http.StatusBadRequest, ""},
+		{"execute nonexistent function", "/prest/public/functions/test_nonexistent", nil, http.StatusNotFound, ""},
+		{"execute function with invalid name", "/prest/public/functions/0test_add", nil, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body != "" {
+			doRequest(t, server.URL+tc.url, tc.request, "POST", tc.status, "ExecuteFunction", tc.body)
+			continue
+		}
+		doRequest(t, server.URL+tc.url, tc.request, "POST", tc.status, "ExecuteFunction")
+	}
+}
+
+func TestSelectFromFunction(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{function:[^/()]+}({arguments:[^/]*})", SelectFromFunction).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+		body        string
+	}{
+		{"select from table function", "/prest/public/test_series(1,3)", http.StatusOK, "[{\"test_series\":1}, \n {\"test_series\":2}, \n {\"test_series\":3}]"},
+		{"select from table function with default argument", "/prest/public/test_series(2)", http.StatusOK, "[{\"test_series\":2}, \n {\"test_series\":3}]"},
+		{"select from table function with where and order", "/prest/public/test_series(1,5)?test_series=$gt.3&_order=-test_series", http.StatusOK, "[{\"test_series\":5}, \n {\"test_series\":4}]"},
+		{"select from table function with pagination", "/prest/public/test_series(1,5)?_page=2&_page_size=2", http.StatusOK, "[{\"test_series\":3}, \n {\"test_series\":4}]"},
+		{"count rows of table function", "/prest/public/test_series(1,5)?_count=*", http.StatusOK, `{"count":5}`},
+		{"select columns of table function", "/prest/public/test_categories_by_parent(1)?_select=name", http.StatusOK, "[{\"name\":\"fantasy\"}]"},
+
+		// errors
+		{"select from table function with too many arguments", "/prest/public/test_series(1,2,3)", http.StatusBadRequest, ""},
+		{"select from table function with invalid argument", "/prest/public/test_series(one)", http.StatusBadRequest, ""},
+		{"select from nonexistent function", "/prest/public/test_nonexistent()", http.StatusNotFound, ""},
+		{"select from function with invalid name", "/prest/public/0test_series(1)", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body != "" {
+			doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "SelectFromFunction", tc.body)
+			continue
+		}
+		doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "SelectFromFunction")
+	}
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+)
+
+// healthTimeout is the maximum time to wait the database ping
+const healthTimeout = 2 * time.Second
+
+var startTime = time.Now()
+
+// Health check the database connection, used by load balancers and orchestrators
+func Health(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	data := make(map[string]interface{})
+	data["status"] = "ok"
+	data["uptime"] = time.Since(startTime).String()
+
+	stats, err := postgres.Ping(healthTimeout)
+	if err != nil {
+		status = http.StatusServiceUnavailable
+		data["status"] = "unavailable"
+		data["error"] = err.Error()
+	} else {
+		data["pool"] = map[string]int{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		}
+		if config.PrestConf.StatementCacheSize > 0 {
+			data["statement_cache"] = postgres.StatementCache()
+		}
+	}
+
+	object, err := json.Marshal(data)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform Health", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(object)
+}
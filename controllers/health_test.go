@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHealth(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_health", Health).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	doRequest(t, server.URL+"/_health", nil, "GET", http.StatusOK, "Health")
+}
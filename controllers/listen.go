@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
+)
+
+// shutdownEvent is the last server-sent event of the streams ended by ShutdownListen
+const shutdownEvent = "event: shutdown\ndata: \n\n"
+
+// listenShutdown is closed by ShutdownListen to end the streams of Listen
+var (
+	listenShutdown     = make(chan struct{})
+	listenShutdownOnce sync.Once
+)
+
+// ShutdownListen end the streams of Listen with a shutdown event, so the clients can
+// reconnect to another server. It's registered with http.Server.RegisterOnShutdown,
+// the graceful shutdown would otherwise wait for the streams until its timeout
+func ShutdownListen() {
+	listenShutdownOnce.Do(func() {
+		close(listenShutdown)
+	})
+}
+
+// Listen stream the NOTIFY payloads of a channel as server-sent events until the
+// client disconnects or the server shuts down
+func Listen(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	channel := vars["channel"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "streaming is not supported", nil)
+		return
+	}
+
+	listener, err := postgres.Listen(database, channel)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform LISTEN", err)
+		return
+	}
+	defer listener.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-listenShutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		payload, err := listener.Wait(ctx)
+		if err != nil {
+			select {
+			case <-listenShutdown:
+				fmt.Fprint(w, shutdownEvent)
+				flusher.Flush()
+			default:
+			}
+			return
+		}
+		fmt.Fprint(w, sseEvent(payload))
+		flusher.Flush()
+	}
+}
+
+// sseEvent format a payload as a server-sent event, every line is a data field
+func sseEvent(payload string) string {
+	lines := strings.Split(payload, "\n")
+	return fmt.Sprintf("data: %s\n\n", strings.Join(lines, "\ndata: "))
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/adapters/postgres/connection"
+)
+
+func TestSSEEvent(t *testing.T) {
+	var testCases = []struct {
+		payload  string
+		expected string
+	}{
+		{`{"id":1}`, "data: {\"id\":1}\n\n"},
+		{"", "data: \n\n"},
+		{"line1\nline2", "data: line1\ndata: line2\n\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("payload %q", tc.payload)
+		if event := sseEvent(tc.payload); event != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, event)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/_listen/{channel}", Listen).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+	}{
+		{"listen with invalid channel", "/prest/_listen/0test", http.StatusBadRequest},
+		{"listen in other database", "/otherdb/_listen/test_events", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "Listen")
+	}
+
+	t.Log("stream notifications")
+	resp, err := http.Get(server.URL + "/prest/_listen/test_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %q", contentType)
+	}
+
+	db := connection.MustGet()
+	if _, err = db.Exec("SELECT pg_notify('test_events', 'hello')"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(resp.Body)
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		if line != "data: hello\n" {
+			t.Errorf("expected %q, got %q", "data: hello\n", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected a notification, got none")
+	}
+}
+
+func TestListenShutdown(t *testing.T) {
+	defer func() {
+		listenShutdown = make(chan struct{})
+		listenShutdownOnce = sync.Once{}
+	}()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/_listen/{channel}", Listen).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/prest/_listen/test_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	ShutdownListen()
+	ShutdownListen()
+
+	body := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	select {
+	case b := <-body:
+		if b != shutdownEvent {
+			t.Errorf("expected %q, got %q", shutdownEvent, b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected the stream to end, it's still open")
+	}
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+	"github.com/nuveo/prest/openapi"
+)
+
+// OpenAPI serve the OpenAPI document of the tables of the configured schemas
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	database := config.PrestConf.PGDatabase
+	columns, err := postgres.SchemasColumns(database, config.PrestConf.OpenAPISchemas)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform OpenAPI", err)
+		return
+	}
+
+	object, err := json.Marshal(openapi.Build(database, columns))
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform OpenAPI", err)
+		return
+	}
+
+	w.Write(object)
+}
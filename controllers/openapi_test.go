@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/openapi"
+)
+
+func TestOpenAPI(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_openapi.json", OpenAPI).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/_openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var doc openapi.Document
+	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Paths["/prest/public/test4"]; !ok {
+		t.Error("expected the path of public.test4")
+	}
+	if _, ok := doc.Components.Schemas["public.test4"].Properties["name"]; !ok {
+		t.Error("expected the column name in the schema of public.test4")
+	}
+}
@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
 	"github.com/nuveo/prest/statements"
 )
 
@@ -12,7 +16,7 @@ import (
 func GetSchemas(w http.ResponseWriter, r *http.Request) {
 	requestWhere, values, err := postgres.WhereByRequest(r, 1)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
 
@@ -24,7 +28,7 @@ func GetSchemas(w http.ResponseWriter, r *http.Request) {
 
 	order, err := postgres.OrderByRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
 		return
 	}
 
@@ -36,16 +40,75 @@ func GetSchemas(w http.ResponseWriter, r *http.Request) {
 
 	page, err := postgres.PaginateIfPossible(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform PaginateIfPossible", err)
 		return
 	}
 
 	sqlSchemas = fmt.Sprint(sqlSchemas, " ", page)
-	object, err := postgres.Query(sqlSchemas, values...)
+	object, err := postgres.QueryContext(r.Context(), sqlSchemas, values...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Query", err)
 		return
 	}
 
 	w.Write(object)
 }
+
+// CreateSchema create the schema named in the body
+func CreateSchema(w http.ResponseWriter, r *http.Request) {
+	database := mux.Vars(r)["database"]
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform CREATE SCHEMA", err)
+		return
+	}
+	defer r.Body.Close()
+
+	object, err := postgres.CreateSchema(r.Context(), database, body.Name)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform CREATE SCHEMA", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// DropSchema drop a schema, _cascade=true drop the objects in it too
+func DropSchema(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	name := vars["name"]
+	cascade := r.URL.Query().Get("_cascade") == "true"
+
+	object, err := postgres.DropSchema(r.Context(), database, name, cascade)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform DROP SCHEMA", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// ddlErrorStatus is the status and code of the errors of the schemas, tables and
+// columns definitions: the invalid names, types and databases are validation errors,
+// the names in use are conflicts and the missing objects are not found
+func ddlErrorStatus(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, postgres.ErrInvalidIdentifier), errors.Is(err, postgres.ErrDatabaseNotConnected):
+		return http.StatusBadRequest, helpers.CodeInvalidParameter
+	case errors.Is(err, postgres.ErrInvalidColumnType):
+		return http.StatusBadRequest, helpers.CodeInvalidColumn
+	case errors.Is(err, postgres.ErrTableWithoutColumns):
+		return http.StatusBadRequest, helpers.CodeInvalidBody
+	case errors.Is(err, postgres.ErrSchemaAlreadyExists), errors.Is(err, postgres.ErrTableAlreadyExists), errors.Is(err, postgres.ErrColumnAlreadyExists):
+		return http.StatusConflict, helpers.CodeConflict
+	case errors.Is(err, postgres.ErrSchemaNotFound), errors.Is(err, postgres.ErrMaterializedViewNotFound):
+		return http.StatusNotFound, helpers.CodeNotFound
+	}
+	return http.StatusBadRequest, helpers.CodeQueryFailed
+}
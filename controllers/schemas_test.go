@@ -22,8 +22,8 @@ func TestGetSchemas(t *testing.T) {
 		{"Get schemas with custom order clause", "/schemas?schema_name=$eq.public&_order=schema_name", "GET", http.StatusOK, "[{\"schema_name\":\"public\"}]"},
 		{"Get schemas with custom where clause and pagination", "/schemas?schema_name=$eq.public&_page=1&_page_size=20", "GET", http.StatusOK, "[{\"schema_name\":\"public\"}]"},
 		{"Get schemas with COUNT clause", "/schemas?_count=*", "GET", http.StatusOK, "[{\"count\":6}]"},
-		{"Get schemas with custom where invalid clause", "/schemas?0schema_name=$eq.public", "GET", http.StatusBadRequest, "invalid identifier: 0schema_name\n"},
-		{"Get schemas with noexistent column", "/schemas?schematame=$eq.test", "GET", http.StatusBadRequest, "pq: column \"schematame\" does not exist\n"},
+		{"Get schemas with custom where invalid clause", "/schemas?0schema_name=$eq.public", "GET", http.StatusBadRequest, `{"error":{"code":"INVALID_WHERE","message":"could not perform WhereByRequest","detail":"invalid identifier: 0schema_name"}}`},
+		{"Get schemas with noexistent column", "/schemas?schematame=$eq.test", "GET", http.StatusBadRequest, `{"error":{"code":"INVALID_COLUMN","message":"could not perform Query","detail":"pq: column \"schematame\" does not exist"}}`},
 	}
 
 	router := mux.NewRouter()
@@ -51,8 +51,8 @@ func TestVersionDependentGetSchemas(t *testing.T) {
 			"GET",
 			http.StatusBadRequest,
 			[]string{
-				"strconv.ParseInt: parsing \"A\": invalid syntax\n",
-				"strconv.Atoi: parsing \"A\": invalid syntax\n",
+				`{"error":{"code":"INVALID_PAGINATION","message":"could not perform PaginateIfPossible","detail":"strconv.ParseInt: parsing \"A\": invalid syntax"}}`,
+				`{"error":{"code":"INVALID_PAGINATION","message":"could not perform PaginateIfPossible","detail":"strconv.Atoi: parsing \"A\": invalid syntax"}}`,
 			},
 		},
 	}
@@ -68,3 +68,35 @@ func TestVersionDependentGetSchemas(t *testing.T) {
 	}
 
 }
+
+func TestCreateAndDropSchema(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/_schema", CreateSchema).Methods("POST")
+	router.HandleFunc("/{database}/_schema/{name}", DropSchema).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"Create schema", "/prest/_schema", "POST", map[string]interface{}{"name": "test_tenant"}, http.StatusOK, `{"schema":"test_tenant"}`},
+		{"Create schema already existing", "/prest/_schema", "POST", map[string]interface{}{"name": "test_tenant"}, http.StatusConflict, `{"error":{"code":"CONFLICT","message":"could not perform CREATE SCHEMA","detail":"schema already exists"}}`},
+		{"Create schema with invalid name", "/prest/_schema", "POST", map[string]interface{}{"name": "test; DROP SCHEMA public"}, http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform CREATE SCHEMA","detail":"Invalid identifier"}}`},
+		{"Create schema in other database", "/otherdb/_schema", "POST", map[string]interface{}{"name": "test_tenant"}, http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform CREATE SCHEMA","detail":"database is not the connected database"}}`},
+		{"Drop schema", "/prest/_schema/test_tenant", "DELETE", nil, http.StatusOK, `{"schema":"test_tenant"}`},
+		{"Drop schema not existing", "/prest/_schema/test_tenant", "DELETE", nil, http.StatusNotFound, `{"error":{"code":"NOT_FOUND","message":"could not perform DROP SCHEMA","detail":"schema not found"}}`},
+		{"Drop schema with invalid name", "/prest/_schema/0test_tenant", "DELETE", nil, http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform DROP SCHEMA","detail":"Invalid identifier"}}`},
+		{"Create schema to drop with cascade", "/prest/_schema", "POST", map[string]interface{}{"name": "test_tenant"}, http.StatusOK, `{"schema":"test_tenant"}`},
+		{"Drop schema with cascade", "/prest/_schema/test_tenant?_cascade=true", "DELETE", nil, http.StatusOK, `{"schema":"test_tenant"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, tc.method, tc.status, "CreateAndDropSchema", tc.body)
+	}
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
 )
 
 // ExecuteScriptQuery is a function to execute and return result of script query
@@ -22,7 +24,7 @@ func ExecuteScriptQuery(rq *http.Request, queriesPath string, script string) ([]
 		return nil, err
 	}
 
-	result, err := postgres.ExecuteScripts(rq.Method, sql, values)
+	result, err := postgres.ExecuteScripts(rq.Context(), rq.Method, sql, values)
 	if err != nil {
 		err = fmt.Errorf("could not execute sql %+v, %s", err, sql)
 		return nil, err
@@ -39,7 +41,28 @@ func ExecuteFromScripts(w http.ResponseWriter, r *http.Request) {
 
 	result, err := ExecuteScriptQuery(r, queriesPath, script)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform ExecuteScriptQuery", err)
+		return
+	}
+
+	w.Write(result)
+}
+
+// RawQuery is a controller to perform a SELECT sent in the body, enabled by enable_raw_query
+func RawQuery(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		SQL    string        `json:"sql"`
+		Params []interface{} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform RawQuery", err)
+		return
+	}
+	defer r.Body.Close()
+
+	result, err := postgres.RawQuery(r.Context(), body.SQL, body.Params...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform RawQuery", err)
 		return
 	}
 
@@ -118,3 +118,32 @@ func TestRenderWithXML(t *testing.T) {
 
 	}
 }
+
+func TestRawQuery(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_query", RawQuery).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"select with bound params", map[string]interface{}{"sql": "SELECT name FROM test2 WHERE number = $1", "params": []interface{}{2}}, http.StatusOK, `[{"name":"tester02"}]`},
+		{"select without rows", map[string]interface{}{"sql": "SELECT name FROM test2 WHERE name = $1", "params": []interface{}{"nobody"}}, http.StatusOK, `[]`},
+		{"delete", map[string]interface{}{"sql": "DELETE FROM test2"}, http.StatusBadRequest, `{"error":{"code":"QUERY_FAILED","message":"could not perform RawQuery","detail":"only a single SELECT statement is allowed"}}`},
+		{"multiple statements", map[string]interface{}{"sql": "SELECT 1; DELETE FROM test2"}, http.StatusBadRequest, `{"error":{"code":"QUERY_FAILED","message":"could not perform RawQuery","detail":"only a single SELECT statement is allowed"}}`},
+		{"data-modifying CTE", map[string]interface{}{"sql": "WITH d AS (DELETE FROM test2 RETURNING *) SELECT * FROM d"}, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body == "" {
+			doRequest(t, server.URL+"/_query", tc.request, "POST", tc.status, "RawQuery")
+			continue
+		}
+		doRequest(t, server.URL+"/_query", tc.request, "POST", tc.status, "RawQuery", tc.body)
+	}
+}
@@ -1,27 +1,35 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
 	"github.com/nuveo/prest/statements"
+	"github.com/nuveo/prest/webhooks"
 )
 
 // GetTables list all (or filter) tables
 func GetTables(w http.ResponseWriter, r *http.Request) {
 	requestWhere, values, err := postgres.WhereByRequest(r, 1)
 	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
 
 	order, err := postgres.OrderByRequest(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform OrderByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
 		return
 	}
 
@@ -39,9 +47,9 @@ func GetTables(w http.ResponseWriter, r *http.Request) {
 
 	sqlTables = fmt.Sprint(sqlTables, order)
 
-	object, err := postgres.Query(sqlTables, values...)
+	object, err := postgres.QueryReplicaInSearchPath(r.Context(), "", sqlTables, values...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Query", err)
 		return
 	}
 
@@ -56,8 +64,7 @@ func GetTablesByDatabaseAndSchema(w http.ResponseWriter, r *http.Request) {
 
 	requestWhere, values, err := postgres.WhereByRequest(r, 3)
 	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
 
@@ -71,8 +78,7 @@ func GetTablesByDatabaseAndSchema(w http.ResponseWriter, r *http.Request) {
 
 	order, err := postgres.OrderByRequest(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform OrderByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
 		return
 	}
 	if order != "" {
@@ -83,8 +89,7 @@ func GetTablesByDatabaseAndSchema(w http.ResponseWriter, r *http.Request) {
 
 	page, err := postgres.PaginateIfPossible(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform PaginateIfPossible: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform PaginateIfPossible", err)
 		return
 	}
 
@@ -95,9 +100,9 @@ func GetTablesByDatabaseAndSchema(w http.ResponseWriter, r *http.Request) {
 	valuesAux = append(valuesAux, schema)
 	valuesAux = append(valuesAux, values...)
 
-	object, err := postgres.Query(sqlSchemaTables, valuesAux...)
+	object, err := postgres.QueryReplicaInSearchPath(r.Context(), "", sqlSchemaTables, valuesAux...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Query", err)
 		return
 	}
 
@@ -106,56 +111,220 @@ func GetTablesByDatabaseAndSchema(w http.ResponseWriter, r *http.Request) {
 
 // SelectFromTables perform select in database
 func SelectFromTables(w http.ResponseWriter, r *http.Request) {
+	selectFromTables(w, r, false)
+}
+
+// SearchTable perform select in a table filtered by the filter tree of the body, the
+// query string parameters are the same of the selects
+func SearchTable(w http.ResponseWriter, r *http.Request) {
+	selectFromTables(w, r, true)
+}
+
+// selectFromTables perform the select, search add the filter tree of the body to the
+// filters of the query string
+func selectFromTables(w http.ResponseWriter, r *http.Request, search bool) {
 	vars := mux.Vars(r)
 	database := vars["database"]
 	schema := vars["schema"]
 	table := vars["table"]
 
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	if err := postgres.ResolveColumnsByRequest(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform ResolveColumnsByRequest", err)
+		return
+	}
+
 	// get selected columns, "*" if empty "_columns"
 	cols := postgres.FieldsPermissions(r, table, "read")
 
 	if len(cols) == 0 {
-		err := fmt.Errorf("you don't have permission for this action, please check the permitted fields for this table")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, "you don't have permission for this action, please check the permitted fields for this table", nil)
+		return
+	}
+
+	cols, err := postgres.ExcludeColumnsByRequest(r, database, schema, table, cols)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform ExcludeColumnsByRequest", err)
+		return
+	}
+
+	// the values of the select are the first parameters of the query
+	selectStr, selectValues, err := postgres.SelectFieldsWithValues(cols, 1)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform SelectFields", err)
+		return
+	}
+
+	if err = postgres.CheckSelectAggregates(r, cols); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform CheckSelectAggregates", err)
+		return
+	}
+
+	if err = postgres.CheckSelectExpressions(r.Context(), database, schema, table, cols); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform CheckSelectExpressions", err)
+		return
+	}
+
+	if err = postgres.CheckSelectJSONBFields(database, schema, table, cols); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidSelect, "could not perform CheckSelectJSONBFields", err)
+		return
+	}
+
+	embeds, err := postgres.EmbedByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform EmbedByRequest", err)
+		return
+	}
+	if len(embeds) > 0 {
+		embedColumns, err := postgres.EmbedColumns(database, schema, table, embeds)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform EmbedColumns", err)
+			return
+		}
+		selectStr = postgres.EmbedSelect(selectStr, embedColumns)
+	}
+
+	tz, err := postgres.TimeZoneByRequest(r, database, schema, table)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform TimeZoneByRequest", err)
+		return
+	}
+
+	if err = postgres.CheckDatePartFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckDatePartFilters", err)
+		return
+	}
+
+	if err = postgres.CheckFTSFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckFTSFilters", err)
+		return
+	}
+
+	requestWhere, whereValues, err := postgres.WhereByRequestInTimeZone(r, len(selectValues)+1, tz)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
+	values := append(selectValues, whereValues...)
+
+	if search {
+		searchWhere, searchValues, err := postgres.SearchWhereByRequest(r, schema, table, len(values)+1)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform SearchWhereByRequest", err)
+			return
+		}
+		if searchWhere != "" {
+			if requestWhere != "" {
+				requestWhere = fmt.Sprint(requestWhere, " AND ")
+			}
+			requestWhere = fmt.Sprint(requestWhere, searchWhere)
+			values = append(values, searchValues...)
+		}
+	}
 
-	selectStr, err := postgres.SelectFields(cols)
+	keyset, err := postgres.KeysetByRequest(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform KeysetByRequest", err)
 		return
 	}
+	if keyset != nil {
+		if keysetWhere, keysetValues := keyset.Where(len(values) + 1); keysetWhere != "" {
+			if requestWhere != "" {
+				requestWhere = fmt.Sprint(requestWhere, " AND ")
+			}
+			requestWhere = fmt.Sprint(requestWhere, keysetWhere)
+			values = append(values, keysetValues...)
+		}
+	}
 
-	query := fmt.Sprintf("%s %s.%s.%s", selectStr, database, schema, table)
+	from := fmt.Sprintf("%s.%s.%s", postgres.QuoteName(database), postgres.QuoteName(schema), postgres.QuoteName(table))
 
-	countQuery, err := postgres.CountByRequest(r)
+	recursiveQuery, err := postgres.RecursiveByRequest(r, from, requestWhere)
 	if err != nil {
-		err = fmt.Errorf("could not perform CountByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform RecursiveByRequest", err)
+		return
+	}
+	searchPath, err := postgres.SearchPathByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform SearchPathByRequest", err)
+		return
+	}
+	tableSample, sampleLimit, err := postgres.SampleByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform SampleByRequest", err)
+		return
+	}
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+	// every request runs in its own transaction, there's no transaction to keep the row locks
+	if r.URL.Query().Get("_lock") != "" {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform select", postgres.ErrRowLockTransaction)
+		return
+	}
+
+	if recursiveQuery != "" {
+		if tableSample != "" {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "_sample percentage can't be used with _recursive", nil)
+			return
+		}
+		// the request where filter the anchor rows inside the CTE
+		from = "tree"
+		requestWhere = ""
+	}
+	from = fmt.Sprint(from, tableSample)
+
+	query := fmt.Sprintf("%s %s", selectStr, from)
+
+	countQuery, estimate, err := postgres.CountByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidCount, "could not perform CountByRequest", err)
 		return
 	}
 	if countQuery != "" {
-		query = fmt.Sprintf("%s %s.%s.%s", countQuery, database, schema, table)
+		// the count replace the select and its values
+		if len(selectValues) > 0 {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidCount, "_count can't be used with the literals of a case, coalesce or string_agg in _select", nil)
+			return
+		}
+		query = fmt.Sprintf("%s %s", countQuery, from)
 	}
 
-	joinValues, err := postgres.JoinByRequest(r)
+	joins, joinValues, err := postgres.JoinByRequestWithValues(r, len(values)+1)
 	if err != nil {
-		err = fmt.Errorf("could not perform JoinByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidJoin, "could not perform JoinByRequest", err)
 		return
 	}
+	values = append(values, joinValues...)
 
-	for _, j := range joinValues {
+	for _, j := range joins {
 		query = fmt.Sprint(query, j)
 	}
 
-	requestWhere, values, err := postgres.WhereByRequest(r, 1)
-	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// the estimate is of the whole table, read from the statistics without a scan
+	if estimate {
+		if requestWhere != "" || len(joins) > 0 || recursiveQuery != "" || tableSample != "" || postgres.GroupByClause(r) != "" {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidCount, "_count=estimate can't be used with filters, _join, _groupby, _recursive or _sample", nil)
+			return
+		}
+		object, err := postgres.CountEstimate(r.Context(), schema, table)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform CountEstimate", err)
+			return
+		}
+		w.Write(object)
 		return
 	}
 
+	if recursiveQuery != "" {
+		query = fmt.Sprint(recursiveQuery, " ", query)
+	}
+
 	sqlSelect := query
 	if requestWhere != "" {
 		sqlSelect = fmt.Sprint(
@@ -164,7 +333,19 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 			requestWhere)
 	}
 
-	groupBySQL := postgres.GroupByClause(r)
+	groupBySQL, groupByValues, err := postgres.GroupByRequest(r, len(values)+1)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidGroupBy, "could not perform GroupByRequest", err)
+		return
+	}
+	values = append(values, groupByValues...)
+	havingSQL, havingValues, err := postgres.HavingByRequest(r, len(values)+1)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidGroupBy, "could not perform HavingByRequest", err)
+		return
+	}
+	values = append(values, havingValues...)
+	groupBySQL = postgres.AppendHaving(groupBySQL, havingSQL)
 
 	if groupBySQL != "" {
 		sqlSelect = fmt.Sprintf("%s %s", sqlSelect, groupBySQL)
@@ -172,33 +353,240 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 
 	order, err := postgres.OrderByRequest(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform OrderByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidOrder, "could not perform OrderByRequest", err)
 		return
 	}
+	if order == "" {
+		// the pages are only deterministic in a total order
+		order, err = postgres.DefaultOrderByRequest(r, database, schema, table, cols)
+		if err != nil {
+			status, code := http.StatusBadRequest, helpers.CodeInvalidOrder
+			if errors.Is(err, postgres.ErrPrimaryKeyOrder) {
+				status, code = http.StatusInternalServerError, helpers.CodeInternal
+			}
+			helpers.ErrorResponse(w, status, code, "could not perform DefaultOrderByRequest", err)
+			return
+		}
+	}
 	if order != "" {
 		sqlSelect = fmt.Sprintf("%s %s", sqlSelect, order)
 	}
 
+	if sampleLimit != "" {
+		sqlSelect = fmt.Sprintf("%s %s", sqlSelect, sampleLimit)
+	}
+
+	// the count of a grouped select is the count of its groups
+	if countQuery != "" && groupBySQL != "" {
+		sqlSelect = postgres.CountGroupsQuery(sqlSelect)
+	}
+
+	// HEAD answer only the count of rows, without pagination
+	if r.Method == "HEAD" {
+		count, err := postgres.CountRowsInSearchPath(r.Context(), searchPath, sqlSelect, values...)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform CountRows", err)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
+		return
+	}
+
+	single, err := postgres.SingleRowByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform SingleRowByRequest", err)
+		return
+	}
+
+	envelope, err := postgres.EnvelopeByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform EnvelopeByRequest", err)
+		return
+	}
+
+	nullBehavior, err := postgres.NullBehaviorByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform NullBehaviorByRequest", err)
+		return
+	}
+
+	keyBy, err := postgres.KeyByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform KeyByRequest", err)
+		return
+	}
+
 	page, err := postgres.PaginateIfPossible(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform PaginateIfPossible: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform PaginateIfPossible", err)
 		return
 	}
-	sqlSelect = fmt.Sprint(sqlSelect, " ", page)
 
-	runQuery := postgres.Query
-	if countQuery != "" {
-		runQuery = postgres.QueryCount
+	if postgres.CountOnlyByRequest(r) {
+		writeCountOnly(w, r, searchPath, sqlSelect, values, envelope)
+		return
+	}
+
+	if single {
+		page = "LIMIT 1"
+	}
+	if keyset != nil {
+		page = keyset.Limit()
+	}
+	// the envelope count the rows of the query before the page
+	if !envelope {
+		sqlSelect = fmt.Sprint(sqlSelect, " ", page)
+	}
+
+	explain, err := postgres.ExplainByRequest(r)
+	if err != nil {
+		status, code := http.StatusBadRequest, helpers.CodeInvalidParameter
+		if err == postgres.ErrExplainAnalyzeDisabled {
+			status, code = http.StatusForbidden, helpers.CodeForbidden
+		}
+		helpers.ErrorResponse(w, status, code, "could not perform ExplainByRequest", err)
+		return
+	}
+
+	runQuery := func(SQL string, params ...interface{}) ([]byte, error) {
+		if isolation != "" {
+			return postgres.QueryIsolated(r.Context(), isolation, searchPath, SQL, params...)
+		}
+		return postgres.QueryReplicaInSearchPath(r.Context(), searchPath, SQL, params...)
+	}
+	rowCap := 0
+	if explain != "" {
+		sqlSelect = fmt.Sprint(explain, sqlSelect)
+		runQuery = func(SQL string, params ...interface{}) ([]byte, error) {
+			return postgres.ExplainInSearchPath(r.Context(), searchPath, SQL, params...)
+		}
+	} else if countQuery != "" {
+		runQuery = func(SQL string, params ...interface{}) ([]byte, error) {
+			if isolation != "" {
+				return postgres.QueryCountIsolated(r.Context(), isolation, searchPath, SQL, params...)
+			}
+			return postgres.QueryCountReplicaInSearchPath(r.Context(), searchPath, SQL, params...)
+		}
+	} else if envelope {
+		pageNumber, pageSize, _ := postgres.PageByRequest(r)
+		runQuery = func(SQL string, params ...interface{}) ([]byte, error) {
+			if isolation != "" {
+				return postgres.QueryEnvelopeIsolated(r.Context(), isolation, searchPath, SQL, page, pageNumber, pageSize, params...)
+			}
+			return postgres.QueryEnvelopeInSearchPath(r.Context(), searchPath, SQL, page, pageNumber, pageSize, params...)
+		}
+	} else {
+		geometryColumn, err := postgres.GeometryColumnByRequest(r, database, schema, table)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform GeometryColumnByRequest", err)
+			return
+		}
+
+		if geometryColumn != "" {
+			w.Header().Set("Content-Type", postgres.GeoJSONContentType)
+			runQuery = func(SQL string, params ...interface{}) ([]byte, error) {
+				return postgres.QueryGeoJSONInSearchPath(r.Context(), searchPath, SQL, geometryColumn, params...)
+			}
+		} else if page == "" && sampleLimit == "" {
+			// one row more than the cap tell if the result was truncated
+			rowCap = postgres.RowCap(schema, table)
+			if rowCap > 0 {
+				sqlSelect = fmt.Sprintf("%s LIMIT %d", sqlSelect, rowCap+1)
+			}
+		}
+	}
+
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		if envelope {
+			sqlSelect = fmt.Sprintf(statements.Envelope, sqlSelect, sqlSelect, page)
+		}
+		writeDryRun(w, sqlSelect, values, err)
+		return
+	}
+
+	if config.PrestConf.QueryCostHeader && explain == "" {
+		// the query isn't failed by its estimate, the header is left out
+		cost, err := postgres.QueryCost(r.Context(), searchPath, sqlSelect, values...)
+		if err != nil {
+			log.Printf("could not perform QueryCost: %v\n", err)
+		} else {
+			w.Header().Set("X-Query-Cost", strconv.FormatFloat(cost, 'f', 2, 64))
+		}
 	}
 
 	object, err := runQuery(sqlSelect, values...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Query", err)
 		return
 	}
 
+	if rowCap > 0 {
+		var truncated bool
+		object, truncated, err = postgres.CapRows(object, rowCap)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform CapRows", err)
+			return
+		}
+		if truncated {
+			log.Printf("{SelectFromTables} %s result of %s.%s.%s truncated to %d rows\n", helpers.RequestID(r), database, schema, table, rowCap)
+			w.Header().Set("X-Result-Truncated", "true")
+		}
+	}
+
+	if keyset != nil {
+		next, err := keyset.NextCursor(object)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidPagination, "could not perform NextCursor", err)
+			return
+		}
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
+	}
+
+	// GeoJSON is a feature collection with the single feature
+	if single && w.Header().Get("Content-Type") != postgres.GeoJSONContentType {
+		object, err = firstRow(object)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform Query", err)
+			return
+		}
+		if object == nil {
+			err = fmt.Errorf("no row of %s.%s.%s matches", database, schema, table)
+			helpers.ErrorResponse(w, http.StatusNotFound, helpers.CodeNotFound, "row not found", err)
+			return
+		}
+	}
+
+	if w.Header().Get("Content-Type") != postgres.GeoJSONContentType {
+		formatNulls := postgres.FormatNulls
+		if envelope {
+			formatNulls = postgres.FormatEnvelopeNulls
+		}
+		object, err = formatNulls(object, nullBehavior)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform FormatNulls", err)
+			return
+		}
+	}
+
+	if keyBy != "" {
+		object, err = postgres.KeyRows(object, keyBy)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform KeyRows", err)
+			return
+		}
+	}
+
+	if postgres.XMLByRequest(r) && !envelope && w.Header().Get("Content-Type") != postgres.GeoJSONContentType {
+		object, err = postgres.RowsXML(object)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform RowsXML", err)
+			return
+		}
+		w.Header().Set("Content-Type", postgres.XMLContentType)
+	}
+
 	w.Write(object)
 }
 
@@ -209,22 +597,93 @@ func InsertInTables(w http.ResponseWriter, r *http.Request) {
 	schema := vars["schema"]
 	table := vars["table"]
 
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	if err := postgres.UUIDColumnsByRequest(r, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform UUIDColumnsByRequest", err)
+		return
+	}
+
 	names, placeholders, values, err := postgres.ParseInsertRequest(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform InsertInTables: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform InsertInTables", err)
 		return
 	}
 
-	sql := fmt.Sprintf(statements.InsertQuery, database, schema, table, names, placeholders)
+	sql := fmt.Sprintf(statements.InsertQuery, postgres.QuoteName(database), postgres.QuoteName(schema), postgres.QuoteName(table), names, placeholders)
 
-	object, err := postgres.Insert(sql, values...)
+	ifNotExists := postgres.IfNotExistsByRequest(r)
+	if ifNotExists {
+		where, whereValues, err := postgres.WhereByRequest(r, len(values)+1)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
+			return
+		}
+		if where == "" {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform InsertInTables", postgres.ErrIfNotExistsWhere)
+			return
+		}
+		casts, err := postgres.InsertIfNotExistsValues(schema, table, names)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform InsertIfNotExistsValues", err)
+			return
+		}
+		sql = postgres.InsertIfNotExistsQuery(database, schema, table, names, casts, where)
+		values = append(values, whereValues...)
+		if lock == nil {
+			lock = postgres.IfNotExistsLock(database, schema, table)
+		}
+	}
+
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		writeDryRun(w, sql, values, err)
+		return
+	}
+
+	var object []byte
+	created := true
+	if ifNotExists {
+		object, created, err = postgres.InsertIfNotExistsWithLock(r.Context(), lock, isolation, sql, values...)
+	} else {
+		object, err = postgres.InsertWithLock(r.Context(), lock, isolation, sql, values...)
+	}
 	if err != nil {
-		err = fmt.Errorf("could not perform InsertInTables: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform InsertInTables", err)
 		return
 	}
 
+	if ifNotExists {
+		w.Header().Set("X-Created", strconv.FormatBool(created))
+	}
+	if created {
+		webhooks.Dispatch(database, schema, table, webhooks.Insert, object)
+	}
+
+	pk, err := postgres.PrimaryKeyColumns(r.Context(), database, schema, table)
+	if err != nil {
+		log.Printf("{InsertInTables} %s %v\n", helpers.RequestID(r), err)
+	}
+	if location := insertLocation(r.URL.Path, pk, object); location != "" {
+		w.Header().Set("Location", location)
+	}
+	if created && config.PrestConf.InsertStatusCreated {
+		w.WriteHeader(http.StatusCreated)
+	}
+
 	w.Write(object)
 }
 
@@ -235,25 +694,67 @@ func DeleteFromTable(w http.ResponseWriter, r *http.Request) {
 	schema := vars["schema"]
 	table := vars["table"]
 
-	where, values, err := postgres.WhereByRequest(r, 1)
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	if err := postgres.ResolveColumnsByRequest(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform ResolveColumnsByRequest", err)
+		return
+	}
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	tz, err := postgres.TimeZoneByRequest(r, database, schema, table)
 	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform TimeZoneByRequest", err)
 		return
 	}
 
-	sql := fmt.Sprintf(statements.DeleteQuery, database, schema, table)
+	if err = postgres.CheckDatePartFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckDatePartFilters", err)
+		return
+	}
+
+	if err = postgres.CheckFTSFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckFTSFilters", err)
+		return
+	}
+
+	where, values, err := postgres.WhereByRequestInTimeZone(r, 1, tz)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
+		return
+	}
+
+	sql := fmt.Sprintf(statements.DeleteQuery, postgres.QuoteName(database), postgres.QuoteName(schema), postgres.QuoteName(table))
 	if where != "" {
 		sql = fmt.Sprint(sql, " WHERE ", where)
 	}
 
-	object, err := postgres.Delete(sql, values...)
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		writeDryRun(w, sql, values, err)
+		return
+	}
+
+	object, err := postgres.DeleteWithLock(r.Context(), lock, isolation, sql, values...)
 	if err != nil {
-		err = fmt.Errorf("could not perform DELETE: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform DELETE", err)
 		return
 	}
 
+	setAffectedRowsHeader(w, object)
+	dispatchWrite(database, schema, table, webhooks.Delete, object)
 	w.Write(object)
 }
 
@@ -264,37 +765,532 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 	schema := vars["schema"]
 	table := vars["table"]
 
-	where, whereValues, err := postgres.WhereByRequest(r, 1)
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	if err := postgres.ResolveColumnsByRequest(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform ResolveColumnsByRequest", err)
+		return
+	}
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
 	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	tz, err := postgres.TimeZoneByRequest(r, database, schema, table)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform TimeZoneByRequest", err)
+		return
+	}
+
+	if err = postgres.CheckDatePartFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckDatePartFilters", err)
+		return
+	}
+
+	if err = postgres.CheckFTSFilters(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform CheckFTSFilters", err)
+		return
+	}
+
+	if err = postgres.CheckMergeColumns(r, database, schema, table); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform CheckMergeColumns", err)
+		return
+	}
+
+	where, whereValues, err := postgres.WhereByRequestInTimeZone(r, 1, tz)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
 		return
 	}
 
 	pid := len(whereValues) + 1 // placeholder id
 
-	setSyntax, values, err := postgres.SetByRequest(r, pid)
+	setSyntax, versionWhere, values, err := postgres.SetVersionByRequest(r, pid)
 	if err != nil {
-		err = fmt.Errorf("could not perform UPDATE: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform UPDATE", err)
 		return
 	}
-	sql := fmt.Sprintf(statements.UpdateQuery, database, schema, table, setSyntax)
+	sql := fmt.Sprintf(statements.UpdateQuery, postgres.QuoteName(database), postgres.QuoteName(schema), postgres.QuoteName(table), setSyntax)
+
+	if versionWhere != "" {
+		if where != "" {
+			where = fmt.Sprint(where, " AND ")
+		}
+		where = fmt.Sprint(where, versionWhere)
+	}
+
+	changed := postgres.ChangedByRequest(r)
+	if changed {
+		sql = postgres.UpdateChangedQuery(database, schema, table, setSyntax, where)
+	}
 
 	if where != "" {
-		sql = fmt.Sprint(
-			sql,
-			" WHERE ",
-			where)
+		if !changed {
+			sql = fmt.Sprint(
+				sql,
+				" WHERE ",
+				where)
+		}
 		values = append(whereValues, values...)
 	}
 
-	object, err := postgres.Update(sql, values...)
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		writeDryRun(w, sql, values, err)
+		return
+	}
+
+	var object []byte
+	var rows int64
+	if changed {
+		object, rows, err = postgres.UpdateChangedWithLock(r.Context(), lock, isolation, sql, values...)
+	} else {
+		object, err = postgres.UpdateWithLock(r.Context(), lock, isolation, sql, values...)
+		rows, _ = affectedRows(object)
+	}
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform UPDATE", err)
+		return
+	}
+
+	if versionWhere != "" && rows == 0 {
+		err = fmt.Errorf("version conflict on %s", r.URL.Query().Get("_version"))
+		helpers.ErrorResponse(w, http.StatusConflict, helpers.CodeConflict, "could not perform UPDATE", err)
+		return
+	}
+
+	w.Header().Set("X-Affected-Rows", strconv.FormatInt(rows, 10))
+	if rows > 0 {
+		webhooks.Dispatch(database, schema, table, webhooks.Update, object)
+	}
+	w.Write(object)
+}
+
+// BulkUpdateTable perform many updates with different values in a single transaction
+func BulkUpdateTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	bulk, err := postgres.BulkUpdateByRequest(r, database, schema, table)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform BulkUpdateByRequest", err)
+		return
+	}
+
+	object, err := postgres.BulkUpdateWithLock(r.Context(), lock, isolation, bulk)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform bulk UPDATE", err)
+		return
+	}
+
+	setAffectedRowsHeader(w, object)
+	dispatchWrite(database, schema, table, webhooks.Update, object)
+	w.Write(object)
+}
+
+// BulkDeleteTable delete the rows with the ids of the body in a single DELETE
+func BulkDeleteTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	bulk, err := postgres.BulkDeleteByRequest(r, database, schema, table)
 	if err != nil {
-		err = fmt.Errorf("could not perform UPDATE: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform BulkDeleteByRequest", err)
 		return
 	}
 
+	if dryRun, err := postgres.DryRunByRequest(r); err != nil || dryRun {
+		writeDryRun(w, bulk.SQL, bulk.Values, err)
+		return
+	}
+
+	object, err := postgres.DeleteWithLock(r.Context(), lock, isolation, bulk.SQL, bulk.Values...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform bulk DELETE", err)
+		return
+	}
+
+	setAffectedRowsHeader(w, object)
+	dispatchWrite(database, schema, table, webhooks.Delete, object)
 	w.Write(object)
 }
+
+// CopyTable copy the rows of the `_from` table matching the filters to the table with
+// INSERT ... SELECT, without pulling them through the client. CSV and NDJSON bodies are
+// loaded by ingestTable
+func CopyTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	lock, err := postgres.LockByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform LockByRequest", err)
+		return
+	}
+
+	isolation, err := postgres.IsolationByRequest(r)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform IsolationByRequest", err)
+		return
+	}
+
+	if postgres.IngestByRequest(r) {
+		ingestTable(w, r, lock, isolation, database, schema, table)
+		return
+	}
+
+	sourceSchema, source, err := postgres.CopySourceByRequest(r, schema)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidParameter, "could not perform CopySourceByRequest", err)
+		return
+	}
+
+	if !tableFound(w, r, database, schema, table) || !tableFound(w, r, database, sourceSchema, source) {
+		return
+	}
+
+	if !postgres.TablePermissions(source, statements.READ) {
+		message := fmt.Sprintf("required authorization to table %s", source)
+		helpers.ErrorResponse(w, http.StatusUnauthorized, helpers.CodeUnauthorized, message, nil)
+		return
+	}
+
+	columns, err := postgres.CopyColumnsByRequest(r, sourceSchema, source, schema, table)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidColumn, "could not perform CopyColumnsByRequest", err)
+		return
+	}
+
+	where, values, err := postgres.WhereByRequest(r, 1)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidWhere, "could not perform WhereByRequest", err)
+		return
+	}
+
+	sql := postgres.CopyQuery(database, sourceSchema, source, schema, table, columns, where)
+	object, err := postgres.CopyWithLock(r.Context(), lock, isolation, sql, values...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform INSERT ... SELECT", err)
+		return
+	}
+
+	setAffectedRowsHeader(w, object)
+	w.Write(object)
+}
+
+// ingestTable load the CSV or NDJSON body of a POST to _copy in the table with COPY, in
+// a single transaction
+func ingestTable(w http.ResponseWriter, r *http.Request, lock *int64, isolation, database, schema, table string) {
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	object, err := postgres.Ingest(r.Context(), lock, isolation, schema, table, r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		code := helpers.CodeQueryFailed
+		switch {
+		case errors.Is(err, postgres.ErrIngestBody):
+			code = helpers.CodeInvalidBody
+		case errors.Is(err, postgres.ErrIngestColumn):
+			code = helpers.CodeInvalidColumn
+		}
+		helpers.ErrorResponse(w, http.StatusBadRequest, code, "could not perform COPY", err)
+		return
+	}
+
+	setAffectedRowsHeader(w, object)
+	w.Write(object)
+}
+
+// PrimaryKey list the primary key columns of a table
+func PrimaryKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	object, err := postgres.PrimaryKey(r.Context(), database, schema, table)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform PrimaryKey", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// Columns list the columns of a table, `_comments=true` add the comment of each column
+func Columns(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	comments := r.URL.Query().Get("_comments") == "true"
+	object, err := postgres.Columns(r.Context(), database, schema, table, comments)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform Columns", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// RefreshMaterializedView perform refresh materialized view
+func RefreshMaterializedView(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	view := vars["table"]
+
+	concurrently := r.URL.Query().Get("_concurrently") == "true"
+
+	object, err := postgres.RefreshMaterializedView(r.Context(), database, schema, view, concurrently)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform REFRESH MATERIALIZED VIEW", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// CreateTable create a table from the columns definition in the body
+func CreateTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+
+	var spec postgres.TableSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform CREATE TABLE", err)
+		return
+	}
+	defer r.Body.Close()
+
+	object, err := postgres.CreateTable(r.Context(), database, schema, spec)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform CREATE TABLE", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// TruncateTable remove all the rows of a table, enabled by enable_truncate
+func TruncateTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	if !tableFound(w, r, database, schema, table) {
+		return
+	}
+
+	restartIdentity := r.URL.Query().Get("_restart_identity") == "true"
+	cascade := r.URL.Query().Get("_cascade") == "true"
+
+	object, err := postgres.TruncateTable(r.Context(), database, schema, table, restartIdentity, cascade)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform TRUNCATE TABLE", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// AddColumn add the column defined in the body to a table
+func AddColumn(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	table := vars["table"]
+
+	var column postgres.ColumnSpec
+	if err := json.NewDecoder(r.Body).Decode(&column); err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not perform ADD COLUMN", err)
+		return
+	}
+	defer r.Body.Close()
+
+	object, err := postgres.AddColumn(r.Context(), database, schema, table, column)
+	if err != nil {
+		status, code := ddlErrorStatus(err)
+		helpers.ErrorResponse(w, status, code, "could not perform ADD COLUMN", err)
+		return
+	}
+
+	w.Write(object)
+}
+
+// tableFound write the error response and return false if the table doesn't exist,
+// so missing tables are 404 instead of a query error
+func tableFound(w http.ResponseWriter, r *http.Request, database, schema, table string) bool {
+	exists, err := postgres.TableExists(r.Context(), database, schema, table)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform TableExists", err)
+		return false
+	}
+	if !exists {
+		err = fmt.Errorf("%s.%s.%s does not exist", database, schema, table)
+		helpers.ErrorResponse(w, http.StatusNotFound, helpers.CodeNotFound, postgres.ErrTableNotFound.Error(), err)
+		return false
+	}
+	return true
+}
+
+// affectedRows read the rows_affected of write responses
+func affectedRows(object []byte) (rows int64, ok bool) {
+	var result struct {
+		RowsAffected *int64 `json:"rows_affected"`
+	}
+	if err := json.Unmarshal(object, &result); err != nil || result.RowsAffected == nil {
+		return
+	}
+	return *result.RowsAffected, true
+}
+
+// setAffectedRowsHeader expose the rows_affected of write responses in X-Affected-Rows header
+func setAffectedRowsHeader(w http.ResponseWriter, object []byte) {
+	if rows, ok := affectedRows(object); ok {
+		w.Header().Set("X-Affected-Rows", strconv.FormatInt(rows, 10))
+	}
+}
+
+// insertLocation is the URL filtering the inserted row by its primary key, e.g.
+// /prest/public/test?id=$eq.42, it's empty without primary key
+func insertLocation(path string, pk []string, object []byte) string {
+	if len(pk) == 0 {
+		return ""
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return ""
+	}
+
+	filters := make([]string, 0, len(pk))
+	for _, column := range pk {
+		value, ok := row[column]
+		if !ok || value == nil {
+			return ""
+		}
+		filters = append(filters, fmt.Sprintf("%s=$eq.%s", column, url.QueryEscape(fmt.Sprint(value))))
+	}
+	return fmt.Sprintf("%s?%s", path, strings.Join(filters, "&"))
+}
+
+// firstRow unwrap the row of a single row select, nil when no row matches
+func firstRow(object []byte) (row []byte, err error) {
+	var rows []json.RawMessage
+	if err = json.Unmarshal(object, &rows); err != nil || len(rows) == 0 {
+		return
+	}
+	row = rows[0]
+	return
+}
+
+// writeDryRun answer the statement of a `_dryrun` request, err is the error of
+// postgres.DryRunByRequest
+func writeDryRun(w http.ResponseWriter, sql string, values []interface{}, err error) {
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, "could not perform DryRunByRequest", err)
+		return
+	}
+	object, err := postgres.DryRun(sql, values)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform DryRun", err)
+		return
+	}
+	w.Write(object)
+}
+
+// writeCountOnly answer `_page_size=0` with no rows and the count of the rows of the
+// query in X-Total-Count, and in the meta of the envelope
+func writeCountOnly(w http.ResponseWriter, r *http.Request, searchPath, sqlSelect string, values []interface{}, envelope bool) {
+	count, err := postgres.CountRowsInSearchPath(r.Context(), searchPath, sqlSelect, values...)
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeQueryFailed, "could not perform CountRows", err)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
+
+	if !envelope {
+		w.Write([]byte("[]"))
+		return
+	}
+	pageNumber, _, _ := postgres.PageByRequest(r)
+	object, err := json.Marshal(postgres.Envelope{
+		Data: json.RawMessage("[]"),
+		Meta: postgres.EnvelopeMeta{Page: pageNumber, Total: count},
+	})
+	if err != nil {
+		helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform json.Marshal", err)
+		return
+	}
+	w.Write(object)
+}
+
+// dispatchWrite notify the webhooks of updates and deletes that affected rows
+func dispatchWrite(database, schema, table, operation string, object []byte) {
+	if rows, ok := affectedRows(object); ok && rows == 0 {
+		return
+	}
+	webhooks.Dispatch(database, schema, table, operation, object)
+}
@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/nuveo/prest/config"
 )
 
 func TestGetTables(t *testing.T) {
@@ -88,9 +94,49 @@ func TestSelectFromTables(t *testing.T) {
 		{"execute select in a table with custom where clause and pagination", "/prest/public/test?name=$eq.nuveo&_page=1&_page_size=20", "GET", http.StatusOK, ""},
 		{"execute select in a table with select fields", "/prest/public/test5?_select=celphone,name", "GET", http.StatusOK, ""},
 		{"execute select in a table with select *", "/prest/public/test5?_select=*", "GET", http.StatusOK, ""},
+		{"execute select in a table keeping the select columns order", "/prest/public/testarray?_select=data,id", "GET", http.StatusOK, "[{\"data\":[\"Gohan\",\"Goten\"],\"id\":100}]"},
+		{"execute select in a table with select fields with alias", "/prest/public/testarray?_select=id:as:code", "GET", http.StatusOK, "[{\"code\":100}]"},
 
 		{"execute select in a table with group by clause", "/prest/public/test_group_by_table?_select=age,sum:salary&_groupby=age", "GET", http.StatusOK, "[{\"age\":20,\"sum\":1350}, \n {\"age\":19,\"sum\":7997}]"},
 		{"Execute select in a table with group by and having clause", "/prest/public/test_group_by_table?_select=age,sum:salary&_groupby=age->>having:sum:salary:$gt:3000", "GET", http.StatusOK, "[{\"age\":19,\"sum\":7997}]"},
+		{"execute select in a table with count of groups", "/prest/public/test_group_by_table?_groupby=age&_count=*", "GET", http.StatusOK, "{\"count\":2}"},
+		{"execute select in a table with count of groups with having clause", "/prest/public/test_group_by_table?_groupby=age->>having:sum:salary:$gt:3000&_count=*", "GET", http.StatusOK, "{\"count\":1}"},
+		{"execute select in a table with group by and multiple having", "/prest/public/test_group_by_table?_select=age,sum:salary&_groupby=age&_having=sum:salary:$gt:1000&_having=max:salary:$lt:3999", "GET", http.StatusOK, "[{\"age\":20,\"sum\":1350}]"},
+		{"execute select in a table keyed by a column", "/prest/public/test_group_by_table?_select=name,salary&age=19&_keyby=name&_order=name", "GET", http.StatusOK, "{\"guitarra humana\":{\"name\":\"guitarra humana\",\"salary\":3998}, \n \"maria\":{\"name\":\"maria\",\"salary\":3999}}"},
+		{"execute select in a table keyed by a column with duplicated values", "/prest/public/test_group_by_table?_keyby=age", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table keyed by a column not selected", "/prest/public/test_group_by_table?_select=name&_keyby=age", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with a case", "/prest/public/test_group_by_table?_select=name,case:salary:$gt:1000:high:low:as:tier&age=20&_order=name", "GET", http.StatusOK, "[{\"name\":\"gopher\",\"tier\":\"low\"}, \n {\"name\":\"joao\",\"tier\":\"high\"}]"},
+		{"execute select in a table with a case without alias", "/prest/public/test_group_by_table?_select=name,case:salary:$gt:1000:high:low", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with array_agg", "/prest/public/test_tags?_select=user_id,array_agg:tag:as:tags&_groupby=user_id&_order=user_id", "GET", http.StatusOK, "[{\"user_id\":1,\"tags\":[\"go\",\"sql\"]}, \n {\"user_id\":2,\"tags\":[\"rest\"]}]"},
+		{"execute select in a table with string_agg", "/prest/public/test_tags?_select=user_id,string_agg:tag:,:as:tags&_groupby=user_id&_order=user_id", "GET", http.StatusOK, "[{\"user_id\":1,\"tags\":\"go,sql\"}, \n {\"user_id\":2,\"tags\":\"rest\"}]"},
+		{"execute select in a table with string_agg without _groupby", "/prest/public/test_tags?_select=user_id,string_agg:tag:%3B", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with string_agg without delimiter", "/prest/public/test_tags?_select=string_agg:tag", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with coalesce", "/prest/public/test_nicknames?_select=coalesce:nickname:name:as:display&_order=id", "GET", http.StatusOK, "[{\"display\":\"ana\"}, \n {\"display\":\"Bruno\"}]"},
+		{"execute select in a table with coalesce of a literal", "/prest/public/test_nicknames?_select=name,coalesce:nickname:'anonymous':as:display&_order=id", "GET", http.StatusOK, "[{\"name\":\"Ana\",\"display\":\"ana\"}, \n {\"name\":\"Bruno\",\"display\":\"anonymous\"}]"},
+		{"execute select in a table with coalesce without alias", "/prest/public/test_nicknames?_select=coalesce:nickname:name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with coalesce of a column without permission", "/prest/public/test_nicknames?_select=coalesce:age:0:as:age", "GET", http.StatusForbidden, ""},
+		{"execute select in a table with a case and a count", "/prest/public/test_group_by_table?_select=case:salary:$gt:1000:high:low:as:tier&_count=*", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with an aggregate without group by", "/prest/public/test_group_by_table?_select=sum:salary", "GET", http.StatusOK, "[{\"sum\":9347}]"},
+		{"execute select in a table with count and sum without group by", "/prest/public/test_group_by_table?_select=count:*,sum:salary&age=20", "GET", http.StatusOK, "[{\"count\":2,\"sum\":1350}]"},
+		{"execute select in a table with an aggregate and a column without group by", "/prest/public/test_group_by_table?_select=name,sum:salary", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table embedding the referencing rows", "/prest/public/test_embed_orders?id=1&_embed=test_embed_items", "GET", http.StatusOK, "[{\"id\":1,\"customer\":\"prest\",\"test_embed_items\":[{\"id\":1,\"order_id\":1,\"name\":\"pen\"}, \n {\"id\":2,\"order_id\":1,\"name\":\"book\"}]}]"},
+		{"execute select in a table embedding no referencing rows", "/prest/public/test_embed_orders?id=2&_embed=test_embed_items", "GET", http.StatusOK, "[{\"id\":2,\"customer\":\"nuveo\",\"test_embed_items\":[]}]"},
+		{"execute select in a table embedding the referenced row", "/prest/public/test_embed_items?id=2&_select=name&_embed=test_embed_orders", "GET", http.StatusOK, "[{\"name\":\"book\",\"test_embed_orders\":{\"id\":1,\"customer\":\"prest\"}}]"},
+		{"execute select in a table embedding a table without foreign key", "/prest/public/test_embed_orders?_embed=test_group_by_table", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table embedding with a count", "/prest/public/test_embed_orders?_embed=test_embed_items&_count=*", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table locking the rows for update", "/prest/public/test_group_by_table?_lock=update", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with an advisory lock", "/prest/public/test_group_by_table?_lock=42", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with having without group by", "/prest/public/test_group_by_table?_having=sum:salary:$gt:1000", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with count of a column of groups", "/prest/public/test_group_by_table?_groupby=age&_count=name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with group by rollup", "/prest/public/test_group_by_table?_select=age,sum:salary&_groupby=rollup:age&_order=age:nullslast", "GET", http.StatusOK, "[{\"age\":19,\"sum\":7997}, \n {\"age\":20,\"sum\":1350}, \n {\"age\":null,\"sum\":9347}]"},
+
+		{"execute select in a table with recursive clause", "/prest/public/test_categories?_recursive=parent_id:id&_select=name&_order=name", "GET", http.StatusOK, "[{\"name\":\"books\"}, \n {\"name\":\"fantasy\"}, \n {\"name\":\"tolkien\"}]"},
+		{"execute select in a table with recursive clause and anchor where", "/prest/public/test_categories?_recursive=parent_id:id&name=$eq.fantasy&_count=*", "GET", http.StatusOK, "{\"count\":2}"},
+
+		{"execute select in a table with sample rows", "/prest/public/test_categories?_sample=2", "GET", http.StatusOK, ""},
+		{"execute select in a table with sample percentage", "/prest/public/test_categories?_sample=100%25&_count=*", "GET", http.StatusOK, "{\"count\":3}"},
+		{"execute select in a table with serializable isolation", "/prest/public/test_categories?_isolation=serializable&_count=*", "GET", http.StatusOK, "{\"count\":3}"},
+		{"execute select in a table with invalid isolation", "/prest/public/test_categories?_isolation=snapshot", "GET", http.StatusBadRequest, ""},
 
 		{"execute select in a view without custom where clause", "/prest/public/view_test", "GET", http.StatusOK, ""},
 		{"execute select in a view with count all fields *", "/prest/public/view_test?_count=*", "GET", http.StatusOK, ""},
@@ -103,15 +149,69 @@ func TestSelectFromTables(t *testing.T) {
 
 		// errors
 		{"execute select in a table with invalid join clause", "/prest/public/test?_join=inner:test2:test2.name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with multiple join clauses", "/prest/public/test?_select=test.name&_join=inner:test8:test8.nameforjoin:$eq:test.name&_join=inner:test2:test2.name:$eq:test.name", "GET", http.StatusOK, ""},
+		{"execute select in a table with more joins than max_joins", "/prest/public/test?_join=inner:test2:test2.id:$eq:test.id&_join=inner:test3:test3.id:$eq:test.id&_join=inner:test4:test4.id:$eq:test.id&_join=inner:test5:test5.id:$eq:test.id&_join=inner:test6:test6.id:$eq:test.id&_join=inner:test7:test7.id:$eq:test.id", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid where clause", "/prest/public/test?0name=$eq.nuveo", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with order clause and column invalid", "/prest/public/test?_order=0name", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid pagination clause", "/prest/public/test?name=$eq.nuveo&_page=A", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid where clause", "/prest/public/test?0name=$eq.nuveo", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid count clause", "/prest/public/test?_count=0name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with count distinct", "/prest/public/test_categories?_count=distinct:name", "GET", http.StatusOK, "{\"count\":3}"},
+		{"execute select in a table with count distinct of a nonexistent column", "/prest/public/test_categories?_count=distinct:email", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with count estimate", "/prest/public/test?_count=estimate", "GET", http.StatusOK, ""},
+		{"execute select in a table with count estimate and filter", "/prest/public/test?_count=estimate&name=$eq.prest", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid order clause", "/prest/public/test?_order=0name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with invalid recursive clause", "/prest/public/test_categories?_recursive=parent_id", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with sample and order", "/prest/public/test?_sample=2&_order=name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with many orders in mixed directions", "/prest/public/test_categories?_select=name&_order=-parent_id:nullslast,name", "GET", http.StatusOK, "[{\"name\":\"tolkien\"}, \n {\"name\":\"fantasy\"}, \n {\"name\":\"books\"}]"},
+		{"execute select in a table with nonexistent column in order", "/prest/public/test_categories?_order=name,-nonexistent", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with invalid column in order", "/prest/public/test_categories?_order=name,-0parent_id", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with timestamptz filter in a time zone", "/prest/public/test_timestamps?_select=id&created_at=$gt.2023-01-01&_tz=America/Sao_Paulo", "GET", http.StatusOK, "[{\"id\":2}]"},
+		{"execute select in a table with timestamp filter in a time zone", "/prest/public/test_timestamps?_select=id&updated_at=$gt.2023-01-01%2001:00&_tz=America/Sao_Paulo&_order=id", "GET", http.StatusOK, "[{\"id\":1}, \n {\"id\":2}]"},
+		{"execute select in a table with invalid time zone", "/prest/public/test_timestamps?created_at=$gt.2023-01-01&_tz=UTC'", "GET", http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform TimeZoneByRequest","detail":"invalid time zone"}}`},
+		{"execute select in a table with computed column", "/prest/public/test_group_by_table?_select=name,salary*12%2B100:as:yearly&name=$eq.gopher", "GET", http.StatusOK, "[{\"name\":\"gopher\",\"yearly\":1300}]"},
+		{"execute select in a table with repeated filter", "/prest/public/test_categories?_select=name&name=$eq.books&name=$eq.tolkien&_order=name", "GET", http.StatusOK, "[{\"name\":\"books\"}, \n {\"name\":\"tolkien\"}]"},
+		{"execute select in a table with repeated range filter", "/prest/public/test_categories?_select=name&id=$gt.1&id=$lt.3", "GET", http.StatusOK, "[{\"name\":\"fantasy\"}]"},
+		{"execute select in a table with date part filter", "/prest/public/test_timestamps?_select=id&updated_at=$year.2023&_order=id", "GET", http.StatusOK, "[{\"id\":1}, \n {\"id\":2}]"},
+		{"execute select in a table with date part filter without match", "/prest/public/test_timestamps?_select=id&created_at=$month.3", "GET", http.StatusOK, "[]"},
+		{"execute select in a table with date part filter of a text column", "/prest/public/test_categories?name=$month.3", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with full text search", "/prest/public/test_articles?_select=title&search_vector=$fts.database+server", "GET", http.StatusOK, "[{\"title\":\"postgres\"}]"},
+		{"execute select in a table with full text search operators", "/prest/public/test_articles?_select=title&search_vector=$fts.postgres%20%7C%20mysql&_order=title", "GET", http.StatusOK, "[{\"title\":\"mysql\"}, \n {\"title\":\"postgres\"}]"},
+		{"execute select in a table with full text search of a text column", "/prest/public/test_articles?title=$fts.postgres", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with computed column of other table", "/prest/public/test_group_by_table?_select=price*2:as:double", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with jsonb key", "/prest/public/test_jsonb_bug?_select=name,data->>techniques:as:techniques&name=$eq.goku", "GET", http.StatusOK, "[{\"name\":\"goku\",\"techniques\":\"[\\\"kamehameha\\\", \\\"kaioken\\\", \\\"genki-dama\\\"]\"}]"},
+		{"execute select in a table with key of a column that isn't jsonb", "/prest/public/test_jsonb_bug?_select=name->>first:as:first", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with computed column without alias", "/prest/public/test_group_by_table?_select=salary*2", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with join in the search path", "/prest/public/test?_select=test.name&_join=inner:test_search_names:test_search_names.name:$eq:test.name&_search_path=test_search,public", "GET", http.StatusOK, "[{\"name\":\"tester02\"}]"},
+		{"execute select in a table with join on a derived table", "/prest/public/test_categories?_select=test_categories.name,children.total&test_categories.name=$ne.tolkien&_join=inner:test_categories@children:children.parent_id:$eq:test_categories.id&_join.children._select=parent_id,count(id):as:total&_join.children._groupby=parent_id&_join.children.name=$ne.none&_order=test_categories.name", "GET", http.StatusOK, "[{\"name\":\"books\",\"total\":1}, \n {\"name\":\"fantasy\",\"total\":1}]"},
+		{"execute select in a table with join on a derived table with invalid alias", "/prest/public/test_categories?_join=inner:test_categories@0children:children.parent_id:$eq:test_categories.id", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with join out of the search path", "/prest/public/test?_select=test.name&_join=inner:test_search_names:test_search_names.name:$eq:test.name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with invalid search path", "/prest/public/test?_search_path=public%3Bdrop", "GET", http.StatusBadRequest, ""},
+		{"execute select of the first row", "/prest/public/test_categories?_select=name&_order=name&_first=true", "GET", http.StatusOK, "{\"name\":\"books\"}"},
+		{"execute select of the last row", "/prest/public/test_categories?_select=name&_order=name&_last=true", "GET", http.StatusOK, "{\"name\":\"tolkien\"}"},
+		{"execute select of the first row without match", "/prest/public/test_categories?_order=name&_first=true&name=$eq.none", "GET", http.StatusNotFound, ""},
+		{"execute select in an envelope", "/prest/public/test_categories?_select=name&_order=name&_envelope=true&_page=1&_page_size=2", "GET", http.StatusOK, "{\"data\":[{\"name\":\"books\"},{\"name\":\"fantasy\"}],\"meta\":{\"page\":1,\"page_size\":2,\"total\":3}}"},
+		{"execute select in an envelope after the last page", "/prest/public/test_categories?_select=name&_envelope=true&_page=5&_page_size=2", "GET", http.StatusOK, "{\"data\":[],\"meta\":{\"page\":5,\"page_size\":2,\"total\":3}}"},
+		{"execute select in an envelope with count", "/prest/public/test_categories?_envelope=true&_count=*", "GET", http.StatusBadRequest, ""},
+		{"execute select with page size zero", "/prest/public/test_categories?_page_size=0", "GET", http.StatusOK, "[]"},
+		{"execute select with page size zero in an envelope", "/prest/public/test_categories?parent_id=$eq.1&_envelope=true&_page=1&_page_size=0", "GET", http.StatusOK, "{\"data\":[],\"meta\":{\"page\":1,\"total\":1}}"},
+		{"execute select of the first row without order", "/prest/public/test_categories?_first=true", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table excluding columns", "/prest/public/test_categories?_exclude=id,parent_id&_order=name", "GET", http.StatusOK, "[{\"name\":\"books\"}, \n {\"name\":\"fantasy\"}, \n {\"name\":\"tolkien\"}]"},
+		{"execute select in a table excluding a nonexistent column", "/prest/public/test_categories?_exclude=id,password", "GET", http.StatusBadRequest, `{"error":{"code":"INVALID_SELECT","message":"could not perform ExcludeColumnsByRequest","detail":"column password does not exist"}}`},
+		{"execute select in a table excluding columns with select", "/prest/public/test_categories?_exclude=id&_select=name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table excluding all columns", "/prest/public/test_categories?_exclude=id,name,parent_id", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with order nulls last", "/prest/public/test_categories?_select=name&_order=-parent_id:nullslast", "GET", http.StatusOK, "[{\"name\":\"tolkien\"}, \n {\"name\":\"fantasy\"}, \n {\"name\":\"books\"}]"},
+		{"execute select in a table with order nulls first", "/prest/public/test_categories?_select=name&_order=-parent_id:nullsfirst&_page=1&_page_size=1", "GET", http.StatusOK, "[{\"name\":\"books\"}]"},
+		{"execute select in a table with invalid order nulls", "/prest/public/test_categories?_order=parent_id:nullsnever", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with explain", "/prest/public/test?name=$eq.nuveo&_explain=true", "GET", http.StatusOK, ""},
+		{"execute select in a table with explain analyze disabled", "/prest/public/test?_explain=analyze", "GET", http.StatusForbidden, `{"error":{"code":"FORBIDDEN","message":"could not perform ExplainByRequest","detail":"_explain=analyze is disabled"}}`},
+		{"execute select in a table with invalid explain", "/prest/public/test?_explain=verbose", "GET", http.StatusBadRequest, ""},
+		{"execute select in a nonexistent table", "/prest/public/test_nonexistent", "GET", http.StatusNotFound, `{"error":{"code":"NOT_FOUND","message":"table not found","detail":"prest.public.test_nonexistent does not exist"}}`},
 		{"execute select in a table with invalid fields using group by clause", "/prest/public/test_group_by_table?_select=pa,sum:pum&_groupby=pa", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with invalid group by rollup column", "/prest/public/test_group_by_table?_select=age,sum:salary&_groupby=rollup:age,0salary", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid fields using group by and having clause", "/prest/public/test_group_by_table?_select=pa,sum:pum&_groupby=pa->>having:sum:pmu:$eq:150", "GET", http.StatusBadRequest, ""},
 
+		{"execute select in a table with invalid alias", "/prest/public/test5?_select=name:as:0name", "GET", http.StatusBadRequest, ""},
 		{"execute select in a view with an other column", "/prest/public/view_test?_select=celphone", "GET", http.StatusBadRequest, ""},
 		{"execute select in a view with where and column invalid", "/prest/public/view_test?0celphone=$eq.888888", "GET", http.StatusBadRequest, ""},
 		{"execute select in a view with custom join clause invalid", "/prest/public/view_test?_join=inner:test2.name:eq:view_test.player", "GET", http.StatusBadRequest, ""},
@@ -130,6 +230,40 @@ func TestSelectFromTables(t *testing.T) {
 	}
 }
 
+func TestSelectFromTablesGeoJSON(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+	}{
+		{"execute select as GeoJSON in a table without geometry column", "/prest/public/test", http.StatusBadRequest},
+		{"execute select as GeoJSON in a table with invalid geometry hint", "/prest/public/test?_geom=name", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("GET", server.URL+tc.url, nil)
+		if err != nil {
+			t.Error("error on New Request", err)
+		}
+		req.Header.Set("Accept", "application/geo+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error("error on Do Request", err)
+		}
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %d, got: %d", tc.status, resp.StatusCode)
+		}
+	}
+}
+
 func TestInsertInTables(t *testing.T) {
 	m := make(map[string]interface{})
 	m["name"] = "prest"
@@ -158,7 +292,17 @@ func TestInsertInTables(t *testing.T) {
 		{"execute insert in a table with invalid database", "/0prest/public/test", m, http.StatusBadRequest},
 		{"execute insert in a table with invalid schema", "/prest/0public/test", m, http.StatusBadRequest},
 		{"execute insert in a table with invalid table", "/prest/public/0test", m, http.StatusBadRequest},
+		{"execute insert in a nonexistent table", "/prest/public/test_nonexistent", m, http.StatusNotFound},
 		{"execute insert in a table with invalid body", "/prest/public/test", nil, http.StatusBadRequest},
+		{"execute insert in a table with duplicated primary key", "/prest/public/test_categories", map[string]interface{}{"id": 1, "name": "books"}, http.StatusConflict},
+		{"execute insert in a table with nonexistent foreign key", "/prest/public/test_categories", map[string]interface{}{"name": "orphan", "parent_id": 999}, http.StatusConflict},
+		{"execute insert in a table with advisory lock", "/prest/public/test?_lock=42", m, http.StatusOK},
+		{"execute insert in a table with invalid advisory lock", "/prest/public/test?_lock=workers", m, http.StatusBadRequest},
+		{"execute insert in a table with repeatable read isolation", "/prest/public/test?_isolation=repeatable_read", m, http.StatusOK},
+		{"execute insert in a table with invalid isolation", "/prest/public/test?_isolation=snapshot", m, http.StatusBadRequest},
+		{"execute insert if not exists without where clause", "/prest/public/test?_if_not_exists=true", m, http.StatusBadRequest},
+		{"execute insert if not exists with invalid where clause", "/prest/public/test?_if_not_exists=true&0name=$eq.prest", m, http.StatusBadRequest},
+		{"execute insert if not exists with nonexistent column", "/prest/public/test?_if_not_exists=true&name=$eq.prest", map[string]interface{}{"nonexistent": 1}, http.StatusBadRequest},
 	}
 
 	for _, tc := range testCases {
@@ -184,7 +328,10 @@ func TestDeleteFromTable(t *testing.T) {
 		{"execute delete in a table with invalid database", "/0prest/public/test", nil, http.StatusBadRequest},
 		{"execute delete in a table with invalid schema", "/prest/0public/test", nil, http.StatusBadRequest},
 		{"execute delete in a table with invalid table", "/prest/public/0test", nil, http.StatusBadRequest},
+		{"execute delete in a nonexistent table", "/prest/public/test_nonexistent", nil, http.StatusNotFound},
 		{"execute delete in a table with invalid where clause", "/prest/public/test?0name=$eq.nuveo", nil, http.StatusBadRequest},
+		{"execute delete in a table with advisory lock", "/prest/public/test?name=$eq.nuveo&_lock=-7", nil, http.StatusOK},
+		{"execute delete in a table with serializable isolation", "/prest/public/test?name=$eq.nuveo&_isolation=serializable", nil, http.StatusOK},
 	}
 
 	for _, tc := range testCases {
@@ -213,8 +360,12 @@ func TestUpdateFromTable(t *testing.T) {
 		{"execute update in a table with invalid database", "/0prest/public/test", m, http.StatusBadRequest},
 		{"execute update in a table with invalid schema", "/prest/0public/test", m, http.StatusBadRequest},
 		{"execute update in a table with invalid table", "/prest/public/0test", m, http.StatusBadRequest},
+		{"execute update in a nonexistent table", "/prest/public/test_nonexistent", m, http.StatusNotFound},
 		{"execute update in a table with invalid where clause", "/prest/public/test?0name=$eq.nuveo", m, http.StatusBadRequest},
 		{"execute update in a table with invalid body", "/prest/public/test?name=$eq.nuveo", nil, http.StatusBadRequest},
+		{"execute update in a table with array field", "/prest/public/testarray?id=$eq.100", map[string]interface{}{"data": []string{"Gohan", "Goten"}}, http.StatusOK},
+		{"execute update in a table with array field of mixed types", "/prest/public/testarray?id=$eq.100", map[string]interface{}{"data": []interface{}{"Gohan", 2}}, http.StatusBadRequest},
+		{"execute update in a table with array field of objects", "/prest/public/testarray?id=$eq.100", map[string]interface{}{"data": []interface{}{map[string]string{"name": "Gohan"}}}, http.StatusBadRequest},
 	}
 
 	for _, tc := range testCases {
@@ -223,3 +374,968 @@ func TestUpdateFromTable(t *testing.T) {
 		doRequest(t, server.URL+tc.url, tc.request, "PATCH", tc.status, "UpdateTable")
 	}
 }
+
+func TestRefreshMaterializedView(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_refresh", RefreshMaterializedView).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+	}{
+		{"execute refresh in a materialized view", "/prest/public/matview_test/_refresh", http.StatusOK},
+		{"execute refresh concurrently in a materialized view", "/prest/public/matview_test/_refresh?_concurrently=true", http.StatusOK},
+		{"execute refresh in a table", "/prest/public/test/_refresh", http.StatusNotFound},
+		{"execute refresh in a view", "/prest/public/view_test/_refresh", http.StatusNotFound},
+		{"execute refresh in a materialized view with invalid schema", "/prest/0public/matview_test/_refresh", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, nil, "POST", tc.status, "RefreshMaterializedView")
+	}
+}
+
+func TestBulkDeleteTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_bulk", BulkDeleteTable).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		body        string
+		status      int
+		response    string
+	}{
+		{"execute bulk delete in a table", "/prest/public/test_bulk_delete/_bulk", `{"ids": [1, 2, 99], "column": "id"}`, http.StatusOK, `{"rows_affected":2}`},
+		{"execute bulk delete by a text column", "/prest/public/test_bulk_delete/_bulk", `{"ids": ["three"], "column": "name"}`, http.StatusOK, `{"rows_affected":1}`},
+		{"execute bulk delete without ids", "/prest/public/test_bulk_delete/_bulk", `{"ids": [], "column": "id"}`, http.StatusBadRequest, ""},
+		{"execute bulk delete without column", "/prest/public/test_bulk_delete/_bulk", `{"ids": [4]}`, http.StatusBadRequest, ""},
+		{"execute bulk delete with nonexistent column", "/prest/public/test_bulk_delete/_bulk", `{"ids": [4], "column": "nonexistent"}`, http.StatusBadRequest, ""},
+		{"execute bulk delete with invalid ids", "/prest/public/test_bulk_delete/_bulk", `{"ids": [{"id": 4}], "column": "id"}`, http.StatusBadRequest, ""},
+		{"execute bulk delete in a nonexistent table", "/prest/public/test_nonexistent/_bulk", `{"ids": [4], "column": "id"}`, http.StatusNotFound, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("DELETE", server.URL+tc.url, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error("error on Delete", err)
+			continue
+		}
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %d, got: %d", tc.status, resp.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error("error on ioutil ReadAll", err)
+		}
+
+		if tc.response != "" && string(body) != tc.response {
+			t.Errorf("expected %s, got: %s", tc.response, string(body))
+		}
+	}
+}
+
+func TestBulkUpdateTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_bulk_update", BulkUpdateTable).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		body        string
+		status      int
+		response    string
+	}{
+		{"execute bulk update in a table", "/prest/public/test7/_bulk_update", `[{"where": {"name": "$eq.gopher"}, "set": {"surname": "bulk"}}, {"where": {"name": "$eq.nobody"}, "set": {"surname": "bulk"}}]`, http.StatusOK, `{"rows_affected":1}`},
+		{"execute bulk update in a table with invalid entry", "/prest/public/test7/_bulk_update", `[{"where": {"name": "$eq.gopher"}, "set": {"surname": "bulk"}}, {"set": {"surname": "bulk"}}]`, http.StatusBadRequest, ""},
+		{"execute bulk update in a table rolling back on error", "/prest/public/test7/_bulk_update", `[{"where": {"name": "$eq.gopher"}, "set": {"surname": "bulk"}}, {"where": {"name": "$eq.gopher"}, "set": {"nonexistent": "bulk"}}]`, http.StatusBadRequest, ""},
+		{"execute bulk update in a nonexistent table", "/prest/public/test_nonexistent/_bulk_update", `[{"where": {"name": "$eq.gopher"}, "set": {"surname": "bulk"}}]`, http.StatusNotFound, ""},
+		{"execute bulk update in a table with empty body", "/prest/public/test7/_bulk_update", `[]`, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Post(server.URL+tc.url, "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Error("error on Post", err)
+			continue
+		}
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %d, got: %d", tc.status, resp.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error("error on ioutil ReadAll", err)
+		}
+
+		if tc.response != "" && string(body) != tc.response {
+			t.Errorf("expected %s, got: %s", tc.response, string(body))
+		}
+	}
+}
+
+func TestIngestTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_copy", CopyTable).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{"ingest a CSV", "/prest/public/test_categories_archive/_copy", "text/csv", "id,title\n10,ingested\n11,\n", http.StatusOK, `{"rows_affected":2}`},
+		{"ingest a NDJSON", "/prest/public/test_categories_archive/_copy", "application/x-ndjson", `{"id": 12, "title": "ingested"}` + "\n" + `{"id": 13}` + "\n", http.StatusOK, `{"rows_affected":2}`},
+		{"ingest a CSV with a nonexistent column", "/prest/public/test_categories_archive/_copy", "text/csv", "id,nonexistent\n14,x\n", http.StatusBadRequest, ""},
+		{"ingest a CSV rolling back on error", "/prest/public/test_categories_archive/_copy", "text/csv", "id,title\n15,ok\nfifteen,invalid\n", http.StatusBadRequest, ""},
+		{"ingest an empty CSV", "/prest/public/test_categories_archive/_copy", "text/csv", "", http.StatusBadRequest, ""},
+		{"ingest in a nonexistent table", "/prest/public/test_nonexistent/_copy", "text/csv", "id\n1\n", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Post(server.URL+tc.url, tc.contentType, strings.NewReader(tc.body))
+		if err != nil {
+			t.Error("error on Post", err)
+			continue
+		}
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %d, got: %d", tc.status, resp.StatusCode)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error("error on ioutil ReadAll", err)
+		}
+
+		if tc.response != "" && string(body) != tc.response {
+			t.Errorf("expected %s, got: %s", tc.response, string(body))
+		}
+	}
+}
+
+func TestAffectedRows(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PUT", "PATCH")
+	router.HandleFunc("/{database}/{schema}/{table}", DeleteFromTable).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		body        string
+		expected    string
+	}{
+		{"update rows matching where clause", "/prest/public/test_affected_rows?name=$eq.two", "PATCH", `{"name": "three"}`, "2"},
+		{"update without rows matching where clause", "/prest/public/test_affected_rows?name=$eq.nobody", "PATCH", `{"name": "three"}`, "0"},
+		{"delete rows matching where clause", "/prest/public/test_affected_rows?name=$eq.one", "DELETE", "", "1"},
+		{"delete without rows matching where clause", "/prest/public/test_affected_rows?name=$eq.one", "DELETE", "", "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest(tc.method, server.URL+tc.url, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected %d, got: %d", http.StatusOK, resp.StatusCode)
+		}
+		if expected := fmt.Sprintf(`{"rows_affected":%s}`, tc.expected); string(body) != expected {
+			t.Errorf("expected %q, got: %q", expected, string(body))
+		}
+		if header := resp.Header.Get("X-Affected-Rows"); header != tc.expected {
+			t.Errorf("expected X-Affected-Rows %q, got: %q", tc.expected, header)
+		}
+	}
+}
+
+func TestSearchTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_search", SearchTable).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"search with and and or", "/prest/public/test_group_by_table/_search?_select=name", map[string]interface{}{
+			"and": []interface{}{
+				map[string]interface{}{"age": 19},
+				map[string]interface{}{"or": []interface{}{
+					map[string]interface{}{"salary": map[string]interface{}{"$gt": 3998}},
+					map[string]interface{}{"name": "joao"},
+				}},
+			},
+		}, http.StatusOK, "[{\"name\":\"maria\"}]"},
+		{"search with query string filters", "/prest/public/test_group_by_table/_search?_select=name&name=$ne.gopher&_order=name", map[string]interface{}{
+			"or": []interface{}{
+				map[string]interface{}{"age": 20},
+				map[string]interface{}{"salary": map[string]interface{}{"$lt": 3999}},
+			},
+		}, http.StatusOK, "[{\"name\":\"guitarra humana\"}, \n {\"name\":\"joao\"}]"},
+		{"search with invalid operator", "/prest/public/test_group_by_table/_search", map[string]interface{}{"age": map[string]interface{}{"$like": 1}}, http.StatusBadRequest, ""},
+		{"search with invalid column", "/prest/public/test_group_by_table/_search", map[string]interface{}{"0age": 1}, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body != "" {
+			doRequest(t, server.URL+tc.url, tc.request, "POST", tc.status, "SearchTable", tc.body)
+			continue
+		}
+		doRequest(t, server.URL+tc.url, tc.request, "POST", tc.status, "SearchTable")
+	}
+}
+
+func TestInsertIfNotExists(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		created     string
+	}{
+		{"insert the row without matching rows", "true"},
+		{"return the existing row", "false"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Post(server.URL+"/prest/public/test?_if_not_exists=true&name=$eq.idempotent", "application/json", strings.NewReader(`{"name": "idempotent"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected %d, got: %d", http.StatusOK, resp.StatusCode)
+		}
+		if header := resp.Header.Get("X-Created"); header != tc.created {
+			t.Errorf("expected X-Created %q, got: %q", tc.created, header)
+		}
+		if !strings.Contains(string(body), `"name":"idempotent"`) {
+			t.Errorf("expected the row, got: %q", string(body))
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_columns", Columns).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+		body        string
+	}{
+		{"columns of a table", "/prest/public/test4/_columns", http.StatusOK, "[{\"name\" : \"id\", \"type\" : \"integer\"}, \n {\"name\" : \"name\", \"type\" : \"text\"}]"},
+		{"columns of a table with comments", "/prest/public/test4/_columns?_comments=true", http.StatusOK, "[{\"name\" : \"id\", \"type\" : \"integer\", \"comment\" : \"\"}, \n {\"name\" : \"name\", \"type\" : \"text\", \"comment\" : \"name of the row\"}]"},
+		{"columns of a table not found", "/prest/public/test_not_found/_columns", http.StatusNotFound, ""},
+		{"columns of an invalid table", "/prest/public/0test/_columns", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body == "" {
+			doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "Columns")
+			continue
+		}
+		doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "Columns", tc.body)
+	}
+}
+
+func TestCopyTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_copy", CopyTable).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+		body        string
+	}{
+		{"copy the common columns", "/prest/public/test_categories_archive/_copy?_from=test_categories&parent_id=$null", http.StatusOK, `{"rows_affected":1}`},
+		{"copy selected columns with target names", "/prest/public/test_categories_archive/_copy?_from=public.test_categories&_select=id,name:as:title&parent_id=$notnull", http.StatusOK, `{"rows_affected":2}`},
+		{"copy without source", "/prest/public/test_categories_archive/_copy", http.StatusBadRequest, ""},
+		{"copy from a table not found", "/prest/public/test_categories_archive/_copy?_from=test_not_found", http.StatusNotFound, ""},
+		{"copy incompatible columns", "/prest/public/test_categories_archive/_copy?_from=test_categories&_select=name:as:parent_id", http.StatusBadRequest, ""},
+		{"copy with invalid where clause", "/prest/public/test_categories_archive/_copy?_from=test_categories&0name=$eq.books", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body == "" {
+			doRequest(t, server.URL+tc.url, nil, "POST", tc.status, "CopyTable")
+			continue
+		}
+		doRequest(t, server.URL+tc.url, nil, "POST", tc.status, "CopyTable", tc.body)
+	}
+}
+
+func TestTruncateTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_truncate", TruncateTable).Methods("POST")
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		status      int
+		body        string
+	}{
+		{"truncate table", "/prest/public/test_truncate/_truncate?_restart_identity=true", "POST", http.StatusOK, `{"cascade":false,"restart_identity":true,"table":"public.test_truncate"}`},
+		{"truncated table is empty", "/prest/public/test_truncate", "GET", http.StatusOK, `[]`},
+		{"truncate table not found", "/prest/public/test_not_found/_truncate", "POST", http.StatusNotFound, ""},
+		{"truncate invalid table", "/prest/public/0test/_truncate", "POST", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if tc.body == "" {
+			doRequest(t, server.URL+tc.url, nil, tc.method, tc.status, "TruncateTable")
+			continue
+		}
+		doRequest(t, server.URL+tc.url, nil, tc.method, tc.status, "TruncateTable", tc.body)
+	}
+}
+
+func TestDryRunTables(t *testing.T) {
+	config.PrestConf.EnableDryRun = true
+	defer func() { config.PrestConf.EnableDryRun = false }()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	router.HandleFunc("/{database}/{schema}/{table}", DeleteFromTable).Methods("DELETE")
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		request     map[string]interface{}
+		body        string
+	}{
+		{"dry run of delete", "/prest/public/test_categories?name=$eq.books&_dryrun=true", "DELETE", nil, `{"sql":"\nDELETE FROM prest.public.test_categories WHERE name = $1","params":["books"]}`},
+		{"dry run of update", "/prest/public/test_categories?name=$eq.books&_dryrun=true", "PATCH", map[string]interface{}{"parent_id": 2}, `{"sql":"\nUPDATE prest.public.test_categories SET parent_id=$2 WHERE name = $1","params":["books",2]}`},
+		{"dry run of insert", "/prest/public/test_categories?_dryrun=true", "POST", map[string]interface{}{"name": "dry"}, `{"sql":"\nINSERT INTO prest.public.test_categories(name) VALUES($1)","params":["dry"]}`},
+		{"rows untouched by the dry runs", "/prest/public/test_categories?_select=name,parent_id&_order=name", "GET", nil, "[{\"name\":\"books\",\"parent_id\":null}, \n {\"name\":\"fantasy\",\"parent_id\":1}, \n {\"name\":\"tolkien\",\"parent_id\":2}]"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, tc.method, http.StatusOK, "DryRunTables", tc.body)
+	}
+
+	t.Log("dry run disabled")
+	config.PrestConf.EnableDryRun = false
+	doRequest(t, server.URL+"/prest/public/test_categories?_dryrun=true", nil, "GET", http.StatusForbidden, "DryRunTables")
+}
+
+func TestQuoteIdentifiers(t *testing.T) {
+	config.PrestConf.QuoteIdentifiers = true
+	defer func() { config.PrestConf.QuoteIdentifiers = false }()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PUT", "PATCH")
+	router.HandleFunc("/{database}/{schema}/{table}", DeleteFromTable).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	doRequest(t, server.URL+"/prest/public/Test_Order?_select=select,userName&_order=-select", nil, "GET", http.StatusOK, "QuoteIdentifiers", "[{\"select\":\"two\",\"userName\":\"nuveo\"}, \n {\"select\":\"one\",\"userName\":\"prest\"}]")
+	doRequest(t, server.URL+"/prest/public/Test_Order?select=$eq.one&_select=userName", nil, "GET", http.StatusOK, "QuoteIdentifiers", "[{\"userName\":\"prest\"}]")
+	doRequest(t, server.URL+"/prest/public/Test_Order", map[string]interface{}{"select": "three", "userName": "quoted"}, "POST", http.StatusOK, "QuoteIdentifiers")
+	doRequest(t, server.URL+"/prest/public/Test_Order?select=$eq.three", map[string]interface{}{"userName": "updated"}, "PATCH", http.StatusOK, "QuoteIdentifiers", "{\"rows_affected\":1}")
+	doRequest(t, server.URL+"/prest/public/Test_Order?userName=$eq.updated", nil, "DELETE", http.StatusOK, "QuoteIdentifiers", "{\"rows_affected\":1}")
+	doRequest(t, server.URL+"/prest/public/Test_Order?select\"=$eq.one", nil, "GET", http.StatusBadRequest, "QuoteIdentifiers")
+}
+
+func TestSelectFromTablesCursor(t *testing.T) {
+	config.PrestConf.CursorSecret = "s3cr3t"
+	defer func() { config.PrestConf.CursorSecret = "" }()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(url string, status int) (body, next string) {
+		resp, err := http.Get(server.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("expected status %d, got %d", status, resp.StatusCode)
+		}
+		byt, _ := ioutil.ReadAll(resp.Body)
+		return string(byt), resp.Header.Get("X-Next-Cursor")
+	}
+
+	t.Log("first page")
+	body, next := get("/prest/public/test_categories?_select=id,name&_order=id&_page_size=2&_cursor=", http.StatusOK)
+	if expected := "[{\"id\":1,\"name\":\"books\"}, \n {\"id\":2,\"name\":\"fantasy\"}]"; body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+	if next == "" {
+		t.Fatal("expected X-Next-Cursor in the first page")
+	}
+
+	t.Log("last page")
+	body, last := get("/prest/public/test_categories?_select=id,name&_order=id&_page_size=2&_cursor="+next, http.StatusOK)
+	if expected := "[{\"id\":3,\"name\":\"tolkien\"}]"; body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+	if last != "" {
+		t.Errorf("expected no X-Next-Cursor in the last page, got %s", last)
+	}
+
+	t.Log("cursor of other order")
+	get("/prest/public/test_categories?_order=-id&_page_size=2&_cursor="+next, http.StatusBadRequest)
+
+	t.Log("tampered cursor")
+	get("/prest/public/test_categories?_order=id&_page_size=2&_cursor="+next[:len(next)-1]+"A", http.StatusBadRequest)
+
+	t.Log("order column not selected")
+	get("/prest/public/test_categories?_select=name&_order=id&_page_size=2&_cursor=", http.StatusBadRequest)
+}
+
+func TestSelectFromTablesQueryCost(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		enabled     bool
+		url         string
+		cost        bool
+	}{
+		{"disabled", false, "/prest/public/test_categories", false},
+		{"select", true, "/prest/public/test_categories?name=books", true},
+		{"explain", true, "/prest/public/test_categories?_explain=true", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.QueryCostHeader = tc.enabled
+		resp, err := http.Get(server.URL + tc.url)
+		config.PrestConf.QueryCostHeader = false
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		cost := resp.Header.Get("X-Query-Cost")
+		if (cost != "") != tc.cost {
+			t.Errorf("expected X-Query-Cost %v, got %q", tc.cost, cost)
+		}
+		if _, err = strconv.ParseFloat(cost, 64); tc.cost && err != nil {
+			t.Errorf("expected a number in X-Query-Cost, got %q", cost)
+		}
+	}
+}
+
+func TestSelectFromTablesHead(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET", "HEAD")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+		count       string
+	}{
+		{"head of a table", "/prest/public/test_categories", http.StatusOK, "3"},
+		{"head of a table with where clause", "/prest/public/test_categories?parent_id=$notnull", http.StatusOK, "2"},
+		{"head of a table ignore pagination", "/prest/public/test_categories?_page=1&_page_size=1", http.StatusOK, "3"},
+		{"head of a table with invalid where clause", "/prest/public/test_categories?0name=$eq.books", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Head(server.URL + tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected status %d, got %d", tc.status, resp.StatusCode)
+		}
+		if count := resp.Header.Get("X-Total-Count"); count != tc.count {
+			t.Errorf("expected X-Total-Count %q, got %q", tc.count, count)
+		}
+		if len(body) > 0 {
+			t.Errorf("expected no body, got %s", body)
+		}
+	}
+}
+
+func TestInsertLocation(t *testing.T) {
+	var testCases = []struct {
+		description string
+		pk          []string
+		object      string
+		expected    string
+	}{
+		{"serial primary key", []string{"id"}, `{"id":42,"name":"prest"}`, "/prest/public/test?id=$eq.42"},
+		{"composite primary key", []string{"b", "a"}, `{"a":1,"b":"x y","name":"prest"}`, "/prest/public/test?b=$eq.x+y&a=$eq.1"},
+		{"big number", []string{"id"}, `{"id":9007199254740993}`, "/prest/public/test?id=$eq.9007199254740993"},
+		{"without primary key", nil, `{"id":42}`, ""},
+		{"null primary key", []string{"id"}, `{"id":null}`, ""},
+		{"invalid object", []string{"id"}, `[]`, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if location := insertLocation("/prest/public/test", tc.pk, []byte(tc.object)); location != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, location)
+		}
+	}
+}
+
+func TestFirstRow(t *testing.T) {
+	var testCases = []struct {
+		description string
+		object      string
+		expected    string
+	}{
+		{"one row", `[{"id":1}]`, `{"id":1}`},
+		{"rows formatted by json_agg", "[{\"id\":1}, \n {\"id\":2}]", `{"id":1}`},
+		{"no rows", `[]`, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		row, err := firstRow([]byte(tc.object))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(row) != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, row)
+		}
+	}
+}
+
+func TestInsertLocationHeader(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		created     bool
+		status      int
+		location    string
+	}{
+		{"insert in a table with primary key", "/prest/public/test4", false, http.StatusOK, "/prest/public/test4?id=$eq."},
+		{"insert in a table without primary key", "/prest/public/test", false, http.StatusOK, ""},
+		{"insert with status created", "/prest/public/test4", true, http.StatusCreated, "/prest/public/test4?id=$eq."},
+	}
+
+	for i, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf.InsertStatusCreated = tc.created
+		body := strings.NewReader(fmt.Sprintf(`{"name": "location %d"}`, i))
+		resp, err := http.Post(server.URL+tc.url, "application/json", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected status %d, got %d", tc.status, resp.StatusCode)
+		}
+		location := resp.Header.Get("Location")
+		if (tc.location == "") != (location == "") || !strings.HasPrefix(location, tc.location) {
+			t.Errorf("expected Location %q, got %q", tc.location, location)
+		}
+	}
+	config.PrestConf.InsertStatusCreated = false
+}
+
+func TestInsertUUIDColumns(t *testing.T) {
+	config.PrestConf.UUIDColumns = []config.UUIDColumnConf{{Name: "public.test_uuid", Column: "id"}}
+	defer func() {
+		config.PrestConf.UUIDColumns = nil
+	}()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		body        string
+		id          string
+	}{
+		{"insert without the uuid column", `{"name": "generated"}`, ""},
+		{"insert with the uuid column", `{"id": "c1e7d7d1-0d64-4b2a-8f4e-0a1c5b1a9e11", "name": "sent"}`, "c1e7d7d1-0d64-4b2a-8f4e-0a1c5b1a9e11"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Post(server.URL+"/prest/public/test_uuid", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var row struct {
+			ID string `json:"id"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&row)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+		if row.ID == "" || (tc.id != "" && row.ID != tc.id) {
+			t.Errorf("expected id %q, got %q", tc.id, row.ID)
+		}
+	}
+}
+
+func TestInsertDefaults(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", InsertInTables).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		body        string
+		null        bool
+	}{
+		{"insert without the column with default", `{"name": "omitted"}`, false},
+		{"insert with null in the column with default", `{"name": "null", "created_at": null}`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		resp, err := http.Post(server.URL+"/prest/public/test_defaults", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var row struct {
+			ID        int     `json:"id"`
+			CreatedAt *string `json:"created_at"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&row)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+		if row.ID == 0 {
+			t.Error("expected the serial default in id")
+		}
+		if (row.CreatedAt == nil) != tc.null {
+			t.Errorf("expected null created_at %v, got %v", tc.null, row.CreatedAt)
+		}
+	}
+}
+
+func TestCaseInsensitiveColumns(t *testing.T) {
+	config.PrestConf.CaseInsensitiveColumns = true
+	defer func() {
+		config.PrestConf.CaseInsensitiveColumns = false
+	}()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", SelectFromTables).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		status      int
+		body        string
+	}{
+		{"select with columns in other case", "/prest/public/test_case_columns?USERID=$eq.1&_select=userid,NAME", "GET", http.StatusOK, "[{\"userId\":1,\"name\":\"prest\"}]"},
+		{"select ordered by column in other case", "/prest/public/test_case_columns?_select=name&_order=-UserID", "GET", http.StatusOK, "[{\"name\":\"nuveo\"}, \n {\"name\":\"prest\"}]"},
+		{"count column in other case", "/prest/public/test_case_columns?_count=USERID", "GET", http.StatusOK, `{"count":2}`},
+		{"select nonexistent column", "/prest/public/test_case_columns?_select=password", "GET", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, nil, tc.method, tc.status, "SelectFromTables", tc.body)
+	}
+}
+
+func TestUpdateTableVersion(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+	}{
+		{"update with current version", "/prest/public/test_versioned?_version=version&id=1", map[string]interface{}{"name": "prest v2", "version": 1}, http.StatusOK},
+		{"update with stale version", "/prest/public/test_versioned?_version=version&id=1", map[string]interface{}{"name": "prest v3", "version": 1}, http.StatusConflict},
+		{"update with incremented version", "/prest/public/test_versioned?_version=version&id=1", map[string]interface{}{"name": "prest v3", "version": 2}, http.StatusOK},
+		{"update without version in body", "/prest/public/test_versioned?_version=version&id=1", map[string]interface{}{"name": "prest v4"}, http.StatusBadRequest},
+		{"update with invalid version column", "/prest/public/test_versioned?_version=0version&id=1", map[string]interface{}{"0version": 3}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, "PATCH", tc.status, "UpdateTableVersion")
+	}
+}
+
+func TestUpdateTableMerge(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+	}{
+		{"merge a jsonb column", "/prest/public/testjson?_merge=data&name=$eq.prest", map[string]interface{}{"data": map[string]interface{}{"status": "done"}}, http.StatusOK},
+		{"remove a key of a jsonb column", "/prest/public/testjson?_merge_remove=data:status&name=$eq.prest", map[string]interface{}{}, http.StatusOK},
+		{"merge a column that isn't jsonb", "/prest/public/testjson?_merge=name&name=$eq.prest", map[string]interface{}{"name": map[string]interface{}{"status": "done"}}, http.StatusBadRequest},
+		{"merge a nonexistent column", "/prest/public/testjson?_merge=nonexistent&name=$eq.prest", map[string]interface{}{"nonexistent": map[string]interface{}{}}, http.StatusBadRequest},
+		{"merge without object in body", "/prest/public/testjson?_merge=data&name=$eq.prest", map[string]interface{}{"data": "done"}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, "PATCH", tc.status, "UpdateTableMerge")
+	}
+}
+
+func TestUpdateTableChanged(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", UpdateTable).Methods("PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+		changed     [][]string
+		affected    string
+	}{
+		{"update returning the changed columns", "/prest/public/test_versioned?_changed=true&id=$eq.1", map[string]interface{}{"name": "prest changed"}, http.StatusOK, [][]string{{"name"}}, "1"},
+		{"update returning no changed columns", "/prest/public/test_versioned?_changed=true&id=$eq.1", map[string]interface{}{"name": "prest changed"}, http.StatusOK, [][]string{{}}, "1"},
+		{"update no rows returning the changed columns", "/prest/public/test_versioned?_changed=true&id=$eq.0", map[string]interface{}{"name": "prest changed"}, http.StatusOK, [][]string{}, "0"},
+		{"update an invalid column returning the changed columns", "/prest/public/test_versioned?_changed=true&id=$eq.1", map[string]interface{}{"nonexistent": "prest"}, http.StatusBadRequest, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		body, err := json.Marshal(tc.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("PATCH", server.URL+tc.url, strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("expected %d, got: %d", tc.status, resp.StatusCode)
+			continue
+		}
+		if tc.changed == nil {
+			continue
+		}
+		if affected := resp.Header.Get("X-Affected-Rows"); affected != tc.affected {
+			t.Errorf("expected X-Affected-Rows %s, got %s", tc.affected, affected)
+		}
+
+		var rows []struct {
+			ID      int      `json:"id"`
+			Name    string   `json:"name"`
+			Changed []string `json:"_changed"`
+		}
+		if err = json.Unmarshal(body, &rows); err != nil {
+			t.Fatalf("expected the updated rows, got %s: %v", body, err)
+		}
+		if len(rows) != len(tc.changed) {
+			t.Errorf("expected %d rows, got %d", len(tc.changed), len(rows))
+			continue
+		}
+		for i, row := range rows {
+			if row.ID != 1 || row.Name != "prest changed" {
+				t.Errorf("expected the updated row, got %+v", row)
+			}
+			if strings.Join(row.Changed, ",") != strings.Join(tc.changed[i], ",") || row.Changed == nil {
+				t.Errorf("expected _changed %v, got %v", tc.changed[i], row.Changed)
+			}
+		}
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/_table", CreateTable).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	spec := map[string]interface{}{
+		"name": "test_created",
+		"columns": []interface{}{
+			map[string]interface{}{"name": "id", "type": "serial", "primary_key": true},
+			map[string]interface{}{"name": "name", "type": "varchar(100)", "nullable": false, "default": "prest"},
+		},
+	}
+	invalidType := map[string]interface{}{
+		"name":    "test_created_invalid",
+		"columns": []interface{}{map[string]interface{}{"name": "id", "type": "money"}},
+	}
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"create table", "/prest/public/_table", spec, http.StatusOK, `{"table":"public.test_created"}`},
+		{"create table already existing", "/prest/public/_table", spec, http.StatusConflict, `{"error":{"code":"CONFLICT","message":"could not perform CREATE TABLE","detail":"table already exists"}}`},
+		{"create table with unknown type", "/prest/public/_table", invalidType, http.StatusBadRequest, `{"error":{"code":"INVALID_COLUMN","message":"could not perform CREATE TABLE","detail":"invalid column type: money"}}`},
+		{"create table with invalid schema", "/prest/0public/_table", spec, http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform CREATE TABLE","detail":"Invalid identifier"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, "POST", tc.status, "CreateTable", tc.body)
+	}
+}
+
+func TestAddColumn(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_column", AddColumn).Methods("PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     map[string]interface{}
+		status      int
+		body        string
+	}{
+		{"add column", "/prest/public/test_affected_rows/_column", map[string]interface{}{"name": "age", "type": "integer", "default": 0}, http.StatusOK, `{"column":"age","table":"public.test_affected_rows"}`},
+		{"add column already existing", "/prest/public/test_affected_rows/_column", map[string]interface{}{"name": "age", "type": "integer"}, http.StatusConflict, `{"error":{"code":"CONFLICT","message":"could not perform ADD COLUMN","detail":"column already exists: age"}}`},
+		{"add column with unknown type", "/prest/public/test_affected_rows/_column", map[string]interface{}{"name": "cost", "type": "money"}, http.StatusBadRequest, `{"error":{"code":"INVALID_COLUMN","message":"could not perform ADD COLUMN","detail":"invalid column type: money"}}`},
+		{"add column with invalid name", "/prest/public/test_affected_rows/_column", map[string]interface{}{"name": "0cost", "type": "integer"}, http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform ADD COLUMN","detail":"Invalid identifier: 0cost"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, tc.request, "PATCH", tc.status, "AddColumn", tc.body)
+	}
+}
+
+func TestPrimaryKey(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/_pk", PrimaryKey).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+		body        string
+	}{
+		{"primary key of a single column", "/prest/public/test4/_pk", http.StatusOK, `["id"]`},
+		{"composite primary key in key order", "/prest/public/test_composite_pk/_pk", http.StatusOK, `["b", "a"]`},
+		{"table without primary key", "/prest/public/test/_pk", http.StatusOK, `[]`},
+		{"invalid table", "/prest/public/0test/_pk", http.StatusBadRequest, `{"error":{"code":"INVALID_PARAMETER","message":"could not perform PrimaryKey","detail":"Invalid identifier"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		doRequest(t, server.URL+tc.url, nil, "GET", tc.status, "PrimaryKey", tc.body)
+	}
+}
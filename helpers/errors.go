@@ -0,0 +1,140 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Error codes of the error responses, clients can rely on them
+const (
+	CodeBadRequest        = "BAD_REQUEST"
+	CodeInvalidWhere      = "INVALID_WHERE"
+	CodeInvalidSelect     = "INVALID_SELECT"
+	CodeInvalidOrder      = "INVALID_ORDER"
+	CodeInvalidGroupBy    = "INVALID_GROUP_BY"
+	CodeInvalidPagination = "INVALID_PAGINATION"
+	CodeInvalidJoin       = "INVALID_JOIN"
+	CodeInvalidCount      = "INVALID_COUNT"
+	CodeInvalidParameter  = "INVALID_PARAMETER"
+	CodeInvalidBody       = "INVALID_BODY"
+	CodeInvalidColumn     = "INVALID_COLUMN"
+	CodeValidationFailed  = "VALIDATION_FAILED"
+	CodeQueryFailed       = "QUERY_FAILED"
+	CodeUnauthorized      = "UNAUTHORIZED"
+	CodeForbidden         = "FORBIDDEN"
+	CodeNotFound          = "NOT_FOUND"
+	CodeMethodNotAllowed  = "METHOD_NOT_ALLOWED"
+	CodeConflict          = "CONFLICT"
+	CodePayloadTooLarge   = "PAYLOAD_TOO_LARGE"
+	CodeTimeout           = "TIMEOUT"
+	CodeUnavailable       = "UNAVAILABLE"
+	CodeInternal          = "INTERNAL_ERROR"
+)
+
+// postgres error codes refined in the response code
+const (
+	undefinedColumn     = "42703"
+	queryCanceled       = "57014"
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+	serialization       = "40001"
+)
+
+// ConstraintViolation is the detail of the errors of unique, foreign key and check
+// constraints, so clients can tell which field of a form failed
+type ConstraintViolation struct {
+	Constraint string `json:"constraint"`
+	Type       string `json:"type"`
+	Detail     string `json:"detail"`
+}
+
+// Error is the body of error responses: {"error": {"code", "message", "detail"}}
+type Error struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// ErrorResponse write err as detail of the error envelope, database errors with a
+// more specific code (e.g. nonexistent columns) override code, constraint violations
+// answer 409 or 422 with a ConstraintViolation, serialization failures answer 409 so
+// the client can retry and bodies over the limit of http.MaxBytesReader answer 413
+func ErrorResponse(w http.ResponseWriter, status int, code, message string, err error) {
+	e := Error{Code: code, Message: message}
+	if err != nil {
+		e.Detail = err.Error()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status, e.Code = http.StatusRequestEntityTooLarge, CodePayloadTooLarge
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case undefinedColumn:
+				e.Code = CodeInvalidColumn
+			case queryCanceled:
+				e.Code = CodeTimeout
+			case uniqueViolation:
+				status, e.Code, e.Detail = http.StatusConflict, CodeConflict, constraintViolation(pqErr, "unique")
+			case foreignKeyViolation:
+				status, e.Code, e.Detail = http.StatusConflict, CodeConflict, constraintViolation(pqErr, "foreign_key")
+			case checkViolation:
+				status, e.Code, e.Detail = http.StatusUnprocessableEntity, CodeValidationFailed, constraintViolation(pqErr, "check")
+			case serialization:
+				status, e.Code = http.StatusConflict, CodeConflict
+			}
+		}
+	}
+	WriteError(w, status, e)
+}
+
+func constraintViolation(pqErr *pq.Error, kind string) ConstraintViolation {
+	detail := pqErr.Detail
+	if detail == "" {
+		detail = pqErr.Message
+	}
+	return ConstraintViolation{Constraint: pqErr.Constraint, Type: kind, Detail: detail}
+}
+
+// WriteError write the error envelope
+func WriteError(w http.ResponseWriter, status int, e Error) {
+	body, err := json.Marshal(map[string]Error{"error": e})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// CodeFromStatus is the error code of responses without a specific one
+func CodeFromStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorResponse(t *testing.T) {
+	var testCases = []struct {
+		description string
+		status      int
+		code        string
+		err         error
+		body        string
+	}{
+		{"error with detail", http.StatusBadRequest, CodeInvalidWhere, errors.New("invalid identifier: 0name"), `{"error":{"code":"INVALID_WHERE","message":"could not perform X","detail":"invalid identifier: 0name"}}`},
+		{"error without detail", http.StatusConflict, CodeConflict, nil, `{"error":{"code":"CONFLICT","message":"could not perform X"}}`},
+		{"nonexistent column", http.StatusBadRequest, CodeQueryFailed, &pq.Error{Code: "42703", Message: `column "x" does not exist`}, `{"error":{"code":"INVALID_COLUMN","message":"could not perform X","detail":"pq: column \"x\" does not exist"}}`},
+		{"statement timeout", http.StatusBadRequest, CodeQueryFailed, &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}, `{"error":{"code":"TIMEOUT","message":"could not perform X","detail":"pq: canceling statement due to statement timeout"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		w := httptest.NewRecorder()
+		ErrorResponse(w, tc.status, tc.code, "could not perform X", tc.err)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d", tc.status, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("expected application/json, got %q", contentType)
+		}
+		if w.Body.String() != tc.body {
+			t.Errorf("expected %q, got %q", tc.body, w.Body.String())
+		}
+	}
+}
+
+func TestErrorResponseBodyTooLarge(t *testing.T) {
+	w := httptest.NewRecorder()
+	ErrorResponse(w, http.StatusBadRequest, CodeInvalidBody, "could not decode body", &http.MaxBytesError{Limit: 10})
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+	body := `{"error":{"code":"PAYLOAD_TOO_LARGE","message":"could not decode body","detail":"http: request body too large"}}`
+	if w.Body.String() != body {
+		t.Errorf("expected %q, got %q", body, w.Body.String())
+	}
+}
+
+func TestErrorResponseSerializationFailure(t *testing.T) {
+	w := httptest.NewRecorder()
+	ErrorResponse(w, http.StatusBadRequest, CodeQueryFailed, "could not perform UPDATE", &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+	body := `{"error":{"code":"CONFLICT","message":"could not perform UPDATE","detail":"pq: could not serialize access due to concurrent update"}}`
+	if w.Body.String() != body {
+		t.Errorf("expected %q, got %q", body, w.Body.String())
+	}
+}
+
+func TestErrorResponseConstraintViolation(t *testing.T) {
+	var testCases = []struct {
+		description string
+		err         *pq.Error
+		status      int
+		body        string
+	}{
+		{"unique violation", &pq.Error{Code: "23505", Constraint: "test_pkey", Message: "duplicate key value violates unique constraint \"test_pkey\"", Detail: "Key (id)=(1) already exists."}, http.StatusConflict, `{"error":{"code":"CONFLICT","message":"could not perform X","detail":{"constraint":"test_pkey","type":"unique","detail":"Key (id)=(1) already exists."}}}`},
+		{"foreign key violation", &pq.Error{Code: "23503", Constraint: "test_parent_id_fkey", Detail: "Key (parent_id)=(9) is not present in table \"test\"."}, http.StatusConflict, `{"error":{"code":"CONFLICT","message":"could not perform X","detail":{"constraint":"test_parent_id_fkey","type":"foreign_key","detail":"Key (parent_id)=(9) is not present in table \"test\"."}}}`},
+		{"check violation without detail", &pq.Error{Code: "23514", Constraint: "test_age_check", Message: "new row violates check constraint \"test_age_check\""}, http.StatusUnprocessableEntity, `{"error":{"code":"VALIDATION_FAILED","message":"could not perform X","detail":{"constraint":"test_age_check","type":"check","detail":"new row violates check constraint \"test_age_check\""}}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		w := httptest.NewRecorder()
+		ErrorResponse(w, http.StatusBadRequest, CodeQueryFailed, "could not perform X", tc.err)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d", tc.status, w.Code)
+		}
+		if w.Body.String() != tc.body {
+			t.Errorf("expected %q, got %q", tc.body, w.Body.String())
+		}
+	}
+}
+
+func TestCodeFromStatus(t *testing.T) {
+	var testCases = []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, CodeBadRequest},
+		{http.StatusUnauthorized, CodeUnauthorized},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusUnprocessableEntity, CodeValidationFailed},
+		{http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+		{http.StatusServiceUnavailable, CodeUnavailable},
+		{http.StatusBadGateway, CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		if code := CodeFromStatus(tc.status); code != tc.code {
+			t.Errorf("expected %s for %d, got %s", tc.code, tc.status, code)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// requestPathKey is the context key of the path of the request
+type requestPathKey struct{}
+
+// WithRequestID return r with the request ID and its path in its context, so the
+// queries run with the context can be logged with them
+func WithRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+	return r.WithContext(context.WithValue(ctx, requestPathKey{}, r.URL.Path))
+}
+
+// RequestID return the request ID of r, it's empty out of the RequestID middleware
+func RequestID(r *http.Request) string {
+	return ContextRequestID(r.Context())
+}
+
+// ContextRequestID return the request ID of the context of a request
+func ContextRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextRequestPath return the path of the request of ctx
+func ContextRequestPath(ctx context.Context) string {
+	path, _ := ctx.Value(requestPathKey{}).(string)
+	return path
+}
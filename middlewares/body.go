@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+// MaxBodyBytes limit the size of the request bodies, the bodies declaring a greater
+// Content-Length are rejected with 413 and the others are read with
+// http.MaxBytesReader, so the decoding of a body over the limit fails with 413. The CSV
+// and NDJSON bodies of _copy aren't limited, they are streamed to COPY row by row
+func MaxBodyBytes(limit int64) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if strings.HasSuffix(r.URL.Path, "/_copy") && postgres.IngestByRequest(r) {
+			next(w, r)
+			return
+		}
+		if r.ContentLength > limit {
+			err := fmt.Errorf("body of %d bytes exceeds the limit of %d bytes", r.ContentLength, limit)
+			helpers.ErrorResponse(w, http.StatusRequestEntityTooLarge, helpers.CodePayloadTooLarge, "request body too large", err)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	})
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	n := negroni.New(MaxBodyBytes(16))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not decode body", err)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	var testCases = []struct {
+		description string
+		body        string
+		chunked     bool
+		status      int
+	}{
+		{"body under the limit", `{"name":"a"}`, false, http.StatusOK},
+		{"body over the limit", `{"name":"prest tester"}`, false, http.StatusRequestEntityTooLarge},
+		{"body over the limit without length", `{"name":"prest tester"}`, true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("POST", "/prest/public/test", strings.NewReader(tc.body))
+		if tc.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMaxBodyBytesIngest(t *testing.T) {
+	n := negroni.New(MaxBodyBytes(16))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not read body", err)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	var testCases = []struct {
+		description string
+		path        string
+		contentType string
+		status      int
+	}{
+		{"CSV ingest over the limit", "/prest/public/test/_copy", "text/csv", http.StatusOK},
+		{"NDJSON ingest over the limit", "/prest/public/test/_copy", "application/x-ndjson", http.StatusOK},
+		{"JSON copy over the limit", "/prest/public/test/_copy", "application/json", http.StatusRequestEntityTooLarge},
+		{"CSV over the limit out of _copy", "/prest/public/test", "text/csv", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("POST", tc.path, strings.NewReader("id,name\n1,prest tester\n"))
+		r.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+	"github.com/urfave/negroni"
+)
+
+// compressedTypes are content types already compressed, compress them again is a waste
+var compressedTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/pdf",
+}
+
+// Compression compress the responses with gzip or deflate as the Accept-Encoding of
+// the request, bodies smaller than config.CompressionMinSize are written as is
+func Compression() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if !config.PrestConf.EnableCompression {
+			next(w, r)
+			return
+		}
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        config.PrestConf.CompressionMinSize,
+			status:         http.StatusOK,
+		}
+		next(cw, r)
+		cw.Close()
+	})
+}
+
+// acceptedEncoding choose gzip or deflate, gzip is preferred, q=0 refuse an encoding
+func acceptedEncoding(header string) (encoding string) {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if len(fields) > 1 {
+			q := strings.Replace(strings.TrimSpace(fields[1]), " ", "", -1)
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(q, "q="), 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		switch name {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			encoding = "deflate"
+		}
+	}
+	return
+}
+
+// compressWriter buffer the body until minSize to decide if it's compressed, the
+// status is written with the decision because Content-Encoding must be set before
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	minSize     int
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		return cw.write(b)
+	}
+
+	if !cw.compressible() {
+		cw.decide(false)
+		return cw.write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush decide to compress, flushed responses are streamed, and flush the compressor
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.compressible())
+	}
+	if f, ok := cw.compressor.(interface {
+		Flush() error
+	}); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack keep websockets and other upgrades working
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hj.Hijack()
+}
+
+// Close write the small bodies as is and finish the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// nothing was written, e.g. 204
+			return nil
+		}
+		cw.decide(false)
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Close()
+	}
+	return nil
+}
+
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	for _, t := range compressedTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide write the headers and the buffered body, compressed or not
+func (cw *compressWriter) decide(compress bool) (err error) {
+	cw.decided = true
+	if compress {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.compressor, err = flate.NewWriter(cw.ResponseWriter, flate.DefaultCompression)
+			if err != nil {
+				return
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) > 0 {
+		_, err = cw.write(cw.buf)
+		cw.buf = nil
+	}
+	return
+}
+
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.compressor != nil {
+		return cw.compressor.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
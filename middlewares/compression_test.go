@@ -0,0 +1,151 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+	"github.com/urfave/negroni"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	var testCases = []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"gzip; q=0", ""},
+		{"br, identity", ""},
+		{"GZIP", "gzip"},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("Accept-Encoding %q", tc.header)
+		if encoding := acceptedEncoding(tc.header); encoding != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, encoding)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	config.PrestConf = &config.Prest{EnableCompression: true, CompressionMinSize: 100}
+	large := strings.Repeat(`{"name":"prest"},`, 20)
+
+	var testCases = []struct {
+		description    string
+		acceptEncoding string
+		contentType    string
+		body           string
+		encoding       string
+	}{
+		{"compress with gzip", "gzip", "application/json", large, "gzip"},
+		{"compress with deflate", "deflate", "application/json", large, "deflate"},
+		{"without Accept-Encoding", "", "application/json", large, ""},
+		{"small body", "gzip", "application/json", `{"name":"prest"}`, ""},
+		{"already compressed content", "gzip", "image/png", large, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		n := negroni.New(Compression())
+		n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			w.WriteHeader(http.StatusCreated)
+			// many writes, the decision is taken over the buffered body
+			for _, part := range strings.SplitAfter(tc.body, ",") {
+				w.Write([]byte(part))
+			}
+		}))
+
+		r, _ := http.NewRequest("GET", "/", nil)
+		if tc.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status 201, got %d", w.Code)
+		}
+		if encoding := w.Header().Get("Content-Encoding"); encoding != tc.encoding {
+			t.Errorf("expected Content-Encoding %q, got %q", tc.encoding, encoding)
+		}
+
+		var reader io.Reader = w.Body
+		switch tc.encoding {
+		case "gzip":
+			var err error
+			reader, err = gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+		case "deflate":
+			reader = flate.NewReader(w.Body)
+		}
+		body, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tc.body {
+			t.Errorf("expected body %q, got %q", tc.body, body)
+		}
+	}
+
+	t.Log("disabled")
+	config.PrestConf.EnableCompression = false
+	n := negroni.New(Compression())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	}))
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	n.ServeHTTP(w, r)
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected no Content-Encoding, got %q", encoding)
+	}
+	config.Load()
+}
+
+func TestCompressionFlush(t *testing.T) {
+	config.PrestConf = &config.Prest{EnableCompression: true, CompressionMinSize: 1024}
+
+	n := negroni.New(Compression())
+	n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"prest"}`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("\n"))
+	}))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	n.ServeHTTP(w, r)
+
+	if !w.Flushed {
+		t.Error("expected flushed response")
+	}
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Errorf("expected streamed response compressed, got %q", encoding)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(reader)
+	if string(body) != "{\"name\":\"prest\"}\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	config.Load()
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nuveo/prest/config"
+	"github.com/rs/cors"
+	"github.com/urfave/negroni"
+)
+
+type corsPolicy struct {
+	path string
+	cors *cors.Cors
+}
+
+// CORS apply the CORS policy of the longest path matching the request,
+// requests without a matching path use the default allowed origins
+func CORS(defaultOrigins []string, paths []config.CORSConf) negroni.Handler {
+	var defaultPolicy *cors.Cors
+	if defaultOrigins != nil {
+		defaultPolicy = cors.New(cors.Options{
+			AllowedOrigins: defaultOrigins,
+		})
+	}
+
+	policies := make([]corsPolicy, 0, len(paths))
+	for _, p := range paths {
+		policies = append(policies, corsPolicy{
+			path: strings.TrimSuffix(p.Path, "/"),
+			cors: cors.New(cors.Options{
+				AllowedOrigins: p.AllowOrigin,
+				AllowedMethods: p.AllowMethods,
+				AllowedHeaders: p.AllowHeaders,
+			}),
+		})
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return len(policies[i].path) > len(policies[j].path)
+	})
+
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		for _, p := range policies {
+			if r.URL.Path == p.path || strings.HasPrefix(r.URL.Path, p.path+"/") {
+				p.cors.ServeHTTP(w, r, next)
+				return
+			}
+		}
+		if defaultPolicy != nil {
+			defaultPolicy.ServeHTTP(w, r, next)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/urfave/negroni"
+)
+
+var inFlight int64
+
+// InFlight count the requests being served, the count is read by InFlightRequests
+func InFlight() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		next(w, r)
+	})
+}
+
+// InFlightRequests return the number of requests being served
+func InFlightRequests() int64 {
+	return atomic.LoadInt64(&inFlight)
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+func TestInFlight(t *testing.T) {
+	var during int64
+	n := negroni.New(InFlight())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = InFlightRequests()
+	})
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/prest/public/test", nil))
+	if during != 1 {
+		t.Errorf("expected 1 request in flight, got %d", during)
+	}
+	if after := InFlightRequests(); after != 0 {
+		t.Errorf("expected no request in flight, got %d", after)
+	}
+}
@@ -0,0 +1,414 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+// JSONSchema is the subset of JSON Schema used to validate bodies: type, properties,
+// required, additionalProperties (boolean), enum, minLength, maxLength, pattern,
+// minimum, maximum, items (a schema), minItems and maxItems. The other keywords are rejected
+// by ParseJSONSchema
+type JSONSchema struct {
+	Type                 interface{}            `json:"type"`
+	Properties           map[string]*JSONSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Enum                 []interface{}          `json:"enum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	Items                *JSONSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+
+	types   []string
+	pattern *regexp.Regexp
+}
+
+// schemaKeywords are the keywords of JSONSchema, validated, and the annotations,
+// ignored. The schemas with other keywords are rejected, they would be ignored too
+var schemaKeywords = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"enum": true, "minLength": true, "maxLength": true, "pattern": true, "minimum": true,
+	"maximum": true, "items": true, "minItems": true, "maxItems": true,
+	"$schema": true, "$id": true, "id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+}
+
+// ParseJSONSchema decode and compile a JSON Schema, the keywords out of JSONSchema
+// are rejected
+func ParseJSONSchema(data []byte) (schema *JSONSchema, err error) {
+	var raw interface{}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	if err = checkKeywords("", raw); err != nil {
+		return
+	}
+
+	schema = &JSONSchema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return
+	}
+	err = schema.compile()
+	return
+}
+
+// checkKeywords reject the keywords of the schema at path and of its subschemas that
+// aren't validated, e.g. oneOf or format, and the subschemas that aren't objects, e.g.
+// the tuples of items
+func checkKeywords(path string, raw interface{}) error {
+	name := path
+	if name == "" {
+		name = "(root)"
+	}
+	schema, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: the schema must be an object", name)
+	}
+
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !schemaKeywords[key] {
+			return fmt.Errorf("%s: unsupported keyword %s", name, key)
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(properties))
+		for property := range properties {
+			names = append(names, property)
+		}
+		sort.Strings(names)
+		for _, property := range names {
+			propertyPath := property
+			if path != "" {
+				propertyPath = fmt.Sprintf("%s.%s", path, property)
+			}
+			if err := checkKeywords(propertyPath, properties[property]); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		return checkKeywords(fmt.Sprintf("%s[]", path), items)
+	}
+	return nil
+}
+
+// LoadJSONSchemas read the schema files of the tables, the key is schema.table
+func LoadJSONSchemas(confs []config.JSONSchemaConf) (schemas map[string]*JSONSchema, err error) {
+	schemas = make(map[string]*JSONSchema, len(confs))
+	for _, conf := range confs {
+		var data []byte
+		data, err = ioutil.ReadFile(conf.File)
+		if err != nil {
+			return
+		}
+		var schema *JSONSchema
+		schema, err = ParseJSONSchema(data)
+		if err != nil {
+			err = fmt.Errorf("invalid JSON Schema %s: %v", conf.File, err)
+			return
+		}
+		schemas[conf.Name] = schema
+	}
+	return
+}
+
+func (s *JSONSchema) compile() (err error) {
+	switch t := s.Type.(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			str, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("invalid type %v", name)
+			}
+			s.types = append(s.types, str)
+		}
+	default:
+		return fmt.Errorf("invalid type %v", t)
+	}
+
+	if s.Pattern != "" {
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return
+		}
+	}
+	for _, property := range s.Properties {
+		if err = property.compile(); err != nil {
+			return
+		}
+	}
+	if s.Items != nil {
+		err = s.Items.compile()
+	}
+	return
+}
+
+// Validate return the validation errors of value, partial skip the required
+// properties of the root object, e.g. to validate updates
+func (s *JSONSchema) Validate(value interface{}, partial bool) (errs []string) {
+	return s.validateAt("", value, partial)
+}
+
+// validateAt is Validate of a value at path of the body, e.g. [0].set
+func (s *JSONSchema) validateAt(path string, value interface{}, partial bool) (errs []string) {
+	s.validate(path, value, !partial, &errs)
+	return
+}
+
+func (s *JSONSchema) validate(path string, value interface{}, required bool, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		name := path
+		if name == "" {
+			name = "(root)"
+		}
+		*errs = append(*errs, fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+
+	if len(s.types) > 0 && !matchType(s.types, value) {
+		fail("must be of type %s", strings.Join(s.types, " or "))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		var found bool
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v", s.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must have at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must have at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be less than or equal to %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, true, errs)
+			}
+		}
+	case map[string]interface{}:
+		if required {
+			for _, name := range s.Required {
+				if _, ok := v[name]; !ok {
+					fail("%s is required", name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					fail("%s is not allowed", key)
+				}
+				continue
+			}
+			propertyPath := key
+			if path != "" {
+				propertyPath = fmt.Sprintf("%s.%s", path, key)
+			}
+			property.validate(propertyPath, v[key], true, errs)
+		}
+	}
+}
+
+func matchType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JSONSchemaValidation validate the rows written by the bodies against the JSON Schema
+// of the table, invalid bodies are rejected with 422 before reaching the database. The
+// inserts, the updates, the sets of _bulk_update and the NDJSON rows of _copy are
+// validated, the CSV rows of _copy are rejected since their values are all strings
+func JSONSchemaValidation(schemas map[string]*JSONSchema) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		mapPath := getVars(r.URL.Path)
+		if mapPath == nil {
+			next(w, r)
+			return
+		}
+		schema, ok := schemas[fmt.Sprintf("%s.%s", mapPath["schema"], mapPath["table"])]
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		var action string
+		if pathList := strings.Split(r.URL.Path, "/"); len(pathList) == 5 {
+			action = pathList[4]
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		var validate func(body []byte) ([]string, error)
+		switch {
+		case action == "" && r.Method == "POST":
+			validate = schema.validateBody(false)
+		case action == "" && (r.Method == "PUT" || r.Method == "PATCH"):
+			validate = schema.validateBody(true)
+		case action == "_bulk_update" && r.Method == "POST":
+			validate = schema.validateBulkUpdate
+		case action == "_copy" && r.Method == "POST" && mediaType == postgres.NDJSONContentType:
+			validate = schema.validateNDJSON
+		case action == "_copy" && r.Method == "POST" && mediaType == postgres.CSVContentType:
+			helpers.ErrorResponse(w, http.StatusUnprocessableEntity, helpers.CodeValidationFailed, "the CSV rows can't be validated against the JSON Schema of the table, use NDJSON", nil)
+			return
+		default:
+			next(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not read body", err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		errs, err := validate(body)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeInvalidBody, "could not decode body", err)
+			return
+		}
+		if len(errs) > 0 {
+			helpers.WriteError(w, http.StatusUnprocessableEntity, helpers.Error{
+				Code:    helpers.CodeValidationFailed,
+				Message: "body does not match the JSON Schema of the table",
+				Detail:  errs,
+			})
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// validateBody validate the body of an insert or, partial, of an update
+func (s *JSONSchema) validateBody(partial bool) func(body []byte) ([]string, error) {
+	return func(body []byte) (errs []string, err error) {
+		var value interface{}
+		if err = json.Unmarshal(body, &value); err != nil {
+			return
+		}
+		errs = s.Validate(value, partial)
+		return
+	}
+}
+
+// validateBulkUpdate validate the sets of the entries of a bulk update, they are partial
+func (s *JSONSchema) validateBulkUpdate(body []byte) (errs []string, err error) {
+	var entries []struct {
+		Set interface{} `json:"set"`
+	}
+	if err = json.Unmarshal(body, &entries); err != nil {
+		return
+	}
+	for i, entry := range entries {
+		errs = append(errs, s.validateAt(fmt.Sprintf("[%d].set", i), entry.Set, true)...)
+	}
+	return
+}
+
+// validateNDJSON validate the rows of an ingested NDJSON body, one object per line
+func (s *JSONSchema) validateNDJSON(body []byte) (errs []string, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for i := 0; ; i++ {
+		var row interface{}
+		if err = decoder.Decode(&row); err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		errs = append(errs, s.validateAt(fmt.Sprintf("[%d]", i), row, false)...)
+	}
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+	"github.com/urfave/negroni"
+)
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["name", "status"],
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 5},
+			"status": {"enum": ["active", "inactive"]},
+			"age": {"type": "integer", "minimum": 0, "maximum": 150},
+			"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+			"address": {"type": "object", "properties": {"zip": {"type": "string"}}, "additionalProperties": false}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testCases = []struct {
+		description string
+		body        string
+		partial     bool
+		errs        []string
+	}{
+		{"valid body", `{"name": "prest", "status": "active", "age": 3, "code": "ABC", "tags": ["a"], "address": {"zip": "1"}}`, false, nil},
+		{"missing required", `{"name": "prest"}`, false, []string{"(root): status is required"}},
+		{"partial skip required", `{"name": "prest"}`, true, nil},
+		{"partial validate properties", `{"status": "deleted"}`, true, []string{"status: must be one of [active inactive]"}},
+		{"string length", `{"name": "p", "status": "active"}`, false, []string{"name: must have at least 2 characters"}},
+		{"integer type", `{"name": "prest", "status": "active", "age": 1.5}`, false, []string{"age: must be of type integer"}},
+		{"number range", `{"name": "prest", "status": "active", "age": 200}`, false, []string{"age: must be less than or equal to 150"}},
+		{"pattern", `{"name": "prest", "status": "active", "code": "abc"}`, false, []string{"code: must match ^[A-Z]{3}$"}},
+		{"array items", `{"name": "prest", "status": "active", "tags": ["a", 1, "c"]}`, false, []string{"tags: must have at most 2 items", "tags[1]: must be of type string"}},
+		{"nested additional properties", `{"name": "prest", "status": "active", "address": {"zip": "1", "city": "x"}}`, false, []string{"address: city is not allowed"}},
+		{"root type", `[{"name": "prest"}]`, false, []string{"(root): must be of type object"}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		var value interface{}
+		if err := json.Unmarshal([]byte(tc.body), &value); err != nil {
+			t.Fatal(err)
+		}
+		errs := schema.Validate(value, tc.partial)
+		if !reflect.DeepEqual(errs, tc.errs) {
+			t.Errorf("expected %q, got %q", tc.errs, errs)
+		}
+	}
+}
+
+func TestParseJSONSchemaErrors(t *testing.T) {
+	var testCases = []struct {
+		description string
+		schema      string
+	}{
+		{"invalid JSON", `{"type":`},
+		{"invalid type", `{"type": 1}`},
+		{"invalid pattern", `{"properties": {"name": {"pattern": "("}}}`},
+		{"unsupported keyword", `{"type": "object", "oneOf": [{"required": ["name"]}]}`},
+		{"unsupported keyword of a property", `{"properties": {"email": {"type": "string", "format": "email"}}}`},
+		{"unsupported keyword of the items", `{"items": {"type": "integer", "exclusiveMinimum": 0}}`},
+		{"tuple items", `{"items": [{"type": "string"}]}`},
+		{"boolean schema", `{"properties": {"name": true}}`},
+		{"additional properties schema", `{"additionalProperties": {"type": "string"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if _, err := ParseJSONSchema([]byte(tc.schema)); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	}
+}
+
+func TestParseJSONSchemaKeywords(t *testing.T) {
+	_, err := ParseJSONSchema([]byte(`{"properties": {"tags": {"items": {"type": "string", "uniqueItems": true}}}}`))
+	if err == nil || err.Error() != "tags[]: unsupported keyword uniqueItems" {
+		t.Errorf("expected the unsupported keyword with its path, got %v", err)
+	}
+
+	// the annotations are accepted
+	_, err = ParseJSONSchema([]byte(`{"$schema": "http://json-schema.org/draft-07/schema#", "title": "users", "properties": {"name": {"description": "full name", "default": ""}}}`))
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestLoadJSONSchemas(t *testing.T) {
+	schemas, err := LoadJSONSchemas([]config.JSONSchemaConf{{Name: "public.test5", File: "../testdata/jsonschema/test5.json"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schemas["public.test5"]; !ok {
+		t.Errorf("expected schema of public.test5, got %v", schemas)
+	}
+
+	_, err = LoadJSONSchemas([]config.JSONSchemaConf{{Name: "public.test5", File: "../testdata/jsonschema/notfound.json"}})
+	if err == nil {
+		t.Error("expected error with nonexistent file, got nil")
+	}
+}
+
+func TestJSONSchemaValidation(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "string", "maxLength": 5}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := negroni.New(JSONSchemaValidation(map[string]*JSONSchema{"public.test": schema}))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	var testCases = []struct {
+		description string
+		method      string
+		url         string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"valid insert", "POST", "/prest/public/test", "", `{"name": "prest"}`, http.StatusOK},
+		{"invalid insert", "POST", "/prest/public/test", "", `{"name": "pREST API"}`, http.StatusUnprocessableEntity},
+		{"insert without required", "POST", "/prest/public/test", "", `{}`, http.StatusUnprocessableEntity},
+		{"partial update", "PATCH", "/prest/public/test", "", `{}`, http.StatusOK},
+		{"invalid update", "PUT", "/prest/public/test", "", `{"name": 1}`, http.StatusUnprocessableEntity},
+		{"valid bulk update", "POST", "/prest/public/test/_bulk_update", "", `[{"where": {"id": "$eq.1"}, "set": {"name": "a"}}]`, http.StatusOK},
+		{"invalid bulk update", "POST", "/prest/public/test/_bulk_update", "", `[{"where": {"id": "$eq.1"}, "set": {"name": "a"}}, {"where": {"id": "$eq.2"}, "set": {"name": "pREST API"}}]`, http.StatusUnprocessableEntity},
+		{"bulk update not decoded", "POST", "/prest/public/test/_bulk_update", "", `{"set": {}}`, http.StatusBadRequest},
+		{"valid NDJSON ingest", "POST", "/prest/public/test/_copy", "application/x-ndjson", "{\"name\": \"a\"}\n{\"name\": \"b\"}\n", http.StatusOK},
+		{"invalid NDJSON ingest", "POST", "/prest/public/test/_copy", "application/x-ndjson", "{\"name\": \"a\"}\n{}\n", http.StatusUnprocessableEntity},
+		{"CSV ingest", "POST", "/prest/public/test/_copy", "text/csv", "name\na\n", http.StatusUnprocessableEntity},
+		{"copy of another table", "POST", "/prest/public/test/_copy?_from=other", "", "", http.StatusOK},
+		{"search", "POST", "/prest/public/test/_search", "", `{"name": "pREST API"}`, http.StatusOK},
+		{"table without schema", "POST", "/prest/public/other", "", `{"name": 1}`, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			r.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestJSONSchemaValidateBulkUpdate(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, err := schema.validateBulkUpdate([]byte(`[{"set": {"age": 1}}, {"set": {"name": 1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"[1].set.name: must be of type string"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected %q, got %q", expected, errs)
+	}
+}
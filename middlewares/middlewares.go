@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"strings"
 
 	"github.com/auth0/go-jwt-middleware"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+	"github.com/nuveo/prest/statements"
 	"github.com/urfave/negroni"
 )
 
@@ -15,10 +20,77 @@ import (
 func HandlerSet() negroni.Handler {
 	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 		format := r.URL.Query().Get("_renderer")
-		recorder := httptest.NewRecorder()
-		negroniResp := negroni.NewResponseWriter(recorder)
+		rw := &renderWriter{w: w, recorder: httptest.NewRecorder()}
+		negroniResp := negroni.NewResponseWriter(rw)
 		next(negroniResp, r)
-		renderFormat(w, recorder, format)
+		if rw.streaming {
+			return
+		}
+		renderFormat(w, rw.recorder, format)
+	})
+}
+
+// renderWriter buffer the response of the handlers in recorder to render it. The
+// responses with the Content-Type of server-sent events are streamed to w instead,
+// they are written until the client disconnects
+type renderWriter struct {
+	w           http.ResponseWriter
+	recorder    *httptest.ResponseRecorder
+	wroteHeader bool
+	streaming   bool
+}
+
+func (rw *renderWriter) Header() http.Header {
+	if rw.streaming {
+		return rw.w.Header()
+	}
+	return rw.recorder.Header()
+}
+
+func (rw *renderWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	if !strings.HasPrefix(rw.recorder.Header().Get("Content-Type"), "text/event-stream") {
+		rw.recorder.WriteHeader(status)
+		return
+	}
+	rw.streaming = true
+	for key, values := range rw.recorder.Header() {
+		rw.w.Header()[key] = values
+	}
+	rw.w.WriteHeader(status)
+}
+
+func (rw *renderWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.streaming {
+		return rw.w.Write(b)
+	}
+	return rw.recorder.Write(b)
+}
+
+// Flush send the events written to the client, the buffered responses are sent once
+// rendered
+func (rw *renderWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := rw.w.(http.Flusher); ok && rw.streaming {
+		flusher.Flush()
+	}
+}
+
+// DefaultSchema rewrite the short paths /{table} to /{database}/{schema}/{table}, so
+// they are handled (and checked) as the full paths
+func DefaultSchema(database, schema string) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		r.URL.Path = fmt.Sprintf("/%s/%s%s", database, schema, r.URL.Path)
+		r.URL.RawPath = ""
+		next(w, r)
 	})
 }
 
@@ -32,6 +104,9 @@ func AccessControl() negroni.Handler {
 		}
 
 		permission := permissionByMethod(rq.Method)
+		if readActions[path.Base(rq.URL.Path)] {
+			permission = statements.READ
+		}
 		if permission == "" {
 			next(rw, rq)
 			return
@@ -42,11 +117,21 @@ func AccessControl() negroni.Handler {
 			return
 		}
 
-		err := fmt.Errorf("required authorization to table %s", mapPath["table"])
-		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		message := fmt.Sprintf("required authorization to table %s", mapPath["table"])
+		helpers.ErrorResponse(rw, http.StatusUnauthorized, helpers.CodeUnauthorized, message, nil)
 	})
 }
 
+// readActions are the table actions sent with POST that only read the table
+var readActions = map[string]bool{
+	"_search": true,
+}
+
+// publicPaths are served without JWT, e.g. infrastructure probes
+var publicPaths = map[string]bool{
+	"/_health": true,
+}
+
 // JwtMiddleware check if actual request have JWT
 func JwtMiddleware(key string) negroni.Handler {
 	jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
@@ -55,5 +140,48 @@ func JwtMiddleware(key string) negroni.Handler {
 		},
 		SigningMethod: jwt.SigningMethodHS256,
 	})
-	return negroni.HandlerFunc(jwtMiddleware.HandlerWithNext)
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if publicPaths[r.URL.Path] {
+			next(w, r)
+			return
+		}
+		jwtMiddleware.HandlerWithNext(w, r, next)
+	})
+}
+
+// AdminOnly restrict the next handlers to requests with the admin role in the JWT,
+// in debug mode there is no JWT and every request is allowed
+func AdminOnly() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if config.PrestConf.Debug || isAdmin(r) {
+			next(w, r)
+			return
+		}
+		helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, "required admin role", nil)
+	})
+}
+
+// isAdmin check the role claim of the request JWT
+func isAdmin(r *http.Request) bool {
+	claims, ok := tokenClaims(r)
+	return ok && claims["role"] == config.PrestConf.AdminRole
+}
+
+// tokenClaims read the claims of the valid JWT of the request
+func tokenClaims(r *http.Request) (claims jwt.MapClaims, ok bool) {
+	tokenString, err := jwtmiddleware.FromAuthHeader(r)
+	if err != nil || tokenString == "" {
+		return
+	}
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(config.PrestConf.JWTKey), nil
+	})
+	if err != nil || !token.Valid {
+		return
+	}
+	claims, ok = token.Claims.(jwt.MapClaims)
+	return
 }
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+	"github.com/urfave/negroni"
+)
+
+func TestAccessControlReadActions(t *testing.T) {
+	config.PrestConf = &config.Prest{AccessConf: config.AccessConf{
+		Restrict: true,
+		Tables: []config.TablesConf{
+			{Name: "readonly", Permissions: []string{"read"}},
+			{Name: "writeonly", Permissions: []string{"write"}},
+		},
+	}}
+
+	n := negroni.New(AccessControl())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+	}{
+		{"search with read permission", "/prest/public/readonly/_search", http.StatusOK},
+		{"search without read permission", "/prest/public/writeonly/_search", http.StatusUnauthorized},
+		{"insert without write permission", "/prest/public/readonly", http.StatusUnauthorized},
+		{"insert with write permission", "/prest/public/writeonly", http.StatusOK},
+		{"function with write permission", "/prest/public/functions/writeonly", http.StatusOK},
+		{"function without write permission", "/prest/public/functions/readonly", http.StatusUnauthorized},
+		{"function not listed", "/prest/public/functions/pg_terminate_backend", http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("POST", tc.url, nil)
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAccessControlListen(t *testing.T) {
+	config.PrestConf = &config.Prest{AccessConf: config.AccessConf{
+		Restrict: true,
+		Tables: []config.TablesConf{
+			{Name: "events", Permissions: []string{"read"}},
+			{Name: "writeonly", Permissions: []string{"write"}},
+		},
+	}}
+
+	n := negroni.New(AccessControl())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		status      int
+	}{
+		{"listen with read permission", "/prest/_listen/events", http.StatusOK},
+		{"listen without read permission", "/prest/_listen/writeonly", http.StatusUnauthorized},
+		{"listen to a channel not listed", "/prest/_listen/other", http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("GET", tc.url, nil)
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+	}
+}
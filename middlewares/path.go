@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/urfave/negroni"
+)
+
+// NormalizePath collapse the repeated slashes and strip the trailing slash of the
+// request path before the routing, e.g. /prest//public/test/ is /prest/public/test.
+// The query string is kept as is
+func NormalizePath() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if path := normalizePath(r.URL.Path); path != r.URL.Path {
+			r.URL.Path = path
+			r.URL.RawPath = ""
+		}
+		next(w, r)
+	})
+}
+
+func normalizePath(path string) string {
+	for strings.Contains(path, "//") {
+		path = strings.Replace(path, "//", "/", -1)
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
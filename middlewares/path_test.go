@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+func TestNormalizePath(t *testing.T) {
+	n := negroni.New(NormalizePath())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+	}{
+		{"normalized path", "/prest/public/test?name=$eq.a", "/prest/public/test?name=$eq.a"},
+		{"trailing slash", "/prest/public/test/?name=$eq.a", "/prest/public/test?name=$eq.a"},
+		{"doubled slashes", "/prest//public///test?name=$eq.a//b/", "/prest/public/test?name=$eq.a//b/"},
+		{"doubled slashes and trailing slashes", "/prest/public//test//", "/prest/public/test?"},
+		{"root", "/", "/?"},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("GET", tc.url, nil)
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Body.String() != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, w.Body.String())
+		}
+	}
+}
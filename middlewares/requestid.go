@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+// defaultRequestIDHeader is the header of the request ID when request_id_header isn't set
+const defaultRequestIDHeader = "X-Request-ID"
+
+// requestIDRegex are the request IDs accepted from the clients, the other are replaced
+// so the IDs can't forge log lines
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// RequestID read the request ID of the request_id_header header, or generate one, put
+// it in the context of the request, see helpers.RequestID, and echo it in the response
+func RequestID() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		header := requestIDHeader()
+		id := r.Header.Get(header)
+		if !requestIDRegex.MatchString(id) {
+			id = newRequestID()
+		}
+		// the header of the request is shared with the outer middlewares, e.g. the logger
+		r.Header.Set(header, id)
+		w.Header().Set(header, id)
+		next(w, helpers.WithRequestID(r, id))
+	})
+}
+
+func requestIDHeader() string {
+	if config.PrestConf == nil || config.PrestConf.RequestIDHeader == "" {
+		return defaultRequestIDHeader
+	}
+	return config.PrestConf.RequestIDHeader
+}
+
+// newRequestID generate a random request ID of 32 hex digits
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return hex.EncodeToString([]byte(time.Now().UTC().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(id)
+}
+
+// LoggerEntry is the access log line of Logger, negroni.LoggerEntry with the request ID
+type LoggerEntry struct {
+	negroni.LoggerEntry
+	RequestID string
+}
+
+// accessLogger write the lines of Logger
+var accessLogger = log.New(os.Stdout, "[negroni] ", 0)
+
+// LoggerFormat is the format of the access log lines of Logger
+var LoggerFormat = "{{.StartTime}} | {{.Status}} | \t {{.Duration}} | {{.Hostname}} | {{.Method}} {{.Path}} | {{.RequestID}} \n"
+
+// Logger log the requests like negroni.Logger, with the request ID set by RequestID
+func Logger() negroni.Handler {
+	format := template.Must(template.New("logger").Parse(LoggerFormat))
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		start := time.Now()
+		next(w, r)
+
+		entry := LoggerEntry{
+			LoggerEntry: negroni.LoggerEntry{
+				StartTime: start.Format(negroni.LoggerDefaultDateFormat),
+				Duration:  time.Since(start),
+				Hostname:  r.Host,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+			},
+			RequestID: r.Header.Get(requestIDHeader()),
+		}
+		if res, ok := w.(negroni.ResponseWriter); ok {
+			entry.Status = res.Status()
+		}
+		var buf bytes.Buffer
+		format.Execute(&buf, entry)
+		accessLogger.Print(buf.String())
+	})
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nuveo/prest/config"
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+func TestRequestID(t *testing.T) {
+	defer func(conf *config.Prest) {
+		config.PrestConf = conf
+	}(config.PrestConf)
+
+	var testCases = []struct {
+		description string
+		header      string
+		requestID   string
+		generated   bool
+	}{
+		{"request ID of the client", "", "abc-123", false},
+		{"generated request ID", "", "", true},
+		{"invalid request ID", "", "abc\n123", true},
+		{"too long request ID", "", strings.Repeat("a", 129), true},
+		{"configured header", "X-Correlation-ID", "abc-123", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		config.PrestConf = &config.Prest{RequestIDHeader: tc.header}
+		header := tc.header
+		if header == "" {
+			header = defaultRequestIDHeader
+		}
+
+		var inContext string
+		n := negroni.New(RequestID())
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inContext = helpers.RequestID(r)
+		})
+		r := httptest.NewRequest("GET", "/prest/public/test", nil)
+		if tc.requestID != "" {
+			r.Header.Set(header, tc.requestID)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+
+		echoed := w.Header().Get(header)
+		if echoed == "" || echoed != inContext {
+			t.Errorf("expected the request ID %q in the context, got %q", echoed, inContext)
+		}
+		if tc.generated && (echoed == tc.requestID || len(echoed) != 32) {
+			t.Errorf("expected a generated request ID, got %q", echoed)
+		}
+		if !tc.generated && echoed != tc.requestID {
+			t.Errorf("expected the request ID %q, got %q", tc.requestID, echoed)
+		}
+	}
+}
+
+func TestLoggerRequestID(t *testing.T) {
+	defer func(logger *log.Logger) {
+		accessLogger = logger
+	}(accessLogger)
+	var buf bytes.Buffer
+	accessLogger = log.New(&buf, "", 0)
+
+	n := negroni.New(Logger(), RequestID())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r := httptest.NewRequest("GET", "/prest/public/test", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+	n.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := buf.String()
+	if !strings.Contains(line, "| 418 |") || !strings.Contains(line, "GET /prest/public/test | abc-123") {
+		t.Errorf("unexpected log line %q", line)
+	}
+}
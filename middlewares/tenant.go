@@ -0,0 +1,133 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/nuveo/prest/adapters/postgres"
+	"github.com/nuveo/prest/helpers"
+	"github.com/urfave/negroni"
+)
+
+// tenantSchemaRegex match the schemas of the claims, plain names
+var tenantSchemaRegex = regexp.MustCompile(`^[\pL_][\pL\pN_]*$`)
+
+// Tenant replace the schema of the paths /{database}/{schema}/... by the schema in the
+// claim of the JWT, the schema sent by the client is ignored so a tenant only reaches
+// its own schema. The unqualified tables of _join are qualified by the schema of the
+// tenant and the channels of /{database}/_listen/{channel} are prefixed by it. The
+// requests without the claim, of a schema that doesn't exist or reaching other schemas
+// with _search_path, _join or _from are rejected
+func Tenant(claim string) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		claims, _ := tokenClaims(r)
+		schema, _ := claims[claim].(string)
+		if schema == "" {
+			message := fmt.Sprintf("required tenant claim %s", claim)
+			helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, message, nil)
+			return
+		}
+		if !tenantSchemaRegex.MatchString(schema) || len(schema) > 63 {
+			message := fmt.Sprintf("invalid tenant schema %q", schema)
+			helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, message, nil)
+			return
+		}
+		if err := checkTenantSchemas(r, schema); err != nil {
+			helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, "could not perform checkTenantSchemas", err)
+			return
+		}
+
+		exists, err := postgres.SchemaExists(r.Context(), schema)
+		if err != nil {
+			helpers.ErrorResponse(w, http.StatusInternalServerError, helpers.CodeInternal, "could not perform SchemaExists", err)
+			return
+		}
+		if !exists {
+			message := fmt.Sprintf("tenant schema %s does not exist", schema)
+			helpers.ErrorResponse(w, http.StatusForbidden, helpers.CodeForbidden, message, nil)
+			return
+		}
+
+		// "", database, schema and the rest of the path
+		parts := strings.SplitN(r.URL.Path, "/", 4)
+		switch {
+		case len(parts) == 4 && parts[2] == "_listen":
+			parts[3] = fmt.Sprintf("%s.%s", schema, parts[3])
+		case len(parts) >= 3:
+			parts[2] = schema
+		}
+		r.URL.Path = strings.Join(parts, "/")
+		r.URL.RawPath = ""
+		qualifyTenantTables(r, schema)
+		next(w, r)
+	})
+}
+
+// checkTenantSchemas reject the parameters reaching tables out of the schema of the
+// tenant, the tables of _join and _from qualified by other schemas and _search_path
+func checkTenantSchemas(r *http.Request, schema string) error {
+	queries := r.URL.Query()
+	if _, ok := queries["_search_path"]; ok {
+		return fmt.Errorf("_search_path can't be used by the tenants")
+	}
+	for _, join := range queries["_join"] {
+		if args := strings.Split(join, ":"); len(args) > 1 && otherSchema(args[1], schema) {
+			return fmt.Errorf("_join table %s is out of the tenant schema", args[1])
+		}
+	}
+	if from := queries.Get("_from"); otherSchema(from, schema) {
+		return fmt.Errorf("_from table %s is out of the tenant schema", from)
+	}
+	return nil
+}
+
+// qualifyTenantTables qualify the unqualified tables of _join, derived tables included,
+// by schema, otherwise they would be read in the search_path of the connection
+func qualifyTenantTables(r *http.Request, schema string) {
+	queries := r.URL.Query()
+	joins, ok := queries["_join"]
+	if !ok {
+		return
+	}
+
+	// postgres fold the unquoted names, the others are quoted
+	if foldName(schema) != schema {
+		schema = fmt.Sprintf(`"%s"`, schema)
+	}
+	for i, join := range joins {
+		args := strings.Split(join, ":")
+		if len(args) < 2 || args[1] == "" || strings.Contains(strings.SplitN(args[1], "@", 2)[0], ".") {
+			continue
+		}
+		args[1] = fmt.Sprintf("%s.%s", schema, args[1])
+		joins[i] = strings.Join(args, ":")
+	}
+	r.URL.RawQuery = queries.Encode()
+}
+
+// otherSchema check if table is qualified by a schema other than schema, the derived
+// tables table@alias are checked by their table. The unquoted names are compared
+// folded to lower case, as postgres reads them
+func otherSchema(table, schema string) bool {
+	table = strings.SplitN(table, "@", 2)[0]
+	parts := strings.SplitN(table, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	if name := parts[0]; len(name) > 1 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		return strings.Trim(name, `"`) != schema
+	}
+	return foldName(parts[0]) != schema
+}
+
+// foldName lower the ASCII letters of an unquoted name, like postgres
+func foldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, name)
+}
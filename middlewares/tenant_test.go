@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/nuveo/prest/config"
+	"github.com/urfave/negroni"
+)
+
+func tenantToken(t *testing.T, claims jwt.MapClaims) string {
+	return signTenantToken(t, config.PrestConf.JWTKey, claims)
+}
+
+func signTenantToken(t *testing.T, key string, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestTenantRejected(t *testing.T) {
+	config.PrestConf = &config.Prest{JWTKey: "secret"}
+
+	n := negroni.New(Tenant("tenant"))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+
+	var testCases = []struct {
+		description   string
+		url           string
+		authorization string
+	}{
+		{"without JWT", "/prest/public/test", ""},
+		{"without the claim", "/prest/public/test", tenantToken(t, jwt.MapClaims{"role": "reader"})},
+		{"with an empty claim", "/prest/public/test", tenantToken(t, jwt.MapClaims{"tenant": ""})},
+		{"with a claim that isn't a string", "/prest/public/test", tenantToken(t, jwt.MapClaims{"tenant": 1})},
+		{"with an invalid claim", "/prest/public/test", tenantToken(t, jwt.MapClaims{"tenant": "acme;drop"})},
+		{"with a qualified claim", "/prest/public/test", tenantToken(t, jwt.MapClaims{"tenant": "other.acme"})},
+		{"signed with other key", "/prest/public/test", signTenantToken(t, "other", jwt.MapClaims{"tenant": "acme"})},
+		{"with _search_path", "/prest/acme/test?_search_path=other", tenantToken(t, jwt.MapClaims{"tenant": "acme"})},
+		{"joining a table of other schema", "/prest/acme/test?_join=inner:other.users:users.id:$eq:test.user_id", tenantToken(t, jwt.MapClaims{"tenant": "acme"})},
+		{"joining a derived table of other schema", "/prest/acme/test?_join=inner:other.users@u:u.id:$eq:test.user_id", tenantToken(t, jwt.MapClaims{"tenant": "acme"})},
+		{"copying from other schema", "/prest/acme/test/_copy?_from=other.test", tenantToken(t, jwt.MapClaims{"tenant": "acme"})},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("GET", tc.url, nil)
+		if tc.authorization != "" {
+			r.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestOtherSchema(t *testing.T) {
+	var testCases = []struct {
+		table    string
+		expected bool
+	}{
+		{"users", false},
+		{"acme.users", false},
+		{`"acme".users`, false},
+		{"acme.users@u", false},
+		{"users@u", false},
+		{"other.users", true},
+		{`"other".users`, true},
+		{"other.users@u", true},
+		{"ACME.users", false},
+		{`"ACME".users`, true},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.table)
+		if other := otherSchema(tc.table, "acme"); other != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, other)
+		}
+	}
+}
+
+func TestOtherSchemaMixedCase(t *testing.T) {
+	var testCases = []struct {
+		table    string
+		expected bool
+	}{
+		{`"Acme".users`, false},
+		{"Acme.users", true},
+		{"acme.users", true},
+		{"users", false},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.table)
+		if other := otherSchema(tc.table, "Acme"); other != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, other)
+		}
+	}
+}
+
+func TestQualifyTenantTables(t *testing.T) {
+	var testCases = []struct {
+		description string
+		query       string
+		schema      string
+		expected    []string
+	}{
+		{"unqualified table", "_join=inner:users:users.id:$eq:test.user_id", "acme", []string{"inner:acme.users:users.id:$eq:test.user_id"}},
+		{"qualified table", "_join=inner:acme.users:users.id:$eq:test.user_id", "acme", []string{"inner:acme.users:users.id:$eq:test.user_id"}},
+		{"derived table", "_join=inner:users@u:u.id:$eq:test.user_id", "acme", []string{"inner:acme.users@u:u.id:$eq:test.user_id"}},
+		{"mixed case schema", "_join=inner:users:users.id:$eq:test.user_id", "Acme", []string{`inner:"Acme".users:users.id:$eq:test.user_id`}},
+		{"repeated joins", "_join=inner:users:users.id:$eq:test.user_id&_join=left:teams@t:t.id:$eq:users.team_id", "acme", []string{"inner:acme.users:users.id:$eq:test.user_id", "left:acme.teams@t:t.id:$eq:users.team_id"}},
+		{"without joins", "name=$eq.test", "acme", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("GET", "/prest/acme/test", nil)
+		r.URL.RawQuery = tc.query
+		qualifyTenantTables(r, tc.schema)
+		joins := r.URL.Query()["_join"]
+		if !reflect.DeepEqual(joins, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, joins)
+		}
+	}
+}
+
+func TestTenantRewriteSchema(t *testing.T) {
+	config.Load()
+
+	n := negroni.New(Tenant("tenant"))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		tenant      string
+		status      int
+		expected    string
+	}{
+		{"schema of the tenant", "/prest/public/test", "public", http.StatusOK, "/prest/public/test"},
+		{"schema of other tenant", "/prest/other/test", "public", http.StatusOK, "/prest/public/test"},
+		{"table action", "/prest/other/test/_search", "public", http.StatusOK, "/prest/public/test/_search"},
+		{"channel of the tenant", "/prest/_listen/events", "public", http.StatusOK, "/prest/_listen/public.events"},
+		{"schema that doesn't exist", "/prest/public/test", "missing_tenant", http.StatusForbidden, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		r := httptest.NewRequest("GET", tc.url, nil)
+		r.Header.Set("Authorization", tenantToken(t, jwt.MapClaims{"tenant": tc.tenant}))
+		w := httptest.NewRecorder()
+		n.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+		}
+		if tc.expected != "" && w.Body.String() != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, w.Body.String())
+		}
+	}
+}
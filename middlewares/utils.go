@@ -9,12 +9,22 @@ import (
 	"strings"
 
 	"github.com/clbanning/mxj/j2x"
+	"github.com/nuveo/prest/helpers"
 	"github.com/nuveo/prest/statements"
 )
 
 func getVars(path string) (paths map[string]string) {
 	pathList := strings.Split(path, "/")
 
+	// table actions, e.g. /{database}/{schema}/{table}/_refresh
+	if len(pathList) == 5 && strings.HasPrefix(pathList[4], "_") {
+		pathList = pathList[:4]
+	}
+	// functions, e.g. /{database}/{schema}/functions/{function}, by the function name
+	if len(pathList) == 5 && pathList[3] == "functions" {
+		pathList = append(pathList[:3], pathList[4])
+	}
+
 	if len(pathList) < 3 || len(pathList) > 4 {
 		return nil
 	} else if len(pathList) == 4 {
@@ -24,13 +34,17 @@ func getVars(path string) (paths map[string]string) {
 	paths["database"] = pathList[0]
 	paths["schema"] = pathList[1]
 	paths["table"] = pathList[2]
+	// table functions, e.g. /{database}/{schema}/{function}(1,10), by the function name
+	if i := strings.Index(pathList[2], "("); i > 0 {
+		paths["table"] = pathList[2][:i]
+	}
 
 	return
 }
 
 func permissionByMethod(method string) (permission string) {
 	switch method {
-	case "GET":
+	case "GET", "HEAD":
 		permission = statements.READ
 	case "POST", "PATCH", "PUT":
 		permission = statements.WRITE
@@ -46,17 +60,28 @@ func permissionByMethod(method string) (permission string) {
 func renderFormat(w http.ResponseWriter, recorder *httptest.ResponseRecorder, format string) {
 	byt, _ := ioutil.ReadAll(recorder.Body)
 
-	if recorder.Code != http.StatusOK {
-		m := make(map[string]string)
-		m["error"] = strings.TrimSpace(string(byt))
-		byt, _ = json.MarshalIndent(m, "", "\t")
+	// errors written by http.Error are plain text, e.g. not found routes
+	isJSON := strings.Contains(recorder.Header().Get("Content-Type"), "json")
+	if recorder.Code >= http.StatusBadRequest && !isJSON {
+		byt, _ = json.Marshal(map[string]helpers.Error{"error": {
+			Code:    helpers.CodeFromStatus(recorder.Code),
+			Message: strings.TrimSpace(string(byt)),
+		}})
+	}
+
+	// headers set by controllers, the content type depends on the renderer
+	for key, values := range recorder.Header() {
+		if key == "Content-Type" || key == "Content-Length" {
+			continue
+		}
+		w.Header()[key] = values
 	}
 
 	switch format {
 	case "xml":
 		xmldata, err := j2x.JsonToXml(byt)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			helpers.ErrorResponse(w, http.StatusBadRequest, helpers.CodeBadRequest, "could not render XML", err)
 			return
 		}
 		xmlStr := fmt.Sprintf("<objects>%s</objects>", string(xmldata))
@@ -64,7 +89,13 @@ func renderFormat(w http.ResponseWriter, recorder *httptest.ResponseRecorder, fo
 		w.WriteHeader(recorder.Code)
 		w.Write([]byte(xmlStr))
 	default:
-		w.Header().Set("Content-Type", "application/json")
+		// keep JSON based media types set by controllers, e.g. application/geo+json, and
+		// the XML rendered by controllers, e.g. the rows of Accept: application/xml
+		contentType := recorder.Header().Get("Content-Type")
+		if !isJSON && !strings.Contains(contentType, "xml") {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
 		w.WriteHeader(recorder.Code)
 		w.Write(byt)
 	}
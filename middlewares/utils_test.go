@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/statements"
+)
+
+func TestPermissionByMethod(t *testing.T) {
+	var testCases = []struct {
+		method   string
+		expected string
+	}{
+		{"GET", statements.READ},
+		{"HEAD", statements.READ},
+		{"POST", statements.WRITE},
+		{"PATCH", statements.WRITE},
+		{"PUT", statements.WRITE},
+		{"DELETE", statements.DELETE},
+		{"OPTIONS", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.method)
+		if permission := permissionByMethod(tc.method); permission != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, permission)
+		}
+	}
+}
+
+func TestGetVars(t *testing.T) {
+	var testCases = []struct {
+		description string
+		path        string
+		expected    map[string]string
+	}{
+		{"table", "/prest/public/test", map[string]string{"database": "prest", "schema": "public", "table": "test"}},
+		{"table action", "/prest/public/test/_pk", map[string]string{"database": "prest", "schema": "public", "table": "test"}},
+		{"table function", "/prest/public/test_series(1,10)", map[string]string{"database": "prest", "schema": "public", "table": "test_series"}},
+		{"function", "/prest/public/functions/pg_terminate_backend", map[string]string{"database": "prest", "schema": "public", "table": "pg_terminate_backend"}},
+		{"not a table", "/prest", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if paths := getVars(tc.path); !reflect.DeepEqual(paths, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, paths)
+		}
+	}
+}
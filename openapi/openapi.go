@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nuveo/prest/adapters/postgres"
+)
+
+// Version of the OpenAPI specification of the documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document describing the tables of a database
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info of the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem operations of a path by HTTP method in lower case
+type PathItem map[string]*Operation
+
+// Operation on a path
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter of an operation, Ref point to the parameters in the components
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody of the writes
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response of an operation by status code
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema of a value, an empty schema accepts any value
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+}
+
+// Components referenced by the paths
+type Components struct {
+	Schemas    map[string]*Schema   `json:"schemas"`
+	Parameters map[string]Parameter `json:"parameters"`
+}
+
+// operators of the where filters in the query string
+const operators = "Filter by the column, the value is optionally prefixed by an operator: " +
+	"$eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $null and $notnull, e.g. $gte.10 or $in.1,2,3"
+
+// queryParameters of the selects, they are shared by all the tables
+var queryParameters = map[string]Parameter{
+	"_select":    {Name: "_select", In: "query", Description: "Comma separated columns to return, column:as:alias renames a column", Schema: &Schema{Type: "string"}},
+	"_exclude":   {Name: "_exclude", In: "query", Description: "Comma separated columns to leave out of the response", Schema: &Schema{Type: "string"}},
+	"_order":     {Name: "_order", In: "query", Description: "Comma separated columns to sort by, -column sorts descending and :nullsfirst or :nullslast place the nulls", Schema: &Schema{Type: "string"}},
+	"_page":      {Name: "_page", In: "query", Description: "Page of the rows, starting at 1", Schema: &Schema{Type: "integer"}},
+	"_page_size": {Name: "_page_size", In: "query", Description: "Rows of each page", Schema: &Schema{Type: "integer"}},
+	"_count":     {Name: "_count", In: "query", Description: "Count the rows, * or a column", Schema: &Schema{Type: "string"}},
+	"_groupby":   {Name: "_groupby", In: "query", Description: "Comma separated columns to group by", Schema: &Schema{Type: "string"}},
+	"_tz":        {Name: "_tz", In: "query", Description: "Time zone of the timestamps compared with the timestamptz columns, e.g. America/Sao_Paulo", Schema: &Schema{Type: "string"}},
+}
+
+// Build the document of the table columns, every table is a path
+// /{database}/{schema}/{table} with its rows as a schema named schema.table
+func Build(database string, columns []postgres.TableColumn) (doc Document) {
+	doc = Document{
+		OpenAPI: Version,
+		Info:    Info{Title: fmt.Sprintf("pREST %s", database), Version: "1.0.0"},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error":        errorSchema(),
+				"RowsAffected": {Type: "object", Properties: map[string]*Schema{"rows_affected": {Type: "integer", Format: "int64"}}},
+			},
+			Parameters: queryParameters,
+		},
+	}
+
+	tables := make(map[string][]postgres.TableColumn)
+	for _, column := range columns {
+		name := fmt.Sprintf("%s.%s", column.Schema, column.Table)
+		tables[name] = append(tables[name], column)
+	}
+
+	for name, columns := range tables {
+		properties := make(map[string]*Schema, len(columns))
+		filters := make([]Parameter, 0, len(columns))
+		for _, column := range columns {
+			schema := columnSchema(column.DataType)
+			schema.Nullable = column.Nullable
+			properties[column.Name] = schema
+			filters = append(filters, Parameter{Name: column.Name, In: "query", Description: operators, Schema: &Schema{Type: "string"}})
+		}
+		sort.Slice(filters, func(i, j int) bool { return filters[i].Name < filters[j].Name })
+		doc.Components.Schemas[name] = &Schema{Type: "object", Properties: properties}
+
+		path := fmt.Sprintf("/%s/%s/%s", database, columns[0].Schema, columns[0].Table)
+		doc.Paths[path] = tablePath(name, filters)
+	}
+	return
+}
+
+// tablePath of the CRUD operations of a table
+func tablePath(name string, filters []Parameter) PathItem {
+	row := &Schema{Ref: "#/components/schemas/" + name}
+	rows := &Schema{Type: "array", Items: row}
+	rowsAffected := &Schema{Ref: "#/components/schemas/RowsAffected"}
+
+	selectParameters := make([]Parameter, 0, len(queryParameters)+len(filters))
+	keys := make([]string, 0, len(queryParameters))
+	for key := range queryParameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		selectParameters = append(selectParameters, Parameter{Ref: "#/components/parameters/" + key})
+	}
+	selectParameters = append(selectParameters, filters...)
+
+	tags := []string{name}
+	update := &Operation{Summary: fmt.Sprintf("Update the rows of %s", name), Tags: tags, Parameters: filters, RequestBody: jsonBody(row), Responses: responses(rowsAffected)}
+	return PathItem{
+		"get":    {Summary: fmt.Sprintf("Select the rows of %s", name), Tags: tags, Parameters: selectParameters, Responses: responses(rows)},
+		"post":   {Summary: fmt.Sprintf("Insert a row in %s", name), Tags: tags, RequestBody: jsonBody(row), Responses: responses(row)},
+		"put":    update,
+		"patch":  update,
+		"delete": {Summary: fmt.Sprintf("Delete the rows of %s", name), Tags: tags, Parameters: filters, Responses: responses(rowsAffected)},
+	}
+}
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
+
+func responses(schema *Schema) map[string]Response {
+	errorContent := map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}}
+	return map[string]Response{
+		"200": {Description: "OK", Content: map[string]MediaType{"application/json": {Schema: schema}}},
+		"400": {Description: "Invalid request", Content: errorContent},
+		"404": {Description: "Table not found", Content: errorContent},
+	}
+}
+
+// errorSchema of the error envelope of helpers.ErrorResponse
+func errorSchema() *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{
+		"error": {Type: "object", Properties: map[string]*Schema{
+			"code":    {Type: "string"},
+			"message": {Type: "string"},
+			"detail":  {Type: "string"},
+		}},
+	}}
+}
+
+// columnSchema map the information_schema data types to OpenAPI types, the types
+// without equivalent, e.g. json, accept any value
+func columnSchema(dataType string) *Schema {
+	switch dataType {
+	case "smallint", "integer":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "bigint":
+		return &Schema{Type: "integer", Format: "int64"}
+	case "real":
+		return &Schema{Type: "number", Format: "float"}
+	case "double precision":
+		return &Schema{Type: "number", Format: "double"}
+	case "numeric":
+		return &Schema{Type: "number"}
+	case "boolean":
+		return &Schema{Type: "boolean"}
+	case "date":
+		return &Schema{Type: "string", Format: "date"}
+	case "timestamp without time zone", "timestamp with time zone":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "bytea":
+		return &Schema{Type: "string", Format: "byte"}
+	case "ARRAY":
+		return &Schema{Type: "array", Items: &Schema{}}
+	case "json", "jsonb":
+		return &Schema{}
+	}
+	return &Schema{Type: "string"}
+}
@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nuveo/prest/adapters/postgres"
+)
+
+func TestBuild(t *testing.T) {
+	columns := []postgres.TableColumn{
+		{Schema: "public", Table: "test", Name: "id", DataType: "integer"},
+		{Schema: "public", Table: "test", Name: "name", DataType: "text", Nullable: true},
+		{Schema: "public", Table: "test", Name: "data", DataType: "jsonb", Nullable: true},
+		{Schema: "other", Table: "test", Name: "created_at", DataType: "timestamp with time zone"},
+	}
+
+	doc := Build("prest", columns)
+	if doc.OpenAPI != Version {
+		t.Errorf("expected openapi %s, got %s", Version, doc.OpenAPI)
+	}
+
+	t.Log("a path per table")
+	for _, path := range []string{"/prest/public/test", "/prest/other/test"} {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("expected path %s", path)
+			continue
+		}
+		for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+			if item[method] == nil {
+				t.Errorf("expected %s in %s", method, path)
+			}
+		}
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("expected 2 paths, got %d", len(doc.Paths))
+	}
+
+	t.Log("columns as schema properties")
+	expected := &Schema{Type: "object", Properties: map[string]*Schema{
+		"id":   {Type: "integer", Format: "int32"},
+		"name": {Type: "string", Nullable: true},
+		"data": {Nullable: true},
+	}}
+	if schema := doc.Components.Schemas["public.test"]; !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected %+v, got %+v", expected, schema)
+	}
+
+	t.Log("query parameters and filters of the select")
+	var refs, filters []string
+	for _, parameter := range doc.Paths["/prest/public/test"]["get"].Parameters {
+		if parameter.Ref != "" {
+			refs = append(refs, parameter.Ref)
+			continue
+		}
+		filters = append(filters, parameter.Name)
+	}
+	if len(refs) != len(queryParameters) {
+		t.Errorf("expected %d query parameters, got %v", len(queryParameters), refs)
+	}
+	if !reflect.DeepEqual(filters, []string{"data", "id", "name"}) {
+		t.Errorf("expected the filters of the columns, got %v", filters)
+	}
+}
+
+func TestColumnSchema(t *testing.T) {
+	var testCases = []struct {
+		dataType string
+		expected *Schema
+	}{
+		{"integer", &Schema{Type: "integer", Format: "int32"}},
+		{"bigint", &Schema{Type: "integer", Format: "int64"}},
+		{"numeric", &Schema{Type: "number"}},
+		{"boolean", &Schema{Type: "boolean"}},
+		{"timestamp without time zone", &Schema{Type: "string", Format: "date-time"}},
+		{"ARRAY", &Schema{Type: "array", Items: &Schema{}}},
+		{"json", &Schema{}},
+		{"character varying", &Schema{Type: "string"}},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.dataType)
+		if schema := columnSchema(tc.dataType); !reflect.DeepEqual(schema, tc.expected) {
+			t.Errorf("expected %+v, got %+v", tc.expected, schema)
+		}
+	}
+}
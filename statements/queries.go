@@ -113,6 +113,19 @@ FROM`
 	InsertQuery = `
 INSERT INTO %s.%s.%s(%s) VALUES(%s)`
 
+	// InsertIfNotExists insert the row only if no row matches the where, the inserted
+	// or the first matching row is returned with true when it was inserted
+	InsertIfNotExists = `
+WITH existing AS (SELECT * FROM %s.%s.%s WHERE %s LIMIT 1),
+inserted AS (INSERT INTO %s.%s.%s(%s) SELECT %s WHERE NOT EXISTS (SELECT 1 FROM existing) RETURNING *)
+SELECT row_to_json(inserted), true FROM inserted
+UNION ALL
+SELECT row_to_json(existing), false FROM existing`
+
+	// CopyQuery copy the rows selected from a table to another
+	CopyQuery = `
+INSERT INTO %s.%s.%s(%s) SELECT %s FROM %s.%s.%s`
+
 	// DeleteQuery query
 	DeleteQuery = `
 DELETE FROM %s.%s.%s`
@@ -121,11 +134,358 @@ DELETE FROM %s.%s.%s`
 	UpdateQuery = `
 UPDATE %s.%s.%s SET %s`
 
+	// UpdateChanged update the rows locked with their previous values, the new rows are
+	// returned with the _changed columns whose value differ from the previous one
+	UpdateChanged = `
+WITH updated AS (
+	UPDATE %s.%s.%s SET %s
+	FROM (SELECT ctid AS _ctid, to_jsonb(%s) AS _old FROM %s.%s.%s%s FOR UPDATE) _prev
+	WHERE %s.ctid = _prev._ctid
+	RETURNING to_jsonb(%s) AS _new, _prev._old
+)
+SELECT
+	COALESCE(json_agg(_new || jsonb_build_object('_changed', (
+		SELECT COALESCE(jsonb_agg(n.key ORDER BY n.key), '[]') FROM jsonb_each(_new) n WHERE n.value IS DISTINCT FROM _old -> n.key
+	))), '[]'),
+	count(*)
+FROM updated`
+
+	// TableExists query, tables and views in information_schema and materialized views
+	TableExists = `
+SELECT EXISTS (
+	SELECT 1 FROM information_schema.tables WHERE table_catalog = $1 AND table_schema = $2 AND table_name = $3
+	UNION ALL
+	SELECT 1 FROM pg_catalog.pg_matviews WHERE current_database() = $1 AND schemaname = $2 AND matviewname = $3
+)`
+
+	// MaterializedViewExists query
+	MaterializedViewExists = `
+SELECT EXISTS (
+	SELECT 1 FROM pg_catalog.pg_matviews WHERE schemaname = $1 AND matviewname = $2
+)`
+
+	// RefreshMaterializedView query
+	RefreshMaterializedView = `
+REFRESH MATERIALIZED VIEW %s%s.%s.%s`
+
+	// PrimaryKey list the primary key columns of a table in the key order
+	PrimaryKey = `
+SELECT
+	COALESCE(json_agg(a.attname ORDER BY k.ord), '[]'::json)
+FROM
+	pg_index i
+	INNER JOIN pg_class c ON c.oid = i.indrelid
+	INNER JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+	INNER JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+WHERE
+	i.indisprimary AND
+	n.nspname = $1 AND
+	c.relname = $2`
+
+	// Columns list the name and type of the columns of a table in the table order
+	Columns = `
+SELECT
+	COALESCE(json_agg(json_build_object(
+		'name', a.attname,
+		'type', format_type(a.atttypid, a.atttypmod)%s
+	) ORDER BY a.attnum), '[]'::json)
+FROM
+	pg_attribute a
+	INNER JOIN pg_class c ON c.oid = a.attrelid
+	INNER JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE
+	a.attnum > 0 AND
+	NOT a.attisdropped AND
+	n.nspname = $1 AND
+	c.relname = $2`
+
+	// ColumnTypes list the name and type of the columns of a table
+	ColumnTypes = `
+SELECT
+	a.attname AS name,
+	a.atttypid::regtype::text AS type
+FROM
+	pg_attribute a
+	INNER JOIN pg_class c ON c.oid = a.attrelid
+	INNER JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE
+	a.attnum > 0 AND
+	NOT a.attisdropped AND
+	n.nspname = $1 AND
+	c.relname = $2
+ORDER BY
+	a.attnum`
+
+	// TypeAssignable check if a value of the type $1 can be stored in a column of the
+	// type $2, like INSERT does: same type, string target or an assignment cast
+	TypeAssignable = `
+SELECT
+	$1::regtype = $2::regtype OR
+	(SELECT typcategory = 'S' FROM pg_type WHERE oid = $2::regtype) OR
+	EXISTS (
+		SELECT 1 FROM pg_cast
+		WHERE castsource = $1::regtype AND casttarget = $2::regtype AND castcontext IN ('a', 'i')
+	)`
+
+	// ColumnsComment field of Columns with the COMMENT ON COLUMN, empty without comment
+	ColumnsComment = `,
+		'comment', COALESCE(col_description(c.oid, a.attnum), '')`
+
+	// Explain prefix of queries returning the plan
+	Explain = "EXPLAIN (FORMAT JSON) "
+
+	// ExplainAnalyze prefix of queries returning the plan with the execution
+	ExplainAnalyze = "EXPLAIN (ANALYZE, FORMAT JSON) "
+
+	// CreateSchema query
+	CreateSchema = `
+CREATE SCHEMA %s`
+
+	// DropSchema query
+	DropSchema = `
+DROP SCHEMA %s%s`
+
+	// SchemaExists query
+	SchemaExists = `
+SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = $1)`
+
+	// CreateTable query
+	CreateTable = `
+CREATE TABLE %s.%s.%s (%s)`
+
+	// AddColumn query
+	AddColumn = `
+ALTER TABLE %s.%s.%s ADD COLUMN %s`
+
+	// SetTransactionIsolation set the isolation level of the transaction, before its first query
+	SetTransactionIsolation = "SET TRANSACTION ISOLATION LEVEL %s"
+
+	// SelectTypes select the types of the columns of a query, a row of nulls of a join on false
+	SelectTypes = "SELECT %s FROM (SELECT 1) one LEFT JOIN (%s) s ON false"
+
+	// SetSearchPath set the search_path until the end of the transaction
+	SetSearchPath = "SET LOCAL search_path TO %s"
+
+	// TruncateTable query
+	TruncateTable = `
+TRUNCATE TABLE %s.%s.%s%s%s`
+
+	// FunctionArguments list input arguments of a function (IN, INOUT and VARIADIC),
+	// one row with null name and type for functions without arguments. proargnames and
+	// proargmodes follow proallargtypes, which is null when all the arguments are IN
+	FunctionArguments = `
+SELECT
+	p.oid,
+	a.name,
+	format_type(a.type, NULL)
+FROM
+	pg_catalog.pg_proc p
+INNER JOIN
+	pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+LEFT JOIN LATERAL
+	unnest(coalesce(p.proallargtypes, p.proargtypes::oid[]), p.proargmodes, p.proargnames)
+		WITH ORDINALITY AS a(type, mode, name, n) ON coalesce(a.mode, 'i') IN ('i', 'b', 'v')
+WHERE
+	n.nspname = $1 AND
+	p.proname = $2
+ORDER BY
+	p.oid, a.n`
+
+	// FunctionCall query
+	FunctionCall = `
+SELECT * FROM %s(%s)`
+
+	// TableColumns list the columns of a table
+	TableColumns = `
+SELECT
+	column_name
+FROM
+	information_schema.columns
+WHERE
+	table_catalog = $1 AND
+	table_schema = $2 AND
+	table_name = $3
+ORDER BY
+	ordinal_position`
+
+	// SchemasColumns list the columns of the tables and views of the schemas, all the
+	// schemas but the system ones when $2 is null
+	SchemasColumns = `
+SELECT
+	table_schema,
+	table_name,
+	column_name,
+	data_type,
+	is_nullable = 'YES' AS nullable
+FROM
+	information_schema.columns
+WHERE
+	table_catalog = $1 AND
+	table_schema NOT IN ('pg_catalog', 'information_schema') AND
+	($2::text[] IS NULL OR table_schema = ANY($2::text[]))
+ORDER BY
+	table_schema,
+	table_name,
+	ordinal_position`
+
+	// Envelope select a page of a query with the total of rows of the query, counted by a
+	// window over the rows before the page. Pages after the last row have no rows to
+	// carry the window so the query is counted again
+	Envelope = `
+SELECT
+	COALESCE(json_agg(s.q), '[]'::json),
+	COALESCE(max(s.total), (SELECT count(*) FROM (%s) c))
+FROM
+	(SELECT q, count(*) OVER() AS total FROM (%s) q %s) s`
+
+	// AdvisoryXactLock wait for the advisory lock of a key until the end of the transaction
+	AdvisoryXactLock = `SELECT pg_advisory_xact_lock($1)`
+
+	// CountEstimate read the estimate of rows of a table in the statistics of the planner,
+	// reltuples is -1 while the table was never vacuumed or analyzed
+	CountEstimate = `
+SELECT
+	GREATEST(reltuples, 0)::bigint
+FROM
+	pg_class
+WHERE
+	oid = format('%I.%I', $1::text, $2::text)::regclass`
+
+	// Activity list the queries running in the server with the duration in milliseconds, the
+	// background processes have no user (backend_type only exists since PostgreSQL 10)
+	Activity = `
+SELECT
+	COALESCE(json_agg(s), '[]'::json)
+FROM
+	(SELECT
+		pid,
+		usename AS user,
+		datname AS database,
+		client_addr::text AS client_addr,
+		state,
+		wait_event_type,
+		query,
+		query_start,
+		(EXTRACT(EPOCH FROM now() - query_start) * 1000)::bigint AS duration_ms
+	FROM
+		pg_stat_activity
+	WHERE
+		state <> 'idle' AND
+		usename IS NOT NULL AND
+		query IS NOT NULL AND
+		pid <> pg_backend_pid()
+	ORDER BY
+		query_start) s`
+
+	// CancelBackend cancel the query of a backend, false when the pid isn't a backend
+	CancelBackend = `SELECT pg_cancel_backend($1)`
+
+	// SelectGroups select the groups of a `_count=*` with `_groupby`, they are counted by CountRows
+	SelectGroups = "SELECT 1 FROM"
+
+	// CountRows count the rows returned by a query
+	CountRows = `
+SELECT COUNT(*) FROM (%s) s`
+
+	// TimestamptzColumns list the timestamp with time zone columns of a table
+	TimestamptzColumns = `
+SELECT
+	column_name
+FROM
+	information_schema.columns
+WHERE
+	table_catalog = $1 AND
+	table_schema = $2 AND
+	table_name = $3 AND
+	data_type = 'timestamp with time zone'`
+
+	// DateColumns list the date and timestamp columns of a table
+	DateColumns = `
+SELECT
+	column_name
+FROM
+	information_schema.columns
+WHERE
+	table_catalog = $1 AND
+	table_schema = $2 AND
+	table_name = $3 AND
+	data_type IN ('date', 'timestamp without time zone', 'timestamp with time zone')`
+
+	// GeometryColumns list geometry and geography columns of a table
+	GeometryColumns = `
+SELECT
+	column_name
+FROM
+	information_schema.columns
+WHERE
+	table_catalog = $1 AND
+	table_schema = $2 AND
+	table_name = $3 AND
+	udt_name IN ('geometry', 'geography')
+ORDER BY
+	ordinal_position`
+
+	// GeoJSONFeatureCollection wrap the rows of a query in a GeoJSON FeatureCollection,
+	// the geometry column is removed from the feature properties
+	GeoJSONFeatureCollection = `
+SELECT json_build_object(
+	'type', 'FeatureCollection',
+	'features', COALESCE(json_agg(json_build_object(
+		'type', 'Feature',
+		'geometry', ST_AsGeoJSON(s.%s)::json,
+		'properties', to_jsonb(s) - '%s'
+	)), '[]')
+) FROM (%s) s`
+
 	// GroupBy query
 	GroupBy = `GROUP BY %s`
 
 	// Having query
 	Having = `HAVING %s %s %s`
+
+	// RecursiveQuery walk a self-referencing table starting from the anchor rows, the path
+	// of the visited fields stops the cycles, tree has only the columns of the table
+	RecursiveQuery = `WITH RECURSIVE tree_walk AS (
+	SELECT node, 0 AS depth, ARRAY[node.%[2]s] AS path FROM %[1]s node WHERE %[4]s
+	UNION ALL
+	SELECT c, w.depth + 1, w.path || c.%[2]s FROM %[1]s c INNER JOIN tree_walk w ON c.%[3]s = (w.node).%[2]s
+	WHERE c.%[2]s <> ALL(w.path)%[5]s
+), tree AS (SELECT (node).* FROM tree_walk)`
+
+	// RecursiveDepth limit the levels walked below the anchor rows
+	RecursiveDepth = ` AND w.depth < %d`
+
+	// ForeignKeys list the single column foreign keys between the tables of a schema
+	// referencing or referenced by a table
+	ForeignKeys = `
+SELECT
+	ct.relname AS child,
+	ca.attname AS child_column,
+	pt.relname AS parent,
+	pa.attname AS parent_column
+FROM
+	pg_constraint k
+	INNER JOIN pg_class ct ON ct.oid = k.conrelid
+	INNER JOIN pg_class pt ON pt.oid = k.confrelid
+	INNER JOIN pg_namespace cn ON cn.oid = ct.relnamespace
+	INNER JOIN pg_namespace pn ON pn.oid = pt.relnamespace
+	INNER JOIN pg_attribute ca ON ca.attrelid = k.conrelid AND ca.attnum = k.conkey[1]
+	INNER JOIN pg_attribute pa ON pa.attrelid = k.confrelid AND pa.attnum = k.confkey[1]
+WHERE
+	k.contype = 'f' AND
+	array_length(k.conkey, 1) = 1 AND
+	cn.nspname = $1 AND
+	pn.nspname = $1 AND
+	(ct.relname = $2 OR pt.relname = $2)
+ORDER BY
+	k.conname`
+
+	// EmbedMany select the rows referencing the row of the outer select as a json array
+	EmbedMany = `(SELECT COALESCE(json_agg(embedded), '[]'::json) FROM (SELECT %s FROM %s.%s.%s embedded WHERE embedded.%s = %s.%s.%s) embedded) AS %s`
+
+	// EmbedOne select the row referenced by the row of the outer select as a json object
+	EmbedOne = `(SELECT row_to_json(embedded) FROM (SELECT %s FROM %s.%s.%s embedded WHERE embedded.%s = %s.%s.%s LIMIT 1) embedded) AS %s`
 )
 
 var (
@@ -19,6 +19,24 @@ psql prest -c "create table testjson(name text, data jsonb);" -U postgres
 psql prest -c "create table testarray(id serial, data character varying(250)[]);" -U postgres
 psql prest -c "create table test_empty_table(id serial, data character varying(250)[]);" -U postgres
 psql prest -c "create table test_group_by_table(id serial, name text, age integer, salary int);" -U postgres
+psql prest -c "create table test_numbers(age integer, population bigint, big bigint, price numeric(10,2), rate float8);" -U postgres
+psql prest -c "create table test_decimals(amount numeric(30,10), price numeric(10,2));" -U postgres
+psql prest -c "create table test_coercions(price money, data bytea, active boolean);" -U postgres
+psql prest -c "create table test_affected_rows(id serial, name text);" -U postgres
+psql prest -c "create table test_versioned(id serial, name text, version integer not null default 1);" -U postgres
+psql prest -c "create table test_composite_pk(a integer, b integer, name text, primary key(b, a));" -U postgres
+psql prest -c "create table test_case_columns(id serial, \"userId\" integer, name text);" -U postgres
+psql prest -c "create table test_timestamps(id serial, created_at timestamptz, updated_at timestamp);" -U postgres
+psql prest -c "comment on column test4.name is 'name of the row';" -U postgres
+psql prest -c "create table test_categories_archive(id integer, title text, parent_id bigint, archived_at timestamptz default now());" -U postgres
+psql prest -c "create table test_bulk_delete(id serial, name text);" -U postgres
+psql prest -c "create table test_articles(id serial, title text, search_vector tsvector);" -U postgres
+psql prest -c "create table \"Test_Order\"(id serial, \"select\" text, \"userName\" text);" -U postgres
+psql prest -c "create table test_categories(id serial primary key, name text, parent_id integer references test_categories(id));" -U postgres
+psql prest -c "create table test_embed_orders(id serial primary key, customer text);" -U postgres
+psql prest -c "create table test_embed_items(id serial primary key, order_id integer references test_embed_orders(id), name text);" -U postgres
+psql prest -c "create table test_tags(id serial primary key, user_id integer, tag text);" -U postgres
+psql prest -c "create table test_nicknames(id serial primary key, name text, nickname text, age integer);" -U postgres
 
 # Inserts
 psql prest -c "insert into test (name) values ('prest tester');" -U postgres
@@ -41,7 +59,45 @@ psql prest -c "insert into test_group_by_table(name, age, salary) values('maria'
 psql prest -c "insert into test_group_by_table(name, age, salary) values('gopher', 20, 100);" -U postgres
 psql prest -c "insert into test_group_by_table(name, age, salary) values('guitarra humana', 19, 3998);" -U postgres
 
+psql prest -c "insert into test_numbers(age, population, big, price, rate) values(30, 7500000000, 9007199254740993, 12.50, 0.25);" -U postgres
+psql prest -c "insert into test_decimals(amount, price) values(12345678901234567890.1234567890, 12.50);" -U postgres
+psql prest -c "insert into test_coercions(price, data, active) values(12.50, 'hello', true);" -U postgres
+psql prest -c "insert into test_affected_rows(name) values ('one'), ('two'), ('two');" -U postgres
+psql prest -c "insert into test_versioned(name) values ('prest');" -U postgres
+psql prest -c "insert into test_bulk_delete(name) values ('one'), ('two'), ('three'), ('four');" -U postgres
+psql prest -c "insert into \"Test_Order\"(\"select\", \"userName\") values ('one', 'prest'), ('two', 'nuveo');" -U postgres
+psql prest -c "insert into test_articles(title, search_vector) values ('postgres', to_tsvector('simple', 'postgres database server')), ('mysql', to_tsvector('simple', 'mysql database'));" -U postgres
+psql prest -c "insert into test_case_columns(\"userId\", name) values (1, 'prest'), (2, 'nuveo');" -U postgres
+psql prest -c "insert into test_timestamps(created_at, updated_at) values ('2023-01-01 02:00:00+00', '2023-01-01 02:00:00'), ('2023-01-01 04:00:00+00', '2023-01-01 04:00:00');" -U postgres
+
+psql prest -c "insert into test_categories(name) values('books');" -U postgres
+psql prest -c "insert into test_categories(name, parent_id) values('fantasy', 1);" -U postgres
+psql prest -c "insert into test_categories(name, parent_id) values('tolkien', 2);" -U postgres
+psql prest -c "insert into test_embed_orders(customer) values('prest'), ('nuveo');" -U postgres
+psql prest -c "insert into test_embed_items(order_id, name) values(1, 'pen'), (1, 'book');" -U postgres
+psql prest -c "insert into test_tags(user_id, tag) values(1, 'go'), (1, 'sql'), (2, 'rest');" -U postgres
+psql prest -c "insert into test_nicknames(name, nickname, age) values('Ana', 'ana', 30), ('Bruno', NULL, NULL);" -U postgres
+psql prest -c "create table test_uuid(id uuid primary key, name text);" -U postgres
+psql prest -c "create table test_defaults(id serial primary key, name text, created_at timestamptz default now());" -U postgres
+psql prest -c "create table test_truncate(id serial primary key, name text);" -U postgres
+psql prest -c "insert into test_truncate(name) values('a'), ('b');" -U postgres
+
 # Views
 psql prest -c "create table table_to_view(id serial, name text, celphone text);" -U postgres
 psql prest -c "insert into table_to_view (name, celphone) values ('gopher', '8888888')" -U postgres
 psql prest -c "create view view_test as select name as player from table_to_view" -U postgres
+
+# Materialized views
+psql prest -c "create materialized view matview_test as select id, name from test_categories" -U postgres
+psql prest -c "create unique index matview_test_id on matview_test(id)" -U postgres
+
+# Functions
+psql prest -c "create function test_add(a integer, b integer default 10) returns integer as 'select a + b' language sql" -U postgres
+psql prest -c "create function test_categories_by_parent(parent integer) returns table(id integer, name text) as 'select id, name from test_categories where parent_id = parent' language sql" -U postgres
+psql prest -c "create function test_series(start integer, stop integer default 3) returns setof integer as 'select generate_series(start, stop)' language sql" -U postgres
+psql prest -c "create function test_now() returns timestamptz as 'select now()' language sql" -U postgres
+
+# Other schemas
+psql prest -c "create schema test_search" -U postgres
+psql prest -c "create table test_search.test_search_names(name text)" -U postgres
+psql prest -c "insert into test_search.test_search_names(name) values ('tester02')" -U postgres
@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nuveo/prest/config"
+)
+
+// Operations sent in the Event of the webhooks
+const (
+	Insert = "insert"
+	Update = "update"
+	Delete = "delete"
+)
+
+var (
+	// Retries after the first failed post
+	Retries = 3
+	// Backoff is the wait before the first retry, it doubles on each retry
+	Backoff = time.Second
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Event is the JSON posted to the webhooks, Data is the response of the write: the
+// row inserted, the count of rows affected by updates and deletes, e.g.
+// {"rows_affected": 2}, or the rows updated with _changed
+type Event struct {
+	Operation string          `json:"operation"`
+	Database  string          `json:"database"`
+	Schema    string          `json:"schema"`
+	Table     string          `json:"table"`
+	Data      json.RawMessage `json:"data"`
+	Time      time.Time       `json:"time"`
+}
+
+// Dispatch post the event to the webhooks of the table in background, failures are
+// retried with backoff and logged, they don't affect the request
+func Dispatch(database, schema, table, operation string, data []byte) {
+	urls := webhookURLs(fmt.Sprintf("%s.%s", schema, table), operation)
+	if len(urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(Event{
+		Operation: operation,
+		Database:  database,
+		Schema:    schema,
+		Table:     table,
+		Data:      json.RawMessage(data),
+		Time:      time.Now().UTC(),
+	})
+	if err != nil {
+		log.Println("{webhooks}", err)
+		return
+	}
+
+	for _, url := range urls {
+		go post(url, body)
+	}
+}
+
+// webhookURLs of the table subscribed to the operation
+func webhookURLs(name, operation string) (urls []string) {
+	for _, conf := range config.PrestConf.Webhooks {
+		if conf.Name != name || !subscribed(conf.Events, operation) {
+			continue
+		}
+		urls = append(urls, conf.URLs...)
+	}
+	return
+}
+
+func subscribed(events []string, operation string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, event := range events {
+		if event == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func post(url string, body []byte) {
+	wait := Backoff
+	for attempt := 0; ; attempt++ {
+		err := send(url, body)
+		if err == nil {
+			return
+		}
+		if attempt == Retries {
+			log.Printf("{webhooks} giving up %s after %d attempts: %v\n", url, attempt+1, err)
+			return
+		}
+		log.Printf("{webhooks} %s failed, retrying in %s: %v\n", url, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func send(url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
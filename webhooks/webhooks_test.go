@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nuveo/prest/config"
+)
+
+func TestWebhookURLs(t *testing.T) {
+	config.PrestConf = &config.Prest{Webhooks: []config.WebhookConf{
+		{Name: "public.test", URLs: []string{"http://a", "http://b"}},
+		{Name: "public.test", URLs: []string{"http://c"}, Events: []string{Delete}},
+		{Name: "public.test2", URLs: []string{"http://d"}},
+	}}
+
+	var testCases = []struct {
+		description string
+		name        string
+		operation   string
+		expected    []string
+	}{
+		{"all events", "public.test", Insert, []string{"http://a", "http://b"}},
+		{"filtered events", "public.test", Delete, []string{"http://a", "http://b", "http://c"}},
+		{"other table", "public.test2", Update, []string{"http://d"}},
+		{"without webhooks", "public.test3", Insert, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		if urls := webhookURLs(tc.name, tc.operation); !reflect.DeepEqual(urls, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, urls)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var attempts int32
+	events := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail the first attempt to check the retry
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+		}
+		events <- event
+	}))
+	defer server.Close()
+
+	config.PrestConf = &config.Prest{Webhooks: []config.WebhookConf{
+		{Name: "public.test", URLs: []string{server.URL}},
+	}}
+	Backoff = time.Millisecond
+
+	Dispatch("prest", "public", "test", Insert, []byte(`{"id":1,"name":"prest"}`))
+
+	select {
+	case event := <-events:
+		if event.Operation != Insert || event.Database != "prest" || event.Schema != "public" || event.Table != "test" {
+			t.Errorf("unexpected event %+v", event)
+		}
+		if string(event.Data) != `{"id":1,"name":"prest"}` {
+			t.Errorf("unexpected data %s", event.Data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the webhook to be called")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}